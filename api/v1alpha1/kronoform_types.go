@@ -20,25 +20,33 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
-// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
-
 // KronoformSpec defines the desired state of Kronoform
 type KronoformSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
-	// The following markers will use OpenAPI v3 schema to validate the value
-	// More info: https://book.kubebuilder.io/reference/markers/crd-validation.html
+	// Manifests contains the YAML manifests to apply, as one or more documents
+	// +optional
+	Manifests string `json:"manifests,omitempty"`
+
+	// TargetNamespace specifies the namespace to apply manifests to
+	// If empty, uses the namespace from manifest or default
+	// +optional
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+
+	// SnapshotRef references the KronoformSnapshot the manifests were taken from, in the
+	// same namespace
+	// +optional
+	SnapshotRef string `json:"snapshotRef,omitempty"`
+
+	// Description provides a human-readable description of this Kronoform
+	// +optional
+	Description string `json:"description,omitempty"`
 
-	// foo is an example field of Kronoform. Edit kronoform_types.go to remove/update
+	// RequestedAt is when the Kronoform was created
 	// +optional
-	Foo *string `json:"foo,omitempty"`
+	RequestedAt *metav1.Time `json:"requestedAt,omitempty"`
 }
 
 // KronoformStatus defines the observed state of Kronoform.
 type KronoformStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
 }
 
 // +kubebuilder:object:root=true
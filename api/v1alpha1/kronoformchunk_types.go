@@ -0,0 +1,93 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ChunkOwnerUIDLabel links a chunk to the snapshot or history whose payload it holds.
+const ChunkOwnerUIDLabel = "history.yu-kod.github.io/chunk-owner-uid"
+
+// Encodings recorded in ManifestsEncoding and ResourceSnapshot.Encoding.
+const (
+	// EncodingPlain stores content as is. It is the encoding of records without a marker.
+	EncodingPlain = ""
+	// EncodingGzipBase64 stores content gzip-compressed and base64-encoded.
+	EncodingGzipBase64 = "gzip+base64"
+	// EncodingEncrypted stores content gzip-compressed, encrypted with AES-256-GCM under a
+	// data key and base64-encoded. The data key is recorded next to the content, encrypted
+	// with a key of the keyring.
+	EncodingEncrypted = "gzip+aes-gcm+base64"
+)
+
+// DataKey is the key encrypting the content of an entry, itself encrypted with a key of
+// the keyring.
+type DataKey struct {
+	// KeyID identifies the keyring key the data key is encrypted with
+	// +required
+	KeyID string `json:"keyID"`
+
+	// Key is the encrypted data key, base64-encoded
+	// +required
+	Key string `json:"key"`
+}
+
+// KronoformChunkSpec defines a part of an encoded payload that is too large for a single object
+type KronoformChunkSpec struct {
+	// Index is the position of this chunk within the payload, starting at 0
+	// +kubebuilder:validation:Minimum=0
+	// +required
+	Index int32 `json:"index"`
+
+	// Data is the part of the encoded payload held by this chunk
+	// +required
+	Data string `json:"data"`
+
+	// Part names the payload of the record held by this chunk: empty for the manifests, or
+	// states.<i>.before and states.<i>.after for the states of the i-th resource snapshot
+	// +optional
+	Part string `json:"part,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Part",type="string",JSONPath=".spec.part"
+// +kubebuilder:printcolumn:name="Index",type="integer",JSONPath=".spec.index"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// KronoformChunk holds part of the manifests of a KronoformSnapshot or KronoformHistory, or of
+// the resource states of a KronoformHistory, whose encoded size exceeds that of a single
+// object. Chunks are owned by their record.
+type KronoformChunk struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec KronoformChunkSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KronoformChunkList contains a list of KronoformChunk
+type KronoformChunkList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KronoformChunk `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KronoformChunk{}, &KronoformChunkList{})
+}
@@ -20,6 +20,133 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// ConditionDrifted reports whether a resource recorded by the history was modified
+// outside kronoform after it was applied.
+const ConditionDrifted = "Drifted"
+
+// ConditionHealthy reports whether the workloads changed by the history rolled out and
+// became ready while kubectl kronoform apply --wait waited for them. Its reason is the
+// worst outcome of their rollouts.
+const ConditionHealthy = "Healthy"
+
+// FieldManager is the field manager name kronoform uses when it applies resources.
+// The change observer skips writes made by this manager since they are already recorded.
+const FieldManager = "kronoform"
+
+// Annotations kubectl kronoform apply sets on the resources it changed, naming the history
+// that recorded their current state.
+const (
+	// AnnotationHistory is the name of the history.
+	AnnotationHistory = "history.yu-kod.github.io/history"
+	// AnnotationHistoryNamespace is the namespace of the history.
+	AnnotationHistoryNamespace = "history.yu-kod.github.io/history-namespace"
+	// AnnotationHistoryID is the short ID of the history.
+	AnnotationHistoryID = "history.yu-kod.github.io/history-id"
+	// AnnotationAppliedBy is the user who applied the change.
+	AnnotationAppliedBy = "history.yu-kod.github.io/applied-by"
+)
+
+// Sources recorded in KronoformHistorySpec.Source.
+const (
+	// HistorySourceApplied marks histories recorded by kubectl kronoform apply.
+	HistorySourceApplied = "Applied"
+	// HistorySourceObserved marks histories recorded by the change observer for
+	// modifications made without kronoform.
+	HistorySourceObserved = "Observed"
+	// HistorySourceAdmission marks histories recorded by the audit admission webhook
+	// with the identity of the requester.
+	HistorySourceAdmission = "Admission"
+)
+
+// Operations recorded in ResourceSnapshot.Operation.
+const (
+	OperationCreated   = "Created"
+	OperationUpdated   = "Updated"
+	OperationDeleted   = "Deleted"
+	OperationUnchanged = "Unchanged"
+)
+
+// Drift reasons recorded in ResourceDrift.Reason.
+const (
+	DriftReasonModified = "Modified"
+	DriftReasonDeleted  = "Deleted"
+)
+
+// Rollout outcomes recorded in WorkloadRollout.Outcome.
+const (
+	RolloutComplete = "Complete"
+	RolloutFailed   = "Failed"
+	RolloutTimedOut = "TimedOut"
+)
+
+// ArchiveReference locates a history archived in object storage
+type ArchiveReference struct {
+	// URL of the archived object (e.g., s3://bucket/cluster/namespace/2025/01/02/...)
+	URL string `json:"url"`
+
+	// SHA256 is the hex-encoded SHA-256 digest of the archived object
+	SHA256 string `json:"sha256"`
+
+	// Size is the size of the archived object in bytes
+	Size int64 `json:"size"`
+}
+
+// ChainLink places a history in the tamper-evident hash chain of its namespace
+type ChainLink struct {
+	// Sequence is the position of the history in the chain of its namespace, starting at 1
+	// +required
+	Sequence int64 `json:"sequence"`
+
+	// Hash is the hex-encoded SHA-256 digest of the recorded content of the history, its
+	// position and the hash of the previous history
+	// +required
+	Hash string `json:"hash"`
+
+	// Previous is the hash of the previous history in the chain, empty for the first one
+	// +optional
+	Previous string `json:"previous,omitempty"`
+
+	// Signature is the base64-encoded ed25519 signature of Hash, when the chain is signed
+	// +optional
+	Signature string `json:"signature,omitempty"`
+}
+
+// Provenance records where applied manifests came from: the git checkout they were read
+// from and the CI job that applied them
+type Provenance struct {
+	// Commit is the SHA of the git commit checked out in the manifest directory
+	// +optional
+	Commit string `json:"commit,omitempty"`
+
+	// Branch is the branch checked out in the manifest directory, or built by the CI job
+	// +optional
+	Branch string `json:"branch,omitempty"`
+
+	// Dirty reports whether the manifest directory had uncommitted changes
+	// +optional
+	Dirty bool `json:"dirty,omitempty"`
+
+	// Remote is the URL of the origin remote of the checkout, without credentials
+	// +optional
+	Remote string `json:"remote,omitempty"`
+
+	// CI is the CI system that ran the apply (e.g. github-actions, gitlab-ci, jenkins)
+	// +optional
+	CI string `json:"ci,omitempty"`
+
+	// JobURL links to the CI job that ran the apply
+	// +optional
+	JobURL string `json:"jobURL,omitempty"`
+
+	// RunID identifies the CI run or build that ran the apply
+	// +optional
+	RunID string `json:"runID,omitempty"`
+
+	// Pipeline is the name of the CI pipeline, workflow or job that ran the apply
+	// +optional
+	Pipeline string `json:"pipeline,omitempty"`
+}
+
 // ResourceSnapshot represents the state of a single Kubernetes resource
 type ResourceSnapshot struct {
 	// APIVersion of the resource
@@ -49,26 +176,222 @@ type ResourceSnapshot struct {
 	// After contains the resource state after applying manifests
 	// +optional
 	After string `json:"after,omitempty"`
+
+	// Encoding of Before and After: empty for plain YAML, gzip+base64 or gzip+aes-gcm+base64
+	// +kubebuilder:validation:Enum="";gzip+base64;gzip+aes-gcm+base64
+	// +optional
+	Encoding string `json:"encoding,omitempty"`
+
+	// Key is the data key of encrypted states
+	// +optional
+	Key *DataKey `json:"key,omitempty"`
+
+	// BeforeChunks is the number of KronoformChunk objects holding the encoded Before state
+	// when it is too large to be stored inline, in which case Before is empty
+	// +optional
+	BeforeChunks int32 `json:"beforeChunks,omitempty"`
+
+	// AfterChunks is the number of KronoformChunk objects holding the encoded After state
+	// when it is too large to be stored inline, in which case After is empty
+	// +optional
+	AfterChunks int32 `json:"afterChunks,omitempty"`
+}
+
+// FieldDrift describes a single field whose live value differs from the recorded state
+type FieldDrift struct {
+	// Path of the field, e.g. .spec.replicas
+	// +required
+	Path string `json:"path"`
+
+	// Expected is the value recorded after the apply ("<none>" if the field was absent)
+	// +optional
+	Expected string `json:"expected,omitempty"`
+
+	// Actual is the live value ("<none>" if the field is absent)
+	// +optional
+	Actual string `json:"actual,omitempty"`
+}
+
+// ResourceDrift describes a resource whose live state no longer matches the recorded After state
+type ResourceDrift struct {
+	// APIVersion of the resource
+	// +required
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the resource
+	// +required
+	Kind string `json:"kind"`
+
+	// Name of the resource
+	// +required
+	Name string `json:"name"`
+
+	// Namespace of the resource (empty for cluster-scoped resources)
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Reason is Modified when fields differ and Deleted when the resource no longer exists
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// DetectedAt indicates when the drift was first observed
+	// +optional
+	DetectedAt *metav1.Time `json:"detectedAt,omitempty"`
+
+	// Fields contains the field-level delta between the recorded and the live state
+	// +optional
+	Fields []FieldDrift `json:"fields,omitempty"`
+}
+
+// PodFailure describes why a pod of a workload was failing
+type PodFailure struct {
+	// Pod is the name of the pod
+	// +required
+	Pod string `json:"pod"`
+
+	// Container is the name of the failing container, empty when the pod itself fails,
+	// e.g. to be scheduled
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// Reason is a brief CamelCase reason, e.g. ImagePullBackOff, CrashLoopBackOff or Unschedulable
+	// +required
+	Reason string `json:"reason"`
+
+	// Message is a human-readable message with details about the failure
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// WorkloadRollout describes how the rollout of a workload changed by a history ended
+type WorkloadRollout struct {
+	// APIVersion of the workload
+	// +required
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the workload: Deployment, StatefulSet, DaemonSet or Job
+	// +required
+	Kind string `json:"kind"`
+
+	// Name of the workload
+	// +required
+	Name string `json:"name"`
+
+	// Namespace of the workload
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Outcome is Complete when the rollout completed, Failed when it cannot complete and
+	// TimedOut when it did not complete in time
+	// +kubebuilder:validation:Enum=Complete;Failed;TimedOut
+	// +required
+	Outcome string `json:"outcome"`
+
+	// Replicas is the number of pods desired: the replicas of Deployments and
+	// StatefulSets, the scheduled pods of DaemonSets and the completions of Jobs
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of those pods that were ready, or succeeded for Jobs
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// Message describes how far the rollout got
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// PodFailures lists why pods of the workload were failing when the rollout ended
+	// +optional
+	PodFailures []PodFailure `json:"podFailures,omitempty"`
 }
 
 // KronoformHistorySpec defines the desired state of KronoformHistory
 type KronoformHistorySpec struct {
-	// Manifests contains the original YAML manifests that were applied
-	// +required
+	// ID is a short identifier derived from the content of the history when it was
+	// recorded. Commands accept it, or a unique prefix of it, wherever a history is expected
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{12}$`
+	// +optional
+	ID string `json:"id,omitempty"`
+
+	// Manifests contains the original YAML manifests that were applied, encoded as
+	// described by ManifestsEncoding. It is empty when the manifests are stored in chunks
+	// +optional
 	Manifests string `json:"manifests"`
 
-	// SnapshotRef references the KronoformSnapshot that created this history
-	// +required
-	SnapshotRef string `json:"snapshotRef"`
+	// ManifestsEncoding is the encoding of Manifests: empty for plain YAML, gzip+base64 or
+	// gzip+aes-gcm+base64
+	// +kubebuilder:validation:Enum="";gzip+base64;gzip+aes-gcm+base64
+	// +optional
+	ManifestsEncoding string `json:"manifestsEncoding,omitempty"`
+
+	// ManifestsChunks is the number of KronoformChunk objects holding the encoded manifests
+	// when they are too large for this object
+	// +optional
+	ManifestsChunks int32 `json:"manifestsChunks,omitempty"`
+
+	// ManifestsKey is the data key of encrypted manifests
+	// +optional
+	ManifestsKey *DataKey `json:"manifestsKey,omitempty"`
+
+	// SnapshotRef references the KronoformSnapshot that created this history.
+	// It is empty for observed histories, which have no snapshot.
+	// +optional
+	SnapshotRef string `json:"snapshotRef,omitempty"`
+
+	// Source indicates how the change was recorded: Applied via kronoform, Observed
+	// by the controller manager for changes made with other tools, or Admission when
+	// recorded by the audit webhook
+	// +kubebuilder:validation:Enum=Applied;Observed;Admission
+	// +optional
+	Source string `json:"source,omitempty"`
+
+	// FieldManager is the managedFields manager that made an observed change (e.g. kubectl-edit)
+	// +optional
+	FieldManager string `json:"fieldManager,omitempty"`
 
 	// Description provides a human-readable description
 	// +optional
 	Description string `json:"description,omitempty"`
 
-	// AppliedBy indicates who/what applied the manifests
+	// Message explains why the change was made, as given by the operator who applied it
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Ticket references the issue or change request the change belongs to (e.g. OPS-1234)
+	// +optional
+	Ticket string `json:"ticket,omitempty"`
+
+	// Annotations are free-form key/value pairs the operator recorded with the change
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Provenance records the git checkout and CI job the applied manifests came from
+	// +optional
+	Provenance *Provenance `json:"provenance,omitempty"`
+
+	// AppliedBy indicates who/what applied the manifests. When webhooks are enabled it is
+	// set from the authenticated user of the create request
 	// +optional
 	AppliedBy string `json:"appliedBy,omitempty"`
 
+	// AppliedByGroups are the groups of the authenticated user that created the history
+	// +optional
+	AppliedByGroups []string `json:"appliedByGroups,omitempty"`
+
+	// AppliedByUID is the UID of the authenticated user that created the history
+	// +optional
+	AppliedByUID string `json:"appliedByUID,omitempty"`
+
+	// ClaimedBy keeps the AppliedBy value reported by the client when it was replaced
+	// with the authenticated user
+	// +optional
+	ClaimedBy string `json:"claimedBy,omitempty"`
+
+	// Archive points at the object holding the manifests and resource states of the history
+	// when they are stored outside the cluster. Manifests and states are then left empty
+	// +optional
+	Archive *ArchiveReference `json:"archive,omitempty"`
+
 	// ResourceTypes contains the list of resource types affected (e.g., ["ConfigMap", "Deployment"])
 	// +optional
 	ResourceTypes []string `json:"resourceTypes,omitempty"`
@@ -95,15 +418,39 @@ type KronoformHistoryStatus struct {
 	// Summary provides a high-level summary of changes
 	// +optional
 	Summary string `json:"summary,omitempty"`
+
+	// Drift lists resources that were modified outside kronoform since this history was applied
+	// +optional
+	Drift []ResourceDrift `json:"drift,omitempty"`
+
+	// Rollouts lists how the rollouts of the workloads changed by the history ended, when
+	// they were waited for
+	// +optional
+	Rollouts []WorkloadRollout `json:"rollouts,omitempty"`
+
+	// Conditions represent the latest available observations of the history's resources
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Chain links the history to the previous history of its namespace. It is set by the
+	// controller manager once the status is recorded and never changes afterwards
+	// +optional
+	Chain *ChainLink `json:"chain,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="ID",type="string",JSONPath=".spec.id"
 // +kubebuilder:printcolumn:name="Snapshot",type="string",JSONPath=".spec.snapshotRef"
 // +kubebuilder:printcolumn:name="Description",type="string",JSONPath=".spec.description"
+// +kubebuilder:printcolumn:name="Message",type="string",JSONPath=".spec.message"
+// +kubebuilder:printcolumn:name="Ticket",type="string",JSONPath=".spec.ticket"
 // +kubebuilder:printcolumn:name="Applied By",type="string",JSONPath=".spec.appliedBy"
+// +kubebuilder:printcolumn:name="Source",type="string",JSONPath=".spec.source"
 // +kubebuilder:printcolumn:name="Resource Types",type="string",JSONPath=".spec.resourceTypes"
 // +kubebuilder:printcolumn:name="Applied At",type="date",JSONPath=".status.appliedAt"
+// +kubebuilder:printcolumn:name="Drifted",type="string",JSONPath=".status.conditions[?(@.type==\"Drifted\")].status"
+// +kubebuilder:printcolumn:name="Healthy",type="string",JSONPath=".status.conditions[?(@.type==\"Healthy\")].status"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // KronoformHistory is the Schema for tracking the history of applied manifests
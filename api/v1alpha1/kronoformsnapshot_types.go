@@ -20,16 +20,53 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// HistoryLinkFinalizer is added to snapshots that are paired with a KronoformHistory.
+// It lets the controller delete the paired history before the snapshot goes away,
+// so neither side of the pair is left dangling.
+const HistoryLinkFinalizer = "history.yu-kod.github.io/history-link"
+
+// ConditionHistoryLinked is set once the snapshot is linked to the history referenced by
+// Status.HistoryRef.
+const ConditionHistoryLinked = "HistoryLinked"
+
 // KronoformSnapshotSpec defines the desired state of KronoformSnapshot
 type KronoformSnapshotSpec struct {
-	// Manifests contains the YAML manifests to apply
-	// +required
+	// Manifests contains the YAML manifests to apply, encoded as described by
+	// ManifestsEncoding. It is empty when the manifests are stored in chunks
+	// +optional
 	Manifests string `json:"manifests"`
 
+	// ManifestsEncoding is the encoding of Manifests: empty for plain YAML, gzip+base64 or
+	// gzip+aes-gcm+base64
+	// +kubebuilder:validation:Enum="";gzip+base64;gzip+aes-gcm+base64
+	// +optional
+	ManifestsEncoding string `json:"manifestsEncoding,omitempty"`
+
+	// ManifestsChunks is the number of KronoformChunk objects holding the encoded manifests
+	// when they are too large for this object
+	// +optional
+	ManifestsChunks int32 `json:"manifestsChunks,omitempty"`
+
+	// ManifestsKey is the data key of encrypted manifests
+	// +optional
+	ManifestsKey *DataKey `json:"manifestsKey,omitempty"`
+
 	// Description provides a human-readable description of this snapshot
 	// +optional
 	Description string `json:"description,omitempty"`
 
+	// Message explains why the manifests are applied, as given by the operator
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Ticket references the issue or change request the change belongs to (e.g. OPS-1234)
+	// +optional
+	Ticket string `json:"ticket,omitempty"`
+
+	// Annotations are free-form key/value pairs the operator recorded with the change
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
 	// DryRun performs a dry run without actually applying the manifests
 	// +optional
 	DryRun bool `json:"dryRun,omitempty"`
@@ -38,6 +75,24 @@ type KronoformSnapshotSpec struct {
 	// If empty, uses the namespace from manifest or default
 	// +optional
 	TargetNamespace string `json:"targetNamespace,omitempty"`
+
+	// AppliedBy indicates who created the snapshot. When webhooks are enabled it is
+	// set from the authenticated user of the create request
+	// +optional
+	AppliedBy string `json:"appliedBy,omitempty"`
+
+	// AppliedByGroups are the groups of the authenticated user that created the snapshot
+	// +optional
+	AppliedByGroups []string `json:"appliedByGroups,omitempty"`
+
+	// AppliedByUID is the UID of the authenticated user that created the snapshot
+	// +optional
+	AppliedByUID string `json:"appliedByUID,omitempty"`
+
+	// ClaimedBy keeps the AppliedBy value reported by the client when it was replaced
+	// with the authenticated user
+	// +optional
+	ClaimedBy string `json:"claimedBy,omitempty"`
 }
 
 // KronoformSnapshotStatus defines the observed state of KronoformSnapshot
@@ -67,6 +122,7 @@ type KronoformSnapshotStatus struct {
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
 // +kubebuilder:printcolumn:name="Description",type="string",JSONPath=".spec.description"
+// +kubebuilder:printcolumn:name="Message",type="string",JSONPath=".spec.message"
 // +kubebuilder:printcolumn:name="Applied At",type="date",JSONPath=".status.appliedAt"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
@@ -0,0 +1,273 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/yu-kod/kronoform/internal/fielddiff"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/redact"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+// Differences between a resource at a point in time and live.
+const (
+	differenceModified = "modified"
+	differenceDeleted  = "deleted"
+	differenceCreated  = "created"
+)
+
+// liveDifference is how a live resource differs from its state at a point in time.
+type liveDifference struct {
+	Key history.ResourceKey
+	// Kind of difference: modified, deleted or created since the time
+	Kind string
+	// Changes are the fields modified, from their value at the time to the live one
+	Changes []fielddiff.Change
+	// Live is the normalized, redacted live state, nil when deleted
+	Live map[string]interface{}
+}
+
+func runAt(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	output, _ := cmd.Flags().GetString("output")
+	compare, _ := cmd.Flags().GetBool("diff")
+	namespace = getTargetNamespace(namespace)
+
+	at, err := history.ParseTime(args[0], time.Now())
+	if err != nil {
+		return err
+	}
+	if output != "" && output != "yaml" {
+		return fmt.Errorf("unsupported output format %q: only yaml is supported", output)
+	}
+	if output != "" && compare {
+		return fmt.Errorf("--output and --diff cannot be used together")
+	}
+
+	// Open the history store
+	store, err := newStore(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+
+	states, err := namespaceAt(store, namespace, at)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case output == "yaml":
+		return writeBundle(os.Stdout, states)
+	case compare:
+		redactor, err := newRedactor(cmd)
+		if err != nil {
+			return err
+		}
+		k8sClient, err := createK8sClient()
+		if err != nil {
+			return fmt.Errorf("failed to create k8s client: %w", err)
+		}
+		differences, err := compareLive(context.Background(), k8sClient, redactor, states)
+		if err != nil {
+			return err
+		}
+		printLiveDifferences(os.Stdout, differences, at)
+		return nil
+	}
+	printStates(os.Stdout, states)
+	return nil
+}
+
+// namespaceAt reconstructs the state at a time of the resources recorded in a namespace.
+func namespaceAt(store storage.Store, namespace string, at time.Time) ([]history.State, error) {
+	histories, err := store.ListHistories(context.TODO(), storage.ListOptions{Namespace: namespace})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list histories: %w", err)
+	}
+	return history.StatesAt(histories, at)
+}
+
+// printStates lists the resources present at a time with the change that left them in
+// their state.
+func printStates(out io.Writer, states []history.State) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RESOURCE\tHISTORY\tCHANGED AT\tBY\tOPERATION")
+	found := false
+	for _, state := range states {
+		if !state.Present() {
+			continue
+		}
+		found = true
+		// The state of resources first recorded after the time was not left by a known change
+		if state.At == nil {
+			_, _ = fmt.Fprintf(w, "%s\t-\t\t\t\n", state.Key)
+			continue
+		}
+		h := state.At.History
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", state.Key, history.ID(h),
+			history.RecordedAt(h).Format("2006-01-02 15:04:05"), h.Spec.AppliedBy, state.At.Operation)
+	}
+	if !found {
+		_, _ = fmt.Fprintln(out, "No resources found")
+		return
+	}
+	_ = w.Flush()
+}
+
+// writeBundle writes the resources present at a time as a multi-document YAML bundle,
+// without their status and server-populated metadata. Resources whose state was recorded
+// only as an applied manifest are written as that manifest.
+func writeBundle(out io.Writer, states []history.State) error {
+	var documents []string
+	for _, state := range states {
+		if !state.Present() {
+			continue
+		}
+		obj, err := fielddiff.Parse(state.State)
+		if err != nil {
+			return fmt.Errorf("failed to read the state of %s: %w", state.Key, err)
+		}
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		documents = append(documents, string(data))
+	}
+	_, _ = fmt.Fprint(out, strings.Join(documents, "---\n"))
+	return nil
+}
+
+// compareLive compares the state of resources at a time with their live state. Live
+// resources are redacted like recorded ones so that redacted values compare.
+func compareLive(ctx context.Context, c client.Client, redactor *redact.Redactor, states []history.State) ([]liveDifference, error) {
+	var differences []liveDifference
+	for _, state := range states {
+		var past map[string]interface{}
+		if state.Present() {
+			var err error
+			if past, err = fielddiff.Parse(state.State); err != nil {
+				return nil, fmt.Errorf("failed to read the state of %s: %w", state.Key, err)
+			}
+		}
+		live, err := getLive(ctx, c, redactor, state)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case past == nil && live == nil:
+			continue
+		case live == nil:
+			differences = append(differences, liveDifference{Key: state.Key, Kind: differenceDeleted})
+		case past == nil:
+			differences = append(differences, liveDifference{Key: state.Key, Kind: differenceCreated, Live: live})
+		default:
+			changes := fielddiff.Diff(past, live)
+			if state.Partial {
+				changes = appliedChanges(past, live)
+			}
+			if len(changes) > 0 {
+				differences = append(differences, liveDifference{Key: state.Key, Kind: differenceModified, Changes: changes, Live: live})
+			}
+		}
+	}
+	return differences, nil
+}
+
+// getLive returns the normalized, redacted live state of a recorded resource, or nil if it
+// does not exist.
+func getLive(ctx context.Context, c client.Client, redactor *redact.Redactor, state history.State) (map[string]interface{}, error) {
+	gvk, err := recordedGVK(state)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	err = c.Get(ctx, client.ObjectKey{Namespace: state.Key.Namespace, Name: state.Key.Name}, obj)
+	// The API of a resource may have been removed since
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", state.Key, err)
+	}
+	redactor.Object(obj.Object)
+	return fielddiff.Normalize(obj.Object)
+}
+
+// recordedGVK returns the group, version and kind a resource was last recorded with.
+func recordedGVK(state history.State) (schema.GroupVersionKind, error) {
+	latest := state.Latest.After
+	if latest == "" {
+		latest = state.Latest.Before
+	}
+	recorded, err := fielddiff.Parse(latest)
+	if err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("failed to read the state of %s: %w", state.Key, err)
+	}
+	apiVersion, _ := recorded["apiVersion"].(string)
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil || apiVersion == "" {
+		return schema.GroupVersionKind{}, fmt.Errorf("failed to read the API version of %s", state.Key)
+	}
+	return gv.WithKind(state.Key.Kind), nil
+}
+
+// appliedChanges returns the fields of an applied manifest whose live value differs. The
+// other live fields were not set by the manifest, e.g. defaults.
+func appliedChanges(applied, live map[string]interface{}) []fielddiff.Change {
+	var changes []fielddiff.Change
+	for _, f := range fielddiff.Fields(applied) {
+		value, _, _ := fielddiff.Lookup(live, f.Path)
+		if !reflect.DeepEqual(f.Value, value) {
+			changes = append(changes, fielddiff.Change{Path: f.Path, Before: f.Value, After: value})
+		}
+	}
+	return changes
+}
+
+// printLiveDifferences prints how live resources differ from their state at a time.
+func printLiveDifferences(out io.Writer, differences []liveDifference, at time.Time) {
+	if len(differences) == 0 {
+		_, _ = fmt.Fprintf(out, "Recorded resources match their state at %s\n", at.Format(time.RFC3339))
+		return
+	}
+	_, _ = fmt.Fprintf(out, "Changes since %s:\n", at.Format(time.RFC3339))
+	for _, d := range differences {
+		_, _ = fmt.Fprintf(out, "  %-9s %s\n", d.Kind, d.Key)
+		for _, change := range d.Changes {
+			_, _ = fmt.Fprintf(out, "    %s: %s\n", change.Path, formatChange(change.Before, change.After))
+		}
+	}
+}
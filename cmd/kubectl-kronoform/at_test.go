@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func TestNamespaceAt(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+
+	base := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	record := func(name string, at time.Time, manifests string, resources ...historyv1alpha1.ResourceSnapshot) {
+		appliedAt := metav1.NewTime(at)
+		h := &historyv1alpha1.KronoformHistory{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       historyv1alpha1.KronoformHistorySpec{AppliedBy: "alice", Manifests: manifests},
+			Status:     historyv1alpha1.KronoformHistoryStatus{AppliedAt: &appliedAt, ResourceSnapshots: resources},
+		}
+		g.Expect(store.SaveHistory(ctx, h)).To(gomega.Succeed())
+	}
+	configMap := func(name, color string) string {
+		return "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + name + "\n  namespace: default\n" +
+			"  resourceVersion: \"42\"\ndata:\n  color: " + color + "\n"
+	}
+	record("create-settings", base, "", historyv1alpha1.ResourceSnapshot{
+		APIVersion: "v1", Kind: "ConfigMap", Name: "settings", Namespace: "default",
+		Operation: "Created", After: configMap("settings", "blue"),
+	})
+	record("apply-theme", base.Add(time.Hour),
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: theme\ndata:\n  color: green\n")
+	record("update-settings", base.Add(2*time.Hour), "", historyv1alpha1.ResourceSnapshot{
+		APIVersion: "v1", Kind: "ConfigMap", Name: "settings", Namespace: "default",
+		Operation: "Updated", Before: configMap("settings", "blue"), After: configMap("settings", "red"),
+	})
+	record("create-extra", base.Add(3*time.Hour), "", historyv1alpha1.ResourceSnapshot{
+		APIVersion: "v1", Kind: "ConfigMap", Name: "extra", Namespace: "default",
+		Operation: "Created", After: configMap("extra", "white"),
+	})
+
+	states, err := namespaceAt(store, "default", base.Add(90*time.Minute))
+	g.Expect(err).To(gomega.BeNil())
+
+	var out bytes.Buffer
+	printStates(&out, states)
+	g.Expect(out.String()).To(gomega.ContainSubstring("ConfigMap/default/settings"))
+	g.Expect(out.String()).To(gomega.ContainSubstring("ConfigMap/default/theme"))
+	g.Expect(out.String()).NotTo(gomega.ContainSubstring("extra"))
+
+	out.Reset()
+	g.Expect(writeBundle(&out, states)).To(gomega.Succeed())
+	g.Expect(out.String()).To(gomega.Equal("apiVersion: v1\ndata:\n  color: blue\nkind: ConfigMap\nmetadata:\n" +
+		"  name: settings\n  namespace: default\n---\napiVersion: v1\ndata:\n  color: green\nkind: ConfigMap\n" +
+		"metadata:\n  name: theme\n"))
+
+	// settings was changed since, theme still has the applied color, extra was created since
+	k8sClient := newWhyClient(t,
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default", Annotations: map[string]string{
+				historyv1alpha1.AnnotationHistory: "update-settings",
+			}},
+			Data: map[string]string{"color": "red"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "theme", Namespace: "default", Labels: map[string]string{"team": "web"}},
+			Data:       map[string]string{"color": "green"},
+		},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "extra", Namespace: "default"}},
+	)
+	differences, err := compareLive(ctx, k8sClient, nil, states)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(differences).To(gomega.HaveLen(2))
+	g.Expect(differences[0].Key.Name).To(gomega.Equal("extra"))
+	g.Expect(differences[0].Kind).To(gomega.Equal(differenceCreated))
+	g.Expect(differences[1].Key.Name).To(gomega.Equal("settings"))
+	g.Expect(differences[1].Kind).To(gomega.Equal(differenceModified))
+
+	out.Reset()
+	printLiveDifferences(&out, differences, base.Add(90*time.Minute))
+	g.Expect(out.String()).To(gomega.Equal("Changes since 2025-08-06T11:30:00Z:\n" +
+		"  created   ConfigMap/default/extra\n" +
+		"  modified  ConfigMap/default/settings\n" +
+		"    .data.color: blue -> red\n"))
+
+	// the resources were all removed outside of the recorded histories
+	states, err = namespaceAt(store, "default", base.Add(4*time.Hour))
+	g.Expect(err).To(gomega.BeNil())
+	differences, err = compareLive(ctx, newWhyClient(t), nil, states)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(differences).To(gomega.HaveLen(3))
+	for _, d := range differences {
+		g.Expect(d.Kind).To(gomega.Equal(differenceDeleted))
+	}
+
+	out.Reset()
+	printStates(&out, []history.State{})
+	g.Expect(out.String()).To(gomega.Equal("No resources found\n"))
+}
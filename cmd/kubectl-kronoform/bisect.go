@@ -0,0 +1,132 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yu-kod/kronoform/internal/fielddiff"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/redact"
+)
+
+func runBisect(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	path, _ := cmd.Flags().GetString("path")
+	namespace = getTargetNamespace(namespace)
+
+	var equals *string
+	if cmd.Flags().Changed("equals") {
+		value, _ := cmd.Flags().GetString("equals")
+		equals = &value
+	}
+	matches, _ := cmd.Flags().GetString("matches")
+	match, err := bisectPredicate(equals, matches)
+	if err != nil {
+		return err
+	}
+	if _, _, err := fielddiff.Lookup(nil, path); err != nil {
+		return err
+	}
+
+	// Open the history store
+	store, err := newStore(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+
+	entries, err := resourceTimeline(store, namespace, args[0])
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no history recorded %s in namespace %s", args[0], namespace)
+	}
+	bisection, err := history.Bisect(entries, path, match)
+	if err != nil {
+		return err
+	}
+	printBisection(os.Stdout, args[0], path, bisection)
+	return nil
+}
+
+// bisectPredicate returns the predicate values must match, from the formatted value they
+// must equal or a regular expression they must match. It returns nil for neither.
+func bisectPredicate(equals *string, matches string) (func(interface{}) bool, error) {
+	switch {
+	case equals != nil && matches != "":
+		return nil, fmt.Errorf("--equals and --matches cannot be used together")
+	case equals != nil:
+		expected := *equals
+		return func(value interface{}) bool {
+			return value != nil && fielddiff.FormatValue(value) == expected
+		}, nil
+	case matches != "":
+		re, err := regexp.Compile(matches)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --matches: %w", err)
+		}
+		return func(value interface{}) bool {
+			return value != nil && re.MatchString(fielddiff.FormatValue(value))
+		}, nil
+	}
+	return nil, nil
+}
+
+// printBisection prints the history responsible for a change of a field, the change of
+// the field and the other fields the history changed in the resource.
+func printBisection(out io.Writer, resource, path string, bisection *history.Bisection) {
+	if bisection == nil {
+		_, _ = fmt.Fprintf(out, "No history of %s changed %s as requested\n", resource, path)
+		return
+	}
+	entry := bisection.Entry
+	printHeader(out, entry.History)
+	_, _ = fmt.Fprintln(out)
+	printField(out, "Resource", fmt.Sprintf("%s (%s)", entry.Key, entry.Operation))
+	printField(out, "Changed", path+": "+formatChange(bisection.Before, bisection.After))
+
+	before, err := fielddiff.Parse(entry.Before)
+	if err != nil {
+		return
+	}
+	after, err := fielddiff.Parse(entry.After)
+	if err != nil {
+		return
+	}
+	changes := fielddiff.Diff(before, after)
+	if len(changes) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintln(out, "Diff:")
+	for _, change := range changes {
+		_, _ = fmt.Fprintf(out, "  %s: %s\n", change.Path, formatChange(change.Before, change.After))
+	}
+}
+
+// formatChange renders the change of a value, hiding redacted values.
+func formatChange(before, after interface{}) string {
+	if redact.IsRedacted(before) || redact.IsRedacted(after) {
+		return redact.Changed
+	}
+	return fielddiff.FormatValue(before) + " -> " + fielddiff.FormatValue(after)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+)
+
+func TestBisectPredicate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	match, err := bisectPredicate(nil, "")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(match).To(gomega.BeNil())
+
+	five := "5"
+	match, err = bisectPredicate(&five, "")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(match(float64(5))).To(gomega.BeTrue())
+	g.Expect(match("5")).To(gomega.BeTrue())
+	g.Expect(match(nil)).To(gomega.BeFalse())
+
+	match, err = bisectPredicate(nil, `^nginx:1\.2[0-9]$`)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(match("nginx:1.25")).To(gomega.BeTrue())
+	g.Expect(match("nginx:1.19")).To(gomega.BeFalse())
+
+	_, err = bisectPredicate(&five, "5")
+	g.Expect(err).NotTo(gomega.BeNil())
+	_, err = bisectPredicate(nil, "(")
+	g.Expect(err).NotTo(gomega.BeNil())
+}
+
+func TestPrintBisection(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	appliedAt := metav1.NewTime(time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC))
+	h := &historyv1alpha1.KronoformHistory{
+		ObjectMeta: metav1.ObjectMeta{Name: "bump-web", Namespace: "default"},
+		Spec:       historyv1alpha1.KronoformHistorySpec{AppliedBy: "alice", Message: "Bump nginx"},
+		Status:     historyv1alpha1.KronoformHistoryStatus{AppliedAt: &appliedAt},
+	}
+	entry := &history.Entry{
+		History:   h,
+		Key:       history.ResourceKey{Group: "apps", Kind: "Deployment", Namespace: "default", Name: "web"},
+		Operation: "Updated",
+		Before:    "spec:\n  replicas: 2\n  image: nginx:1.21\n",
+		After:     "spec:\n  replicas: 3\n  image: nginx:1.25\n",
+	}
+
+	var out bytes.Buffer
+	printBisection(&out, "deployment.apps/web", ".spec.image", &history.Bisection{Entry: entry, Before: "nginx:1.21", After: "nginx:1.25"})
+	g.Expect(out.String()).To(gomega.ContainSubstring("Author:      alice\n"))
+	g.Expect(out.String()).To(gomega.ContainSubstring("    Bump nginx\n"))
+	g.Expect(out.String()).To(gomega.ContainSubstring("Resource:    Deployment.apps/default/web (Updated)\n"))
+	g.Expect(out.String()).To(gomega.ContainSubstring("Changed:     .spec.image: nginx:1.21 -> nginx:1.25\n"))
+	g.Expect(out.String()).To(gomega.HaveSuffix("Diff:\n  .spec.image: nginx:1.21 -> nginx:1.25\n  .spec.replicas: 2 -> 3\n"))
+
+	out.Reset()
+	printBisection(&out, "deployment.apps/web", ".spec.paused", nil)
+	g.Expect(out.String()).To(gomega.Equal("No history of deployment.apps/web changed .spec.paused as requested\n"))
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yu-kod/kronoform/internal/fielddiff"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func runResourceHistory(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	namespace = getTargetNamespace(namespace)
+
+	// Open the history store
+	store, err := newStore(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+
+	entries, err := resourceTimeline(store, namespace, args[0])
+	if err != nil {
+		return err
+	}
+	printResourceHistory(os.Stdout, entries)
+	return nil
+}
+
+// resourceTimeline returns the changes of a resource given as kind[.group]/name recorded
+// in a namespace, oldest first.
+func resourceTimeline(store storage.Store, namespace, resource string) ([]history.Entry, error) {
+	histories, err := store.ListHistories(context.TODO(), storage.ListOptions{Namespace: namespace})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list histories: %w", err)
+	}
+	return history.Timeline(histories, scopeResource(resource, namespace))
+}
+
+// scopeResource places a resource given as kind[.group]/name in a namespace.
+func scopeResource(resource, namespace string) string {
+	if strings.Count(resource, "/") != 1 {
+		return resource
+	}
+	kind, name, _ := strings.Cut(resource, "/")
+	return kind + "/" + namespace + "/" + name
+}
+
+// printResourceHistory prints the changes of a resource, oldest first.
+func printResourceHistory(out io.Writer, entries []history.Entry) {
+	if len(entries) == 0 {
+		_, _ = fmt.Fprintln(out, "No histories found")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tAPPLIED AT\tBY\tOPERATION\tMESSAGE")
+	for _, entry := range entries {
+		h := entry.History
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", history.ID(h),
+			history.RecordedAt(h).Format("2006-01-02 15:04:05"), h.Spec.AppliedBy, entry.Operation, changeSubject(h))
+	}
+	_ = w.Flush()
+}
+
+func runBlame(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	namespace = getTargetNamespace(namespace)
+
+	// Open the history store
+	store, err := newStore(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+
+	entries, err := resourceTimeline(store, namespace, args[0])
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no history recorded %s in namespace %s", args[0], namespace)
+	}
+	lines, err := history.Blame(entries)
+	if err != nil {
+		return err
+	}
+	printBlame(os.Stdout, lines)
+	return nil
+}
+
+// printBlame prints each field of a resource after the change that last set it, like git
+// blame. Fields changed without being recorded are shown with a dash.
+func printBlame(out io.Writer, lines []history.BlameLine) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, line := range lines {
+		if line.Entry == nil {
+			_, _ = fmt.Fprintf(w, "-\t\t\t\t%s: %s\n", line.Path, fielddiff.FormatValue(line.Value))
+			continue
+		}
+		h := line.Entry.History
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s: %s\n", history.ID(h), h.Spec.AppliedBy,
+			history.RecordedAt(h).Format("2006-01-02 15:04:05"), truncate(changeSubject(h), 40),
+			line.Path, fielddiff.FormatValue(line.Value))
+	}
+	_ = w.Flush()
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func TestResourceHistoryAndBlame(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+
+	base := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	record := func(name, user, message string, at time.Time, manifests string, resources ...historyv1alpha1.ResourceSnapshot) {
+		appliedAt := metav1.NewTime(at)
+		h := &historyv1alpha1.KronoformHistory{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       historyv1alpha1.KronoformHistorySpec{AppliedBy: user, Message: message, Manifests: manifests},
+			Status: historyv1alpha1.KronoformHistoryStatus{
+				AppliedAt:         &appliedAt,
+				ResourceSnapshots: resources,
+			},
+		}
+		g.Expect(history.AssignID(h)).To(gomega.Succeed())
+		g.Expect(store.SaveHistory(ctx, h)).To(gomega.Succeed())
+	}
+	record("create-web", "alice", "Deploy web", base,
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 2\n  paused: false\n")
+	record("scale-web", "bob", "Scale web for the launch", base.Add(time.Hour), "",
+		historyv1alpha1.ResourceSnapshot{
+			APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "default", Operation: "Updated",
+			Before: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: default\nspec:\n  replicas: 2\n  paused: false\n",
+			After:  "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: default\nspec:\n  replicas: 5\n  paused: false\n",
+		})
+	record("create-config", "alice", "Add settings", base.Add(2*time.Hour),
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  color: blue\n")
+
+	entries, err := resourceTimeline(store, "default", "deployment.apps/web")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(entries).To(gomega.HaveLen(2))
+
+	var out bytes.Buffer
+	printResourceHistory(&out, entries)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	g.Expect(lines).To(gomega.HaveLen(3))
+	g.Expect(lines[1]).To(gomega.ContainSubstring("alice"))
+	g.Expect(lines[1]).To(gomega.ContainSubstring("Applied"))
+	g.Expect(lines[1]).To(gomega.ContainSubstring("Deploy web"))
+	g.Expect(lines[2]).To(gomega.ContainSubstring("Updated"))
+	g.Expect(lines[2]).To(gomega.ContainSubstring("2025-08-06 11:00:00"))
+
+	blame, err := history.Blame(entries)
+	g.Expect(err).To(gomega.BeNil())
+	out.Reset()
+	printBlame(&out, blame)
+	byPath := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		fields := strings.Fields(line)
+		byPath[fields[len(fields)-2]] = line
+	}
+	g.Expect(byPath[".spec.replicas:"]).To(gomega.HavePrefix(history.ID(mustGetHistory(g, store, "scale-web"))))
+	g.Expect(byPath[".spec.replicas:"]).To(gomega.ContainSubstring("bob"))
+	g.Expect(byPath[".spec.replicas:"]).To(gomega.HaveSuffix(": 5"))
+	g.Expect(byPath[".spec.paused:"]).To(gomega.ContainSubstring("alice"))
+	// the namespace was not in the applied manifest
+	g.Expect(byPath[".metadata.namespace:"]).To(gomega.HavePrefix("-"))
+
+	entries, err = resourceTimeline(store, "default", "deployment.apps/missing")
+	g.Expect(err).To(gomega.BeNil())
+	out.Reset()
+	printResourceHistory(&out, entries)
+	g.Expect(out.String()).To(gomega.Equal("No histories found\n"))
+}
+
+func mustGetHistory(g *gomega.WithT, store storage.Store, name string) *historyv1alpha1.KronoformHistory {
+	h, err := store.GetHistory(context.Background(), "default", name)
+	g.Expect(err).To(gomega.BeNil())
+	return h
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+// changeContext is what is recorded about why a change is made and where it comes from.
+type changeContext struct {
+	Message     string
+	Ticket      string
+	Annotations map[string]string
+	Provenance  *historyv1alpha1.Provenance
+}
+
+// addChangeFlags adds the flags that record why a change is made to a command that
+// records changes.
+func addChangeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("message", "m", "", "Message explaining why the change is made, recorded with it")
+	cmd.Flags().String("ticket", "", "Issue or change request the change belongs to, e.g. OPS-1234")
+	cmd.Flags().StringArray("annotation", nil, "Annotation recorded with the change as key=value (can be repeated)")
+}
+
+// getChangeContext reads the flags added by addChangeFlags.
+func getChangeContext(cmd *cobra.Command) (changeContext, error) {
+	message, _ := cmd.Flags().GetString("message")
+	ticket, _ := cmd.Flags().GetString("ticket")
+	annotations, _ := cmd.Flags().GetStringArray("annotation")
+
+	change := changeContext{Message: strings.TrimSpace(message), Ticket: strings.TrimSpace(ticket)}
+	for _, annotation := range annotations {
+		key, value, ok := strings.Cut(annotation, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return changeContext{}, fmt.Errorf("invalid annotation %q: expected key=value", annotation)
+		}
+		if change.Annotations == nil {
+			change.Annotations = map[string]string{}
+		}
+		change.Annotations[key] = value
+	}
+	return change, nil
+}
+
+// messageRequired reports whether a record was rejected because the policy of its
+// namespace requires a message.
+func messageRequired(err error) bool {
+	return apierrors.IsInvalid(err) && strings.Contains(err.Error(), "spec.message")
+}
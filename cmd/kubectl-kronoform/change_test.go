@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestGetChangeContext(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	cmd := &cobra.Command{}
+	addChangeFlags(cmd)
+	g.Expect(cmd.ParseFlags([]string{
+		"-m", " Scale up for the launch ", "--ticket", "OPS-1234",
+		"--annotation", "team=web", "--annotation", "pipeline=deploy=prod",
+	})).To(gomega.Succeed())
+	change, err := getChangeContext(cmd)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(change).To(gomega.Equal(changeContext{
+		Message:     "Scale up for the launch",
+		Ticket:      "OPS-1234",
+		Annotations: map[string]string{"team": "web", "pipeline": "deploy=prod"},
+	}))
+
+	cmd = &cobra.Command{}
+	addChangeFlags(cmd)
+	g.Expect(cmd.ParseFlags([]string{"--annotation", "team"})).To(gomega.Succeed())
+	_, err = getChangeContext(cmd)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("expected key=value")))
+}
+
+func TestMessageRequired(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	gk := schema.GroupKind{Group: "history.yu-kod.github.io", Kind: "KronoformSnapshot"}
+
+	g.Expect(messageRequired(apierrors.NewInvalid(gk, "snapshot", field.ErrorList{
+		field.Required(field.NewPath("spec", "message"), "changes applied to namespace prod must explain why they are made"),
+	}))).To(gomega.BeTrue())
+	g.Expect(messageRequired(apierrors.NewInvalid(gk, "snapshot", field.ErrorList{
+		field.TooLong(field.NewPath("spec", "manifests"), "", 10),
+	}))).To(gomega.BeFalse())
+	g.Expect(messageRequired(nil)).To(gomega.BeFalse())
+	g.Expect(messageRequired(errors.New("connection refused"))).To(gomega.BeFalse())
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/yu-kod/kronoform/internal/envelope"
+	"github.com/yu-kod/kronoform/internal/redact"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+// configEnv names the environment variable pointing at the configuration file.
+const configEnv = "KRONOFORM_CONFIG"
+
+// pluginConfig is the configuration file of the plugin, by default ~/.kronoform/config.yaml:
+//
+//	storage:
+//	  backend: directory
+//	  path: /var/lib/kronoform
+//	redaction:
+//	  rules:
+//	  - kind: Deployment.apps
+//	    path: $..env[?(@.name=~"PASSWORD")].value
+//	encryption:
+//	  secret: kronoform-system/kronoform-keyring
+type pluginConfig struct {
+	Storage    storage.Config  `json:"storage,omitempty"`
+	Redaction  redact.Config   `json:"redaction,omitempty"`
+	Encryption envelope.Config `json:"encryption,omitempty"`
+}
+
+// addConfigFlags adds the flags that select the configuration and history store.
+func addConfigFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("config", "",
+		fmt.Sprintf("Path to the configuration file (default $%s or ~/.kronoform/config.yaml)", configEnv))
+	cmd.PersistentFlags().String("storage", "",
+		fmt.Sprintf("History storage backend, one of %v (default %q)", storage.Backends(), storage.BackendCRD))
+	cmd.PersistentFlags().String("storage-path", "", "Directory used by the directory storage backend")
+	cmd.PersistentFlags().String("encryption-key-file", "",
+		"Keyring file used to encrypt new records and decrypt stored ones, instead of the configured keyring")
+}
+
+// loadConfig reads the configuration file. A missing default file is not an error; a
+// file that was asked for explicitly must exist.
+func loadConfig(path string) (*pluginConfig, error) {
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv(configEnv)
+		explicit = path != ""
+	}
+	if !explicit {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return &pluginConfig{}, nil
+		}
+		path = filepath.Join(home, ".kronoform", "config.yaml")
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 - the configuration file is chosen by the user
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return &pluginConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	cfg := &pluginConfig{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// storageConfig returns the storage configuration for a command: the configuration file
+// with the storage flags applied on top.
+func storageConfig(cmd *cobra.Command) (storage.Config, error) {
+	path, _ := cmd.Flags().GetString("config")
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return storage.Config{}, err
+	}
+	if cmd.Flags().Changed("storage") {
+		cfg.Storage.Backend, _ = cmd.Flags().GetString("storage")
+	}
+	if cmd.Flags().Changed("storage-path") {
+		cfg.Storage.Path, _ = cmd.Flags().GetString("storage-path")
+	}
+	cfg.Storage.Client = createK8sClient
+	return cfg.Storage, nil
+}
+
+// newRedactor returns the redaction of recorded manifests configured for a command. Its
+// digests are keyed with the keyring, if one is configured, like those of the manager.
+func newRedactor(cmd *cobra.Command) (*redact.Redactor, error) {
+	path, _ := cmd.Flags().GetString("config")
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := redact.New(cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+	keyring, err := newKeyring(cmd)
+	if err != nil || keyring == nil {
+		return redactor, err
+	}
+	return redactor.WithKey(keyring.DigestKey()), nil
+}
+
+// newKeyring returns the keyring configured for a command: the --encryption-key-file
+// flag, or the encryption section of the configuration file. It returns nil if records
+// are not encrypted.
+func newKeyring(cmd *cobra.Command) (*envelope.Keyring, error) {
+	path, _ := cmd.Flags().GetString("config")
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Flags().Changed("encryption-key-file") {
+		keyFile, _ := cmd.Flags().GetString("encryption-key-file")
+		cfg.Encryption = envelope.Config{KeyFile: keyFile}
+	}
+	// Only a keyring kept in a Secret needs the cluster
+	var reader client.Reader
+	if cfg.Encryption.Secret != "" {
+		if reader, err = createK8sClient(); err != nil {
+			return nil, fmt.Errorf("failed to create k8s client: %w", err)
+		}
+	}
+	return envelope.Load(context.Background(), cfg.Encryption, reader)
+}
+
+// newStore opens the history store selected for a command.
+func newStore(cmd *cobra.Command) (storage.Store, error) {
+	cfg, err := storageConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Keyring, err = newKeyring(cmd); err != nil {
+		return nil, err
+	}
+	return storage.New(cfg)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func TestStorageConfig(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(configEnv, "")
+
+	newCommand := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{Use: "test", RunE: func(*cobra.Command, []string) error { return nil }}
+		addConfigFlags(cmd)
+		g.Expect(cmd.ParseFlags(args)).To(gomega.Succeed())
+		return cmd
+	}
+
+	// Without a configuration file the CRD backend is used
+	cfg, err := storageConfig(newCommand())
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(cfg.Backend).To(gomega.BeEmpty())
+	g.Expect(cfg.Client).NotTo(gomega.BeNil())
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	g.Expect(os.WriteFile(path, []byte("storage:\n  backend: directory\n  path: /srv/kronoform\n"), 0o600)).To(gomega.Succeed())
+
+	cfg, err = storageConfig(newCommand("--config", path))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(cfg.Backend).To(gomega.Equal(storage.BackendDirectory))
+	g.Expect(cfg.Path).To(gomega.Equal("/srv/kronoform"))
+
+	// The environment selects the file and flags override it
+	t.Setenv(configEnv, path)
+	cfg, err = storageConfig(newCommand("--storage-path", "/tmp/records"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(cfg.Backend).To(gomega.Equal(storage.BackendDirectory))
+	g.Expect(cfg.Path).To(gomega.Equal("/tmp/records"))
+
+	cfg, err = storageConfig(newCommand("--storage", "memory"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(cfg.Backend).To(gomega.Equal(storage.BackendMemory))
+
+	_, err = storageConfig(newCommand("--config", filepath.Join(t.TempDir(), "missing.yaml")))
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("failed to read config")))
+
+	g.Expect(os.WriteFile(path, []byte("storage:\n  bucket: records\n"), 0o600)).To(gomega.Succeed())
+	_, err = storageConfig(newCommand())
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("invalid config")))
+}
+
+func TestRedactManifests(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(configEnv, "")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	g.Expect(os.WriteFile(path, []byte(`redaction:
+  rules:
+  - kind: ConfigMap
+    path: .data.token
+`), 0o600)).To(gomega.Succeed())
+	cmd := &cobra.Command{Use: "test", RunE: func(*cobra.Command, []string) error { return nil }}
+	addConfigFlags(cmd)
+	g.Expect(cmd.ParseFlags([]string{"--config", path})).To(gomega.Succeed())
+
+	manifests := "apiVersion: v1\nkind: Secret\nstringData:\n  password: hunter2\n---\n" +
+		"apiVersion: v1\nkind: ConfigMap\ndata:\n  token: abc123\n  mode: fast\n"
+	recorded, err := redactManifests(cmd, manifests)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(recorded).NotTo(gomega.ContainSubstring("hunter2"))
+	g.Expect(recorded).NotTo(gomega.ContainSubstring("abc123"))
+	g.Expect(recorded).To(gomega.ContainSubstring("mode: fast"))
+}
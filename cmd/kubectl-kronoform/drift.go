@@ -0,0 +1,131 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/redact"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+// driftedResource is a drift entry together with the history that recorded it.
+type driftedResource struct {
+	historyv1alpha1.ResourceDrift
+	History string
+}
+
+func runDrift(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	allNamespaces, _ := cmd.Flags().GetBool("all-namespaces")
+	if allNamespaces {
+		namespace = ""
+	} else {
+		namespace = getTargetNamespace(namespace)
+	}
+
+	// Open the history store
+	store, err := newStore(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+
+	drifted, err := listDrift(store, namespace)
+	if err != nil {
+		return err
+	}
+
+	printDrift(os.Stdout, drifted)
+	return nil
+}
+
+// listDrift returns the drifted resources in a namespace (all namespaces if empty).
+// Histories are stored in the namespace given at apply time, which may differ from the
+// namespace of the resources they recorded, so all histories are searched. Only the
+// latest history of each resource is considered.
+func listDrift(store storage.Store, namespace string) ([]driftedResource, error) {
+	histories, err := store.ListHistories(context.TODO(), storage.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list histories: %w", err)
+	}
+
+	var drifted []driftedResource
+	for i := range histories {
+		h := &histories[i]
+		for _, entry := range h.Status.Drift {
+			if namespace != "" && entry.Namespace != namespace {
+				continue
+			}
+			key := history.KeyOf(historyv1alpha1.ResourceSnapshot{
+				APIVersion: entry.APIVersion,
+				Kind:       entry.Kind,
+				Name:       entry.Name,
+				Namespace:  entry.Namespace,
+			})
+			if latest := history.Latest(histories, key); latest != nil && latest.UID != h.UID {
+				continue
+			}
+			drifted = append(drifted, driftedResource{ResourceDrift: entry, History: h.Name})
+		}
+	}
+
+	sort.Slice(drifted, func(i, j int) bool {
+		if drifted[i].Namespace != drifted[j].Namespace {
+			return drifted[i].Namespace < drifted[j].Namespace
+		}
+		if drifted[i].Kind != drifted[j].Kind {
+			return drifted[i].Kind < drifted[j].Kind
+		}
+		return drifted[i].Name < drifted[j].Name
+	})
+	return drifted, nil
+}
+
+// printDrift prints drifted resources grouped by namespace, followed by their field deltas.
+func printDrift(out io.Writer, drifted []driftedResource) {
+	if len(drifted) == 0 {
+		_, _ = fmt.Fprintln(out, "No drifted resources found")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAMESPACE\tRESOURCE\tREASON\tHISTORY\tDETECTED")
+	for _, d := range drifted {
+		detected := ""
+		if d.DetectedAt != nil {
+			detected = d.DetectedAt.Format("2006-01-02 15:04:05")
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s/%s\t%s\t%s\t%s\n", d.Namespace, d.Kind, d.Name, d.Reason, d.History, detected)
+		for _, field := range d.Fields {
+			if redact.IsRedacted(field.Expected) || redact.IsRedacted(field.Actual) {
+				_, _ = fmt.Fprintf(w, "\t  %s: %s\t\t\t\n", field.Path, redact.Changed)
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "\t  %s: %s -> %s\t\t\t\n", field.Path, field.Expected, field.Actual)
+		}
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/redact"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func TestListDrift(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	scheme := runtime.NewScheme()
+	if err := historyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add scheme: %v", err)
+	}
+
+	web := historyv1alpha1.ResourceSnapshot{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "prod"}
+	config := historyv1alpha1.ResourceSnapshot{APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "staging"}
+	drift := func(rs historyv1alpha1.ResourceSnapshot) historyv1alpha1.ResourceDrift {
+		return historyv1alpha1.ResourceDrift{
+			APIVersion: rs.APIVersion,
+			Kind:       rs.Kind,
+			Name:       rs.Name,
+			Namespace:  rs.Namespace,
+			Reason:     historyv1alpha1.DriftReasonModified,
+			Fields:     []historyv1alpha1.FieldDrift{{Path: ".spec.replicas", Expected: "2", Actual: "1"}},
+		}
+	}
+
+	older := metav1.NewTime(time.Now().Add(-time.Hour))
+	newer := metav1.Now()
+	histories := []historyv1alpha1.KronoformHistory{
+		{
+			// Superseded by "latest" for the web Deployment; its stale entry is ignored
+			ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: "default", UID: types.UID("stale")},
+			Status: historyv1alpha1.KronoformHistoryStatus{
+				AppliedAt:         &older,
+				ResourceSnapshots: []historyv1alpha1.ResourceSnapshot{web},
+				Drift:             []historyv1alpha1.ResourceDrift{drift(web)},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "latest", Namespace: "default", UID: types.UID("latest")},
+			Status: historyv1alpha1.KronoformHistoryStatus{
+				AppliedAt:         &newer,
+				ResourceSnapshots: []historyv1alpha1.ResourceSnapshot{web, config},
+				Drift:             []historyv1alpha1.ResourceDrift{drift(web), drift(config)},
+			},
+		},
+	}
+
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	for i := range histories {
+		builder = builder.WithObjects(&histories[i])
+	}
+	store := storage.NewCRDStore(builder.Build())
+
+	drifted, err := listDrift(store, "prod")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(drifted).To(gomega.HaveLen(1))
+	g.Expect(drifted[0].History).To(gomega.Equal("latest"))
+	g.Expect(drifted[0].Name).To(gomega.Equal("web"))
+
+	drifted, err = listDrift(store, "")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(drifted).To(gomega.HaveLen(2))
+
+	var out bytes.Buffer
+	printDrift(&out, drifted)
+	g.Expect(out.String()).To(gomega.ContainSubstring("Deployment/web"))
+	g.Expect(out.String()).To(gomega.ContainSubstring(".spec.replicas: 2 -> 1"))
+}
+
+func TestPrintDriftRedacted(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	var out bytes.Buffer
+	var redactor *redact.Redactor
+	printDrift(&out, []driftedResource{{
+		ResourceDrift: historyv1alpha1.ResourceDrift{
+			Kind:      "Secret",
+			Name:      "db",
+			Namespace: "prod",
+			Reason:    historyv1alpha1.DriftReasonModified,
+			Fields: []historyv1alpha1.FieldDrift{
+				{Path: ".data.password", Expected: redactor.Value("hunter2"), Actual: redactor.Value("hunter3")},
+			},
+		},
+		History: "latest",
+	}})
+	g.Expect(out.String()).To(gomega.ContainSubstring(".data.password: <changed (redacted)>"))
+	g.Expect(out.String()).NotTo(gomega.ContainSubstring(redact.Prefix))
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/yu-kod/kronoform/internal/gitexport"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func runExport(cmd *cobra.Command, args []string) error {
+	repo, _ := cmd.Flags().GetString("git")
+	namespace, _ := cmd.Flags().GetString("namespace")
+	allNamespaces, _ := cmd.Flags().GetBool("all-namespaces")
+	if allNamespaces {
+		namespace = ""
+	} else {
+		namespace = getTargetNamespace(namespace)
+	}
+
+	// Open the history store
+	store, err := newStore(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+
+	count, err := exportHistories(context.TODO(), store, namespace, &gitexport.Exporter{Repo: repo})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[%s] Kronoform: Exported %d histories to %s\n", time.Now().Format("15:04:05"), count, repo)
+	return nil
+}
+
+// exportHistories commits the histories of a namespace (all namespaces if empty) that
+// the repository of the exporter does not hold yet.
+func exportHistories(ctx context.Context, store storage.Store, namespace string, exporter *gitexport.Exporter) (int, error) {
+	histories, err := store.ListHistories(ctx, storage.ListOptions{Namespace: namespace})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list histories: %w", err)
+	}
+	return exporter.Export(ctx, histories)
+}
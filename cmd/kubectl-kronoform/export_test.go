@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/gitexport"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func TestExportHistories(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	store := storage.NewMemoryStore()
+	appliedAt := metav1.NewTime(time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC))
+	for _, namespace := range []string{"prod", "staging"} {
+		h := &historyv1alpha1.KronoformHistory{
+			ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: namespace},
+			Spec: historyv1alpha1.KronoformHistorySpec{
+				Manifests:   "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n",
+				AppliedBy:   "alice",
+				Description: "Deploy settings",
+			},
+			Status: historyv1alpha1.KronoformHistoryStatus{AppliedAt: &appliedAt},
+		}
+		g.Expect(store.SaveHistory(ctx, h)).To(gomega.Succeed())
+	}
+
+	repo := filepath.Join(t.TempDir(), "timeline")
+	count, err := exportHistories(ctx, store, "prod", &gitexport.Exporter{Repo: repo})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(count).To(gomega.Equal(1))
+	g.Expect(filepath.Join(repo, "prod", "configmap", "settings.yaml")).To(gomega.BeARegularFile())
+	_, err = os.Stat(filepath.Join(repo, "staging"))
+	g.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+
+	count, err = exportHistories(ctx, store, "", &gitexport.Exporter{Repo: repo})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(count).To(gomega.Equal(1))
+	g.Expect(filepath.Join(repo, "staging", "configmap", "settings.yaml")).To(gomega.BeARegularFile())
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func runLog(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	limit, _ := cmd.Flags().GetInt("limit")
+	oneline, _ := cmd.Flags().GetBool("oneline")
+	namespace = getTargetNamespace(namespace)
+
+	// Open the history store
+	store, err := newStore(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+
+	histories, err := listLog(store, namespace, limit)
+	if err != nil {
+		return err
+	}
+	printLog(os.Stdout, histories, oneline)
+	return nil
+}
+
+// listLog returns the histories of a namespace, latest first, at most limit of them
+// if limit is positive.
+func listLog(store storage.Store, namespace string, limit int) ([]historyv1alpha1.KronoformHistory, error) {
+	histories, err := store.ListHistories(context.TODO(), storage.ListOptions{Namespace: namespace})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list histories: %w", err)
+	}
+	history.Sort(histories)
+	for i, j := 0, len(histories)-1; i < j; i, j = i+1, j-1 {
+		histories[i], histories[j] = histories[j], histories[i]
+	}
+	if limit > 0 && len(histories) > limit {
+		histories = histories[:limit]
+	}
+	return histories, nil
+}
+
+// printLog prints histories like git log, or one line per history with oneline.
+func printLog(out io.Writer, histories []historyv1alpha1.KronoformHistory, oneline bool) {
+	if len(histories) == 0 {
+		_, _ = fmt.Fprintln(out, "No histories found")
+		return
+	}
+	for i := range histories {
+		h := &histories[i]
+		if oneline {
+			subject := changeSubject(h)
+			if meta.IsStatusConditionFalse(h.Status.Conditions, historyv1alpha1.ConditionHealthy) {
+				subject += " (unhealthy)"
+			}
+			_, _ = fmt.Fprintf(out, "%s %s\n", history.ID(h), subject)
+			continue
+		}
+		if i > 0 {
+			_, _ = fmt.Fprintln(out)
+		}
+		printHeader(out, h)
+	}
+}
+
+func runShow(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	namespace = getTargetNamespace(namespace)
+
+	// Open the history store
+	store, err := newStore(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+
+	h, err := getHistory(store, namespace, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get history %s: %w", args[0], err)
+	}
+	printShow(os.Stdout, h)
+	return nil
+}
+
+// printShow prints the header of a history followed by what it recorded.
+func printShow(out io.Writer, h *historyv1alpha1.KronoformHistory) {
+	printHeader(out, h)
+	_, _ = fmt.Fprintln(out)
+	printField(out, "Name", h.Namespace+"/"+h.Name)
+	printField(out, "Snapshot", h.Spec.SnapshotRef)
+	if len(h.Spec.Annotations) > 0 {
+		keys := make([]string, 0, len(h.Spec.Annotations))
+		for key := range h.Spec.Annotations {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, len(keys))
+		for i, key := range keys {
+			pairs[i] = key + "=" + h.Spec.Annotations[key]
+		}
+		printField(out, "Annotations", strings.Join(pairs, ", "))
+	}
+	printField(out, "Summary", h.Status.Summary)
+	if h.Status.Chain != nil {
+		printField(out, "Chain", fmt.Sprintf("sequence %d, hash %s", h.Status.Chain.Sequence, h.Status.Chain.Hash))
+	}
+	if meta.IsStatusConditionTrue(h.Status.Conditions, historyv1alpha1.ConditionDrifted) {
+		printField(out, "Drifted", fmt.Sprintf("%d resources modified since (see kubectl kronoform drift)", len(h.Status.Drift)))
+	}
+	if len(h.Status.Rollouts) > 0 {
+		_, _ = fmt.Fprintln(out, "Rollouts:")
+		for _, r := range h.Status.Rollouts {
+			key := history.KeyOf(historyv1alpha1.ResourceSnapshot{APIVersion: r.APIVersion, Kind: r.Kind, Name: r.Name, Namespace: r.Namespace})
+			_, _ = fmt.Fprintf(out, "  %-10s %s: %d/%d ready, %s\n", r.Outcome, key, r.ReadyReplicas, r.Replicas, r.Message)
+			for _, f := range r.PodFailures {
+				pod := f.Pod
+				if f.Container != "" {
+					pod += "/" + f.Container
+				}
+				_, _ = fmt.Fprintf(out, "    %s: %s\n", pod, strings.TrimSpace(f.Reason+" "+f.Message))
+			}
+		}
+	}
+	if len(h.Status.ResourceSnapshots) > 0 {
+		_, _ = fmt.Fprintln(out, "Resources:")
+		for _, rs := range h.Status.ResourceSnapshots {
+			operation := rs.Operation
+			if operation == "" {
+				operation = "-"
+			}
+			if rs.Encoding == historyv1alpha1.EncodingEncrypted {
+				_, _ = fmt.Fprintf(out, "  %-10s %s (encrypted)\n", operation, history.KeyOf(rs))
+				continue
+			}
+			_, _ = fmt.Fprintf(out, "  %-10s %s\n", operation, history.KeyOf(rs))
+		}
+	}
+}
+
+// printHeader prints the identity, author, date, provenance and message of a history.
+func printHeader(out io.Writer, h *historyv1alpha1.KronoformHistory) {
+	_, _ = fmt.Fprintf(out, "history %s (%s)\n", history.ID(h), h.Name)
+	author := h.Spec.AppliedBy
+	if h.Spec.ClaimedBy != "" {
+		author += fmt.Sprintf(" (claimed %s)", h.Spec.ClaimedBy)
+	}
+	printField(out, "Author", author)
+	printField(out, "Date", history.RecordedAt(h).Format(time.RFC3339))
+	if h.Spec.Source != "" && h.Spec.Source != historyv1alpha1.HistorySourceApplied {
+		source := h.Spec.Source
+		if h.Spec.FieldManager != "" {
+			source += " from " + h.Spec.FieldManager
+		}
+		printField(out, "Source", source)
+	}
+	printField(out, "Ticket", h.Spec.Ticket)
+	printField(out, "Health", describeHealth(h))
+	if p := h.Spec.Provenance; p != nil {
+		printField(out, "Commit", describeCommit(p))
+		printField(out, "Remote", p.Remote)
+		printField(out, "Pipeline", describePipeline(p))
+		printField(out, "Job", p.JobURL)
+	}
+	_, _ = fmt.Fprintln(out)
+	for _, line := range strings.Split(changeMessage(h), "\n") {
+		if line == "" {
+			_, _ = fmt.Fprintln(out)
+			continue
+		}
+		_, _ = fmt.Fprintf(out, "    %s\n", line)
+	}
+}
+
+// printField prints a labelled value, skipping empty ones.
+func printField(out io.Writer, label, value string) {
+	if value == "" {
+		return
+	}
+	_, _ = fmt.Fprintf(out, "%-12s %s\n", label+":", value)
+}
+
+// changeMessage returns the message a history was applied with, or its description.
+func changeMessage(h *historyv1alpha1.KronoformHistory) string {
+	if m := strings.TrimSpace(h.Spec.Message); m != "" {
+		return m
+	}
+	if d := strings.TrimSpace(h.Spec.Description); d != "" {
+		return d
+	}
+	return h.Name
+}
+
+// changeSubject returns the first line of the message of a history.
+func changeSubject(h *historyv1alpha1.KronoformHistory) string {
+	line, _, _ := strings.Cut(changeMessage(h), "\n")
+	return line
+}
+
+// describeCommit renders the commit of a provenance with its branch and dirty state,
+// e.g. 3f9a2c1 (main, uncommitted changes).
+func describeCommit(p *historyv1alpha1.Provenance) string {
+	if p.Commit == "" {
+		return ""
+	}
+	var notes []string
+	if p.Branch != "" {
+		notes = append(notes, p.Branch)
+	}
+	if p.Dirty {
+		notes = append(notes, "uncommitted changes")
+	}
+	if len(notes) == 0 {
+		return p.Commit
+	}
+	return fmt.Sprintf("%s (%s)", p.Commit, strings.Join(notes, ", "))
+}
+
+// describePipeline renders the CI job of a provenance, e.g. deploy (github-actions run 42).
+func describePipeline(p *historyv1alpha1.Provenance) string {
+	run := p.CI
+	if p.RunID != "" {
+		run = strings.TrimSpace(p.CI + " run " + p.RunID)
+	}
+	if p.Pipeline == "" {
+		return run
+	}
+	if run == "" {
+		return p.Pipeline
+	}
+	return fmt.Sprintf("%s (%s)", p.Pipeline, run)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func TestLogAndShow(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	store := storage.NewMemoryStore()
+
+	base := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, spec := range []historyv1alpha1.KronoformHistorySpec{
+		{ID: "aaaaaaaaaaaa", AppliedBy: "alice", Description: "Applied by alice"},
+		{
+			ID: "bbbbbbbbbbbb", AppliedBy: "ci-bot", Message: "Scale web for the launch\n\nApproved in the review.",
+			Ticket: "OPS-1234", Annotations: map[string]string{"team": "web", "approved-by": "bob"},
+			Provenance: &historyv1alpha1.Provenance{
+				Commit: "0123456789abcdef", Branch: "main", Dirty: true, Remote: "https://git.example.com/org/manifests.git",
+				CI: "github-actions", RunID: "42", Pipeline: "deploy", JobURL: "https://github.com/org/manifests/actions/runs/42",
+			},
+		},
+	} {
+		appliedAt := metav1.NewTime(base.Add(time.Duration(i) * time.Hour))
+		record := &historyv1alpha1.KronoformHistory{
+			ObjectMeta: metav1.ObjectMeta{Name: "kronoform-history-" + spec.ID[:1], Namespace: "prod"},
+			Spec:       spec,
+			Status: historyv1alpha1.KronoformHistoryStatus{
+				AppliedAt: &appliedAt,
+				Summary:   "Successfully applied manifests",
+				ResourceSnapshots: []historyv1alpha1.ResourceSnapshot{{
+					APIVersion: "apps/v1", Kind: "Deployment", Namespace: "prod", Name: "web",
+					Operation: historyv1alpha1.OperationUpdated,
+				}},
+			},
+		}
+		g.Expect(store.SaveHistory(context.TODO(), record)).To(gomega.Succeed())
+	}
+
+	histories, err := listLog(store, "prod", 0)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(histories).To(gomega.HaveLen(2))
+
+	var out strings.Builder
+	printLog(&out, histories, true)
+	g.Expect(out.String()).To(gomega.Equal("bbbbbbbbbbbb Scale web for the launch\naaaaaaaaaaaa Applied by alice\n"))
+
+	limited, err := listLog(store, "prod", 1)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	out.Reset()
+	printLog(&out, limited, false)
+	g.Expect(out.String()).To(gomega.Equal(`history bbbbbbbbbbbb (kronoform-history-b)
+Author:      ci-bot
+Date:        2025-01-02T04:04:05Z
+Ticket:      OPS-1234
+Commit:      0123456789abcdef (main, uncommitted changes)
+Remote:      https://git.example.com/org/manifests.git
+Pipeline:    deploy (github-actions run 42)
+Job:         https://github.com/org/manifests/actions/runs/42
+
+    Scale web for the launch
+
+    Approved in the review.
+`))
+
+	out.Reset()
+	printShow(&out, &histories[0])
+	g.Expect(out.String()).To(gomega.HaveSuffix(`
+Name:        prod/kronoform-history-b
+Annotations: approved-by=bob, team=web
+Summary:     Successfully applied manifests
+Resources:
+  Updated    Deployment.apps/prod/web
+`))
+
+	out.Reset()
+	printLog(&out, nil, false)
+	g.Expect(out.String()).To(gomega.Equal("No histories found\n"))
+}
@@ -19,25 +19,35 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/sergi/go-diff/diffmatchpatch"
 	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/payload"
+	"github.com/yu-kod/kronoform/internal/redact"
+	"github.com/yu-kod/kronoform/internal/rollout"
+	"github.com/yu-kod/kronoform/internal/storage"
 )
 
 func main() {
@@ -49,6 +59,9 @@ Simply use 'kubectl kronoform apply' instead of 'kubectl apply' to automatically
 record and accumulate successful YAML files along with the actual resource states.`,
 	}
 
+	// Select where records are stored
+	addConfigFlags(rootCmd)
+
 	var applyCmd = &cobra.Command{
 		Use:   "apply",
 		Short: "Apply configuration to a resource and record the change",
@@ -61,18 +74,262 @@ This command combines kubectl apply with automatic history tracking.`,
 	applyCmd.Flags().StringSliceP("filename", "f", []string{}, "Filename, directory, or URL to files to use to create the resource")
 	applyCmd.Flags().Bool("dry-run", false, "If true, only print the object that would be sent, without sending it")
 	applyCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+	applyCmd.Flags().Bool("wait", false, "If true, wait for the rollouts of the Deployments, StatefulSets, DaemonSets and Jobs applied and record their health")
+	applyCmd.Flags().Duration("timeout", 5*time.Minute, "The length of time to wait for rollouts with --wait")
+	addChangeFlags(applyCmd)
 
 	var diffCmd = &cobra.Command{
-		Use:   "diff <history-id>",
+		Use:   "diff <history>",
 		Short: "Show diff between before and after applying a change",
 		Long: `Show the difference between the manifest before and after applying a change.
-This helps you understand what exactly changed in your resources.`,
+This helps you understand what exactly changed in your resources.
+
+A history is given by name, by a unique prefix of its ID, as HEAD or HEAD~N for the latest
+histories of the namespace, or as @{<time>} for the latest one applied before a time, e.g.
+@{1h ago} or @{2025-01-02 15:04}. Append :<kind>/<name> to count only the histories of a
+resource, e.g. HEAD~1:deployment.apps/web.`,
 		Args: cobra.ExactArgs(1),
 		RunE: runDiff,
 	}
 
+	diffCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+
+	var driftCmd = &cobra.Command{
+		Use:   "drift",
+		Short: "List resources modified outside kronoform",
+		Long: `List resources whose live state no longer matches the state recorded by their latest history.
+Drift is detected by the kronoform controller manager and recorded on the history.`,
+		Args: cobra.NoArgs,
+		RunE: runDrift,
+	}
+
+	driftCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+	driftCmd.Flags().BoolP("all-namespaces", "A", false, "If present, list drifted resources across all namespaces")
+
+	var migrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Compress large records written by older versions",
+		Long: `Compress the manifests and resource states of records written before kronoform encoded
+large content, moving manifests that remain too large into chunks. Records are only
+re-encoded; their decoded content does not change.`,
+		Args: cobra.NoArgs,
+		RunE: runMigrate,
+	}
+
+	migrateCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+	migrateCmd.Flags().BoolP("all-namespaces", "A", false, "If present, migrate records across all namespaces")
+	migrateCmd.Flags().Bool("dry-run", false, "If true, only list the records that would be migrated")
+
+	var rotateKeysCmd = &cobra.Command{
+		Use:   "rotate-keys",
+		Short: "Encrypt the data keys of encrypted records with the current key",
+		Long: `Encrypt the data keys of encrypted records again with the current key of the keyring, so
+that keys rotated out can be removed from the keyring once no record uses them. Only the data
+keys change; the encrypted manifests and resource states are left as they are.
+
+The admission webhook only lets the controller manager and members of the break-glass group
+(kronoform:break-glass by default) replace data keys, so run this command as one of them.`,
+		Args: cobra.NoArgs,
+		RunE: runRotateKeys,
+	}
+
+	rotateKeysCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+	rotateKeysCmd.Flags().BoolP("all-namespaces", "A", false, "If present, rotate records across all namespaces")
+	rotateKeysCmd.Flags().Bool("dry-run", false, "If true, only list the records that would be rotated")
+
+	var exportCmd = &cobra.Command{
+		Use:   "export --git <repo-path>",
+		Short: "Export the change timeline to a git repository",
+		Long: `Export each history as a commit of a local git repository, so that the change timeline
+can be browsed with git log -p and git blame. Each resource is kept in its own file holding
+its state after the change; commits are authored by the user who applied the change, at
+the time it was applied. Histories already in the repository are skipped, so the export
+can be repeated to bring the repository up to date.`,
+		Args: cobra.NoArgs,
+		RunE: runExport,
+	}
+
+	exportCmd.Flags().String("git", "", "Path of the git repository to export to, created if it does not exist")
+	exportCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+	exportCmd.Flags().BoolP("all-namespaces", "A", false, "If present, export histories across all namespaces")
+	_ = exportCmd.MarkFlagRequired("git")
+
+	var verifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "Verify that recorded histories were not modified or removed",
+		Long: `Walk the hash chain the controller manager keeps over the histories of each namespace and
+report histories whose content no longer matches their hash, histories inserted into the
+chain and histories missing from it. With --public-key, the signatures of the chain are
+checked as well. Compare the reported head with one recorded earlier to detect histories
+removed from the end of the chain.`,
+		Args: cobra.NoArgs,
+		RunE: runVerify,
+	}
+
+	verifyCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+	verifyCmd.Flags().BoolP("all-namespaces", "A", false, "If present, verify histories across all namespaces")
+	verifyCmd.Flags().String("public-key", "", "Path of the PEM-encoded ed25519 public key the chain is signed with")
+
+	var logCmd = &cobra.Command{
+		Use:   "log",
+		Short: "List the changes recorded in a namespace",
+		Long: `List the histories recorded in a namespace, latest first, with who applied each change,
+when, why, and the git commit and CI job it came from.`,
+		Args: cobra.NoArgs,
+		RunE: runLog,
+	}
+
+	logCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+	logCmd.Flags().Int("limit", 0, "If positive, the maximum number of histories to list")
+	logCmd.Flags().Bool("oneline", false, "If true, print each history on a single line")
+
+	var showCmd = &cobra.Command{
+		Use:   "show <history>",
+		Short: "Show a recorded change",
+		Long: `Show who applied a change, when and why, the git commit and CI job it came from, and the
+resources it recorded. The history is given as for diff.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runShow,
+	}
+
+	showCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+
+	var whyCmd = &cobra.Command{
+		Use:   "why <kind>/<name>",
+		Short: "Show the change that produced the current state of a resource",
+		Long: `Show the history that last changed a live resource, found through the annotations
+kubectl kronoform apply sets on the resources it changes. The resource is given as
+kind[.group]/name, e.g. deployment.apps/web or configmap/settings.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runWhy,
+	}
+
+	whyCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+
+	var historyCmd = &cobra.Command{
+		Use:   "history <kind>/<name>",
+		Short: "List the changes recorded for a resource",
+		Long: `List every history that changed a resource, oldest first. The resource is given as
+kind[.group]/name, e.g. deployment.apps/web or configmap/settings. Histories that only
+recorded the applied manifests are listed when the manifest of the resource changed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runResourceHistory,
+	}
+
+	historyCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+
+	var blameCmd = &cobra.Command{
+		Use:   "blame <kind>/<name>",
+		Short: "Show the change that last set each field of a resource",
+		Long: `Show each field of the latest recorded state of a resource with the history that last
+changed it: its ID, who applied it, when and why. Fields are attributed from the states
+recorded before and after each change; fields changed without being recorded are shown
+with a dash. The resource is given as for history.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runBlame,
+	}
+
+	blameCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+
+	var bisectCmd = &cobra.Command{
+		Use:   "bisect <kind>/<name> --path <path>",
+		Short: "Find the change that set a field of a resource",
+		Long: `Walk the recorded states of a resource, oldest first, and show the first history that
+changed the field at --path from its oldest recorded value, with the other fields it
+changed. With --equals or --matches, show the first history that made the field equal the
+value or match the regular expression instead. The path is written as in blame, e.g.
+.spec.template.spec.containers[0].image or .metadata.labels["app.kubernetes.io/version"].
+The resource is given as for history.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runBisect,
+	}
+
+	bisectCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+	bisectCmd.Flags().String("path", "", "Path of the field to bisect, e.g. .spec.replicas")
+	bisectCmd.Flags().String("equals", "", "If present, find the change that made the field equal this value")
+	bisectCmd.Flags().String("matches", "", "If present, find the change that made the field match this regular expression")
+	_ = bisectCmd.MarkFlagRequired("path")
+
+	var atCmd = &cobra.Command{
+		Use:   "at <time>",
+		Short: "Show the recorded resources of a namespace as they were at a point in time",
+		Long: `Reconstruct the state at a point in time of every resource recorded in a namespace from
+the states recorded by its histories. The time is given as in history references, e.g.
+"2025-01-02 15:04", "2 days ago" or yesterday, in local time unless it has an offset.
+Resources whose changes recorded only the applied manifests are reconstructed as the last
+manifest applied. With -o yaml, the resources are written as a multi-document YAML bundle;
+with --diff, they are compared with the live resources.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runAt,
+	}
+
+	atCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+	atCmd.Flags().StringP("output", "o", "", "Output format. One of: yaml")
+	atCmd.Flags().Bool("diff", false, "If true, compare the resources with their live state")
+
+	var restoreCmd = &cobra.Command{
+		Use:   "restore --to <time> [-l selector | <kind>/<name>...] [--path <path>...]",
+		Short: "Restore the recorded resources of a namespace to a point in time",
+		Long: `Return the resources recorded in a namespace to their state at a point in time,
+reconstructed as for at. --to is a time, e.g. "2025-01-02 15:04" or "2 hours ago", or a
+history whose resources are restored to their state right after it. Restore all recorded
+resources, the ones matching a label selector, or the ones given as kind[.group]/name, as
+arguments or with --only. With --path, only the fields at or under the paths given are
+restored, in resources that exist both at the time and live; their other fields, e.g. those
+changed in the same history, keep their live value.
+
+The resources to create, update and delete are shown, then applied: creates and updates in
+dependency order, then deletes in reverse order. The restore is recorded as one history.
+Resources whose recorded state holds redacted values that changed since cannot be restored.`,
+		RunE: runRestore,
+	}
+
+	restoreCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+	restoreCmd.Flags().String("to", "", "Time or history to restore the resources to")
+	restoreCmd.Flags().StringP("selector", "l", "", "Selector (label query) to filter the resources to restore on")
+	restoreCmd.Flags().StringArray("only", nil, "Resource to restore as kind[.group]/name (can be repeated)")
+	restoreCmd.Flags().StringArray("path", nil, "Path of a field to restore, e.g. .spec.template.spec.containers[0].image (can be repeated)")
+	restoreCmd.Flags().Bool("dry-run", false, "If true, only show what would be restored")
+	addChangeFlags(restoreCmd)
+	_ = restoreCmd.MarkFlagRequired("to")
+
+	var revertCmd = &cobra.Command{
+		Use:   "revert <history>",
+		Short: "Revert the changes of a history onto the live resources",
+		Long: `Undo the changes recorded by a history while keeping the changes made since: the fields
+it changed are returned to their value before it in the live resources, the resources it
+created are deleted and the ones it deleted are created again. The other fields keep their
+live value.
+
+Fields and resources changed again since by later histories, or outside of them, are
+reported as conflicts and nothing is reverted; with --force, they are reverted anyway.
+The revert is recorded as one history, with a message naming the reverted history unless
+one is given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runRevert,
+	}
+
+	revertCmd.Flags().StringP("namespace", "n", "", "If present, the namespace scope for this CLI request")
+	revertCmd.Flags().Bool("force", false, "If true, revert fields changed again since the history")
+	revertCmd.Flags().Bool("dry-run", false, "If true, only show what would be reverted")
+	addChangeFlags(revertCmd)
+
 	rootCmd.AddCommand(applyCmd)
 	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(logCmd)
+	rootCmd.AddCommand(showCmd)
+	rootCmd.AddCommand(whyCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(blameCmd)
+	rootCmd.AddCommand(bisectCmd)
+	rootCmd.AddCommand(atCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(revertCmd)
+	rootCmd.AddCommand(driftCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(rotateKeysCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(verifyCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -87,6 +344,13 @@ func runApply(cmd *cobra.Command, args []string) error {
 	filenames, _ := cmd.Flags().GetStringSlice("filename")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	namespace, _ := cmd.Flags().GetString("namespace")
+	wait, _ := cmd.Flags().GetBool("wait")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	change, err := getChangeContext(cmd)
+	if err != nil {
+		return err
+	}
+	change.Provenance = detectProvenance(filenames, os.Getenv)
 
 	// Read the manifest content
 	var manifestContent string
@@ -98,16 +362,28 @@ func runApply(cmd *cobra.Command, args []string) error {
 		manifestContent = content
 	}
 
-	// Create Kubernetes client
-	k8sClient, err := createK8sClient()
+	// Open the history store
+	store, err := newStore(cmd)
 	if err != nil {
-		fmt.Printf("[%s] Kronoform: Warning - Could not create k8s client, skipping history recording: %v\n", time.Now().Format("15:04:05"), err)
+		fmt.Printf("[%s] Kronoform: Warning - Could not open history store, skipping history recording: %v\n", time.Now().Format("15:04:05"), err)
 	}
 
-	// Create snapshot record before applying (if not dry-run and client available)
+	// Only redacted manifests are recorded; kubectl applies the files themselves
+	if store != nil && manifestContent != "" {
+		manifestContent, err = redactManifests(cmd, manifestContent)
+		if err != nil {
+			fmt.Printf("[%s] Kronoform: Warning - Could not redact manifests, skipping history recording: %v\n", time.Now().Format("15:04:05"), err)
+			store = nil
+		}
+	}
+
+	// Create snapshot record before applying (if not dry-run and store available)
 	var snapshotName string
-	if !dryRun && k8sClient != nil && manifestContent != "" {
-		snapshotName, err = createSnapshot(k8sClient, manifestContent, namespace)
+	if !dryRun && store != nil && manifestContent != "" {
+		snapshotName, err = createSnapshot(store, manifestContent, namespace, change)
+		if messageRequired(err) {
+			return fmt.Errorf("refusing to apply without a message, use -m to explain the change: %w", err)
+		}
 		if err != nil {
 			fmt.Printf("[%s] Kronoform: Warning - Could not create snapshot: %v\n", time.Now().Format("15:04:05"), err)
 		} else {
@@ -115,8 +391,19 @@ func runApply(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Read the live state of the applied resources, so that the change is recorded with
+	// their state before and after it
+	var recorder *applyRecorder
+	if snapshotName != "" {
+		recorder, err = readAppliedState(cmd, manifestContent, namespace)
+		if err != nil {
+			fmt.Printf("[%s] Kronoform: Warning - Could not read the live state of the applied resources, recording the manifests only: %v\n", time.Now().Format("15:04:05"), err)
+		}
+	}
+
 	// Build kubectl args
-	kubectlArgs := []string{"apply"}
+	// Apply with kronoform's field manager so the change observer can tell these writes apart
+	kubectlArgs := []string{"apply", "--field-manager=" + historyv1alpha1.FieldManager}
 
 	// Add filenames
 	for _, filename := range filenames {
@@ -149,32 +436,92 @@ func runApply(cmd *cobra.Command, args []string) error {
 	if err := kubectlCmd.Run(); err != nil {
 		return fmt.Errorf("kubectl apply failed: %w", err)
 	}
+	// The change is recorded as applied now, however long its rollouts take
+	appliedAt := metav1.Now()
 
 	fmt.Printf("[%s] Kronoform: Apply operation completed successfully\n", time.Now().Format("15:04:05"))
 
 	// Check if there were actual changes by analyzing kubectl output
 	hasChanges := analyzeKubectlOutput(stdout.String())
 
+	var resources []historyv1alpha1.ResourceSnapshot
+	if !dryRun && store != nil && snapshotName != "" && hasChanges && recorder != nil {
+		if resources, err = recorder.recorded(context.Background(), stdout.String()); err != nil {
+			fmt.Printf("[%s] Kronoform: Warning - Could not read the live state of the applied resources, recording the manifests only: %v\n", time.Now().Format("15:04:05"), err)
+		}
+	}
+
+	// Wait for the workloads changed to roll out, so that their health is recorded with the
+	// change. An interrupted wait still records the change, without its health.
+	var rollouts []historyv1alpha1.WorkloadRollout
+	if wait && !dryRun && hasChanges {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		rollouts, err = waitForAppliedRollouts(ctx, manifestContent, stdout.String(), namespace, timeout)
+		interrupted := ctx.Err() != nil
+		stop()
+		if interrupted {
+			fmt.Printf("[%s] Kronoform: Warning - Interrupted while waiting for rollouts, recording the change without its health\n", time.Now().Format("15:04:05"))
+		} else if err != nil {
+			fmt.Printf("[%s] Kronoform: Warning - Could not wait for rollouts: %v\n", time.Now().Format("15:04:05"), err)
+		}
+	}
+
 	// Create history record after successful apply only if there were changes
-	if !dryRun && k8sClient != nil && snapshotName != "" && hasChanges {
-		err = createHistory(k8sClient, manifestContent, snapshotName, namespace)
+	if !dryRun && store != nil && snapshotName != "" && hasChanges {
+		record, err := createHistory(store, manifestContent, snapshotName, namespace, change, appliedAt, resources, rollouts)
 		if err != nil {
 			fmt.Printf("[%s] Kronoform: Warning - Could not create history: %v\n", time.Now().Format("15:04:05"), err)
 		} else {
 			fmt.Printf("[%s] Kronoform: History recorded successfully\n", time.Now().Format("15:04:05"))
+
+			// Let the changed resources point back at their history
+			if err := stampApplied(record, manifestContent, stdout.String(), namespace); err != nil {
+				fmt.Printf("[%s] Kronoform: Warning - Could not annotate applied resources: %v\n", time.Now().Format("15:04:05"), err)
+			}
 		}
 	} else if !hasChanges {
 		fmt.Printf("[%s] Kronoform: No changes detected, skipping history recording\n", time.Now().Format("15:04:05"))
 
 		// Clean up the snapshot since no changes were made
-		if k8sClient != nil && snapshotName != "" {
-			cleanupSnapshot(k8sClient, snapshotName, namespace)
+		if store != nil && snapshotName != "" {
+			cleanupSnapshot(store, snapshotName, namespace)
 		}
 	}
 
+	if condition := rollout.Condition(rollouts); len(rollouts) > 0 && condition.Status != metav1.ConditionTrue {
+		return fmt.Errorf("rollouts did not become healthy: %s", condition.Message)
+	}
 	return nil
 }
 
+// readAppliedState reads the live state of the resources of the applied manifests.
+func readAppliedState(cmd *cobra.Command, manifests, namespace string) (*applyRecorder, error) {
+	redactor, err := newRedactor(cmd)
+	if err != nil {
+		return nil, err
+	}
+	k8sClient, err := createK8sClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return newApplyRecorder(context.Background(), k8sClient, redactor, manifests, namespace)
+}
+
+// waitForAppliedRollouts waits for the rollouts of the workloads kubectl created or
+// configured, until they end, timeout expires or ctx is done.
+func waitForAppliedRollouts(ctx context.Context, manifests, kubectlOutput, namespace string,
+	timeout time.Duration) ([]historyv1alpha1.WorkloadRollout, error) {
+	workloads, err := appliedWorkloads(manifests, kubectlOutput, namespace)
+	if err != nil || len(workloads) == 0 {
+		return nil, err
+	}
+	k8sClient, err := createK8sClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return waitForRollouts(ctx, k8sClient, os.Stdout, workloads, timeout)
+}
+
 // readManifestFiles reads and concatenates content from multiple manifest files
 func readManifestFiles(filenames []string) (string, error) {
 	var allContent strings.Builder
@@ -243,7 +590,7 @@ func createK8sClient() (client.Client, error) {
 }
 
 // createSnapshot creates a KronoformSnapshot resource
-func createSnapshot(k8sClient client.Client, manifestContent string, namespace string) (string, error) {
+func createSnapshot(store storage.Store, manifestContent string, namespace string, change changeContext) (string, error) {
 	ctx := context.Background()
 	now := metav1.Now()
 
@@ -265,23 +612,38 @@ func createSnapshot(k8sClient client.Client, manifestContent string, namespace s
 		Spec: historyv1alpha1.KronoformSnapshotSpec{
 			Manifests:       manifestContent,
 			Description:     fmt.Sprintf("Applied by %s at %s", appliedBy, now.Format(time.RFC3339)),
+			Message:         change.Message,
+			Ticket:          change.Ticket,
+			Annotations:     change.Annotations,
 			TargetNamespace: namespace,
+			AppliedBy:       appliedBy,
 		},
 		Status: historyv1alpha1.KronoformSnapshotStatus{
 			Phase: "Pending",
 		},
 	}
 
-	if err := k8sClient.Create(ctx, snapshot); err != nil {
+	if err := store.SaveSnapshot(ctx, snapshot); err != nil {
 		return "", err
 	}
 
 	return snapshotName, nil
 }
 
-// createHistory creates a KronoformHistory resource
-func createHistory(k8sClient client.Client, manifestContent string, snapshotName string, namespace string) error {
-	ctx := context.Background()
+// createHistory creates a KronoformHistory resource of a change applied at appliedAt and
+// returns it as recorded
+func createHistory(store storage.Store, manifestContent string, snapshotName string, namespace string, change changeContext,
+	appliedAt metav1.Time, resources []historyv1alpha1.ResourceSnapshot, rollouts []historyv1alpha1.WorkloadRollout) (*historyv1alpha1.KronoformHistory, error) {
+	record := newHistoryRecord(manifestContent, snapshotName, namespace, change)
+	record.Status.AppliedAt = &appliedAt
+	record.Status.Summary = "Successfully applied manifests"
+	recordResources(record, resources)
+	recordRollouts(record, rollouts)
+	return record, saveHistoryRecord(store, record)
+}
+
+// newHistoryRecord returns a history of a change applied by the current user.
+func newHistoryRecord(manifestContent string, snapshotName string, namespace string, change changeContext) *historyv1alpha1.KronoformHistory {
 	now := metav1.Now()
 
 	// Get current user for tracking
@@ -294,42 +656,56 @@ func createHistory(k8sClient client.Client, manifestContent string, snapshotName
 	// Generate history name
 	historyName := fmt.Sprintf("kronoform-history-%d", now.Unix())
 
-	history := &historyv1alpha1.KronoformHistory{
+	return &historyv1alpha1.KronoformHistory{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      historyName,
 			Namespace: getTargetNamespace(namespace),
 		},
 		Spec: historyv1alpha1.KronoformHistorySpec{
-			Manifests:   manifestContent,
-			SnapshotRef: snapshotName,
-			Description: fmt.Sprintf("Applied by %s", appliedBy),
-			AppliedBy:   appliedBy,
+			Manifests:    manifestContent,
+			SnapshotRef:  snapshotName,
+			Source:       historyv1alpha1.HistorySourceApplied,
+			FieldManager: historyv1alpha1.FieldManager,
+			Description:  fmt.Sprintf("Applied by %s", appliedBy),
+			Message:      change.Message,
+			Ticket:       change.Ticket,
+			Annotations:  change.Annotations,
+			Provenance:   change.Provenance,
+			AppliedBy:    appliedBy,
 		},
 		Status: historyv1alpha1.KronoformHistoryStatus{
 			AppliedAt: &now,
-			Summary:   "Successfully applied manifests",
 		},
 	}
+}
 
-	if err := k8sClient.Create(ctx, history); err != nil {
+// saveHistoryRecord saves a history and marks the snapshot it references as completed.
+func saveHistoryRecord(store storage.Store, record *historyv1alpha1.KronoformHistory) error {
+	ctx := context.Background()
+	if err := store.SaveHistory(ctx, record); err != nil {
 		return err
 	}
 
 	// Update snapshot status to reference the history
-	snapshot := &historyv1alpha1.KronoformSnapshot{}
-	if err := k8sClient.Get(ctx, client.ObjectKey{
-		Name:      snapshotName,
-		Namespace: getTargetNamespace(namespace),
-	}, snapshot); err != nil {
+	snapshot, err := store.GetSnapshot(ctx, record.Namespace, record.Spec.SnapshotRef)
+	if err != nil {
 		return err
 	}
 
+	// Make the snapshot owned by its history so that deleting the history removes it too
+	snapshot.OwnerReferences = append(snapshot.OwnerReferences, metav1.OwnerReference{
+		APIVersion: historyv1alpha1.GroupVersion.String(),
+		Kind:       "KronoformHistory",
+		Name:       record.Name,
+		UID:        record.UID,
+	})
+
 	snapshot.Status.Phase = "Completed"
-	snapshot.Status.AppliedAt = &now
-	snapshot.Status.HistoryRef = historyName
+	snapshot.Status.AppliedAt = record.Status.AppliedAt
+	snapshot.Status.HistoryRef = record.Name
 	snapshot.Status.Message = "Successfully applied and recorded"
 
-	return k8sClient.Status().Update(ctx, snapshot)
+	return store.SaveSnapshot(ctx, snapshot)
 }
 
 // getTargetNamespace returns the appropriate namespace to use
@@ -368,15 +744,10 @@ func analyzeKubectlOutput(output string) bool {
 }
 
 // cleanupSnapshot removes a snapshot that was created but not needed due to no changes
-func cleanupSnapshot(k8sClient client.Client, snapshotName string, namespace string) {
+func cleanupSnapshot(store storage.Store, snapshotName string, namespace string) {
 	ctx := context.Background()
-	snapshot := &historyv1alpha1.KronoformSnapshot{}
-
-	err := k8sClient.Get(ctx, client.ObjectKey{
-		Name:      snapshotName,
-		Namespace: getTargetNamespace(namespace),
-	}, snapshot)
 
+	snapshot, err := store.GetSnapshot(ctx, getTargetNamespace(namespace), snapshotName)
 	if err != nil {
 		// Snapshot doesn't exist, nothing to clean up
 		return
@@ -386,12 +757,12 @@ func cleanupSnapshot(k8sClient client.Client, snapshotName string, namespace str
 	snapshot.Status.Phase = "NoChanges"
 	snapshot.Status.Message = "No changes detected, snapshot not needed"
 
-	if err := k8sClient.Status().Update(ctx, snapshot); err != nil {
+	if err := store.SaveSnapshot(ctx, snapshot); err != nil {
 		fmt.Printf("[%s] Kronoform: Warning - Could not update snapshot status: %v\n", time.Now().Format("15:04:05"), err)
 	}
 
 	// Optionally delete the snapshot entirely
-	if err := k8sClient.Delete(ctx, snapshot); err != nil {
+	if err := store.DeleteSnapshot(ctx, snapshot.Namespace, snapshot.Name); err != nil {
 		fmt.Printf("[%s] Kronoform: Warning - Could not delete unused snapshot: %v\n", time.Now().Format("15:04:05"), err)
 	} else {
 		fmt.Printf("[%s] Kronoform: Cleaned up unused snapshot: %s\n", time.Now().Format("15:04:05"), snapshotName)
@@ -402,52 +773,127 @@ func runDiff(cmd *cobra.Command, args []string) error {
 	fmt.Printf("[%s] Kronoform: Starting diff operation...\n", time.Now().Format("15:04:05"))
 
 	historyID := args[0]
+	namespace, _ := cmd.Flags().GetString("namespace")
 
-	// Create Kubernetes client
-	k8sClient, err := createK8sClient()
+	// Open the history store
+	store, err := newStore(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to create k8s client: %w", err)
+		return fmt.Errorf("failed to open history store: %w", err)
 	}
 
-	// Get history
-	history, err := getHistory(k8sClient, historyID)
+	history, snapshot, err := getHistoryPair(store, getTargetNamespace(namespace), historyID)
 	if err != nil {
-		return fmt.Errorf("failed to get history: %w", err)
+		return err
+	}
+	if err := readablePair(history, snapshot); err != nil {
+		return err
 	}
 
-	// Get snapshot
-	snapshot, err := getSnapshot(k8sClient, history.Spec.SnapshotRef)
-	if err != nil {
-		return fmt.Errorf("failed to get snapshot: %w", err)
+	// Observed histories have no snapshot; they record the states around the change
+	if snapshot == nil {
+		for _, rs := range history.Status.ResourceSnapshots {
+			fmt.Printf("%s/%s (%s by %s)\n", rs.Kind, rs.Name, strings.ToLower(rs.Operation), history.Spec.FieldManager)
+			if err := showDiff(rs.Before, rs.After); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
 	// Show diff
 	return showDiff(snapshot.Spec.Manifests, history.Spec.Manifests)
 }
 
-func getHistory(k8sClient client.Client, historyID string) (*historyv1alpha1.KronoformHistory, error) {
-	history := &historyv1alpha1.KronoformHistory{}
-	err := k8sClient.Get(context.TODO(), client.ObjectKey{
-		Name: historyID,
-	}, history)
+// getHistoryPair fetches a history together with the snapshot it references.
+// A history whose snapshot has been deleted is reported as orphaned rather than
+// surfacing the bare not-found error of the snapshot lookup. Observed histories
+// are returned without a snapshot.
+func getHistoryPair(store storage.Store, namespace, historyID string) (*historyv1alpha1.KronoformHistory, *historyv1alpha1.KronoformSnapshot, error) {
+	history, err := getHistory(store, namespace, historyID)
+	if apierrors.IsNotFound(err) {
+		return nil, nil, fmt.Errorf("history %s not found", historyID)
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("failed to get history: %w", err)
+	}
+
+	if history.Spec.Source == historyv1alpha1.HistorySourceObserved {
+		return history, nil, nil
 	}
-	return history, nil
+
+	if history.Spec.SnapshotRef == "" {
+		return nil, nil, fmt.Errorf("history %s is orphaned: it does not reference a snapshot", historyID)
+	}
+
+	snapshot, err := getSnapshot(store, history.Namespace, history.Spec.SnapshotRef)
+	if apierrors.IsNotFound(err) {
+		return nil, nil, fmt.Errorf("history %s is orphaned: snapshot %s no longer exists", historyID, history.Spec.SnapshotRef)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	if snapshot.Status.HistoryRef != "" && snapshot.Status.HistoryRef != history.Name {
+		return nil, nil, fmt.Errorf("history %s is orphaned: snapshot %s is linked to history %s",
+			historyID, snapshot.Name, snapshot.Status.HistoryRef)
+	}
+
+	return history, snapshot, nil
 }
 
-func getSnapshot(k8sClient client.Client, snapshotName string) (*historyv1alpha1.KronoformSnapshot, error) {
-	snapshot := &historyv1alpha1.KronoformSnapshot{}
-	err := k8sClient.Get(context.TODO(), client.ObjectKey{
-		Name: snapshotName,
-	}, snapshot)
+// getHistory returns the history of a namespace designated by ref: its name, a unique
+// prefix of its ID, HEAD~N or @{<time>}, optionally scoped to a resource, as accepted by
+// history.Resolve. References that designate no history fail with a NotFound error.
+func getHistory(store storage.Store, namespace, ref string) (*historyv1alpha1.KronoformHistory, error) {
+	ctx := context.TODO()
+	if len(validation.IsDNS1123Subdomain(ref)) == 0 {
+		h, err := store.GetHistory(ctx, namespace, ref)
+		if !apierrors.IsNotFound(err) {
+			return h, err
+		}
+	}
+
+	histories, err := store.ListHistories(ctx, storage.ListOptions{Namespace: namespace})
 	if err != nil {
 		return nil, err
 	}
-	return snapshot, nil
+	h, err := history.Resolve(histories, ref, time.Now())
+	if errors.Is(err, history.ErrUnknownRef) {
+		return nil, apierrors.NewNotFound(historyv1alpha1.GroupVersion.WithResource("kronoformhistories").GroupResource(), ref)
+	}
+	return h, err
+}
+
+// readablePair checks that the manifests and states of a history and its snapshot could be
+// decrypted by the store.
+func readablePair(h *historyv1alpha1.KronoformHistory, snapshot *historyv1alpha1.KronoformSnapshot) error {
+	if err := history.Readable(h); err != nil {
+		return err
+	}
+	if snapshot != nil && snapshot.Spec.ManifestsEncoding == historyv1alpha1.EncodingEncrypted {
+		return fmt.Errorf("cannot read the manifests of snapshot %s: %w with a key that is not configured",
+			snapshot.Name, payload.ErrEncrypted)
+	}
+	return nil
+}
+
+func getSnapshot(store storage.Store, namespace, snapshotName string) (*historyv1alpha1.KronoformSnapshot, error) {
+	return store.GetSnapshot(context.TODO(), namespace, snapshotName)
+}
+
+// redactManifests redacts the manifests of an apply as configured for the command.
+func redactManifests(cmd *cobra.Command, manifestContent string) (string, error) {
+	redactor, err := newRedactor(cmd)
+	if err != nil {
+		return "", err
+	}
+	return redactor.Manifests(manifestContent)
 }
 
+// showDiff prints the difference between two renderings. Redacted values are shown as
+// "<redacted>", or as "<changed (redacted)>" where they changed.
 func showDiff(before, after string) error {
+	before, after = redact.Mask(before, after)
 	dmp := diffmatchpatch.New()
 	diffs := dmp.DiffMain(before, after, false)
 
@@ -4,13 +4,16 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/storage"
 )
 
 func TestRunDiff(t *testing.T) {
@@ -52,12 +55,12 @@ func TestRunDiff(t *testing.T) {
 	g.Expect(err).To(gomega.BeNil())
 
 	// Test getHistory
-	retrievedHistory, err := getHistory(fakeClient, "test-history")
+	retrievedHistory, err := getHistory(storage.NewCRDStore(fakeClient), "", "test-history")
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(retrievedHistory.Spec.Manifests).To(gomega.Equal(history.Spec.Manifests))
 
 	// Test getSnapshot
-	retrievedSnapshot, err := getSnapshot(fakeClient, "test-snapshot")
+	retrievedSnapshot, err := getSnapshot(storage.NewCRDStore(fakeClient), "", "test-snapshot")
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(retrievedSnapshot.Spec.Manifests).To(gomega.Equal(snapshot.Spec.Manifests))
 
@@ -100,3 +103,135 @@ func TestReadManifestFiles(t *testing.T) {
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(result).To(gomega.Equal(content))
 }
+
+func TestGetHistoryPairOrphaned(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	scheme := runtime.NewScheme()
+	if err := historyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add scheme: %v", err)
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	store := storage.NewCRDStore(fakeClient)
+
+	// History whose snapshot has been deleted
+	history := &historyv1alpha1.KronoformHistory{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "orphaned-history",
+		},
+		Spec: historyv1alpha1.KronoformHistorySpec{
+			Manifests:   "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test",
+			SnapshotRef: "deleted-snapshot",
+		},
+	}
+	g.Expect(fakeClient.Create(context.TODO(), history)).To(gomega.Succeed())
+
+	_, _, err := getHistoryPair(store, "", "orphaned-history")
+	g.Expect(err).To(gomega.HaveOccurred())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("orphaned"))
+	g.Expect(err.Error()).To(gomega.ContainSubstring("deleted-snapshot"))
+
+	// Missing history is reported as not found
+	_, _, err = getHistoryPair(store, "", "missing-history")
+	g.Expect(err).To(gomega.MatchError("history missing-history not found"))
+
+	// Intact pair is returned as-is
+	snapshot := &historyv1alpha1.KronoformSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "deleted-snapshot",
+		},
+		Spec: historyv1alpha1.KronoformSnapshotSpec{
+			Manifests: history.Spec.Manifests,
+		},
+	}
+	g.Expect(fakeClient.Create(context.TODO(), snapshot)).To(gomega.Succeed())
+
+	retrievedHistory, retrievedSnapshot, err := getHistoryPair(store, "", "orphaned-history")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(retrievedHistory.Name).To(gomega.Equal("orphaned-history"))
+	g.Expect(retrievedSnapshot.Name).To(gomega.Equal("deleted-snapshot"))
+}
+
+func TestRecordApplyInStore(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	store := storage.NewMemoryStore()
+	manifests := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test"
+
+	change := changeContext{Message: "Raise the cache size", Ticket: "OPS-1234", Annotations: map[string]string{"team": "web"}}
+
+	snapshotName, err := createSnapshot(store, manifests, "prod", change)
+	g.Expect(err).To(gomega.BeNil())
+	// The change is recorded as applied when kubectl returned, not when the history is saved
+	appliedAt := metav1.NewTime(time.Now().Add(-5 * time.Minute).Truncate(time.Second))
+	record, err := createHistory(store, manifests, snapshotName, "prod", change, appliedAt, nil, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(record.Spec.ID).NotTo(gomega.BeEmpty())
+
+	histories, err := store.ListHistories(context.TODO(), storage.ListOptions{Namespace: "prod"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(histories).To(gomega.HaveLen(1))
+
+	history, snapshot, err := getHistoryPair(store, "prod", histories[0].Name)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(history.Spec.Manifests).To(gomega.Equal(manifests))
+	g.Expect(history.Spec.Message).To(gomega.Equal("Raise the cache size"))
+	g.Expect(history.Spec.Ticket).To(gomega.Equal("OPS-1234"))
+	g.Expect(history.Spec.Annotations).To(gomega.Equal(map[string]string{"team": "web"}))
+	g.Expect(snapshot.Spec.Message).To(gomega.Equal("Raise the cache size"))
+	g.Expect(snapshot.Status.HistoryRef).To(gomega.Equal(history.Name))
+	g.Expect(history.Status.AppliedAt.Time).To(gomega.BeTemporally("==", appliedAt.Time))
+	g.Expect(snapshot.Status.AppliedAt.Time).To(gomega.BeTemporally("==", appliedAt.Time))
+	g.Expect(snapshot.OwnerReferences).To(gomega.HaveLen(1))
+	g.Expect(snapshot.OwnerReferences[0].UID).To(gomega.Equal(history.UID))
+
+	// Snapshots of applies without changes are removed
+	unused := &historyv1alpha1.KronoformSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "unused", Namespace: "prod"},
+		Spec:       historyv1alpha1.KronoformSnapshotSpec{Manifests: manifests},
+	}
+	g.Expect(store.SaveSnapshot(context.TODO(), unused)).To(gomega.Succeed())
+	cleanupSnapshot(store, "unused", "prod")
+	_, err = store.GetSnapshot(context.TODO(), "prod", "unused")
+	g.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue())
+}
+
+func TestGetHistoryRefs(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	store, err := storage.New(storage.Config{Backend: storage.BackendMemory})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	base := time.Now().Add(-3 * time.Hour)
+	var ids []string
+	for i, name := range []string{"kronoform-history-1", "kronoform-history-2", "kronoform-history-3"} {
+		appliedAt := metav1.NewTime(base.Add(time.Duration(i) * time.Hour))
+		record := &historyv1alpha1.KronoformHistory{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       historyv1alpha1.KronoformHistorySpec{Manifests: "kind: ConfigMap\n"},
+			Status:     historyv1alpha1.KronoformHistoryStatus{AppliedAt: &appliedAt},
+		}
+		g.Expect(store.SaveHistory(ctx, record)).To(gomega.Succeed())
+		g.Expect(record.Spec.ID).To(gomega.HaveLen(12))
+		ids = append(ids, record.Spec.ID)
+	}
+
+	for ref, name := range map[string]string{
+		"kronoform-history-2": "kronoform-history-2",
+		ids[0]:                "kronoform-history-1",
+		ids[0][:8]:            "kronoform-history-1",
+		"HEAD":                "kronoform-history-3",
+		"HEAD~2":              "kronoform-history-1",
+		"@{90 minutes ago}":   "kronoform-history-2",
+	} {
+		h, err := getHistory(store, "default", ref)
+		g.Expect(err).NotTo(gomega.HaveOccurred(), ref)
+		g.Expect(h.Name).To(gomega.Equal(name), ref)
+	}
+
+	_, err = getHistory(store, "default", "HEAD~5")
+	g.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue())
+	_, _, err = getHistoryPair(store, "default", "missing")
+	g.Expect(err).To(gomega.MatchError("history missing not found"))
+}
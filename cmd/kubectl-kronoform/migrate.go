@@ -0,0 +1,167 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	allNamespaces, _ := cmd.Flags().GetBool("all-namespaces")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if allNamespaces {
+		namespace = ""
+	} else {
+		namespace = getTargetNamespace(namespace)
+	}
+
+	fmt.Printf("[%s] Kronoform: Starting migrate operation...\n", time.Now().Format("15:04:05"))
+
+	// Create Kubernetes client
+	k8sClient, err := createK8sClient()
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %w", err)
+	}
+
+	migrated, err := migrateRecords(context.Background(), k8sClient, namespace, dryRun, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Printf("[%s] Kronoform: %d records would be migrated (dry run)\n", time.Now().Format("15:04:05"), migrated)
+	} else {
+		fmt.Printf("[%s] Kronoform: Migrated %d records\n", time.Now().Format("15:04:05"), migrated)
+	}
+	return nil
+}
+
+// migrateRecords re-encodes records written before manifests and resource states were
+// compressed, in a namespace (all namespaces if empty). Records that are already encoded,
+// or small enough to be stored as plain text, are left untouched. It returns the number of
+// records that were, or with dryRun would be, migrated.
+func migrateRecords(ctx context.Context, c client.Client, namespace string, dryRun bool, out io.Writer) (int, error) {
+	migrated := 0
+
+	snapshots := &historyv1alpha1.KronoformSnapshotList{}
+	if err := c.List(ctx, snapshots, client.InNamespace(namespace)); err != nil {
+		return migrated, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	for i := range snapshots.Items {
+		s := &snapshots.Items[i]
+		spec := &s.Spec
+		if !needsEncoding(spec.Manifests, spec.ManifestsEncoding, spec.ManifestsChunks) {
+			continue
+		}
+		fmt.Fprintf(out, "KronoformSnapshot %s/%s: manifests\n", s.Namespace, s.Name)
+		migrated++
+		if dryRun {
+			continue
+		}
+		if err := migrateManifests(ctx, c, s, &spec.Manifests, &spec.ManifestsEncoding, &spec.ManifestsChunks); err != nil {
+			return migrated, fmt.Errorf("failed to migrate snapshot %s: %w", s.Name, err)
+		}
+	}
+
+	histories := &historyv1alpha1.KronoformHistoryList{}
+	if err := c.List(ctx, histories, client.InNamespace(namespace)); err != nil {
+		return migrated, fmt.Errorf("failed to list histories: %w", err)
+	}
+	for i := range histories.Items {
+		h := &histories.Items[i]
+		spec := &h.Spec
+		manifests := needsEncoding(spec.Manifests, spec.ManifestsEncoding, spec.ManifestsChunks)
+		states := statesNeedEncoding(h.Status.ResourceSnapshots)
+		if !manifests && !states {
+			continue
+		}
+		var parts []string
+		if manifests {
+			parts = append(parts, "manifests")
+		}
+		if states {
+			parts = append(parts, "states")
+		}
+		fmt.Fprintf(out, "KronoformHistory %s/%s: %s\n", h.Namespace, h.Name, strings.Join(parts, ", "))
+		migrated++
+		if dryRun {
+			continue
+		}
+		if manifests {
+			if err := migrateManifests(ctx, c, h, &spec.Manifests, &spec.ManifestsEncoding, &spec.ManifestsChunks); err != nil {
+				return migrated, fmt.Errorf("failed to migrate history %s: %w", h.Name, err)
+			}
+		}
+		if states {
+			for j := range h.Status.ResourceSnapshots {
+				rs := &h.Status.ResourceSnapshots[j]
+				if rs.Encoding != historyv1alpha1.EncodingPlain {
+					continue
+				}
+				if err := payload.EncodeStates(rs, rs.Before, rs.After); err != nil {
+					return migrated, fmt.Errorf("failed to encode states of history %s: %w", h.Name, err)
+				}
+			}
+			if err := c.Status().Update(ctx, h); err != nil {
+				return migrated, fmt.Errorf("failed to migrate states of history %s: %w", h.Name, err)
+			}
+		}
+	}
+
+	return migrated, nil
+}
+
+// needsEncoding reports whether plain manifests are large enough to be compressed.
+func needsEncoding(data, encoding string, chunks int32) bool {
+	return encoding == historyv1alpha1.EncodingPlain && chunks == 0 && len(data) > payload.CompressThreshold
+}
+
+// statesNeedEncoding reports whether any plain resource state is large enough to be compressed.
+func statesNeedEncoding(snapshots []historyv1alpha1.ResourceSnapshot) bool {
+	for _, rs := range snapshots {
+		if rs.Encoding == historyv1alpha1.EncodingPlain && max(len(rs.Before), len(rs.After)) > payload.CompressThreshold {
+			return true
+		}
+	}
+	return false
+}
+
+// migrateManifests encodes the plain manifests of a record in place and updates it. Chunks
+// are created before the update so that the record never points at missing chunks.
+func migrateManifests(ctx context.Context, c client.Client, obj client.Object, data, encoding *string, chunks *int32) error {
+	packed, packedEncoding, packedChunks, err := payload.Pack(*data)
+	if err != nil {
+		return err
+	}
+	if err := payload.CreateChunks(ctx, c, obj, "", packedChunks); err != nil {
+		return err
+	}
+	*data, *encoding, *chunks = packed, packedEncoding, int32(len(packedChunks))
+	return c.Update(ctx, obj)
+}
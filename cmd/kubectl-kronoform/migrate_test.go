@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+func TestMigrateRecords(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	if err := historyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add scheme: %v", err)
+	}
+
+	large := "apiVersion: v1\nkind: ConfigMap\ndata:\n  blob: " + strings.Repeat("x", 2*payload.CompressThreshold) + "\n"
+	small := "apiVersion: v1\nkind: ConfigMap\n"
+
+	snapshot := &historyv1alpha1.KronoformSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "large", Namespace: "default", UID: types.UID("snapshot")},
+		Spec:       historyv1alpha1.KronoformSnapshotSpec{Manifests: large},
+	}
+	smallSnapshot := &historyv1alpha1.KronoformSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "small", Namespace: "default", UID: types.UID("small")},
+		Spec:       historyv1alpha1.KronoformSnapshotSpec{Manifests: small},
+	}
+	history := &historyv1alpha1.KronoformHistory{
+		ObjectMeta: metav1.ObjectMeta{Name: "observed", Namespace: "default", UID: types.UID("history")},
+		Spec:       historyv1alpha1.KronoformHistorySpec{Manifests: large},
+		Status: historyv1alpha1.KronoformHistoryStatus{
+			ResourceSnapshots: []historyv1alpha1.ResourceSnapshot{{Kind: "ConfigMap", Name: "blob", Before: small, After: large}},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(snapshot, smallSnapshot, history).
+		WithStatusSubresource(&historyv1alpha1.KronoformHistory{}).
+		Build()
+
+	// A dry run only reports the records to migrate
+	var out bytes.Buffer
+	migrated, err := migrateRecords(ctx, fakeClient, "default", true, &out)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(migrated).To(gomega.Equal(2))
+	g.Expect(out.String()).To(gomega.ContainSubstring("KronoformSnapshot default/large: manifests"))
+	g.Expect(out.String()).To(gomega.ContainSubstring("KronoformHistory default/observed: manifests, states"))
+	g.Expect(out.String()).NotTo(gomega.ContainSubstring("default/small"))
+
+	stored := &historyv1alpha1.KronoformSnapshot{}
+	g.Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(snapshot), stored)).To(gomega.Succeed())
+	g.Expect(stored.Spec.ManifestsEncoding).To(gomega.BeEmpty())
+
+	migrated, err = migrateRecords(ctx, fakeClient, "default", false, &bytes.Buffer{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(migrated).To(gomega.Equal(2))
+
+	g.Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(snapshot), stored)).To(gomega.Succeed())
+	g.Expect(stored.Spec.ManifestsEncoding).To(gomega.Equal(historyv1alpha1.EncodingGzipBase64))
+	g.Expect(payload.SnapshotManifests(ctx, fakeClient, stored)).To(gomega.Equal(large))
+
+	storedHistory := &historyv1alpha1.KronoformHistory{}
+	g.Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(history), storedHistory)).To(gomega.Succeed())
+	g.Expect(payload.HistoryManifests(ctx, fakeClient, storedHistory)).To(gomega.Equal(large))
+	rs := storedHistory.Status.ResourceSnapshots[0]
+	g.Expect(rs.Encoding).To(gomega.Equal(historyv1alpha1.EncodingGzipBase64))
+	before, after, err := payload.States(rs)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(before).To(gomega.Equal(small))
+	g.Expect(after).To(gomega.Equal(large))
+
+	// Migrated records are left alone
+	migrated, err = migrateRecords(ctx, fakeClient, "default", false, &bytes.Buffer{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(migrated).To(gomega.BeZero())
+}
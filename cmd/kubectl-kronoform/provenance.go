@@ -0,0 +1,181 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+// ciSystem describes the environment variables a CI system sets in its jobs.
+type ciSystem struct {
+	Name string
+	// Detect is set when running in the CI system
+	Detect string
+	// JobURL builds the URL of the running job
+	JobURL func(getenv func(string) string) string
+	// RunID, Pipeline, Commit and Branch name the variables holding them
+	RunID, Pipeline, Commit, Branch string
+}
+
+// ciSystems are the CI systems whose jobs are recorded in the provenance of a change.
+var ciSystems = []ciSystem{
+	{
+		Name:   "github-actions",
+		Detect: "GITHUB_ACTIONS",
+		JobURL: func(getenv func(string) string) string {
+			if getenv("GITHUB_SERVER_URL") == "" || getenv("GITHUB_REPOSITORY") == "" || getenv("GITHUB_RUN_ID") == "" {
+				return ""
+			}
+			return getenv("GITHUB_SERVER_URL") + "/" + getenv("GITHUB_REPOSITORY") + "/actions/runs/" + getenv("GITHUB_RUN_ID")
+		},
+		RunID: "GITHUB_RUN_ID", Pipeline: "GITHUB_WORKFLOW", Commit: "GITHUB_SHA", Branch: "GITHUB_REF_NAME",
+	},
+	{
+		Name:   "gitlab-ci",
+		Detect: "GITLAB_CI",
+		JobURL: variable("CI_JOB_URL"),
+		RunID:  "CI_PIPELINE_ID", Pipeline: "CI_PROJECT_PATH", Commit: "CI_COMMIT_SHA", Branch: "CI_COMMIT_REF_NAME",
+	},
+	{
+		Name:   "jenkins",
+		Detect: "JENKINS_URL",
+		JobURL: variable("BUILD_URL"),
+		RunID:  "BUILD_NUMBER", Pipeline: "JOB_NAME", Commit: "GIT_COMMIT", Branch: "GIT_BRANCH",
+	},
+	{
+		Name:   "circleci",
+		Detect: "CIRCLECI",
+		JobURL: variable("CIRCLE_BUILD_URL"),
+		RunID:  "CIRCLE_WORKFLOW_ID", Pipeline: "CIRCLE_JOB", Commit: "CIRCLE_SHA1", Branch: "CIRCLE_BRANCH",
+	},
+	{
+		Name:   "azure-pipelines",
+		Detect: "TF_BUILD",
+		JobURL: func(getenv func(string) string) string {
+			if getenv("SYSTEM_COLLECTIONURI") == "" || getenv("BUILD_BUILDID") == "" {
+				return ""
+			}
+			return getenv("SYSTEM_COLLECTIONURI") + url.PathEscape(getenv("SYSTEM_TEAMPROJECT")) +
+				"/_build/results?buildId=" + getenv("BUILD_BUILDID")
+		},
+		RunID: "BUILD_BUILDID", Pipeline: "BUILD_DEFINITIONNAME", Commit: "BUILD_SOURCEVERSION", Branch: "BUILD_SOURCEBRANCHNAME",
+	},
+	{
+		Name:   "buildkite",
+		Detect: "BUILDKITE",
+		JobURL: variable("BUILDKITE_BUILD_URL"),
+		RunID:  "BUILDKITE_BUILD_ID", Pipeline: "BUILDKITE_PIPELINE_SLUG", Commit: "BUILDKITE_COMMIT", Branch: "BUILDKITE_BRANCH",
+	},
+}
+
+func variable(name string) func(getenv func(string) string) string {
+	return func(getenv func(string) string) string { return getenv(name) }
+}
+
+// detectProvenance returns where the manifests applied from filenames come from: the git
+// checkout of the first local file or directory, or of the working directory for stdin,
+// and the CI job running the apply. It returns nil when neither is found.
+func detectProvenance(filenames []string, getenv func(string) string) *historyv1alpha1.Provenance {
+	provenance := &historyv1alpha1.Provenance{}
+	if dir := manifestDir(filenames); dir != "" {
+		detectCheckout(provenance, dir)
+	}
+	for _, ci := range ciSystems {
+		if getenv(ci.Detect) == "" {
+			continue
+		}
+		provenance.CI = ci.Name
+		provenance.JobURL = ci.JobURL(getenv)
+		provenance.RunID = getenv(ci.RunID)
+		provenance.Pipeline = getenv(ci.Pipeline)
+		// CI jobs often check out a detached commit, whose branch only the job knows
+		if provenance.Commit == "" {
+			provenance.Commit = getenv(ci.Commit)
+		}
+		if provenance.Branch == "" {
+			provenance.Branch = getenv(ci.Branch)
+		}
+		break
+	}
+	if *provenance == (historyv1alpha1.Provenance{}) {
+		return nil
+	}
+	return provenance
+}
+
+// manifestDir returns the directory of the first local manifest file, the directory itself
+// when a directory is applied, or the working directory when manifests are read from stdin.
+func manifestDir(filenames []string) string {
+	for _, filename := range filenames {
+		if strings.Contains(filename, "://") {
+			continue
+		}
+		if filename == "-" {
+			filename = "."
+		}
+		info, err := os.Stat(filename)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			return filename
+		}
+		return filepath.Dir(filename)
+	}
+	return ""
+}
+
+// detectCheckout records the git commit, branch, dirty state and remote of dir. Nothing
+// is recorded when git is not installed or dir is not in a repository.
+func detectCheckout(provenance *historyv1alpha1.Provenance, dir string) {
+	commit, err := runGit(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return
+	}
+	provenance.Commit = commit
+	if branch, err := runGit(dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+		provenance.Branch = branch
+	}
+	if status, err := runGit(dir, "status", "--porcelain", "--", "."); err == nil {
+		provenance.Dirty = status != ""
+	}
+	if remote, err := runGit(dir, "remote", "get-url", "origin"); err == nil {
+		provenance.Remote = stripCredentials(remote)
+	}
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// stripCredentials removes the user information of an HTTP remote URL, which may hold a
+// token. SSH remotes such as git@github.com:org/repo.git are kept as they are.
+func stripCredentials(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return remote
+	}
+	u.User = nil
+	return u.String()
+}
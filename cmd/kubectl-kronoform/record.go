@@ -0,0 +1,140 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/redact"
+)
+
+// applyRecorder records the live state of the resources an apply changes, before and
+// after kubectl applies them, like restore and revert record the resources they change.
+type applyRecorder struct {
+	client    client.Client
+	redactor  *redact.Redactor
+	manifests string
+	namespace string
+	// before is the live state of each applied resource before the apply, nil when it did
+	// not exist
+	before map[history.ResourceKey]*unstructured.Unstructured
+}
+
+// newApplyRecorder reads the live state of the resources of the applied manifests.
+func newApplyRecorder(ctx context.Context, c client.Client, redactor *redact.Redactor,
+	manifests, namespace string) (*applyRecorder, error) {
+	objects, err := appliedObjects(manifests, namespace)
+	if err != nil {
+		return nil, err
+	}
+	r := &applyRecorder{
+		client:    c,
+		redactor:  redactor,
+		manifests: manifests,
+		namespace: namespace,
+		before:    map[history.ResourceKey]*unstructured.Unstructured{},
+	}
+	for _, obj := range objects {
+		key, live, err := r.getLive(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		r.before[key] = live
+	}
+	return r, nil
+}
+
+// getLive returns the key and live state of an applied object, nil when it does not exist.
+func (r *applyRecorder) getLive(ctx context.Context, obj *unstructured.Unstructured) (history.ResourceKey, *unstructured.Unstructured, error) {
+	target, err := liveTarget(r.client, obj)
+	if err != nil {
+		return history.ResourceKey{}, nil, fmt.Errorf("%s/%s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	key := history.ResourceKey{
+		Group:     target.GroupVersionKind().Group,
+		Kind:      target.GetKind(),
+		Namespace: target.GetNamespace(),
+		Name:      target.GetName(),
+	}
+	err = r.client.Get(ctx, client.ObjectKeyFromObject(target), target)
+	if apierrors.IsNotFound(err) {
+		return key, nil, nil
+	}
+	if err != nil {
+		return key, nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	return key, target, nil
+}
+
+// recorded returns the recorded states of the resources kubectl reported as created or
+// configured, before and after the apply.
+func (r *applyRecorder) recorded(ctx context.Context, kubectlOutput string) ([]historyv1alpha1.ResourceSnapshot, error) {
+	changed, err := changedObjects(r.manifests, kubectlOutput, r.namespace)
+	if err != nil {
+		return nil, err
+	}
+	var resources []historyv1alpha1.ResourceSnapshot
+	for _, obj := range changed {
+		key, after, err := r.getLive(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		before := r.before[key]
+		if before == nil && after == nil {
+			// Created and deleted again since, e.g. by a controller
+			continue
+		}
+		rs := historyv1alpha1.ResourceSnapshot{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       key.Kind,
+			Name:       key.Name,
+			Namespace:  key.Namespace,
+			Operation:  historyv1alpha1.OperationUpdated,
+		}
+		switch {
+		case after == nil:
+			rs.Operation = historyv1alpha1.OperationDeleted
+		case before == nil:
+			rs.Operation = historyv1alpha1.OperationCreated
+		}
+		if rs.Before, err = history.StateYAML(r.redactor.Unstructured(before)); err != nil {
+			return nil, err
+		}
+		if rs.After, err = history.StateYAML(r.redactor.Unstructured(after)); err != nil {
+			return nil, err
+		}
+		resources = append(resources, rs)
+	}
+	return resources, nil
+}
+
+// recordResources records the states of the resources changed by a history.
+func recordResources(record *historyv1alpha1.KronoformHistory, resources []historyv1alpha1.ResourceSnapshot) {
+	record.Status.ResourceSnapshots = resources
+	for _, rs := range resources {
+		record.Spec.ResourceTypes = append(record.Spec.ResourceTypes, rs.Kind)
+		record.Spec.ResourceNames = append(record.Spec.ResourceNames, rs.Name)
+		record.Spec.ResourceNamespaces = append(record.Spec.ResourceNamespaces, rs.Namespace)
+	}
+}
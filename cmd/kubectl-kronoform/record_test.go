@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func TestRecordAppliedStates(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+	manifests := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  color: red
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+stringData:
+  password: hunter2
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+`
+	k8sClient := newWhyClient(t,
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "prod"},
+			Data:       map[string]string{"color": "blue"},
+		},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unchanged", Namespace: "prod"}},
+	)
+
+	recorder, err := newApplyRecorder(ctx, k8sClient, nil, manifests, "prod")
+	g.Expect(err).To(gomega.BeNil())
+
+	// what kubectl apply does
+	settings := &corev1.ConfigMap{}
+	g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "prod", Name: "settings"}, settings)).To(gomega.Succeed())
+	settings.Data["color"] = "red"
+	g.Expect(k8sClient.Update(ctx, settings)).To(gomega.Succeed())
+	g.Expect(k8sClient.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "prod"},
+		Data:       map[string][]byte{"password": []byte("hunter2")},
+	})).To(gomega.Succeed())
+	g.Expect(k8sClient.Create(ctx, &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "reader"}})).To(gomega.Succeed())
+
+	resources, err := recorder.recorded(ctx, "configmap/settings configured\nsecret/credentials created\n"+
+		"clusterrole.rbac.authorization.k8s.io/reader created\nconfigmap/unchanged unchanged\n")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(resources).To(gomega.HaveLen(3))
+
+	g.Expect(history.KeyOf(resources[0]).String()).To(gomega.Equal("ConfigMap/prod/settings"))
+	g.Expect(resources[0].Operation).To(gomega.Equal(historyv1alpha1.OperationUpdated))
+	g.Expect(resources[0].Before).To(gomega.ContainSubstring("color: blue"))
+	g.Expect(resources[0].After).To(gomega.ContainSubstring("color: red"))
+
+	// Secret data is redacted
+	g.Expect(resources[1].Operation).To(gomega.Equal(historyv1alpha1.OperationCreated))
+	g.Expect(resources[1].Before).To(gomega.BeEmpty())
+	g.Expect(resources[1].After).NotTo(gomega.ContainSubstring("aHVudGVyMg=="))
+
+	// cluster-scoped resources are recorded without a namespace
+	g.Expect(history.KeyOf(resources[2]).String()).To(gomega.Equal("ClusterRole.rbac.authorization.k8s.io/reader"))
+
+	// the history is the latest record of the resources it changed
+	store := storage.NewMemoryStore()
+	snapshotName, err := createSnapshot(store, manifests, "prod", changeContext{})
+	g.Expect(err).To(gomega.BeNil())
+	record, err := createHistory(store, manifests, snapshotName, "prod", changeContext{}, metav1.Now(), resources, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(record.Spec.ResourceTypes).To(gomega.Equal([]string{"ConfigMap", "Secret", "ClusterRole"}))
+	histories, err := store.ListHistories(ctx, storage.ListOptions{Namespace: "prod"})
+	g.Expect(err).To(gomega.BeNil())
+	latest := history.Latest(histories, history.KeyOf(resources[0]))
+	g.Expect(latest).NotTo(gomega.BeNil())
+	g.Expect(latest.Name).To(gomega.Equal(record.Name))
+}
@@ -0,0 +1,568 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/fielddiff"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/redact"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+// Actions of a restore plan.
+const (
+	restoreCreate = "create"
+	restoreUpdate = "update"
+	restoreDelete = "delete"
+)
+
+// restoreOrder is the order in which kinds are created and updated, so that resources
+// exist before the resources using them; kinds are deleted in the reverse order. It
+// follows the install order of Helm. Other kinds come last.
+var restoreOrder = []string{
+	"Namespace", "NetworkPolicy", "ResourceQuota", "LimitRange", "PodDisruptionBudget",
+	"ServiceAccount", "Secret", "ConfigMap", "StorageClass", "PersistentVolume",
+	"PersistentVolumeClaim", "CustomResourceDefinition", "ClusterRole", "ClusterRoleBinding",
+	"Role", "RoleBinding", "Service", "DaemonSet", "Pod", "ReplicationController", "ReplicaSet",
+	"Deployment", "HorizontalPodAutoscaler", "StatefulSet", "Job", "CronJob", "IngressClass",
+	"Ingress", "APIService",
+}
+
+// restoreAction is a change returning a resource to its state at a point in time.
+type restoreAction struct {
+	Key    history.ResourceKey
+	GVK    schema.GroupVersionKind
+	Action string
+	// Target is the state to restore, nil for deletions
+	Target map[string]interface{}
+	// Partial is set when Target is an applied manifest, merged into the live state
+	Partial bool
+	// Changes are the fields an update restores, from their live value to the restored one
+	Changes []fielddiff.Change
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	to, _ := cmd.Flags().GetString("to")
+	selector, _ := cmd.Flags().GetString("selector")
+	only, _ := cmd.Flags().GetStringArray("only")
+	paths, _ := cmd.Flags().GetStringArray("path")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	namespace = getTargetNamespace(namespace)
+	change, err := getChangeContext(cmd)
+	if err != nil {
+		return err
+	}
+	for i, path := range paths {
+		if paths[i], err = restorePath(path); err != nil {
+			return err
+		}
+	}
+
+	// Open the history store
+	store, err := newStore(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+	redactor, err := newRedactor(cmd)
+	if err != nil {
+		return err
+	}
+
+	at, err := restorePoint(store, namespace, to)
+	if err != nil {
+		return err
+	}
+	states, err := namespaceAt(store, namespace, at)
+	if err != nil {
+		return err
+	}
+	states, err = selectStates(states, namespace, selector, append(args, only...))
+	if err != nil {
+		return err
+	}
+	if len(states) == 0 {
+		return fmt.Errorf("no recorded resource of namespace %s matches", namespace)
+	}
+
+	// Create Kubernetes client
+	k8sClient, err := createK8sClient()
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	ctx := context.Background()
+	plan, err := planRestore(ctx, k8sClient, redactor, states, paths)
+	if err != nil {
+		return err
+	}
+	printPlan(os.Stdout, plan, "Restore to "+at.Format(time.RFC3339),
+		"Recorded resources already match their state at "+at.Format(time.RFC3339))
+	if len(plan) == 0 || dryRun {
+		return nil
+	}
+
+	record, err := restore(ctx, k8sClient, store, redactor, plan, at, namespace, change)
+	if record != nil {
+		_, _ = fmt.Fprintf(os.Stdout, "Recorded history %s (%s)\n", history.ID(record), record.Name)
+	}
+	return err
+}
+
+// restorePoint returns the point in time given by --to: a time as in history references,
+// or a history, whose resources are restored to their state right after it.
+func restorePoint(store storage.Store, namespace, to string) (time.Time, error) {
+	at, err := history.ParseTime(to, time.Now())
+	if err == nil {
+		return at, nil
+	}
+	h, refErr := getHistory(store, namespace, to)
+	if refErr != nil {
+		return time.Time{}, fmt.Errorf("--to %q is neither a time (%v) nor a history: %w", to, err, refErr)
+	}
+	return history.RecordedAt(h), nil
+}
+
+// restorePath validates a field path given to restore, and returns it as written by
+// fielddiff.Diff.
+func restorePath(path string) (string, error) {
+	if _, _, err := fielddiff.Lookup(nil, path); err != nil {
+		return "", fmt.Errorf("invalid path %q: %w", path, err)
+	}
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	if path == "." || !strings.HasPrefix(path, ".") {
+		return "", fmt.Errorf("invalid path %q: expected the path of a field, e.g. .spec.replicas", path)
+	}
+	return path, nil
+}
+
+// selectStates keeps the states of the resources matching a label selector and given as
+// kind[.group]/name. Resources that did not exist at the time are matched by the labels
+// they were last recorded with.
+func selectStates(states []history.State, namespace, selector string, resources []string) ([]history.State, error) {
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+	var matchers []func(history.ResourceKey) bool
+	for _, resource := range resources {
+		match, err := history.MatchResource(scopeResource(resource, namespace))
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, match)
+	}
+
+	var selected []history.State
+	for _, state := range states {
+		if len(matchers) > 0 && !matchesAny(matchers, state.Key) {
+			continue
+		}
+		if !sel.Empty() {
+			set, err := stateLabels(state)
+			if err != nil {
+				return nil, err
+			}
+			if !sel.Matches(set) {
+				continue
+			}
+		}
+		selected = append(selected, state)
+	}
+	return selected, nil
+}
+
+func matchesAny(matchers []func(history.ResourceKey) bool, key history.ResourceKey) bool {
+	for _, match := range matchers {
+		if match(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// stateLabels returns the labels of a resource at the time, or last recorded.
+func stateLabels(state history.State) (labels.Set, error) {
+	content := state.State
+	if content == "" {
+		if content = state.Latest.After; content == "" {
+			content = state.Latest.Before
+		}
+	}
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(content), &obj.Object); err != nil {
+		return nil, fmt.Errorf("failed to read the state of %s: %w", state.Key, err)
+	}
+	return obj.GetLabels(), nil
+}
+
+// planRestore returns the actions returning resources to their state at a time, in the
+// order to apply them: creates and updates in dependency order, then deletes in reverse.
+// When paths are given, only the fields at or under them are restored, in resources that
+// exist both at the time and live; their other fields keep their live value. Recorded
+// states hold redacted values in place of secrets; resources whose redacted values
+// changed since cannot be restored.
+func planRestore(ctx context.Context, c client.Client, redactor *redact.Redactor, states []history.State,
+	paths []string) ([]restoreAction, error) {
+	differences, err := compareLive(ctx, c, redactor, states)
+	if err != nil {
+		return nil, err
+	}
+	byKey := map[history.ResourceKey]history.State{}
+	for _, state := range states {
+		byKey[state.Key] = state
+	}
+
+	var plan []restoreAction
+	for _, d := range differences {
+		if len(paths) > 0 {
+			if d.Kind != differenceModified {
+				continue
+			}
+			if d.Changes = changesUnder(d.Changes, paths); len(d.Changes) == 0 {
+				continue
+			}
+		}
+		state := byKey[d.Key]
+		gvk, err := recordedGVK(state)
+		if err != nil {
+			return nil, err
+		}
+		action := restoreAction{Key: d.Key, GVK: gvk, Partial: state.Partial}
+		if d.Kind != differenceCreated {
+			if action.Target, err = fielddiff.Parse(state.State); err != nil {
+				return nil, fmt.Errorf("failed to read the state of %s: %w", d.Key, err)
+			}
+		}
+		switch d.Kind {
+		case differenceCreated:
+			action.Action = restoreDelete
+		case differenceDeleted:
+			action.Action = restoreCreate
+			for _, f := range fielddiff.Fields(action.Target) {
+				if redact.IsRedacted(f.Value) {
+					return nil, fmt.Errorf("cannot recreate %s: the value of %s was redacted when recorded", d.Key, f.Path)
+				}
+			}
+		case differenceModified:
+			action.Action = restoreUpdate
+			for _, change := range d.Changes {
+				if redact.IsRedacted(change.Before) {
+					return nil, fmt.Errorf("cannot restore %s: the value of %s was redacted when recorded and changed since", d.Key, change.Path)
+				}
+				action.Changes = append(action.Changes, fielddiff.Change{Path: change.Path, Before: change.After, After: change.Before})
+			}
+			if len(paths) > 0 {
+				if action.Target, err = mergeChanges(d.Live, action.Changes); err != nil {
+					return nil, fmt.Errorf("cannot restore %s: %w", d.Key, err)
+				}
+				action.Partial = false
+			}
+		}
+		plan = append(plan, action)
+	}
+
+	sortPlan(plan)
+	return plan, nil
+}
+
+// changesUnder keeps the changes of the fields at or under one of paths.
+func changesUnder(changes []fielddiff.Change, paths []string) []fielddiff.Change {
+	var kept []fielddiff.Change
+	for _, change := range changes {
+		for _, path := range paths {
+			if change.Path == path || strings.HasPrefix(change.Path, path) &&
+				strings.ContainsAny(change.Path[len(path):len(path)+1], ".[") {
+				kept = append(kept, change)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// sortPlan orders the actions of a plan to apply them: creates and updates in dependency
+// order, then deletes in reverse.
+func sortPlan(plan []restoreAction) {
+	sort.SliceStable(plan, func(i, j int) bool {
+		a, b := plan[i], plan[j]
+		if (a.Action == restoreDelete) != (b.Action == restoreDelete) {
+			return b.Action == restoreDelete
+		}
+		if a.Action == restoreDelete {
+			return kindOrder(a.Key.Kind) > kindOrder(b.Key.Kind)
+		}
+		return kindOrder(a.Key.Kind) < kindOrder(b.Key.Kind)
+	})
+}
+
+// kindOrder returns the position of a kind in restoreOrder.
+func kindOrder(kind string) int {
+	for i, k := range restoreOrder {
+		if k == kind {
+			return i
+		}
+	}
+	return len(restoreOrder)
+}
+
+// printPlan prints the actions of a plan under a title, or none when there are none.
+func printPlan(out io.Writer, plan []restoreAction, title, none string) {
+	if len(plan) == 0 {
+		_, _ = fmt.Fprintln(out, none)
+		return
+	}
+	_, _ = fmt.Fprintln(out, title+":")
+	for _, action := range plan {
+		_, _ = fmt.Fprintf(out, "  %-7s %s\n", action.Action, action.Key)
+		for _, change := range action.Changes {
+			_, _ = fmt.Fprintf(out, "    %s: %s\n", change.Path, formatChange(change.Before, change.After))
+		}
+	}
+}
+
+// restore applies a restore plan and records it as one history.
+func restore(ctx context.Context, c client.Client, store storage.Store, redactor *redact.Redactor, plan []restoreAction,
+	at time.Time, namespace string, change changeContext) (*historyv1alpha1.KronoformHistory, error) {
+	point := at.Format(time.RFC3339)
+	return applyPlan(ctx, c, store, redactor, plan, namespace, change, func(record *historyv1alpha1.KronoformHistory, applied int, err error) {
+		record.Spec.Description = fmt.Sprintf("Restored by %s to %s", record.Spec.AppliedBy, point)
+		record.Status.Summary = fmt.Sprintf("Restored %d resources to their state at %s", applied, point)
+		if err != nil {
+			record.Status.Summary = fmt.Sprintf("Restored %d of %d resources to their state at %s, then failed: %v",
+				applied, len(plan), point, err)
+		}
+	})
+}
+
+// applyPlan applies a plan and records it as one history, which describe completes with
+// the number of actions applied and the error that stopped the plan. The history is
+// recorded with the actions applied so far when an action fails.
+func applyPlan(ctx context.Context, c client.Client, store storage.Store, redactor *redact.Redactor, plan []restoreAction,
+	namespace string, change changeContext, describe func(record *historyv1alpha1.KronoformHistory, applied int, err error)) (*historyv1alpha1.KronoformHistory, error) {
+	manifests, err := restoreManifests(redactor, plan)
+	if err != nil {
+		return nil, err
+	}
+	// The snapshot is created first so that a message required by policy is asked for
+	// before anything changes
+	snapshotName, err := createSnapshot(store, manifests, namespace, change)
+	if messageRequired(err) {
+		return nil, fmt.Errorf("refusing to change resources without a message, use -m to explain the change: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	resources, changed, applyErr := applyRestore(ctx, c, redactor, plan)
+	if len(resources) == 0 {
+		cleanupSnapshot(store, snapshotName, namespace)
+		return nil, applyErr
+	}
+
+	record := newHistoryRecord(manifests, snapshotName, namespace, change)
+	recordResources(record, resources)
+	describe(record, len(resources), applyErr)
+	if err := saveHistoryRecord(store, record); err != nil {
+		return nil, fmt.Errorf("changed %d resources but failed to record the history: %w", len(resources), errors.Join(applyErr, err))
+	}
+
+	// Let the changed resources point back at their history
+	if err := stampObjects(ctx, c, changed, record); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: could not annotate changed resources: %v\n", err)
+	}
+	return record, applyErr
+}
+
+// restoreManifests renders the states a plan restores as a redacted multi-document bundle.
+func restoreManifests(redactor *redact.Redactor, plan []restoreAction) (string, error) {
+	var documents []string
+	for _, action := range plan {
+		if action.Target == nil {
+			continue
+		}
+		data, err := yaml.Marshal(action.Target)
+		if err != nil {
+			return "", err
+		}
+		documents = append(documents, string(data))
+	}
+	return redactor.Manifests(strings.Join(documents, "---\n"))
+}
+
+// applyRestore applies the actions of a plan in order with kronoform's field manager, and
+// returns the recorded states of the resources changed and the resources still existing.
+// It stops at the first action that fails.
+func applyRestore(ctx context.Context, c client.Client, redactor *redact.Redactor,
+	plan []restoreAction) ([]historyv1alpha1.ResourceSnapshot, []*unstructured.Unstructured, error) {
+	var resources []historyv1alpha1.ResourceSnapshot
+	var changed []*unstructured.Unstructured
+	for _, action := range plan {
+		before, after, err := applyAction(ctx, c, redactor, action)
+		if err != nil {
+			return resources, changed, fmt.Errorf("failed to %s %s: %w", action.Action, action.Key, err)
+		}
+		rs := historyv1alpha1.ResourceSnapshot{
+			APIVersion: action.GVK.GroupVersion().String(),
+			Kind:       action.GVK.Kind,
+			Name:       action.Key.Name,
+			Namespace:  action.Key.Namespace,
+		}
+		switch action.Action {
+		case restoreCreate:
+			rs.Operation = historyv1alpha1.OperationCreated
+		case restoreUpdate:
+			rs.Operation = historyv1alpha1.OperationUpdated
+		case restoreDelete:
+			rs.Operation = historyv1alpha1.OperationDeleted
+		}
+		if rs.Before, err = history.StateYAML(redactor.Unstructured(before)); err != nil {
+			return resources, changed, err
+		}
+		if rs.After, err = history.StateYAML(redactor.Unstructured(after)); err != nil {
+			return resources, changed, err
+		}
+		resources = append(resources, rs)
+		if after != nil {
+			changed = append(changed, after)
+		}
+	}
+	return resources, changed, nil
+}
+
+// applyAction applies an action of a restore plan and returns the resource before and
+// after it, nil where it does not exist.
+func applyAction(ctx context.Context, c client.Client, redactor *redact.Redactor,
+	action restoreAction) (*unstructured.Unstructured, *unstructured.Unstructured, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(action.GVK)
+	err := c.Get(ctx, client.ObjectKey{Namespace: action.Key.Namespace, Name: action.Key.Name}, live)
+	if apierrors.IsNotFound(err) {
+		live = nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	switch action.Action {
+	case restoreDelete:
+		if live == nil {
+			return nil, nil, nil
+		}
+		return live, nil, c.Delete(ctx, live)
+	case restoreCreate:
+		if live != nil {
+			return nil, nil, fmt.Errorf("it was created again since the plan was made")
+		}
+		target := &unstructured.Unstructured{Object: runtime.DeepCopyJSON(action.Target)}
+		target.SetGroupVersionKind(action.GVK)
+		if err := c.Create(ctx, target, client.FieldOwner(historyv1alpha1.FieldManager)); err != nil {
+			return nil, nil, err
+		}
+		return nil, target, nil
+	}
+
+	if live == nil {
+		return nil, nil, fmt.Errorf("it was deleted since the plan was made")
+	}
+	target := &unstructured.Unstructured{Object: runtime.DeepCopyJSON(action.Target)}
+	if err := unredact(target.Object, live, redactor); err != nil {
+		return nil, nil, err
+	}
+	target.SetGroupVersionKind(action.GVK)
+	// Applied manifests hold only the fields they set; the others are left as they are
+	if action.Partial {
+		patch, err := json.Marshal(target.Object)
+		if err != nil {
+			return nil, nil, err
+		}
+		target.SetNamespace(action.Key.Namespace)
+		target.SetName(action.Key.Name)
+		if err := c.Patch(ctx, target, client.RawPatch(types.MergePatchType, patch),
+			client.FieldOwner(historyv1alpha1.FieldManager)); err != nil {
+			return nil, nil, err
+		}
+		return live, target, nil
+	}
+	target.SetResourceVersion(live.GetResourceVersion())
+	if err := c.Update(ctx, target, client.FieldOwner(historyv1alpha1.FieldManager)); err != nil {
+		return nil, nil, err
+	}
+	return live, target, nil
+}
+
+// mergeChanges returns a copy of live with the fields of changes set to their After
+// value, or removed where it is nil.
+func mergeChanges(live map[string]interface{}, changes []fielddiff.Change) (map[string]interface{}, error) {
+	merged := runtime.DeepCopyJSON(live)
+	// List items are appended in order, and removed last first so that the indexes of the
+	// others still hold
+	for _, change := range changes {
+		if change.After == nil {
+			continue
+		}
+		if err := fielddiff.Set(merged, change.Path, change.After); err != nil {
+			return nil, err
+		}
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		if change := changes[i]; change.After == nil {
+			if err := fielddiff.Delete(merged, change.Path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return merged, nil
+}
+
+// unredact replaces the redacted values of a state to restore with the live values they
+// were redacted from. Values that changed since cannot be restored.
+func unredact(target map[string]interface{}, live *unstructured.Unstructured, redactor *redact.Redactor) error {
+	redacted := redactor.Unstructured(live)
+	for _, f := range fielddiff.Fields(target) {
+		if !redact.IsRedacted(f.Value) {
+			continue
+		}
+		if value, _, _ := fielddiff.Lookup(redacted.Object, f.Path); value != f.Value {
+			return fmt.Errorf("the value of %s was redacted when recorded and changed since", f.Path)
+		}
+		value, _, _ := fielddiff.Lookup(live.Object, f.Path)
+		if err := fielddiff.Set(target, f.Path, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/redact"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func TestRestore(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+
+	base := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	record := func(name string, at time.Time, resources ...historyv1alpha1.ResourceSnapshot) {
+		appliedAt := metav1.NewTime(at)
+		h := &historyv1alpha1.KronoformHistory{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       historyv1alpha1.KronoformHistorySpec{AppliedBy: "alice"},
+			Status:     historyv1alpha1.KronoformHistoryStatus{AppliedAt: &appliedAt, ResourceSnapshots: resources},
+		}
+		g.Expect(store.SaveHistory(ctx, h)).To(gomega.Succeed())
+	}
+	configMap := func(name, operation, before, after string) historyv1alpha1.ResourceSnapshot {
+		return historyv1alpha1.ResourceSnapshot{
+			APIVersion: "v1", Kind: "ConfigMap", Name: name, Namespace: "default",
+			Operation: operation, Before: before, After: after,
+		}
+	}
+	configMapState := func(name, color string) string {
+		return "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + name + "\n  namespace: default\ndata:\n  color: " + color + "\n"
+	}
+	secret := map[string]interface{}{
+		"apiVersion": "v1", "kind": "Secret",
+		"metadata": map[string]interface{}{"name": "creds", "namespace": "default", "labels": map[string]interface{}{"tier": "a"}},
+		"data":     map[string]interface{}{"password": "aHVudGVyMg=="},
+	}
+	var noRedaction *redact.Redactor
+	noRedaction.Object(secret)
+	secretState, err := yaml.Marshal(secret)
+	g.Expect(err).To(gomega.BeNil())
+
+	record("create", base,
+		configMap("settings", historyv1alpha1.OperationCreated, "", configMapState("settings", "blue")),
+		historyv1alpha1.ResourceSnapshot{APIVersion: "v1", Kind: "Secret", Name: "creds", Namespace: "default",
+			Operation: historyv1alpha1.OperationCreated, After: string(secretState)})
+	record("update", base.Add(2*time.Hour),
+		configMap("settings", historyv1alpha1.OperationUpdated, configMapState("settings", "blue"), configMapState("settings", "red")),
+		configMap("extra", historyv1alpha1.OperationCreated, "", configMapState("extra", "white")))
+	record("remove", base.Add(3*time.Hour),
+		configMap("old", historyv1alpha1.OperationDeleted, configMapState("old", "black"), ""))
+
+	k8sClient := newWhyClient(t,
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"}, Data: map[string]string{"color": "red"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "extra", Namespace: "default"}, Data: map[string]string{"color": "white"}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "default", Labels: map[string]string{"tier": "b"}},
+			Data:       map[string][]byte{"password": []byte("hunter2")},
+		},
+	)
+
+	at := base.Add(time.Hour)
+	states, err := namespaceAt(store, "default", at)
+	g.Expect(err).To(gomega.BeNil())
+
+	selected, err := selectStates(states, "default", "", []string{"configmap/settings", "secret/creds"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(selected).To(gomega.HaveLen(2))
+	selected, err = selectStates(states, "default", "tier=a", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(selected).To(gomega.HaveLen(1))
+	g.Expect(selected[0].Key.Kind).To(gomega.Equal("Secret"))
+
+	plan, err := planRestore(ctx, k8sClient, nil, states, nil)
+	g.Expect(err).To(gomega.BeNil())
+	var out bytes.Buffer
+	printPlan(&out, plan, "Restore to 2025-08-06T11:00:00Z", "Recorded resources already match their state at 2025-08-06T11:00:00Z")
+	g.Expect(out.String()).To(gomega.Equal("Restore to 2025-08-06T11:00:00Z:\n" +
+		"  update  Secret/default/creds\n" +
+		"    .metadata.labels.tier: b -> a\n" +
+		"  create  ConfigMap/default/old\n" +
+		"  update  ConfigMap/default/settings\n" +
+		"    .data.color: red -> blue\n" +
+		"  delete  ConfigMap/default/extra\n"))
+
+	restored, err := restore(ctx, k8sClient, store, nil, plan, at, "default", changeContext{Message: "Undo the launch"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(restored.Spec.Message).To(gomega.Equal("Undo the launch"))
+	g.Expect(restored.Status.ResourceSnapshots).To(gomega.HaveLen(4))
+	g.Expect(restored.Status.Summary).To(gomega.Equal("Restored 4 resources to their state at 2025-08-06T11:00:00Z"))
+
+	settings := &corev1.ConfigMap{}
+	g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "settings"}, settings)).To(gomega.Succeed())
+	g.Expect(settings.Data).To(gomega.Equal(map[string]string{"color": "blue"}))
+	g.Expect(settings.Annotations).To(gomega.HaveKeyWithValue(historyv1alpha1.AnnotationHistory, restored.Name))
+	old := &corev1.ConfigMap{}
+	g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "old"}, old)).To(gomega.Succeed())
+	g.Expect(old.Data).To(gomega.Equal(map[string]string{"color": "black"}))
+	err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "extra"}, &corev1.ConfigMap{})
+	g.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue())
+	// the redacted password was restored from the live secret
+	creds := &corev1.Secret{}
+	g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "creds"}, creds)).To(gomega.Succeed())
+	g.Expect(creds.Labels).To(gomega.HaveKeyWithValue("tier", "a"))
+	g.Expect(creds.Data).To(gomega.HaveKeyWithValue("password", []byte("hunter2")))
+
+	// the restore is part of the timeline and recorded redacted
+	entries, err := resourceTimeline(store, "default", "secret/creds")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(entries).To(gomega.HaveLen(2))
+	g.Expect(entries[1].After).NotTo(gomega.ContainSubstring("aHVudGVyMg=="))
+	g.Expect(entries[1].After).To(gomega.ContainSubstring(redact.Prefix))
+
+	plan, err = planRestore(ctx, k8sClient, nil, states, nil)
+	g.Expect(err).To(gomega.BeNil())
+	out.Reset()
+	printPlan(&out, plan, "Restore to 2025-08-06T11:00:00Z", "Recorded resources already match their state at 2025-08-06T11:00:00Z")
+	g.Expect(out.String()).To(gomega.Equal("Recorded resources already match their state at 2025-08-06T11:00:00Z\n"))
+
+	// secrets cannot be recreated from their redacted state
+	_, err = planRestore(ctx, newWhyClient(t), nil, states, nil)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("cannot recreate Secret/default/creds")))
+
+	h, err := getHistory(store, "default", history.ID(restored))
+	g.Expect(err).To(gomega.BeNil())
+	point, err := restorePoint(store, "default", h.Name)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(point).To(gomega.Equal(history.RecordedAt(h)))
+	_, err = restorePoint(store, "default", "not a time")
+	g.Expect(err).NotTo(gomega.BeNil())
+}
+
+func TestRestorePaths(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+
+	base := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	record := func(name string, at time.Time, resources ...historyv1alpha1.ResourceSnapshot) {
+		appliedAt := metav1.NewTime(at)
+		h := &historyv1alpha1.KronoformHistory{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       historyv1alpha1.KronoformHistorySpec{AppliedBy: "alice"},
+			Status:     historyv1alpha1.KronoformHistoryStatus{AppliedAt: &appliedAt, ResourceSnapshots: resources},
+		}
+		g.Expect(store.SaveHistory(ctx, h)).To(gomega.Succeed())
+	}
+	deployment := func(image string) string {
+		return "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: default\n" +
+			"spec:\n  replicas: 2\n  template:\n    spec:\n      containers:\n      - name: web\n        image: " + image + "\n"
+	}
+	configMap := func(color string) string {
+		return "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n  namespace: default\ndata:\n  color: " + color + "\n"
+	}
+	record("deploy", base,
+		historyv1alpha1.ResourceSnapshot{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "default",
+			Operation: historyv1alpha1.OperationCreated, After: deployment("web:1.0")},
+		historyv1alpha1.ResourceSnapshot{APIVersion: "v1", Kind: "ConfigMap", Name: "settings", Namespace: "default",
+			Operation: historyv1alpha1.OperationCreated, After: configMap("blue")})
+	record("upgrade", base.Add(time.Hour),
+		historyv1alpha1.ResourceSnapshot{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "default",
+			Operation: historyv1alpha1.OperationUpdated, Before: deployment("web:1.0"), After: deployment("web:1.1")},
+		historyv1alpha1.ResourceSnapshot{APIVersion: "v1", Kind: "ConfigMap", Name: "settings", Namespace: "default",
+			Operation: historyv1alpha1.OperationUpdated, Before: configMap("blue"), After: configMap("red")})
+
+	// the deployment was scaled since
+	replicas := int32(5)
+	k8sClient := newWhyClient(t,
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{Replicas: &replicas, Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "web", Image: "web:1.1"}},
+			}}},
+		},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"}, Data: map[string]string{"color": "red"}},
+	)
+
+	states, err := namespaceAt(store, "default", base)
+	g.Expect(err).To(gomega.BeNil())
+	states, err = selectStates(states, "default", "", []string{"Deployment/web"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(states).To(gomega.HaveLen(1))
+
+	path, err := restorePath("{.spec.template.spec.containers[0].image}")
+	g.Expect(err).To(gomega.BeNil())
+	plan, err := planRestore(ctx, k8sClient, nil, states, []string{path})
+	g.Expect(err).To(gomega.BeNil())
+	var out bytes.Buffer
+	printPlan(&out, plan, "Restore", "Nothing to restore")
+	g.Expect(out.String()).To(gomega.Equal("Restore:\n" +
+		"  update  Deployment.apps/default/web\n" +
+		"    .spec.template.spec.containers[0].image: web:1.1 -> web:1.0\n"))
+
+	_, err = restore(ctx, k8sClient, store, nil, plan, base, "default", changeContext{Message: "Roll back the image"})
+	g.Expect(err).To(gomega.BeNil())
+
+	// the replicas and the config map changed in the same batch keep their live value
+	web := &appsv1.Deployment{}
+	g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web"}, web)).To(gomega.Succeed())
+	g.Expect(web.Spec.Template.Spec.Containers[0].Image).To(gomega.Equal("web:1.0"))
+	g.Expect(*web.Spec.Replicas).To(gomega.Equal(int32(5)))
+	settings := &corev1.ConfigMap{}
+	g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "settings"}, settings)).To(gomega.Succeed())
+	g.Expect(settings.Data).To(gomega.Equal(map[string]string{"color": "red"}))
+
+	// a parent path restores the fields under it
+	states, err = namespaceAt(store, "default", base)
+	g.Expect(err).To(gomega.BeNil())
+	plan, err = planRestore(ctx, k8sClient, nil, states, []string{".data", ".spec.replica"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(plan).To(gomega.HaveLen(1))
+	g.Expect(plan[0].Key.Kind).To(gomega.Equal("ConfigMap"))
+
+	_, err = restorePath("spec.replicas")
+	g.Expect(err).NotTo(gomega.BeNil())
+}
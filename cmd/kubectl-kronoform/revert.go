@@ -0,0 +1,318 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/fielddiff"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/redact"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+// revertConflict is a change of a history that cannot be reverted cleanly, as the field
+// or resource it changed was changed again since.
+type revertConflict struct {
+	Key history.ResourceKey
+	// Path of the field, empty when the conflict is on the whole resource
+	Path   string
+	Reason string
+}
+
+func runRevert(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	namespace = getTargetNamespace(namespace)
+	change, err := getChangeContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Open the history store
+	store, err := newStore(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+	redactor, err := newRedactor(cmd)
+	if err != nil {
+		return err
+	}
+
+	h, err := getHistory(store, namespace, args[0])
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("history %s not found", args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get history: %w", err)
+	}
+	histories, err := store.ListHistories(context.TODO(), storage.ListOptions{Namespace: namespace})
+	if err != nil {
+		return fmt.Errorf("failed to list histories: %w", err)
+	}
+	entries, err := history.Changes(histories)
+	if err != nil {
+		return err
+	}
+	if change.Message == "" {
+		change.Message = revertMessage(h)
+	}
+
+	// Create Kubernetes client
+	k8sClient, err := createK8sClient()
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	ctx := context.Background()
+	plan, conflicts, err := planRevert(ctx, k8sClient, redactor, entries, h, force)
+	if err != nil {
+		return err
+	}
+	printConflicts(os.Stdout, conflicts)
+	if len(conflicts) > 0 && !force {
+		return fmt.Errorf("history %s conflicts with later changes, use --force to revert it anyway", history.ID(h))
+	}
+	printPlan(os.Stdout, plan, fmt.Sprintf("Revert history %s (%s)", history.ID(h), h.Name),
+		fmt.Sprintf("The changes of history %s are already reverted", history.ID(h)))
+	if len(plan) == 0 || dryRun {
+		return nil
+	}
+
+	record, err := revert(ctx, k8sClient, store, redactor, plan, h, namespace, change)
+	if record != nil {
+		_, _ = fmt.Fprintf(os.Stdout, "Recorded history %s (%s)\n", history.ID(record), record.Name)
+	}
+	return err
+}
+
+// revertMessage is the message recorded with a revert when none is given.
+func revertMessage(h *historyv1alpha1.KronoformHistory) string {
+	return fmt.Sprintf("Revert %q\n\nThis reverts history %s.", changeSubject(h), history.ID(h))
+}
+
+// planRevert returns the actions undoing the changes of history h onto the live state of
+// its resources. entries are the changes recorded in the namespace of h, oldest first.
+// Fields changed by h are returned to their value before it, unless they were changed
+// again since: such fields are conflicts, reverted anyway with force. Fields h did not
+// change keep their live value.
+func planRevert(ctx context.Context, c client.Client, redactor *redact.Redactor, entries []history.Entry,
+	h *historyv1alpha1.KronoformHistory, force bool) ([]restoreAction, []revertConflict, error) {
+	var plan []restoreAction
+	var conflicts []revertConflict
+	found, revertable := false, false
+	for i, entry := range entries {
+		if entry.History.Name != h.Name || entry.History.Namespace != h.Namespace {
+			continue
+		}
+		found = true
+		revertable = revertable || !unrevertable(entry)
+		action, entryConflicts, err := revertEntry(ctx, c, redactor, entry, entries[i+1:], force)
+		if err != nil {
+			return nil, nil, err
+		}
+		conflicts = append(conflicts, entryConflicts...)
+		if action != nil {
+			plan = append(plan, *action)
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("history %s recorded no change to revert", history.ID(h))
+	}
+	if !revertable {
+		return nil, nil, fmt.Errorf("history %s cannot be reverted: the state of its resources before it was not recorded",
+			history.ID(h))
+	}
+	sortPlan(plan)
+	return plan, conflicts, nil
+}
+
+// unrevertable reports whether a change cannot be undone whatever the live state: the
+// resource was applied without its state before being recorded, so whether it existed is
+// unknown.
+func unrevertable(entry history.Entry) bool {
+	return entry.Partial() && entry.Before == ""
+}
+
+// revertEntry returns the action undoing a change, nil when there is nothing to undo, and
+// its conflicts with the later changes.
+func revertEntry(ctx context.Context, c client.Client, redactor *redact.Redactor, entry history.Entry,
+	later []history.Entry, force bool) (*restoreAction, []revertConflict, error) {
+	state := history.State{Key: entry.Key, Latest: &entry}
+	gvk, err := recordedGVK(state)
+	if err != nil {
+		return nil, nil, err
+	}
+	live, err := getLive(ctx, c, redactor, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	var before, after map[string]interface{}
+	if entry.Before != "" {
+		if before, err = fielddiff.Parse(entry.Before); err != nil {
+			return nil, nil, fmt.Errorf("failed to read the state of %s: %w", entry.Key, err)
+		}
+	}
+	if entry.After != "" {
+		if after, err = fielddiff.Parse(entry.After); err != nil {
+			return nil, nil, fmt.Errorf("failed to read the state of %s: %w", entry.Key, err)
+		}
+	}
+	conflict := func(path string) revertConflict {
+		return revertConflict{Key: entry.Key, Path: path, Reason: changedSince(later, entry.Key, path)}
+	}
+	action := &restoreAction{Key: entry.Key, GVK: gvk}
+
+	switch {
+	case unrevertable(entry):
+		// The resource was applied for the first time, whether it existed before is unknown
+		return nil, []revertConflict{{Key: entry.Key, Reason: "its state before the history was not recorded"}}, nil
+	case after == nil:
+		// The history deleted the resource
+		if live == nil {
+			for _, f := range fielddiff.Fields(before) {
+				if redact.IsRedacted(f.Value) {
+					return nil, nil, fmt.Errorf("cannot recreate %s: the value of %s was redacted when recorded", entry.Key, f.Path)
+				}
+			}
+			action.Action = restoreCreate
+			action.Target = before
+			return action, nil, nil
+		}
+		changes := fielddiff.Diff(live, before)
+		if len(changes) == 0 {
+			return nil, nil, nil
+		}
+		conflicts := []revertConflict{conflict("")}
+		if !force {
+			return nil, conflicts, nil
+		}
+		action.Action = restoreUpdate
+		action.Target = before
+		action.Changes = changes
+		return action, conflicts, nil
+	case before == nil:
+		// The history created the resource
+		if live == nil {
+			return nil, nil, nil
+		}
+		var conflicts []revertConflict
+		if len(fielddiff.Diff(after, live)) > 0 {
+			conflicts = append(conflicts, conflict(""))
+			if !force {
+				return nil, conflicts, nil
+			}
+		}
+		action.Action = restoreDelete
+		return action, conflicts, nil
+	case live == nil:
+		return nil, []revertConflict{conflict("")}, nil
+	}
+
+	// The history updated the resource: revert the fields it changed onto the live state
+	var conflicts []revertConflict
+	for _, change := range fielddiff.Diff(before, after) {
+		value, _, _ := fielddiff.Lookup(live, change.Path)
+		if reflect.DeepEqual(value, change.Before) {
+			continue
+		}
+		if !reflect.DeepEqual(value, change.After) {
+			conflicts = append(conflicts, conflict(change.Path))
+			if !force {
+				continue
+			}
+		}
+		if redact.IsRedacted(change.Before) {
+			return nil, nil, fmt.Errorf("cannot revert %s: the value of %s was redacted when recorded", entry.Key, change.Path)
+		}
+		action.Changes = append(action.Changes, fielddiff.Change{Path: change.Path, Before: value, After: change.Before})
+	}
+	if len(action.Changes) == 0 {
+		return nil, conflicts, nil
+	}
+
+	merged, err := mergeChanges(live, action.Changes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot revert %s: %w", entry.Key, err)
+	}
+	action.Action = restoreUpdate
+	action.Target = merged
+	return action, conflicts, nil
+}
+
+// changedSince describes the first later change of a resource, or of one of its fields
+// when path is given.
+func changedSince(later []history.Entry, key history.ResourceKey, path string) string {
+	value := func(state string) interface{} {
+		obj, err := fielddiff.Parse(state)
+		if err != nil {
+			return nil
+		}
+		v, _, _ := fielddiff.Lookup(obj, path)
+		return v
+	}
+	for _, entry := range later {
+		if entry.Key != key {
+			continue
+		}
+		if path != "" && reflect.DeepEqual(value(entry.Before), value(entry.After)) {
+			continue
+		}
+		return fmt.Sprintf("%s since by history %s (%s)", strings.ToLower(entry.Operation),
+			history.ID(entry.History), entry.History.Name)
+	}
+	return "changed since outside of the recorded histories"
+}
+
+// printConflicts prints the conflicts of a revert, if any.
+func printConflicts(out io.Writer, conflicts []revertConflict) {
+	if len(conflicts) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintln(out, "Conflicts with later changes:")
+	for _, conflict := range conflicts {
+		target := conflict.Key.String()
+		if conflict.Path != "" {
+			target += " " + conflict.Path
+		}
+		_, _ = fmt.Fprintf(out, "  %s: %s\n", target, conflict.Reason)
+	}
+}
+
+// revert applies a revert plan and records it as one history.
+func revert(ctx context.Context, c client.Client, store storage.Store, redactor *redact.Redactor, plan []restoreAction,
+	h *historyv1alpha1.KronoformHistory, namespace string, change changeContext) (*historyv1alpha1.KronoformHistory, error) {
+	reverted := fmt.Sprintf("history %s (%s)", history.ID(h), h.Name)
+	return applyPlan(ctx, c, store, redactor, plan, namespace, change, func(record *historyv1alpha1.KronoformHistory, applied int, err error) {
+		record.Spec.Description = fmt.Sprintf("Reverted by %s %s", record.Spec.AppliedBy, reverted)
+		record.Status.Summary = fmt.Sprintf("Reverted the changes of %s to %d resources", reverted, applied)
+		if err != nil {
+			record.Status.Summary = fmt.Sprintf("Reverted the changes of %s to %d of %d resources, then failed: %v",
+				reverted, applied, len(plan), err)
+		}
+	})
+}
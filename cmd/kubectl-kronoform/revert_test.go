@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func TestRevert(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+
+	base := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	record := func(name string, at time.Time, resources ...historyv1alpha1.ResourceSnapshot) {
+		appliedAt := metav1.NewTime(at)
+		h := &historyv1alpha1.KronoformHistory{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       historyv1alpha1.KronoformHistorySpec{AppliedBy: "alice", Message: "Change " + name},
+			Status:     historyv1alpha1.KronoformHistoryStatus{AppliedAt: &appliedAt, ResourceSnapshots: resources},
+		}
+		g.Expect(store.SaveHistory(ctx, h)).To(gomega.Succeed())
+	}
+	configMap := func(name, operation, before, after string) historyv1alpha1.ResourceSnapshot {
+		return historyv1alpha1.ResourceSnapshot{
+			APIVersion: "v1", Kind: "ConfigMap", Name: name, Namespace: "default",
+			Operation: operation, Before: before, After: after,
+		}
+	}
+	configMapState := func(name, color, size string) string {
+		return "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + name + "\n  namespace: default\n" +
+			"data:\n  color: " + color + "\n  size: " + size + "\n"
+	}
+
+	record("create", base,
+		configMap("settings", historyv1alpha1.OperationCreated, "", configMapState("settings", "blue", "small")),
+		configMap("old", historyv1alpha1.OperationCreated, "", configMapState("old", "black", "small")))
+	record("launch", base.Add(time.Hour),
+		configMap("settings", historyv1alpha1.OperationUpdated,
+			configMapState("settings", "blue", "small"), configMapState("settings", "red", "large")),
+		configMap("theme", historyv1alpha1.OperationCreated, "", configMapState("theme", "green", "small")),
+		configMap("old", historyv1alpha1.OperationDeleted, configMapState("old", "black", "small"), ""))
+	record("resize", base.Add(2*time.Hour),
+		configMap("settings", historyv1alpha1.OperationUpdated,
+			configMapState("settings", "red", "large"), configMapState("settings", "red", "huge")))
+
+	k8sClient := newWhyClient(t,
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default", Labels: map[string]string{"team": "web"}},
+			Data:       map[string]string{"color": "red", "size": "huge"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "theme", Namespace: "default"},
+			Data:       map[string]string{"color": "green", "size": "small"},
+		},
+	)
+
+	histories, err := store.ListHistories(ctx, storage.ListOptions{Namespace: "default"})
+	g.Expect(err).To(gomega.BeNil())
+	entries, err := history.Changes(histories)
+	g.Expect(err).To(gomega.BeNil())
+	launch, err := getHistory(store, "default", "launch")
+	g.Expect(err).To(gomega.BeNil())
+	resize, err := getHistory(store, "default", "resize")
+	g.Expect(err).To(gomega.BeNil())
+
+	// the size was changed again since the launch
+	plan, conflicts, err := planRevert(ctx, k8sClient, nil, entries, launch, false)
+	g.Expect(err).To(gomega.BeNil())
+	var out bytes.Buffer
+	printConflicts(&out, conflicts)
+	g.Expect(out.String()).To(gomega.Equal("Conflicts with later changes:\n" +
+		"  ConfigMap/default/settings .data.size: updated since by history " + history.ID(resize) + " (resize)\n"))
+
+	out.Reset()
+	printPlan(&out, plan, "Revert history launch", "Nothing to revert")
+	g.Expect(out.String()).To(gomega.Equal("Revert history launch:\n" +
+		"  update  ConfigMap/default/settings\n" +
+		"    .data.color: red -> blue\n" +
+		"  create  ConfigMap/default/old\n" +
+		"  delete  ConfigMap/default/theme\n"))
+
+	plan, conflicts, err = planRevert(ctx, k8sClient, nil, entries, launch, true)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(conflicts).To(gomega.HaveLen(1))
+	g.Expect(plan[0].Changes).To(gomega.HaveLen(2))
+
+	change := changeContext{Message: revertMessage(launch)}
+	g.Expect(change.Message).To(gomega.Equal("Revert \"Change launch\"\n\nThis reverts history " + history.ID(launch) + "."))
+	reverted, err := revert(ctx, k8sClient, store, nil, plan, launch, "default", change)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(reverted.Status.ResourceSnapshots).To(gomega.HaveLen(3))
+	g.Expect(reverted.Status.Summary).To(gomega.Equal("Reverted the changes of history " + history.ID(launch) +
+		" (launch) to 3 resources"))
+
+	// the fields the launch did not change keep their live value
+	settings := &corev1.ConfigMap{}
+	g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "settings"}, settings)).To(gomega.Succeed())
+	g.Expect(settings.Data).To(gomega.Equal(map[string]string{"color": "blue", "size": "small"}))
+	g.Expect(settings.Labels).To(gomega.HaveKeyWithValue("team", "web"))
+	g.Expect(settings.Annotations).To(gomega.HaveKeyWithValue(historyv1alpha1.AnnotationHistory, reverted.Name))
+	old := &corev1.ConfigMap{}
+	g.Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "old"}, old)).To(gomega.Succeed())
+	g.Expect(old.Data).To(gomega.Equal(map[string]string{"color": "black", "size": "small"}))
+	err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "theme"}, &corev1.ConfigMap{})
+	g.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue())
+
+	// nothing is left to revert
+	histories, err = store.ListHistories(ctx, storage.ListOptions{Namespace: "default"})
+	g.Expect(err).To(gomega.BeNil())
+	entries, err = history.Changes(histories)
+	g.Expect(err).To(gomega.BeNil())
+	plan, conflicts, err = planRevert(ctx, k8sClient, nil, entries, launch, false)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(plan).To(gomega.BeEmpty())
+	g.Expect(conflicts).To(gomega.BeEmpty())
+}
+
+func TestRevertUnrecordedStates(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	appliedAt := metav1.NewTime(time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC))
+	applied := historyv1alpha1.KronoformHistory{
+		ObjectMeta: metav1.ObjectMeta{Name: "applied", Namespace: "default"},
+		Spec: historyv1alpha1.KronoformHistorySpec{
+			AppliedBy: "alice",
+			Manifests: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  color: blue\n",
+		},
+		Status: historyv1alpha1.KronoformHistoryStatus{AppliedAt: &appliedAt},
+	}
+	entries, err := history.Changes([]historyv1alpha1.KronoformHistory{applied})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(entries).To(gomega.HaveLen(1))
+
+	// A history whose changes cannot be undone is not reported as already reverted
+	k8sClient := newWhyClient(t, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"},
+		Data:       map[string]string{"color": "blue"},
+	})
+	for _, force := range []bool{false, true} {
+		_, _, err = planRevert(ctx, k8sClient, nil, entries, &applied, force)
+		g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("cannot be reverted")))
+	}
+}
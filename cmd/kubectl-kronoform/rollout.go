@@ -0,0 +1,89 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/rollout"
+)
+
+// rolloutInterval is how often rollouts are checked while waiting for them.
+const rolloutInterval = 2 * time.Second
+
+// appliedWorkloads returns the workloads among the resources kubectl created or
+// configured, whose rollouts are waited for.
+func appliedWorkloads(manifests, kubectlOutput, namespace string) ([]*unstructured.Unstructured, error) {
+	objects, err := changedObjects(manifests, kubectlOutput, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var workloads []*unstructured.Unstructured
+	for _, obj := range objects {
+		if rollout.Supported(obj.GroupVersionKind().GroupKind()) {
+			workloads = append(workloads, obj)
+		}
+	}
+	return workloads, nil
+}
+
+// waitForRollouts waits up to timeout for the rollouts of workloads and prints how they
+// ended.
+func waitForRollouts(ctx context.Context, c client.Client, out io.Writer, workloads []*unstructured.Unstructured,
+	timeout time.Duration) ([]historyv1alpha1.WorkloadRollout, error) {
+	_, _ = fmt.Fprintf(out, "[%s] Kronoform: Waiting up to %s for %d rollouts...\n", time.Now().Format("15:04:05"), timeout, len(workloads))
+	rollouts, err := rollout.Wait(ctx, c, workloads, timeout, rolloutInterval)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rollouts {
+		_, _ = fmt.Fprintf(out, "[%s] Kronoform: %s (%s)\n", time.Now().Format("15:04:05"), rollout.Describe(r), r.Message)
+	}
+	return rollouts, nil
+}
+
+// recordRollouts records how the rollouts of the workloads of a history ended in its
+// status, with the Healthy condition summarizing them.
+func recordRollouts(record *historyv1alpha1.KronoformHistory, rollouts []historyv1alpha1.WorkloadRollout) {
+	if len(rollouts) == 0 {
+		return
+	}
+	record.Status.Rollouts = rollouts
+	meta.SetStatusCondition(&record.Status.Conditions, rollout.Condition(rollouts))
+}
+
+// describeHealth describes the recorded health of the workloads of a history, empty when
+// it was not recorded.
+func describeHealth(h *historyv1alpha1.KronoformHistory) string {
+	condition := meta.FindStatusCondition(h.Status.Conditions, historyv1alpha1.ConditionHealthy)
+	if condition == nil {
+		return ""
+	}
+	if condition.Status == metav1.ConditionTrue {
+		return "healthy, " + condition.Message
+	}
+	return "unhealthy, " + condition.Message
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+func TestRecordRollouts(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	appliedAt := metav1.NewTime(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	record := &historyv1alpha1.KronoformHistory{
+		ObjectMeta: metav1.ObjectMeta{Name: "kronoform-history-a", Namespace: "prod"},
+		Spec:       historyv1alpha1.KronoformHistorySpec{ID: "aaaaaaaaaaaa", AppliedBy: "alice", Message: "Bump web"},
+		Status:     historyv1alpha1.KronoformHistoryStatus{AppliedAt: &appliedAt},
+	}
+	recordRollouts(record, nil)
+	g.Expect(record.Status.Conditions).To(gomega.BeEmpty())
+	g.Expect(describeHealth(record)).To(gomega.BeEmpty())
+
+	recordRollouts(record, []historyv1alpha1.WorkloadRollout{
+		{
+			APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "prod",
+			Outcome: historyv1alpha1.RolloutTimedOut, Replicas: 3, ReadyReplicas: 1, Message: "1 of 3 updated replicas available",
+			PodFailures: []historyv1alpha1.PodFailure{
+				{Pod: "web-1", Container: "app", Reason: "ImagePullBackOff", Message: "Back-off pulling image \"web:bad\""},
+				{Pod: "web-2", Reason: "Unschedulable"},
+			},
+		},
+		{
+			APIVersion: "batch/v1", Kind: "Job", Name: "migrate", Namespace: "prod",
+			Outcome: historyv1alpha1.RolloutComplete, Replicas: 1, ReadyReplicas: 1, Message: "completed",
+		},
+	})
+	g.Expect(record.Status.Rollouts).To(gomega.HaveLen(2))
+	g.Expect(describeHealth(record)).To(gomega.Equal("unhealthy, Deployment/web: TimedOut, 1/3 ready, ImagePullBackOff, Unschedulable"))
+
+	var out strings.Builder
+	printLog(&out, []historyv1alpha1.KronoformHistory{*record}, true)
+	g.Expect(out.String()).To(gomega.Equal("aaaaaaaaaaaa Bump web (unhealthy)\n"))
+
+	out.Reset()
+	printShow(&out, record)
+	g.Expect(out.String()).To(gomega.ContainSubstring(
+		"Health:      unhealthy, Deployment/web: TimedOut, 1/3 ready, ImagePullBackOff, Unschedulable\n"))
+	g.Expect(out.String()).To(gomega.HaveSuffix(`
+Rollouts:
+  TimedOut   Deployment.apps/prod/web: 1/3 ready, 1 of 3 updated replicas available
+    web-1/app: ImagePullBackOff Back-off pulling image "web:bad"
+    web-2: Unschedulable
+  Complete   Job.batch/prod/migrate: 1/1 ready, completed
+`))
+}
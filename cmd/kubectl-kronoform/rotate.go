@@ -0,0 +1,156 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/envelope"
+)
+
+func runRotateKeys(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	allNamespaces, _ := cmd.Flags().GetBool("all-namespaces")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if allNamespaces {
+		namespace = ""
+	} else {
+		namespace = getTargetNamespace(namespace)
+	}
+
+	fmt.Printf("[%s] Kronoform: Starting rotate-keys operation...\n", time.Now().Format("15:04:05"))
+
+	keyring, err := newKeyring(cmd)
+	if err != nil {
+		return err
+	}
+	if keyring == nil {
+		return fmt.Errorf("no keyring is configured: set --encryption-key-file or the encryption section of the config")
+	}
+
+	// Create Kubernetes client
+	k8sClient, err := createK8sClient()
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %w", err)
+	}
+
+	rotated, err := rotateKeys(context.Background(), k8sClient, keyring, namespace, dryRun, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Printf("[%s] Kronoform: %d records would be rotated to key %s (dry run)\n", time.Now().Format("15:04:05"), rotated, keyring.Current())
+	} else {
+		fmt.Printf("[%s] Kronoform: Rotated %d records to key %s\n", time.Now().Format("15:04:05"), rotated, keyring.Current())
+	}
+	return nil
+}
+
+// rotateKeys encrypts the data keys of encrypted records again with the current key of
+// keyring, in a namespace (all namespaces if empty), so that older keys can be removed
+// from the keyring. The encrypted content itself does not change. It returns the number
+// of records that were, or with dryRun would be, rotated.
+func rotateKeys(ctx context.Context, c client.Client, keyring *envelope.Keyring, namespace string, dryRun bool, out io.Writer) (int, error) {
+	rotated := 0
+
+	snapshots := &historyv1alpha1.KronoformSnapshotList{}
+	if err := c.List(ctx, snapshots, client.InNamespace(namespace)); err != nil {
+		return rotated, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	for i := range snapshots.Items {
+		s := &snapshots.Items[i]
+		key, err := keyring.Rewrap(s.Spec.ManifestsKey)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to rotate snapshot %s: %w", s.Name, err)
+		}
+		if key == nil {
+			continue
+		}
+		fmt.Fprintf(out, "KronoformSnapshot %s/%s: manifests (key %s)\n", s.Namespace, s.Name, s.Spec.ManifestsKey.KeyID)
+		rotated++
+		if dryRun {
+			continue
+		}
+		s.Spec.ManifestsKey = key
+		if err := c.Update(ctx, s); err != nil {
+			return rotated, fmt.Errorf("failed to rotate snapshot %s: %w", s.Name, err)
+		}
+	}
+
+	histories := &historyv1alpha1.KronoformHistoryList{}
+	if err := c.List(ctx, histories, client.InNamespace(namespace)); err != nil {
+		return rotated, fmt.Errorf("failed to list histories: %w", err)
+	}
+	for i := range histories.Items {
+		h := &histories.Items[i]
+		manifestsKey, err := keyring.Rewrap(h.Spec.ManifestsKey)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to rotate history %s: %w", h.Name, err)
+		}
+		stateKeys := make([]*historyv1alpha1.DataKey, len(h.Status.ResourceSnapshots))
+		states := false
+		for j, rs := range h.Status.ResourceSnapshots {
+			if stateKeys[j], err = keyring.Rewrap(rs.Key); err != nil {
+				return rotated, fmt.Errorf("failed to rotate states of history %s: %w", h.Name, err)
+			}
+			states = states || stateKeys[j] != nil
+		}
+		if manifestsKey == nil && !states {
+			continue
+		}
+		var parts []string
+		if manifestsKey != nil {
+			parts = append(parts, "manifests")
+		}
+		if states {
+			parts = append(parts, "states")
+		}
+		fmt.Fprintf(out, "KronoformHistory %s/%s: %s\n", h.Namespace, h.Name, strings.Join(parts, ", "))
+		rotated++
+		if dryRun {
+			continue
+		}
+		if manifestsKey != nil {
+			h.Spec.ManifestsKey = manifestsKey
+			if err := c.Update(ctx, h); err != nil {
+				return rotated, fmt.Errorf("failed to rotate history %s: %w", h.Name, err)
+			}
+		}
+		if states {
+			for j, key := range stateKeys {
+				if key != nil {
+					h.Status.ResourceSnapshots[j].Key = key
+				}
+			}
+			if err := c.Status().Update(ctx, h); err != nil {
+				return rotated, fmt.Errorf("failed to rotate states of history %s: %w", h.Name, err)
+			}
+		}
+	}
+
+	return rotated, nil
+}
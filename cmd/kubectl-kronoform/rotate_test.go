@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/envelope"
+)
+
+func TestRotateKeys(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	if err := historyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add scheme: %v", err)
+	}
+
+	key := func(fill string) string {
+		return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(fill, envelope.KeySize)))
+	}
+	old, err := envelope.Parse([]byte("current: k1\nkeys:\n  k1: " + key("a") + "\n"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	keyring, err := envelope.Parse([]byte("current: k2\nkeys:\n  k1: " + key("a") + "\n  k2: " + key("b") + "\n"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	manifests := "apiVersion: v1\nkind: ConfigMap\n"
+	snapshot := &historyv1alpha1.KronoformSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "encrypted", Namespace: "default"},
+		Spec:       historyv1alpha1.KronoformSnapshotSpec{Manifests: manifests},
+	}
+	g.Expect(old.SealSnapshot(snapshot)).To(gomega.Succeed())
+	plain := &historyv1alpha1.KronoformSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "plain", Namespace: "default"},
+		Spec:       historyv1alpha1.KronoformSnapshotSpec{Manifests: manifests},
+	}
+	history := &historyv1alpha1.KronoformHistory{
+		ObjectMeta: metav1.ObjectMeta{Name: "encrypted", Namespace: "default"},
+		Spec:       historyv1alpha1.KronoformHistorySpec{Manifests: manifests},
+		Status: historyv1alpha1.KronoformHistoryStatus{
+			ResourceSnapshots: []historyv1alpha1.ResourceSnapshot{{Kind: "ConfigMap", Name: "settings", After: manifests}},
+		},
+	}
+	g.Expect(old.SealHistory(history)).To(gomega.Succeed())
+	sealed := history.Spec.Manifests
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(snapshot, plain, history).
+		WithStatusSubresource(&historyv1alpha1.KronoformHistory{}).
+		Build()
+
+	// A dry run only reports the records to rotate
+	var out bytes.Buffer
+	rotated, err := rotateKeys(ctx, fakeClient, keyring, "default", true, &out)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(rotated).To(gomega.Equal(2))
+	g.Expect(out.String()).To(gomega.ContainSubstring("KronoformSnapshot default/encrypted: manifests (key k1)"))
+	g.Expect(out.String()).To(gomega.ContainSubstring("KronoformHistory default/encrypted: manifests, states"))
+	g.Expect(out.String()).NotTo(gomega.ContainSubstring("default/plain"))
+
+	rotated, err = rotateKeys(ctx, fakeClient, keyring, "default", false, &bytes.Buffer{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(rotated).To(gomega.Equal(2))
+
+	// Only the data keys changed, so the records can be read with the current key alone
+	current, err := envelope.Parse([]byte("current: k2\nkeys:\n  k2: " + key("b") + "\n"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	stored := &historyv1alpha1.KronoformHistory{}
+	g.Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(history), stored)).To(gomega.Succeed())
+	g.Expect(stored.Spec.Manifests).To(gomega.Equal(sealed))
+	g.Expect(stored.Spec.ManifestsKey.KeyID).To(gomega.Equal("k2"))
+	g.Expect(current.Manifests(stored.Spec.Manifests, stored.Spec.ManifestsEncoding, stored.Spec.ManifestsKey)).
+		To(gomega.Equal(manifests))
+	_, after, err := current.States(stored.Status.ResourceSnapshots[0])
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(after).To(gomega.Equal(manifests))
+
+	storedSnapshot := &historyv1alpha1.KronoformSnapshot{}
+	g.Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(snapshot), storedSnapshot)).To(gomega.Succeed())
+	g.Expect(storedSnapshot.Spec.ManifestsKey.KeyID).To(gomega.Equal("k2"))
+
+	// Rotated records are left alone
+	rotated, err = rotateKeys(ctx, fakeClient, keyring, "default", false, &bytes.Buffer{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(rotated).To(gomega.BeZero())
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/chain"
+)
+
+func TestVerifyChains(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	if err := historyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add scheme: %v", err)
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	now := metav1.Now()
+	var objects []historyv1alpha1.KronoformHistory
+	for _, ns := range []string{"default", "staging"} {
+		var head *historyv1alpha1.ChainLink
+		for _, name := range []string{"first", "second"} {
+			h := historyv1alpha1.KronoformHistory{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+				Spec:       historyv1alpha1.KronoformHistorySpec{Manifests: "kind: ConfigMap\n"},
+				Status:     historyv1alpha1.KronoformHistoryStatus{AppliedAt: &now, Summary: "Applied " + name},
+			}
+			link, err := chain.Link(ctx, reader, &h, head, nil)
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			h.Status.Chain, head = link, link
+			objects = append(objects, h)
+		}
+	}
+	// The staging histories were altered after they were linked
+	objects[3].Status.Summary = "rewritten"
+
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+	for i := range objects {
+		builder = builder.WithObjects(&objects[i])
+	}
+	fakeClient := builder.Build()
+
+	var out bytes.Buffer
+	issues, err := verifyChains(ctx, fakeClient, "default", nil, &out)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(issues).To(gomega.BeZero())
+	g.Expect(out.String()).To(gomega.ContainSubstring("default: 2 histories verified, head " + objects[1].Status.Chain.Hash))
+
+	out.Reset()
+	issues, err = verifyChains(ctx, fakeClient, "", nil, &out)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(issues).To(gomega.Equal(1))
+	g.Expect(out.String()).To(gomega.ContainSubstring("staging: 1 histories verified"))
+	g.Expect(out.String()).To(gomega.ContainSubstring("Modified  second (sequence 2): recorded content does not match its hash"))
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+// changedObjects returns the objects of the applied manifests that kubectl reported as
+// created or configured. kubectl names them kind[.group]/name; objects without a
+// namespace are placed in the namespace of the apply.
+func changedObjects(manifests, kubectlOutput, namespace string) ([]*unstructured.Unstructured, error) {
+	changed := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(kubectlOutput))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && (fields[1] == "created" || fields[1] == "configured") {
+			changed[fields[0]] = true
+		}
+	}
+
+	applied, err := appliedObjects(manifests, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var objects []*unstructured.Unstructured
+	for _, obj := range applied {
+		gvk := obj.GroupVersionKind()
+		kind := strings.ToLower(gvk.Kind)
+		if gvk.Group != "" {
+			kind += "." + gvk.Group
+		}
+		if changed[kind+"/"+obj.GetName()] {
+			objects = append(objects, obj)
+		}
+	}
+	return objects, nil
+}
+
+// appliedObjects returns the objects of the applied manifests. Objects without a
+// namespace are placed in the namespace of the apply.
+func appliedObjects(manifests, namespace string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifests), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse manifests: %w", err)
+		}
+		if obj.Object == nil || obj.GetName() == "" {
+			continue
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(getTargetNamespace(namespace))
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// liveTarget returns an empty object addressing the live state of an applied object.
+// Cluster-scoped objects are addressed without the namespace they were placed in.
+func liveTarget(c client.Client, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	target := &unstructured.Unstructured{}
+	target.SetGroupVersionKind(obj.GroupVersionKind())
+	target.SetName(obj.GetName())
+	namespaced, err := c.IsObjectNamespaced(target)
+	if err != nil {
+		return nil, err
+	}
+	if namespaced {
+		target.SetNamespace(obj.GetNamespace())
+	}
+	return target, nil
+}
+
+// stampObjects annotates objects with the history that recorded their current state. The
+// patch is made with kronoform's field manager, so it is not recorded as a change itself.
+func stampObjects(ctx context.Context, c client.Client, objects []*unstructured.Unstructured, record *historyv1alpha1.KronoformHistory) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				historyv1alpha1.AnnotationHistory:          record.Name,
+				historyv1alpha1.AnnotationHistoryNamespace: record.Namespace,
+				historyv1alpha1.AnnotationHistoryID:        history.ID(record),
+				historyv1alpha1.AnnotationAppliedBy:        record.Spec.AppliedBy,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, obj := range objects {
+		target, err := liveTarget(c, obj)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: %w", obj.GetKind(), obj.GetName(), err))
+			continue
+		}
+		if err := c.Patch(ctx, target, client.RawPatch(types.MergePatchType, patch),
+			client.FieldOwner(historyv1alpha1.FieldManager)); err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: %w", obj.GetKind(), obj.GetName(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stampApplied annotates the objects an apply changed with the history that recorded it.
+func stampApplied(record *historyv1alpha1.KronoformHistory, manifests, kubectlOutput, namespace string) error {
+	objects, err := changedObjects(manifests, kubectlOutput, namespace)
+	if err != nil || len(objects) == 0 {
+		return err
+	}
+	k8sClient, err := createK8sClient()
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return stampObjects(context.Background(), k8sClient, objects, record)
+}
+
+func runWhy(cmd *cobra.Command, args []string) error {
+	namespace, _ := cmd.Flags().GetString("namespace")
+	namespace = getTargetNamespace(namespace)
+
+	// Open the history store
+	store, err := newStore(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+
+	// Create Kubernetes client
+	k8sClient, err := createK8sClient()
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %w", err)
+	}
+
+	return explainObject(context.Background(), k8sClient, store, namespace, args[0], os.Stdout)
+}
+
+// explainObject prints the history named by the annotations of a live object, given as
+// kind[.group]/name.
+func explainObject(ctx context.Context, c client.Client, store storage.Store, namespace, resource string, out io.Writer) error {
+	kind, name, ok := strings.Cut(resource, "/")
+	if !ok || kind == "" || name == "" {
+		return fmt.Errorf("invalid resource %q: expected kind[.group]/name", resource)
+	}
+	gvk, err := c.RESTMapper().KindFor(schema.ParseGroupResource(kind).WithVersion(""))
+	if err != nil {
+		return fmt.Errorf("unknown kind %q, kinds outside the core group need it, e.g. deployment.apps: %w", kind, err)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	key := client.ObjectKey{Name: name}
+	if namespaced, err := c.IsObjectNamespaced(obj); err != nil {
+		return err
+	} else if namespaced {
+		key.Namespace = namespace
+	}
+	if err := c.Get(ctx, key, obj); err != nil {
+		return fmt.Errorf("failed to get %s: %w", resource, err)
+	}
+
+	annotations := obj.GetAnnotations()
+	historyName := annotations[historyv1alpha1.AnnotationHistory]
+	if historyName == "" {
+		return fmt.Errorf("%s was not changed by kubectl kronoform apply, or before its changes were annotated", resource)
+	}
+	h, err := store.GetHistory(ctx, annotations[historyv1alpha1.AnnotationHistoryNamespace], historyName)
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("%s was last changed by history %s (%s), applied by %s, which no longer exists", resource,
+			annotations[historyv1alpha1.AnnotationHistoryID], historyName, annotations[historyv1alpha1.AnnotationAppliedBy])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get history %s: %w", historyName, err)
+	}
+	printShow(out, h)
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+func newWhyClient(t *testing.T, objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add scheme: %v", err)
+	}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Secret"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("ClusterRole"), meta.RESTScopeRoot)
+	return fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithObjects(objects...).Build()
+}
+
+func TestChangedObjects(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	manifests := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+`
+	output := "deployment.apps/web configured\nconfigmap/settings created\nconfigmap/unchanged unchanged\n" +
+		"clusterrole.rbac.authorization.k8s.io/reader created\n"
+
+	objects, err := changedObjects(manifests, output, "prod")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	names := make([]string, len(objects))
+	for i, obj := range objects {
+		names[i] = obj.GetKind() + " " + obj.GetNamespace() + "/" + obj.GetName()
+	}
+	g.Expect(names).To(gomega.Equal([]string{"Deployment prod/web", "ConfigMap config/settings", "ClusterRole prod/reader"}))
+}
+
+func TestStampAndExplain(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+	c := newWhyClient(t,
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "reader"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "manual", Namespace: "prod"}},
+	)
+	store := storage.NewMemoryStore()
+	record := &historyv1alpha1.KronoformHistory{
+		ObjectMeta: metav1.ObjectMeta{Name: "kronoform-history-1", Namespace: "prod"},
+		Spec: historyv1alpha1.KronoformHistorySpec{
+			ID: "3f9a2c1b4d5e", AppliedBy: "alice", Message: "Scale web for the launch",
+		},
+	}
+	g.Expect(store.SaveHistory(ctx, record)).To(gomega.Succeed())
+
+	objects, err := changedObjects("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n---\n"+
+		"apiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRole\nmetadata:\n  name: reader\n",
+		"deployment.apps/web configured\nclusterrole.rbac.authorization.k8s.io/reader configured\n", "prod")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(stampObjects(ctx, c, objects, record)).To(gomega.Succeed())
+
+	deployment := &appsv1.Deployment{}
+	g.Expect(c.Get(ctx, client.ObjectKey{Namespace: "prod", Name: "web"}, deployment)).To(gomega.Succeed())
+	g.Expect(deployment.Annotations).To(gomega.Equal(map[string]string{
+		historyv1alpha1.AnnotationHistory:          "kronoform-history-1",
+		historyv1alpha1.AnnotationHistoryNamespace: "prod",
+		historyv1alpha1.AnnotationHistoryID:        "3f9a2c1b4d5e",
+		historyv1alpha1.AnnotationAppliedBy:        "alice",
+	}))
+
+	var out strings.Builder
+	g.Expect(explainObject(ctx, c, store, "prod", "deployment.apps/web", &out)).To(gomega.Succeed())
+	g.Expect(out.String()).To(gomega.HavePrefix("history 3f9a2c1b4d5e (kronoform-history-1)\n"))
+	g.Expect(out.String()).To(gomega.ContainSubstring("    Scale web for the launch\n"))
+
+	out.Reset()
+	g.Expect(explainObject(ctx, c, store, "other", "clusterrole.rbac.authorization.k8s.io/reader", &out)).To(gomega.Succeed())
+	g.Expect(out.String()).To(gomega.HavePrefix("history 3f9a2c1b4d5e"))
+
+	g.Expect(explainObject(ctx, c, store, "prod", "configmap/manual", &out)).
+		To(gomega.MatchError(gomega.ContainSubstring("was not changed by kubectl kronoform apply")))
+	g.Expect(explainObject(ctx, c, store, "prod", "web", &out)).
+		To(gomega.MatchError(gomega.ContainSubstring("expected kind[.group]/name")))
+
+	g.Expect(store.DeleteHistory(ctx, "prod", "kronoform-history-1")).To(gomega.Succeed())
+	g.Expect(explainObject(ctx, c, store, "prod", "deployment.apps/web", &out)).
+		To(gomega.MatchError(gomega.ContainSubstring("history 3f9a2c1b4d5e (kronoform-history-1), applied by alice, which no longer exists")))
+}
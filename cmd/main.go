@@ -17,10 +17,13 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
 	"crypto/tls"
 	"flag"
 	"os"
 	"path/filepath"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -38,7 +41,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/chain"
 	"github.com/yu-kod/kronoform/internal/controller"
+	"github.com/yu-kod/kronoform/internal/envelope"
+	"github.com/yu-kod/kronoform/internal/gitexport"
+	"github.com/yu-kod/kronoform/internal/redact"
 	webhookv1alpha1 "github.com/yu-kod/kronoform/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
@@ -55,6 +62,9 @@ func init() {
 	// +kubebuilder:scaffold:scheme
 }
 
+// The keyring Secret is read from the namespace of the manager only.
+// +kubebuilder:rbac:groups="",namespace=system,resources=secrets,verbs=get
+
 // nolint:gocyclo
 func main() {
 	var metricsAddr string
@@ -64,6 +74,15 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var driftKinds, driftIgnoredManagers, observeKinds, observedHistoryNamespace string
+	var auditResources, auditHistoryNamespace string
+	var serviceAccount, breakGlassGroup string
+	var messageNamespaces string
+	var gitExportRepo string
+	var redactionConfig string
+	var encryptionKeyFile, encryptionKeySecret string
+	var chainSigningKey string
+	var gitExportInterval time.Duration
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -82,6 +101,47 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&driftKinds, "drift-kinds", controller.DefaultDriftKinds,
+		"Comma-separated kinds (group/version/Kind, or version/Kind for core) whose recorded resources are checked "+
+			"for drift. The manager must be allowed to read them. Leave empty to disable.")
+	flag.StringVar(&driftIgnoredManagers, "drift-ignored-managers", controller.DefaultDriftIgnoredManagers,
+		"Comma-separated field managers of controllers whose additions to recorded resources are not drift, "+
+			"such as the revision annotation of deployments.")
+	flag.StringVar(&observeKinds, "observe-kinds", "",
+		"Comma-separated kinds (group/version/Kind, or version/Kind for core) whose changes made without kronoform "+
+			"are recorded as histories, e.g. v1/ConfigMap,apps/v1/Deployment. Leave empty to disable.")
+	flag.StringVar(&observedHistoryNamespace, "observed-history-namespace", "default",
+		"The namespace where histories of observed changes to cluster-scoped resources are stored.")
+	flag.StringVar(&auditResources, "audit-resources", "",
+		"Comma-separated resources (resource[.group], e.g. configmaps,deployments.apps) recorded by the audit webhook. "+
+			"Only namespaces labeled history.yu-kod.github.io/audit=enabled are audited. Leave empty to disable.")
+	flag.StringVar(&auditHistoryNamespace, "audit-history-namespace", "default",
+		"The namespace where audit histories of cluster-scoped resources are stored.")
+	flag.StringVar(&serviceAccount, "service-account", webhookv1alpha1.DefaultServiceAccount,
+		"The username of the controller manager, the only user allowed to update the status of recorded histories.")
+	flag.StringVar(&breakGlassGroup, "break-glass-group", webhookv1alpha1.DefaultBreakGlassGroup,
+		"A group whose members may delete recorded histories and snapshots in addition to the controller manager.")
+	flag.StringVar(&messageNamespaces, "require-message-namespaces", "",
+		"Comma-separated namespaces, or patterns such as prod-*, where changes applied with kronoform must "+
+			"carry a message (kubectl kronoform apply -m). Leave empty to make messages optional.")
+	flag.StringVar(&gitExportRepo, "git-export-repo", "",
+		"The path of a git repository the change timeline is exported to, one commit per history. "+
+			"Requires git in the manager image. Leave empty to disable.")
+	flag.DurationVar(&gitExportInterval, "git-export-interval", controller.DefaultGitExportInterval,
+		"How often new histories are exported to the git repository.")
+	flag.StringVar(&redactionConfig, "redaction-config", "",
+		"The path of a YAML file with the redaction rules applied to recorded states, "+
+			"e.g. rules: [{kind: Deployment.apps, path: '$..env[?(@.name=~\"PASSWORD\")].value'}]. "+
+			"Secret data is always redacted unless keepSecrets is set.")
+	flag.StringVar(&encryptionKeyFile, "encryption-key-file", "",
+		"The path of a keyring file used to encrypt recorded manifests and states, and to decrypt them for "+
+			"drift detection and git export. Leave empty to store them unencrypted.")
+	flag.StringVar(&encryptionKeySecret, "encryption-key-secret", "",
+		"A Secret holding the keyring under "+envelope.SecretKey+", as <namespace>/<name>, "+
+			"used instead of --encryption-key-file. The keyring is read once at startup.")
+	flag.StringVar(&chainSigningKey, "chain-signing-key", "",
+		"The path of a PEM-encoded ed25519 private key used to sign the hash chain of recorded histories. "+
+			"Leave empty to chain histories without signatures.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -203,6 +263,24 @@ func main() {
 		os.Exit(1)
 	}
 
+	redactor, err := redact.LoadFile(redactionConfig)
+	if err != nil {
+		setupLog.Error(err, "invalid --redaction-config")
+		os.Exit(1)
+	}
+
+	// The cache is not started yet, so the Secret is read from the API server
+	keyring, err := envelope.Load(context.Background(),
+		envelope.Config{KeyFile: encryptionKeyFile, Secret: encryptionKeySecret}, mgr.GetAPIReader())
+	if err != nil {
+		setupLog.Error(err, "unable to load encryption keyring")
+		os.Exit(1)
+	}
+	if keyring != nil {
+		setupLog.Info("Encrypting recorded manifests and states", "key", keyring.Current())
+		redactor = redactor.WithKey(keyring.DigestKey())
+	}
+
 	if err := (&controller.KronoformReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
@@ -210,12 +288,110 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Kronoform")
 		os.Exit(1)
 	}
+	if err := (&controller.KronoformSnapshotReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "KronoformSnapshot")
+		os.Exit(1)
+	}
+	var signingKey ed25519.PrivateKey
+	if chainSigningKey != "" {
+		if signingKey, err = chain.LoadSigningKey(chainSigningKey); err != nil {
+			setupLog.Error(err, "invalid --chain-signing-key")
+			os.Exit(1)
+		}
+	}
+	if err := (&controller.ChainReconciler{
+		Client:     mgr.GetClient(),
+		Reader:     mgr.GetAPIReader(),
+		SigningKey: signingKey,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Chain")
+		os.Exit(1)
+	}
+	if driftKinds != "" {
+		kinds, err := controller.ParseKinds(driftKinds)
+		if err != nil {
+			setupLog.Error(err, "invalid --drift-kinds")
+			os.Exit(1)
+		}
+		if err := (&controller.DriftReconciler{
+			Client:          mgr.GetClient(),
+			Scheme:          mgr.GetScheme(),
+			Kinds:           kinds,
+			Redactor:        redactor,
+			Keyring:         keyring,
+			IgnoredManagers: controller.ParseManagers(driftIgnoredManagers),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Drift")
+			os.Exit(1)
+		}
+	}
+	if observeKinds != "" {
+		kinds, err := controller.ParseKinds(observeKinds)
+		if err != nil {
+			setupLog.Error(err, "invalid --observe-kinds")
+			os.Exit(1)
+		}
+		if err := mgr.Add(&controller.ChangeObserver{
+			Client:           mgr.GetClient(),
+			Cache:            mgr.GetCache(),
+			Kinds:            kinds,
+			HistoryNamespace: observedHistoryNamespace,
+			Redactor:         redactor,
+			Keyring:          keyring,
+		}); err != nil {
+			setupLog.Error(err, "unable to add change observer")
+			os.Exit(1)
+		}
+	}
+	if gitExportRepo != "" {
+		if err := mgr.Add(&controller.GitExporter{
+			Client:   mgr.GetClient(),
+			Exporter: &gitexport.Exporter{Repo: gitExportRepo},
+			Interval: gitExportInterval,
+			Keyring:  keyring,
+		}); err != nil {
+			setupLog.Error(err, "unable to add git exporter")
+			os.Exit(1)
+		}
+	}
 	// nolint:goconst
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
 		if err := webhookv1alpha1.SetupKronoformWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Kronoform")
 			os.Exit(1)
 		}
+		recordPolicy := webhookv1alpha1.RecordPolicy{
+			ServiceAccount:    serviceAccount,
+			BreakGlassGroup:   breakGlassGroup,
+			MessageNamespaces: webhookv1alpha1.ParseNamespacePatterns(messageNamespaces),
+		}
+		if err := webhookv1alpha1.SetupKronoformHistoryWebhookWithManager(mgr, recordPolicy); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "KronoformHistory")
+			os.Exit(1)
+		}
+		if err := webhookv1alpha1.SetupKronoformSnapshotWebhookWithManager(mgr, recordPolicy); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "KronoformSnapshot")
+			os.Exit(1)
+		}
+		if err := webhookv1alpha1.SetupKronoformChunkWebhookWithManager(mgr, recordPolicy); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "KronoformChunk")
+			os.Exit(1)
+		}
+		if auditResources != "" {
+			if err := webhookv1alpha1.SetupAuditWebhookWithManager(mgr, &webhookv1alpha1.AuditRecorder{
+				Client:           mgr.GetClient(),
+				Resources:        webhookv1alpha1.ParseAuditResources(auditResources),
+				HistoryNamespace: auditHistoryNamespace,
+				Redactor:         redactor,
+				Keyring:          keyring,
+			}); err != nil {
+				setupLog.Error(err, "unable to create webhook", "webhook", "Audit")
+				os.Exit(1)
+			}
+		}
 	}
 	// +kubebuilder:scaffold:builder
 
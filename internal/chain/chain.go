@@ -0,0 +1,319 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chain links the histories of each namespace into a tamper-evident hash chain.
+// Every history records a hash of its content, its position and the hash of the history
+// before it, optionally signed with an ed25519 key, so that modified, inserted and
+// removed histories can be detected. The manager appends histories to the chain and the
+// kubectl plugin verifies it.
+package chain
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+// version is part of every hash, so that the hashed content can change in later versions.
+const version = 1
+
+// signaturePrefix separates chain signatures from other uses of the signing key.
+const signaturePrefix = "kronoform-chain:"
+
+// entry is the content a chain hash is computed over.
+type entry struct {
+	Version           int                                  `json:"version"`
+	Namespace         string                               `json:"namespace"`
+	Name              string                               `json:"name"`
+	Sequence          int64                                `json:"sequence"`
+	Previous          string                               `json:"previous"`
+	Spec              historyv1alpha1.KronoformHistorySpec `json:"spec"`
+	AppliedAt         *metav1.Time                         `json:"appliedAt,omitempty"`
+	Summary           string                               `json:"summary,omitempty"`
+	ResourceSnapshots []historyv1alpha1.ResourceSnapshot   `json:"resourceSnapshots,omitempty"`
+}
+
+// Hash returns the hex-encoded SHA-256 digest of h at the given position of the chain,
+// after the history with the hash previous. Manifests and states are hashed decoded, so
+// that compressing them or moving them to chunks does not change the hash. Encrypted
+// content is hashed as stored, without its data key, so that the hash can be checked
+// without the keyring and survives key rotation. c reads the chunks of the manifests and
+// states.
+// Drift and conditions are observations that change over time and are not hashed.
+func Hash(ctx context.Context, c client.Reader, h *historyv1alpha1.KronoformHistory, sequence int64, previous string) (string, error) {
+	spec := h.Spec.DeepCopy()
+	manifests, err := payload.Reassemble(ctx, c, h, spec.Manifests, spec.ManifestsChunks)
+	if err != nil {
+		return "", err
+	}
+	if spec.ManifestsEncoding != historyv1alpha1.EncodingEncrypted {
+		if manifests, err = payload.Decode(manifests, spec.ManifestsEncoding); err != nil {
+			return "", fmt.Errorf("failed to decode manifests of history %s: %w", h.Name, err)
+		}
+		spec.ManifestsEncoding = historyv1alpha1.EncodingPlain
+	}
+	spec.Manifests, spec.ManifestsChunks, spec.ManifestsKey = manifests, 0, nil
+
+	recorded := h.DeepCopy()
+	if err := payload.ReassembleStates(ctx, c, recorded); err != nil {
+		return "", err
+	}
+	states := make([]historyv1alpha1.ResourceSnapshot, len(recorded.Status.ResourceSnapshots))
+	for i, rs := range recorded.Status.ResourceSnapshots {
+		if rs.Encoding != historyv1alpha1.EncodingEncrypted {
+			if rs.Before, rs.After, err = payload.States(rs); err != nil {
+				return "", fmt.Errorf("failed to decode states of history %s: %w", h.Name, err)
+			}
+			rs.Encoding = historyv1alpha1.EncodingPlain
+		}
+		rs.Key = nil
+		states[i] = rs
+	}
+
+	data, err := json.Marshal(entry{
+		Version:           version,
+		Namespace:         h.Namespace,
+		Name:              h.Name,
+		Sequence:          sequence,
+		Previous:          previous,
+		Spec:              *spec,
+		AppliedAt:         h.Status.AppliedAt,
+		Summary:           h.Status.Summary,
+		ResourceSnapshots: states,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Link returns the link appending h to a chain whose last link is head, nil for an empty
+// chain. The link is signed with key if it is set.
+func Link(ctx context.Context, c client.Reader, h *historyv1alpha1.KronoformHistory, head *historyv1alpha1.ChainLink,
+	key ed25519.PrivateKey) (*historyv1alpha1.ChainLink, error) {
+	link := &historyv1alpha1.ChainLink{Sequence: 1}
+	if head != nil {
+		link.Sequence, link.Previous = head.Sequence+1, head.Hash
+	}
+	hash, err := Hash(ctx, c, h, link.Sequence, link.Previous)
+	if err != nil {
+		return nil, err
+	}
+	link.Hash = hash
+	if key != nil {
+		link.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(signaturePrefix+hash)))
+	}
+	return link, nil
+}
+
+// Head returns the last link of the chain formed by items, or nil if none is linked.
+func Head(items []historyv1alpha1.KronoformHistory) *historyv1alpha1.ChainLink {
+	var head *historyv1alpha1.ChainLink
+	for i := range items {
+		if link := items[i].Status.Chain; link != nil && (head == nil || link.Sequence > head.Sequence) {
+			head = link
+		}
+	}
+	return head
+}
+
+// validSignature reports whether the signature of link was made with the key matching key.
+func validSignature(key ed25519.PublicKey, link *historyv1alpha1.ChainLink) bool {
+	signature, err := base64.StdEncoding.DecodeString(link.Signature)
+	return err == nil && ed25519.Verify(key, []byte(signaturePrefix+link.Hash), signature)
+}
+
+// Kinds of issues reported by Verify.
+const (
+	// IssueModified marks a history whose content no longer matches its hash or whose
+	// signature is invalid.
+	IssueModified = "Modified"
+	// IssueInserted marks a history that does not link to the history before it, or that
+	// takes the position of another history.
+	IssueInserted = "Inserted"
+	// IssueMissing marks positions of the chain that no history holds any more.
+	IssueMissing = "Missing"
+)
+
+// Issue is a problem found in a chain.
+type Issue struct {
+	// Kind is IssueModified, IssueInserted or IssueMissing.
+	Kind string
+	// Sequence is the position of the history in the chain, or the first missing position.
+	Sequence int64
+	// Name of the history, empty for missing histories.
+	Name string
+	// Message describes the problem.
+	Message string
+}
+
+// Report is the result of verifying the chain of a namespace.
+type Report struct {
+	// Verified is the number of histories whose hash and link are intact.
+	Verified int
+	// Head is the last intact link of the chain.
+	Head *historyv1alpha1.ChainLink
+	// Pending lists histories that are not linked yet.
+	Pending []string
+	// Issues lists the problems found, in chain order.
+	Issues []Issue
+}
+
+// Verify walks the chain formed by the histories of one namespace and reports modified,
+// inserted and missing histories. Signatures are checked if key is set, in which case
+// unsigned histories are reported as modified. Histories at the end of the chain that
+// were removed together cannot be told apart from a shorter chain; compare the head
+// with one recorded earlier to detect them.
+func Verify(ctx context.Context, c client.Reader, items []historyv1alpha1.KronoformHistory, key ed25519.PublicKey) (*Report, error) {
+	report := &Report{}
+	var linked []*historyv1alpha1.KronoformHistory
+	for i := range items {
+		if items[i].Status.Chain == nil {
+			report.Pending = append(report.Pending, items[i].Name)
+			continue
+		}
+		linked = append(linked, &items[i])
+	}
+	sort.SliceStable(linked, func(i, j int) bool {
+		if linked[i].Status.Chain.Sequence != linked[j].Status.Chain.Sequence {
+			return linked[i].Status.Chain.Sequence < linked[j].Status.Chain.Sequence
+		}
+		return linked[i].Name < linked[j].Name
+	})
+
+	expected, previous := int64(1), ""
+	for start := 0; start < len(linked); {
+		sequence := linked[start].Status.Chain.Sequence
+		end := start
+		for end < len(linked) && linked[end].Status.Chain.Sequence == sequence {
+			end++
+		}
+		if sequence > expected {
+			message := fmt.Sprintf("history %d is missing", expected)
+			if sequence-expected > 1 {
+				message = fmt.Sprintf("histories %d to %d are missing", expected, sequence-1)
+			}
+			report.Issues = append(report.Issues, Issue{Kind: IssueMissing, Sequence: expected, Message: message})
+		}
+
+		var genuine *historyv1alpha1.ChainLink
+		for _, h := range linked[start:end] {
+			link := h.Status.Chain
+			issue := Issue{Sequence: sequence, Name: h.Name}
+			hash, err := Hash(ctx, c, h, link.Sequence, link.Previous)
+			switch {
+			case err != nil:
+				return nil, err
+			case hash != link.Hash:
+				issue.Kind, issue.Message = IssueModified, "recorded content does not match its hash"
+			case key != nil && !validSignature(key, link):
+				issue.Kind, issue.Message = IssueModified, "signature is missing or invalid"
+			case sequence == expected && link.Previous != previous:
+				issue.Kind, issue.Message = IssueInserted, fmt.Sprintf("does not link to history %d", sequence-1)
+			case genuine != nil:
+				issue.Kind, issue.Message = IssueInserted, "takes the position of another history"
+			default:
+				genuine = link
+				report.Verified++
+				continue
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+
+		// A broken history does not break the links after it
+		if genuine != nil {
+			report.Head, previous = genuine, genuine.Hash
+		} else {
+			previous = linked[start].Status.Chain.Hash
+		}
+		expected, start = sequence+1, end
+	}
+	return report, nil
+}
+
+// LoadSigningKey reads an ed25519 private key from a PKCS #8 PEM file, as written by
+// openssl genpkey -algorithm ed25519.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseSigningKey(path, block)
+}
+
+func parseSigningKey(path string, block *pem.Block) (ed25519.PrivateKey, error) {
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key %s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid signing key %s: not an ed25519 key", path)
+	}
+	return key, nil
+}
+
+// LoadVerifyingKey reads an ed25519 public key from a PKIX PEM file, as written by
+// openssl pkey -pubout. The public key of a private key file is accepted as well.
+func LoadVerifyingKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "PRIVATE KEY" {
+		key, err := parseSigningKey(path, block)
+		if err != nil {
+			return nil, err
+		}
+		return key.Public().(ed25519.PublicKey), nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid public key %s: not an ed25519 key", path)
+	}
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid key %s: no PEM block found", path)
+	}
+	return block, nil
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chain
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+func newReader(t *testing.T) client.Reader {
+	scheme := runtime.NewScheme()
+	if err := historyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add scheme: %v", err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).Build()
+}
+
+// linkedHistories returns count histories linked into a chain, signed with key if set.
+func linkedHistories(g *gomega.WithT, c client.Reader, count int, key ed25519.PrivateKey) []historyv1alpha1.KronoformHistory {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	var items []historyv1alpha1.KronoformHistory
+	var head *historyv1alpha1.ChainLink
+	for i := range count {
+		appliedAt := metav1.NewTime(start.Add(time.Duration(i) * time.Minute))
+		h := historyv1alpha1.KronoformHistory{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("history-%d", i), Namespace: "default"},
+			Spec:       historyv1alpha1.KronoformHistorySpec{Manifests: fmt.Sprintf("kind: ConfigMap\n# %d\n", i), AppliedBy: "alice"},
+			Status: historyv1alpha1.KronoformHistoryStatus{
+				AppliedAt:         &appliedAt,
+				ResourceSnapshots: []historyv1alpha1.ResourceSnapshot{{Kind: "ConfigMap", Name: "settings", After: "replicas: 1\n"}},
+			},
+		}
+		link, err := Link(context.Background(), c, &h, head, key)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		h.Status.Chain, head = link, link
+		items = append(items, h)
+	}
+	return items
+}
+
+func TestVerify(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+	c := newReader(t)
+
+	items := linkedHistories(g, c, 4, nil)
+	g.Expect(items[0].Status.Chain.Previous).To(gomega.BeEmpty())
+	g.Expect(items[1].Status.Chain.Previous).To(gomega.Equal(items[0].Status.Chain.Hash))
+	g.Expect(Head(items)).To(gomega.Equal(items[3].Status.Chain))
+
+	report, err := Verify(ctx, c, items, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(report.Verified).To(gomega.Equal(4))
+	g.Expect(report.Issues).To(gomega.BeEmpty())
+	g.Expect(report.Head).To(gomega.Equal(items[3].Status.Chain))
+
+	// Compressing the recorded content and recording drift keep the hash
+	reencoded := make([]historyv1alpha1.KronoformHistory, len(items))
+	for i := range items {
+		items[i].DeepCopyInto(&reencoded[i])
+	}
+	compressed, err := payload.Compress(items[1].Spec.Manifests)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	reencoded[1].Spec.Manifests = base64.StdEncoding.EncodeToString(compressed)
+	reencoded[1].Spec.ManifestsEncoding = historyv1alpha1.EncodingGzipBase64
+	reencoded[1].Status.Drift = []historyv1alpha1.ResourceDrift{{Kind: "ConfigMap", Name: "settings"}}
+	report, err = Verify(ctx, c, reencoded, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(report.Issues).To(gomega.BeEmpty())
+
+	// A modified history is reported without breaking the links after it
+	modified := append([]historyv1alpha1.KronoformHistory{}, items...)
+	modified[1] = *items[1].DeepCopy()
+	modified[1].Status.ResourceSnapshots[0].After = "replicas: 5\n"
+	report, err = Verify(ctx, c, modified, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(report.Verified).To(gomega.Equal(3))
+	g.Expect(report.Issues).To(gomega.ConsistOf(Issue{
+		Kind: IssueModified, Sequence: 2, Name: "history-1", Message: "recorded content does not match its hash",
+	}))
+
+	// Rehashing a modified history breaks the link of the next one
+	rehashed := *modified[1].DeepCopy()
+	rehashed.Status.Chain, err = Link(ctx, c, &rehashed, items[0].Status.Chain, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	modified[1] = rehashed
+	report, err = Verify(ctx, c, modified, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(report.Issues).To(gomega.ConsistOf(Issue{
+		Kind: IssueInserted, Sequence: 3, Name: "history-2", Message: "does not link to history 2",
+	}))
+
+	// A history added at the position of another one is reported as inserted
+	inserted := append([]historyv1alpha1.KronoformHistory{}, items...)
+	forged := *items[2].DeepCopy()
+	forged.Name = "history-2a"
+	forged.Status.Chain, err = Link(ctx, c, &forged, items[1].Status.Chain, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	inserted = append(inserted, forged)
+	report, err = Verify(ctx, c, inserted, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(report.Issues).To(gomega.ConsistOf(Issue{
+		Kind: IssueInserted, Sequence: 3, Name: "history-2a", Message: "takes the position of another history",
+	}))
+
+	// Removed histories leave a gap
+	removed := []historyv1alpha1.KronoformHistory{items[0], items[3]}
+	report, err = Verify(ctx, c, removed, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(report.Verified).To(gomega.Equal(2))
+	g.Expect(report.Issues).To(gomega.ConsistOf(Issue{
+		Kind: IssueMissing, Sequence: 2, Message: "histories 2 to 3 are missing",
+	}))
+
+	// Histories that are not linked yet are listed separately
+	pending := append([]historyv1alpha1.KronoformHistory{}, items...)
+	pending[3] = *items[3].DeepCopy()
+	pending[3].Status.Chain = nil
+	report, err = Verify(ctx, c, pending, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(report.Issues).To(gomega.BeEmpty())
+	g.Expect(report.Pending).To(gomega.ConsistOf("history-3"))
+}
+
+func TestVerifySignatures(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+	c := newReader(t)
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	items := linkedHistories(g, c, 2, private)
+	g.Expect(items[0].Status.Chain.Signature).NotTo(gomega.BeEmpty())
+
+	report, err := Verify(ctx, c, items, public)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(report.Issues).To(gomega.BeEmpty())
+
+	// Without the signing key a rehashed history cannot be signed again
+	unsigned := linkedHistories(g, c, 2, nil)
+	report, err = Verify(ctx, c, unsigned, public)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(report.Verified).To(gomega.BeZero())
+	g.Expect(report.Issues).To(gomega.HaveLen(2))
+	g.Expect(report.Issues[0].Message).To(gomega.Equal("signature is missing or invalid"))
+}
+
+func TestLoadKeys(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir := t.TempDir()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	privatePath, publicPath := filepath.Join(dir, "chain.key"), filepath.Join(dir, "chain.pub")
+	g.Expect(os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600)).
+		To(gomega.Succeed())
+	g.Expect(os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600)).
+		To(gomega.Succeed())
+
+	g.Expect(LoadSigningKey(privatePath)).To(gomega.Equal(private))
+	g.Expect(LoadVerifyingKey(publicPath)).To(gomega.Equal(public))
+	g.Expect(LoadVerifyingKey(privatePath)).To(gomega.Equal(public))
+
+	_, err = LoadSigningKey(publicPath)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("invalid signing key")))
+	g.Expect(os.WriteFile(publicPath, []byte("not a key"), 0o600)).To(gomega.Succeed())
+	_, err = LoadVerifyingKey(publicPath)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("no PEM block found")))
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/ed25519"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/chain"
+	"github.com/yu-kod/kronoform/internal/history"
+)
+
+// ChainReconciler appends recorded histories to the hash chain of their namespace. Requests
+// carry the namespace only, so that the histories of a namespace are linked one at a time.
+type ChainReconciler struct {
+	client.Client
+	// Reader lists histories from the API server rather than the cache, so that the head
+	// of a chain is never read from a stale copy and two histories never share a position.
+	Reader client.Reader
+	// SigningKey signs the links. Links are not signed if nil.
+	SigningKey ed25519.PrivateKey
+}
+
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformhistories,verbs=get;list;watch
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformhistories/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformchunks,verbs=get;list;watch
+
+// Reconcile links the histories of a namespace whose status is recorded, oldest first.
+func (r *ChainReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	histories := &historyv1alpha1.KronoformHistoryList{}
+	if err := r.Reader.List(ctx, histories, client.InNamespace(req.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+	history.Sort(histories.Items)
+
+	head := chain.Head(histories.Items)
+	for i := range histories.Items {
+		h := &histories.Items[i]
+		// Histories are linked once the creator has recorded their status
+		if h.Status.Chain != nil || h.Status.AppliedAt == nil || !h.DeletionTimestamp.IsZero() {
+			continue
+		}
+		link, err := chain.Link(ctx, r.Reader, h, head, r.SigningKey)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		h.Status.Chain = link
+		if err := r.Status().Update(ctx, h); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.V(1).Info("Linked history", "history", h.Name, "sequence", link.Sequence)
+		head = link
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ChainReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("chain").
+		Watches(&historyv1alpha1.KronoformHistory{}, handler.EnqueueRequestsFromMapFunc(chainForHistory)).
+		Complete(r)
+}
+
+// chainForHistory maps a history event to the chain of its namespace.
+func chainForHistory(_ context.Context, obj client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace()}}}
+}
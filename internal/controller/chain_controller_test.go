@@ -0,0 +1,97 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/chain"
+)
+
+var _ = Describe("Chain Controller", func() {
+	Context("When histories are recorded in a namespace", func() {
+		const namespace = "chain-test"
+
+		ctx := context.Background()
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+
+			By("recording two histories, the second one without a status yet")
+			for i, name := range []string{"chain-first", "chain-second"} {
+				history := &historyv1alpha1.KronoformHistory{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+					Spec:       historyv1alpha1.KronoformHistorySpec{Manifests: "kind: ConfigMap"},
+				}
+				Expect(k8sClient.Create(ctx, history)).To(Succeed())
+				if i == 0 {
+					appliedAt := metav1.NewTime(time.Now().Add(-time.Minute))
+					history.Status.AppliedAt = &appliedAt
+					Expect(k8sClient.Status().Update(ctx, history)).To(Succeed())
+				}
+			}
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(ctx, &historyv1alpha1.KronoformHistory{}, client.InNamespace(namespace))).To(Succeed())
+		})
+
+		It("should link recorded histories in the order they were applied", func() {
+			reconciler := &ChainReconciler{Client: k8sClient, Reader: k8sClient}
+			request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace}}
+
+			_, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+
+			first := &historyv1alpha1.KronoformHistory{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "chain-first", Namespace: namespace}, first)).To(Succeed())
+			Expect(first.Status.Chain).NotTo(BeNil())
+			Expect(first.Status.Chain.Sequence).To(BeEquivalentTo(1))
+
+			By("linking the second history once its status is recorded")
+			second := &historyv1alpha1.KronoformHistory{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "chain-second", Namespace: namespace}, second)).To(Succeed())
+			Expect(second.Status.Chain).To(BeNil())
+			now := metav1.Now()
+			second.Status.AppliedAt = &now
+			Expect(k8sClient.Status().Update(ctx, second)).To(Succeed())
+
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "chain-second", Namespace: namespace}, second)).To(Succeed())
+			Expect(second.Status.Chain.Sequence).To(BeEquivalentTo(2))
+			Expect(second.Status.Chain.Previous).To(Equal(first.Status.Chain.Hash))
+
+			histories := &historyv1alpha1.KronoformHistoryList{}
+			Expect(k8sClient.List(ctx, histories, client.InNamespace(namespace))).To(Succeed())
+			report, err := chain.Verify(ctx, k8sClient, histories.Items, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Verified).To(Equal(2))
+			Expect(report.Issues).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,414 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/envelope"
+	"github.com/yu-kod/kronoform/internal/fielddiff"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/payload"
+	"github.com/yu-kod/kronoform/internal/redact"
+)
+
+// DefaultDriftKinds are the kinds checked for drift by default, those the manager is
+// allowed to read.
+const DefaultDriftKinds = "v1/ConfigMap,v1/Service,apps/v1/Deployment,apps/v1/StatefulSet,apps/v1/DaemonSet"
+
+// DefaultDriftIgnoredManagers are the field managers whose additions are not drift by
+// default, those of the built-in controllers such as the deployment revision annotation.
+const DefaultDriftIgnoredManagers = "kube-controller-manager"
+
+const (
+	// historyResourceIndex indexes histories by the key of each resource they recorded.
+	historyResourceIndex = "status.resourceSnapshots.key"
+	// maxDriftFields caps the number of fields recorded per drifted resource.
+	maxDriftFields = 50
+	// maxDriftValueLength caps the length of a recorded field value.
+	maxDriftValueLength = 256
+)
+
+// DriftReconciler compares the live state of every resource whose latest history has an
+// After state with that recorded state, and records out-of-band modifications on the history.
+// Live objects are watched through informers that are started on demand for each kind.
+type DriftReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// Kinds are the kinds of resources checked for drift. The manager needs to be allowed to
+	// read them; resources of other kinds are not checked.
+	Kinds []schema.GroupVersionKind
+	// Redactor redacts live objects like their recorded states before they are compared.
+	// Secret data is redacted if nil.
+	Redactor *redact.Redactor
+	// Keyring decrypts encrypted states. Resources with encrypted states are not checked if nil.
+	Keyring *envelope.Keyring
+	// IgnoredManagers are the field managers of controllers, such as kube-controller-manager.
+	// Fields they alone set on the live object that the recorded state does not have are
+	// not drift.
+	IgnoredManagers []string
+
+	cache      cache.Cache
+	controller controller.Controller
+	// indexed is set once histories are indexed by historyResourceIndex
+	indexed bool
+
+	mu       sync.Mutex
+	watching map[schema.GroupVersionKind]bool
+}
+
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformhistories,verbs=get;list;watch
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformhistories/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=configmaps;services,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch
+
+// Reconcile records the drift of the resources for which this history is the latest record.
+func (r *DriftReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	current := &historyv1alpha1.KronoformHistory{}
+	if err := r.Get(ctx, req.NamespacedName, current); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// Large states are read from their chunks without writing them back inline
+	recordedStates := current.DeepCopy()
+	if err := payload.ReassembleStates(ctx, r.Client, recordedStates); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var drift []historyv1alpha1.ResourceDrift
+	for _, rs := range recordedStates.Status.ResourceSnapshots {
+		if rs.After == "" || (rs.Encoding == historyv1alpha1.EncodingEncrypted && r.Keyring == nil) || !r.checks(rs) {
+			continue
+		}
+		key := history.KeyOf(rs)
+		recorded, err := r.historiesRecording(ctx, key)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if latest := history.Latest(recorded, key); latest == nil || latest.UID != current.UID {
+			// A newer history owns drift detection for this resource.
+			continue
+		}
+
+		entry, err := r.detectDrift(ctx, rs)
+		if err != nil {
+			log.Error(err, "Failed to check resource for drift", "resource", key.String())
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+		if previous := findDrift(current.Status.Drift, rs); previous != nil && previous.DetectedAt != nil {
+			entry.DetectedAt = previous.DetectedAt
+		}
+		drift = append(drift, *entry)
+	}
+
+	condition := metav1.Condition{
+		Type:               historyv1alpha1.ConditionDrifted,
+		Status:             metav1.ConditionFalse,
+		Reason:             "InSync",
+		Message:            "Recorded resources match their live state",
+		ObservedGeneration: current.Generation,
+	}
+	if len(drift) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "OutOfBandChange"
+		condition.Message = fmt.Sprintf("%d resource(s) were modified outside kronoform", len(drift))
+	}
+
+	driftChanged := !equality.Semantic.DeepEqual(drift, current.Status.Drift)
+	current.Status.Drift = drift
+	conditionChanged := meta.SetStatusCondition(&current.Status.Conditions, condition)
+	if !driftChanged && !conditionChanged {
+		return ctrl.Result{}, nil
+	}
+	if len(drift) > 0 {
+		log.Info("Detected drift", "resources", len(drift))
+	}
+	return ctrl.Result{}, r.Status().Update(ctx, current)
+}
+
+// checks reports whether a recorded resource is of a kind checked for drift.
+func (r *DriftReconciler) checks(rs historyv1alpha1.ResourceSnapshot) bool {
+	gk := schema.FromAPIVersionAndKind(rs.APIVersion, rs.Kind).GroupKind()
+	for _, kind := range r.Kinds {
+		if kind.GroupKind() == gk {
+			return true
+		}
+	}
+	return false
+}
+
+// historiesRecording returns the histories that recorded a resource.
+func (r *DriftReconciler) historiesRecording(ctx context.Context, key history.ResourceKey) ([]historyv1alpha1.KronoformHistory, error) {
+	list := &historyv1alpha1.KronoformHistoryList{}
+	if r.indexed {
+		if err := r.List(ctx, list, client.MatchingFields{historyResourceIndex: key.String()}); err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	}
+	// Without the index (e.g. when reconciling directly in tests) every history is listed
+	if err := r.List(ctx, list); err != nil {
+		return nil, err
+	}
+	var recorded []historyv1alpha1.KronoformHistory
+	for i := range list.Items {
+		if _, ok := history.Find(&list.Items[i], key); ok {
+			recorded = append(recorded, list.Items[i])
+		}
+	}
+	return recorded, nil
+}
+
+// ParseManagers parses a comma-separated list of field managers.
+func ParseManagers(value string) []string {
+	var managers []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			managers = append(managers, item)
+		}
+	}
+	return managers
+}
+
+// resourceKeys indexes a history by the keys of the resources it recorded.
+func resourceKeys(obj client.Object) []string {
+	h, ok := obj.(*historyv1alpha1.KronoformHistory)
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(h.Status.ResourceSnapshots))
+	for _, rs := range h.Status.ResourceSnapshots {
+		keys = append(keys, history.KeyOf(rs).String())
+	}
+	return keys
+}
+
+// detectDrift compares a recorded After state with the live object.
+// It returns nil when the live object matches.
+func (r *DriftReconciler) detectDrift(ctx context.Context, rs historyv1alpha1.ResourceSnapshot) (*historyv1alpha1.ResourceDrift, error) {
+	gvk := schema.FromAPIVersionAndKind(rs.APIVersion, rs.Kind)
+	if err := r.ensureWatch(gvk); err != nil {
+		return nil, err
+	}
+
+	_, after, err := r.Keyring.States(rs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode recorded state: %w", err)
+	}
+	expected, err := fielddiff.Parse(after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse recorded state: %w", err)
+	}
+	// States recorded before a value was redacted are compared with it redacted as well
+	r.Redactor.Object(expected)
+
+	now := metav1.Now()
+	entry := &historyv1alpha1.ResourceDrift{
+		APIVersion: rs.APIVersion,
+		Kind:       rs.Kind,
+		Name:       rs.Name,
+		Namespace:  rs.Namespace,
+		DetectedAt: &now,
+	}
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	err = r.Get(ctx, client.ObjectKey{Namespace: rs.Namespace, Name: rs.Name}, live)
+	if apierrors.IsNotFound(err) {
+		entry.Reason = historyv1alpha1.DriftReasonDeleted
+		return entry, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	redacted := r.Redactor.Unstructured(live)
+	if err := r.pruneControllerFields(redacted, expected); err != nil {
+		return nil, err
+	}
+	actual, err := fielddiff.Normalize(redacted.Object)
+	if err != nil {
+		return nil, err
+	}
+	changes := fielddiff.Diff(expected, actual)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	entry.Reason = historyv1alpha1.DriftReasonModified
+	for i, change := range changes {
+		if i == maxDriftFields {
+			break
+		}
+		entry.Fields = append(entry.Fields, historyv1alpha1.FieldDrift{
+			Path:     change.Path,
+			Expected: truncate(fielddiff.FormatValue(change.Before)),
+			Actual:   truncate(fielddiff.FormatValue(change.After)),
+		})
+	}
+	return entry, nil
+}
+
+// pruneControllerFields removes from live the fields that only IgnoredManagers set
+// according to its managedFields and that expected does not have, as controllers add
+// them after every apply.
+func (r *DriftReconciler) pruneControllerFields(live *unstructured.Unstructured, expected map[string]interface{}) error {
+	// ignoredOnly is true for the paths managed by IgnoredManagers alone
+	ignoredOnly := map[string]bool{}
+	for _, entry := range live.GetManagedFields() {
+		if entry.FieldsV1 == nil {
+			continue
+		}
+		paths, err := fielddiff.ManagedPaths(entry.FieldsV1.Raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse the fields managed by %s: %w", entry.Manager, err)
+		}
+		ignored := slices.Contains(r.IgnoredManagers, entry.Manager)
+		for _, path := range paths {
+			if only, seen := ignoredOnly[path]; !seen || only {
+				ignoredOnly[path] = ignored
+			}
+		}
+	}
+	for path, only := range ignoredOnly {
+		if !only {
+			continue
+		}
+		if _, found, err := fielddiff.Lookup(expected, path); err != nil || found {
+			continue
+		}
+		if err := fielddiff.Delete(live.Object, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureWatch starts watching live objects of the given kind, once per kind.
+func (r *DriftReconciler) ensureWatch(gvk schema.GroupVersionKind) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Without a controller (e.g. when reconciling directly in tests) live objects
+	// are only compared when the history itself is reconciled.
+	if r.controller == nil || r.watching[gvk] {
+		return nil
+	}
+	if _, err := r.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		return fmt.Errorf("cannot watch %s: %w", gvk, err)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	if err := r.controller.Watch(source.Kind(r.cache, client.Object(obj),
+		handler.EnqueueRequestsFromMapFunc(r.historiesForObject))); err != nil {
+		return err
+	}
+	r.watching[gvk] = true
+	return nil
+}
+
+// historiesForObject maps a live object event to the histories that recorded it, so that
+// the latest one re-evaluates its drift and older ones drop stale entries.
+func (r *DriftReconciler) historiesForObject(ctx context.Context, obj client.Object) []reconcile.Request {
+	key := history.ResourceKey{
+		Group:     obj.GetObjectKind().GroupVersionKind().Group,
+		Kind:      obj.GetObjectKind().GroupVersionKind().Kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+	recorded, err := r.historiesRecording(ctx, key)
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to list histories")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(recorded))
+	for _, h := range recorded {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: h.Namespace,
+			Name:      h.Name,
+		}})
+	}
+	return requests
+}
+
+// findDrift returns the drift entry previously recorded for a resource.
+func findDrift(entries []historyv1alpha1.ResourceDrift, rs historyv1alpha1.ResourceSnapshot) *historyv1alpha1.ResourceDrift {
+	for i := range entries {
+		if entries[i].APIVersion == rs.APIVersion && entries[i].Kind == rs.Kind &&
+			entries[i].Namespace == rs.Namespace && entries[i].Name == rs.Name {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+func truncate(value string) string {
+	if len(value) <= maxDriftValueLength {
+		return value
+	}
+	return value[:maxDriftValueLength] + "..."
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DriftReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &historyv1alpha1.KronoformHistory{},
+		historyResourceIndex, resourceKeys); err != nil {
+		return err
+	}
+	r.indexed = true
+
+	c, err := ctrl.NewControllerManagedBy(mgr).
+		For(&historyv1alpha1.KronoformHistory{}).
+		Named("drift").
+		Build(r)
+	if err != nil {
+		return err
+	}
+	r.cache = mgr.GetCache()
+	r.controller = c
+	r.watching = map[schema.GroupVersionKind]bool{}
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+var _ = Describe("Drift Controller", func() {
+	Context("When a recorded resource is modified out of band", func() {
+		const (
+			historyName   = "drift-history"
+			configMapName = "drift-config"
+		)
+
+		ctx := context.Background()
+
+		historyKey := types.NamespacedName{Name: historyName, Namespace: "default"}
+
+		BeforeEach(func() {
+			By("creating the live resource with a modified value")
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: configMapName, Namespace: "default"},
+				Data:       map[string]string{"key": "changed"},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+
+			By("recording the applied state in a history")
+			history := &historyv1alpha1.KronoformHistory{
+				ObjectMeta: metav1.ObjectMeta{Name: historyName, Namespace: "default"},
+				Spec: historyv1alpha1.KronoformHistorySpec{
+					Manifests:   "kind: ConfigMap",
+					SnapshotRef: "drift-snapshot",
+				},
+			}
+			Expect(k8sClient.Create(ctx, history)).To(Succeed())
+			now := metav1.Now()
+			history.Status.AppliedAt = &now
+			history.Status.ResourceSnapshots = []historyv1alpha1.ResourceSnapshot{{
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				Name:       configMapName,
+				Namespace:  "default",
+				Operation:  "Created",
+				After: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + configMapName +
+					"\n  namespace: default\ndata:\n  key: expected\n",
+			}}
+			Expect(k8sClient.Status().Update(ctx, history)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, &historyv1alpha1.KronoformHistory{
+				ObjectMeta: metav1.ObjectMeta{Name: historyName, Namespace: "default"},
+			})).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: configMapName, Namespace: "default"},
+			})).To(Succeed())
+		})
+
+		It("should record a Drifted condition and the field-level delta", func() {
+			reconciler := &DriftReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Kinds:  []schema.GroupVersionKind{{Version: "v1", Kind: "ConfigMap"}},
+			}
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: historyKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			history := &historyv1alpha1.KronoformHistory{}
+			Expect(k8sClient.Get(ctx, historyKey, history)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(history.Status.Conditions, historyv1alpha1.ConditionDrifted)).To(BeTrue())
+			Expect(history.Status.Drift).To(HaveLen(1))
+			Expect(history.Status.Drift[0].Reason).To(Equal(historyv1alpha1.DriftReasonModified))
+			Expect(history.Status.Drift[0].Fields).To(ConsistOf(historyv1alpha1.FieldDrift{
+				Path:     ".data.key",
+				Expected: "expected",
+				Actual:   "changed",
+			}))
+		})
+
+		It("should not record fields that a controller added as drift", func() {
+			By("annotating the live resource as the deployment controller does")
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: configMapName, Namespace: "default"}, configMap)).To(Succeed())
+			patch := client.MergeFrom(configMap.DeepCopy())
+			configMap.Annotations = map[string]string{"deployment.kubernetes.io/revision": "2"}
+			Expect(k8sClient.Patch(ctx, configMap, patch, client.FieldOwner("kube-controller-manager"))).To(Succeed())
+
+			reconciler := &DriftReconciler{
+				Client:          k8sClient,
+				Scheme:          k8sClient.Scheme(),
+				Kinds:           []schema.GroupVersionKind{{Version: "v1", Kind: "ConfigMap"}},
+				IgnoredManagers: []string{"kube-controller-manager"},
+			}
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: historyKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			history := &historyv1alpha1.KronoformHistory{}
+			Expect(k8sClient.Get(ctx, historyKey, history)).To(Succeed())
+			Expect(history.Status.Drift).To(HaveLen(1))
+			Expect(history.Status.Drift[0].Fields).To(ConsistOf(historyv1alpha1.FieldDrift{
+				Path:     ".data.key",
+				Expected: "expected",
+				Actual:   "changed",
+			}))
+		})
+	})
+})
@@ -0,0 +1,104 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/envelope"
+	"github.com/yu-kod/kronoform/internal/gitexport"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/storage"
+)
+
+// DefaultGitExportInterval is how often the GitExporter exports new histories by default.
+const DefaultGitExportInterval = time.Minute
+
+// GitExporter periodically commits new histories of all namespaces to a git repository
+// on a volume of the manager, keeping the repository in step with the cluster.
+type GitExporter struct {
+	Client client.Client
+
+	// Exporter writes the repository.
+	Exporter *gitexport.Exporter
+	// Interval is the time between exports. DefaultGitExportInterval is used if zero.
+	Interval time.Duration
+	// Keyring decrypts encrypted histories, which are exported decrypted. The export
+	// stops at the first encrypted history if nil.
+	Keyring *envelope.Keyring
+}
+
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformhistories,verbs=get;list;watch
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformchunks,verbs=get;list;watch
+
+// Start exports histories every interval until the context is done.
+func (e *GitExporter) Start(ctx context.Context) error {
+	interval := e.Interval
+	if interval == 0 {
+		interval = DefaultGitExportInterval
+	}
+	wait.UntilWithContext(ctx, e.export, interval)
+	return nil
+}
+
+// NeedLeaderElection reports true: only one replica may write the repository.
+func (e *GitExporter) NeedLeaderElection() bool {
+	return true
+}
+
+func (e *GitExporter) export(ctx context.Context) {
+	log := logf.FromContext(ctx).WithName("git-export")
+
+	store := storage.NewCRDStore(e.Client)
+	store.Keyring = e.Keyring
+	list := &historyv1alpha1.KronoformHistoryList{}
+	if err := e.Client.List(ctx, list); err != nil {
+		log.Error(err, "Failed to list histories")
+		return
+	}
+	// Histories are read one by one. One that cannot be read is passed on marked as such,
+	// so that the export stops there and resumes from it once it can be read. Archived
+	// histories only hold a reference to their content, which the manager cannot read.
+	var histories []historyv1alpha1.KronoformHistory
+	for _, item := range list.Items {
+		if item.Spec.Archive != nil {
+			continue
+		}
+		h, err := store.GetHistory(ctx, item.Namespace, item.Name)
+		if err != nil {
+			h = item.DeepCopy()
+			if h.Annotations == nil {
+				h.Annotations = map[string]string{}
+			}
+			h.Annotations[history.UnreadableAnnotation] = err.Error()
+		}
+		histories = append(histories, *h)
+	}
+	count, err := e.Exporter.Export(ctx, histories)
+	if count > 0 {
+		log.Info("Exported histories", "repository", e.Exporter.Repo, "count", count)
+	}
+	if err != nil {
+		log.Error(err, "Failed to export histories", "repository", e.Exporter.Repo)
+	}
+}
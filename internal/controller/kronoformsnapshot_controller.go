@@ -0,0 +1,162 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+// KronoformSnapshotReconciler keeps a KronoformSnapshot and the KronoformHistory it
+// references consistent. The snapshot is owned by its history, so deleting the history
+// garbage-collects the snapshot; a finalizer on the snapshot deletes the history when
+// the snapshot is deleted first.
+type KronoformSnapshotReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformsnapshots,verbs=get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformsnapshots/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformsnapshots/finalizers,verbs=update
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformhistories,verbs=get;list;watch;delete
+
+// Reconcile links a snapshot to its history with an owner reference and a finalizer,
+// and deletes the history when a linked snapshot is being deleted.
+func (r *KronoformSnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	snapshot := &historyv1alpha1.KronoformSnapshot{}
+	if err := r.Get(ctx, req.NamespacedName, snapshot); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !snapshot.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalize(ctx, snapshot)
+	}
+
+	// Snapshots without a history (pending or NoChanges) are not part of a pair yet.
+	if snapshot.Status.HistoryRef == "" {
+		return ctrl.Result{}, nil
+	}
+
+	history := &historyv1alpha1.KronoformHistory{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: snapshot.Namespace, Name: snapshot.Status.HistoryRef}, history)
+	if apierrors.IsNotFound(err) {
+		// A linked snapshot is garbage-collected with its history; records left unlinked
+		// by older versions are reported as orphaned by the read commands.
+		log.V(1).Info("Snapshot references a history that no longer exists", "history", snapshot.Status.HistoryRef)
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Older snapshots were recorded before the pair was linked; adopt them here.
+	updated := controllerutil.AddFinalizer(snapshot, historyv1alpha1.HistoryLinkFinalizer)
+	hasOwner, err := controllerutil.HasOwnerReference(snapshot.OwnerReferences, history, r.Scheme)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !hasOwner {
+		if err := controllerutil.SetOwnerReference(history, snapshot, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		updated = true
+	}
+	if updated {
+		if err := r.Update(ctx, snapshot); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, r.setLinkedCondition(ctx, snapshot, history)
+}
+
+// finalize deletes the history paired with a snapshot and releases the finalizer.
+func (r *KronoformSnapshotReconciler) finalize(ctx context.Context, snapshot *historyv1alpha1.KronoformSnapshot) error {
+	if !controllerutil.ContainsFinalizer(snapshot, historyv1alpha1.HistoryLinkFinalizer) {
+		return nil
+	}
+
+	if snapshot.Status.HistoryRef != "" {
+		history := &historyv1alpha1.KronoformHistory{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: snapshot.Namespace, Name: snapshot.Status.HistoryRef}, history)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && history.DeletionTimestamp.IsZero() {
+			logf.FromContext(ctx).Info("Deleting history paired with deleted snapshot", "history", history.Name)
+			if err := r.Delete(ctx, history); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+	}
+
+	controllerutil.RemoveFinalizer(snapshot, historyv1alpha1.HistoryLinkFinalizer)
+	return r.Update(ctx, snapshot)
+}
+
+// setLinkedCondition records that the snapshot is linked to its history.
+func (r *KronoformSnapshotReconciler) setLinkedCondition(ctx context.Context, snapshot *historyv1alpha1.KronoformSnapshot,
+	history *historyv1alpha1.KronoformHistory) error {
+	changed := meta.SetStatusCondition(&snapshot.Status.Conditions, metav1.Condition{
+		Type:               historyv1alpha1.ConditionHistoryLinked,
+		Status:             metav1.ConditionTrue,
+		Reason:             "HistoryFound",
+		Message:            "Linked to KronoformHistory " + history.Name,
+		ObservedGeneration: snapshot.Generation,
+	})
+	if !changed {
+		return nil
+	}
+	return r.Status().Update(ctx, snapshot)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *KronoformSnapshotReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&historyv1alpha1.KronoformSnapshot{}).
+		Watches(&historyv1alpha1.KronoformHistory{}, handler.EnqueueRequestsFromMapFunc(snapshotForHistory)).
+		Named("kronoformsnapshot").
+		Complete(r)
+}
+
+// snapshotForHistory maps a history event to the snapshot it references.
+func snapshotForHistory(_ context.Context, obj client.Object) []reconcile.Request {
+	history, ok := obj.(*historyv1alpha1.KronoformHistory)
+	if !ok || history.Spec.SnapshotRef == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: history.Namespace,
+		Name:      history.Spec.SnapshotRef,
+	}}}
+}
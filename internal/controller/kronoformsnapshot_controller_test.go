@@ -0,0 +1,135 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+var _ = Describe("KronoformSnapshot Controller", func() {
+	Context("When a snapshot is paired with a history", func() {
+		const (
+			snapshotName = "test-snapshot"
+			historyName  = "test-history"
+		)
+
+		ctx := context.Background()
+
+		snapshotKey := types.NamespacedName{Name: snapshotName, Namespace: "default"}
+		historyKey := types.NamespacedName{Name: historyName, Namespace: "default"}
+
+		var reconciler *KronoformSnapshotReconciler
+
+		BeforeEach(func() {
+			reconciler = &KronoformSnapshotReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			By("creating the snapshot and its history")
+			snapshot := &historyv1alpha1.KronoformSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: snapshotName, Namespace: "default"},
+				Spec:       historyv1alpha1.KronoformSnapshotSpec{Manifests: "kind: ConfigMap"},
+			}
+			Expect(k8sClient.Create(ctx, snapshot)).To(Succeed())
+			snapshot.Status.HistoryRef = historyName
+			Expect(k8sClient.Status().Update(ctx, snapshot)).To(Succeed())
+
+			history := &historyv1alpha1.KronoformHistory{
+				ObjectMeta: metav1.ObjectMeta{Name: historyName, Namespace: "default"},
+				Spec: historyv1alpha1.KronoformHistorySpec{
+					Manifests:   "kind: ConfigMap",
+					SnapshotRef: snapshotName,
+				},
+			}
+			Expect(k8sClient.Create(ctx, history)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			snapshot := &historyv1alpha1.KronoformSnapshot{}
+			if err := k8sClient.Get(ctx, snapshotKey, snapshot); err == nil {
+				snapshot.Finalizers = nil
+				Expect(k8sClient.Update(ctx, snapshot)).To(Succeed())
+				Expect(k8sClient.Delete(ctx, snapshot)).To(Succeed())
+			}
+			history := &historyv1alpha1.KronoformHistory{}
+			if err := k8sClient.Get(ctx, historyKey, history); err == nil {
+				Expect(k8sClient.Delete(ctx, history)).To(Succeed())
+			}
+		})
+
+		It("should link the snapshot to its history", func() {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: snapshotKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			snapshot := &historyv1alpha1.KronoformSnapshot{}
+			Expect(k8sClient.Get(ctx, snapshotKey, snapshot)).To(Succeed())
+			Expect(controllerutil.ContainsFinalizer(snapshot, historyv1alpha1.HistoryLinkFinalizer)).To(BeTrue())
+			Expect(snapshot.OwnerReferences).To(HaveLen(1))
+			Expect(snapshot.OwnerReferences[0].Name).To(Equal(historyName))
+			Expect(meta.IsStatusConditionTrue(snapshot.Status.Conditions, historyv1alpha1.ConditionHistoryLinked)).To(BeTrue())
+		})
+
+		It("should delete the history when the snapshot is deleted", func() {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: snapshotKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			snapshot := &historyv1alpha1.KronoformSnapshot{}
+			Expect(k8sClient.Get(ctx, snapshotKey, snapshot)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, snapshot)).To(Succeed())
+
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: snapshotKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, historyKey, &historyv1alpha1.KronoformHistory{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, snapshotKey, &historyv1alpha1.KronoformSnapshot{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should leave a snapshot whose history is gone to the garbage collector", func() {
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: snapshotKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			history := &historyv1alpha1.KronoformHistory{}
+			Expect(k8sClient.Get(ctx, historyKey, history)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, history)).To(Succeed())
+
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: snapshotKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			// envtest runs no garbage collector: the owner reference is what deletes it
+			snapshot := &historyv1alpha1.KronoformSnapshot{}
+			Expect(k8sClient.Get(ctx, snapshotKey, snapshot)).To(Succeed())
+			Expect(snapshot.OwnerReferences).To(HaveLen(1))
+			Expect(snapshot.OwnerReferences[0].UID).To(Equal(history.UID))
+			Expect(snapshot.Status.Conditions).To(HaveLen(1))
+			Expect(meta.IsStatusConditionTrue(snapshot.Status.Conditions, historyv1alpha1.ConditionHistoryLinked)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,208 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/envelope"
+	"github.com/yu-kod/kronoform/internal/fielddiff"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/redact"
+)
+
+// ChangeObserver watches the configured kinds and records a KronoformHistory for every
+// change, such as kubectl edit, Helm or other controllers. Writes recorded by kubectl
+// kronoform apply are skipped, as they are by the audit webhook, so that one apply yields
+// one history.
+type ChangeObserver struct {
+	Client client.Client
+	Cache  cache.Cache
+
+	// Kinds are the resource kinds to observe.
+	Kinds []schema.GroupVersionKind
+	// HistoryNamespace is where histories of cluster-scoped resources are stored.
+	HistoryNamespace string
+	// Redactor redacts the recorded states. Secret data is redacted if nil.
+	Redactor *redact.Redactor
+	// Keyring encrypts the recorded manifests and states. They are stored unencrypted if nil.
+	Keyring *envelope.Keyring
+}
+
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformhistories,verbs=create
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformchunks,verbs=create
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformhistories/status,verbs=get;update;patch
+
+// Start registers event handlers on the informers of the observed kinds and blocks
+// until the context is done.
+func (o *ChangeObserver) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("observer")
+
+	for _, gvk := range o.Kinds {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		informer, err := o.Cache.GetInformer(ctx, obj)
+		if err != nil {
+			return fmt.Errorf("failed to get informer for %s: %w", gvk, err)
+		}
+		if _, err := informer.AddEventHandler(toolscache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj interface{}, isInInitialList bool) {
+				// Objects listed at startup existed before the observer ran
+				if isInInitialList {
+					return
+				}
+				o.OnAdd(ctx, obj)
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				o.OnUpdate(ctx, oldObj, newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				o.OnDelete(ctx, obj)
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", gvk, err)
+		}
+		log.Info("Observing out-of-band changes", "kind", gvk.String())
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// OnAdd records the creation of a resource.
+func (o *ChangeObserver) OnAdd(ctx context.Context, obj interface{}) {
+	after, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	o.record(ctx, nil, after, history.LastManager(after))
+}
+
+// OnUpdate records a modification of a resource. Updates that only touch status or
+// metadata such as resourceVersion are ignored.
+func (o *ChangeObserver) OnUpdate(ctx context.Context, oldObj, newObj interface{}) {
+	before, ok := oldObj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	after, ok := newObj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	if after.GetGeneration() > 0 {
+		if before.GetGeneration() == after.GetGeneration() {
+			return
+		}
+	} else {
+		// Kinds without a generation (e.g. ConfigMaps) are compared by content
+		beforeState, err := fielddiff.Normalize(before.Object)
+		if err != nil {
+			return
+		}
+		afterState, err := fielddiff.Normalize(after.Object)
+		if err != nil {
+			return
+		}
+		if len(fielddiff.Diff(beforeState, afterState)) == 0 {
+			return
+		}
+	}
+	o.record(ctx, before, after, history.LastManager(after))
+}
+
+// OnDelete records the deletion of a resource. The deleting client is not known.
+func (o *ChangeObserver) OnDelete(ctx context.Context, obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	before, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	o.record(ctx, before, nil, "")
+}
+
+func (o *ChangeObserver) record(ctx context.Context, before, after *unstructured.Unstructured, manager string) {
+	log := logf.FromContext(ctx).WithName("observer")
+
+	if history.RecordedByApply(manager, before, after) {
+		// Writes made by kubectl kronoform apply are recorded by their applied history
+		return
+	}
+
+	h, err := history.FromChange(o.Redactor.Unstructured(before), o.Redactor.Unstructured(after),
+		historyv1alpha1.HistorySourceObserved, manager)
+	if err != nil {
+		log.Error(err, "Failed to build history for observed change")
+		return
+	}
+	h.Spec.FieldManager = manager
+	if h.Namespace == "" {
+		h.Namespace = o.HistoryNamespace
+	}
+	if err := o.Keyring.SealHistory(h); err != nil {
+		log.Error(err, "Failed to encrypt history for observed change")
+		return
+	}
+
+	if err := history.Create(ctx, o.Client, h); err != nil {
+		log.Error(err, "Failed to record observed change", "summary", h.Status.Summary)
+		return
+	}
+	log.Info("Recorded observed change", "history", h.Name, "summary", h.Status.Summary, "fieldManager", manager)
+}
+
+// ParseKinds parses a comma-separated list of kinds in the form group/version/Kind, or
+// version/Kind for the core group (e.g. "v1/ConfigMap,apps/v1/Deployment").
+func ParseKinds(value string) ([]schema.GroupVersionKind, error) {
+	var kinds []schema.GroupVersionKind
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, "/")
+		var gvk schema.GroupVersionKind
+		switch len(parts) {
+		case 2:
+			gvk = schema.GroupVersionKind{Version: parts[0], Kind: parts[1]}
+		case 3:
+			gvk = schema.GroupVersionKind{Group: parts[0], Version: parts[1], Kind: parts[2]}
+		default:
+			return nil, fmt.Errorf("invalid kind %q: expected group/version/Kind or version/Kind", item)
+		}
+		if gvk.Version == "" || gvk.Kind == "" {
+			return nil, fmt.Errorf("invalid kind %q: version and kind are required", item)
+		}
+		if gvk.Group == historyv1alpha1.GroupVersion.Group {
+			// Recording kronoform's own records would record the recording itself
+			return nil, fmt.Errorf("invalid kind %q: kronoform resources cannot be watched", item)
+		}
+		kinds = append(kinds, gvk)
+	}
+	return kinds, nil
+}
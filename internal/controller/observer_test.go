@@ -0,0 +1,150 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/payload"
+	"github.com/yu-kod/kronoform/internal/redact"
+)
+
+var _ = Describe("Change Observer", func() {
+	Context("When an observed resource changes", func() {
+		const namespace = "observer-test"
+
+		ctx := context.Background()
+
+		var observer *ChangeObserver
+
+		configMap := func(value, manager string) *unstructured.Unstructured {
+			obj := &unstructured.Unstructured{}
+			obj.SetAPIVersion("v1")
+			obj.SetKind("ConfigMap")
+			obj.SetName("observed")
+			obj.SetNamespace(namespace)
+			Expect(unstructured.SetNestedField(obj.Object, value, "data", "key")).To(Succeed())
+			obj.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: manager}})
+			return obj
+		}
+
+		listHistories := func() []historyv1alpha1.KronoformHistory {
+			histories := &historyv1alpha1.KronoformHistoryList{}
+			Expect(k8sClient.List(ctx, histories, client.InNamespace(namespace))).To(Succeed())
+			return histories.Items
+		}
+
+		BeforeEach(func() {
+			observer = &ChangeObserver{Client: k8sClient, HistoryNamespace: "default"}
+			ns := &unstructured.Unstructured{}
+			ns.SetAPIVersion("v1")
+			ns.SetKind("Namespace")
+			ns.SetName(namespace)
+			Expect(client.IgnoreAlreadyExists(k8sClient.Create(ctx, ns))).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(ctx, &historyv1alpha1.KronoformHistory{}, client.InNamespace(namespace))).To(Succeed())
+		})
+
+		It("should record an edit made by another field manager", func() {
+			observer.OnUpdate(ctx, configMap("a", "kubectl-edit"), configMap("b", "kubectl-edit"))
+
+			histories := listHistories()
+			Expect(histories).To(HaveLen(1))
+			Expect(histories[0].Spec.Source).To(Equal(historyv1alpha1.HistorySourceObserved))
+			Expect(histories[0].Spec.FieldManager).To(Equal("kubectl-edit"))
+			Expect(histories[0].Status.ResourceSnapshots).To(HaveLen(1))
+			Expect(histories[0].Status.ResourceSnapshots[0].Operation).To(Equal(historyv1alpha1.OperationUpdated))
+		})
+
+		It("should record one history for one kronoform apply", func() {
+			before, after := configMap("a", "kubectl-edit"), configMap("b", historyv1alpha1.FieldManager)
+			applied, err := history.FromChange(before, after, historyv1alpha1.HistorySourceApplied, "alice")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(history.Create(ctx, k8sClient, applied)).To(Succeed())
+			stamped := configMap("b", "kubectl-client-side-apply")
+			stamped.SetAnnotations(map[string]string{historyv1alpha1.AnnotationHistory: applied.Name})
+
+			observer.OnUpdate(ctx, before, after)
+			observer.OnUpdate(ctx, after, stamped)
+
+			histories := listHistories()
+			Expect(histories).To(HaveLen(1))
+			Expect(histories[0].Spec.Source).To(Equal(historyv1alpha1.HistorySourceApplied))
+		})
+
+		It("should skip updates without content changes", func() {
+			stamped := configMap("a", historyv1alpha1.FieldManager)
+			stamped.SetAnnotations(map[string]string{historyv1alpha1.AnnotationHistory: "kronoform-history-1"})
+			observer.OnUpdate(ctx, configMap("a", "kubectl-edit"), configMap("a", "kubectl-edit"))
+			observer.OnUpdate(ctx, configMap("a", "kubectl-edit"), stamped)
+
+			Expect(listHistories()).To(BeEmpty())
+		})
+
+		It("should record states redacted", func() {
+			redactor, err := redact.New(redact.Config{Rules: []redact.Rule{{Kind: "ConfigMap", Path: ".data.key"}}})
+			Expect(err).NotTo(HaveOccurred())
+			observer.Redactor = redactor
+			observer.OnUpdate(ctx, configMap("hunter2", "kubectl-edit"), configMap("hunter3", "kubectl-edit"))
+
+			histories := listHistories()
+			Expect(histories).To(HaveLen(1))
+			before, after, err := payload.States(histories[0].Status.ResourceSnapshots[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(before).To(ContainSubstring(redactor.Value("hunter2")))
+			Expect(after).To(ContainSubstring(redactor.Value("hunter3")))
+			Expect(after).NotTo(ContainSubstring("hunter3"))
+		})
+
+		It("should record deletions", func() {
+			observer.OnDelete(ctx, configMap("a", "kubectl-edit"))
+
+			histories := listHistories()
+			Expect(histories).To(HaveLen(1))
+			Expect(histories[0].Status.ResourceSnapshots[0].Operation).To(Equal(historyv1alpha1.OperationDeleted))
+		})
+	})
+
+	Context("When parsing observed kinds", func() {
+		It("should accept core and grouped kinds", func() {
+			kinds, err := ParseKinds("v1/ConfigMap, apps/v1/Deployment")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(kinds).To(Equal([]schema.GroupVersionKind{
+				{Version: "v1", Kind: "ConfigMap"},
+				{Group: "apps", Version: "v1", Kind: "Deployment"},
+			}))
+		})
+
+		It("should reject malformed and kronoform kinds", func() {
+			_, err := ParseKinds("ConfigMap")
+			Expect(err).To(HaveOccurred())
+			_, err = ParseKinds("history.yu-kod.github.io/v1alpha1/KronoformHistory")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,443 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package envelope encrypts the manifests and resource states of records. Each entry is
+// encrypted with AES-256-GCM under a data key of its own, which is recorded next to it
+// encrypted with a key of a keyring, so that rotating the keyring only re-encrypts data
+// keys. Keyrings are read from a local key file or a Kubernetes Secret. It is shared by
+// the manager and the kubectl plugin.
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+const (
+	// SecretKey is the key of a Secret holding a keyring file.
+	SecretKey = "keyring.yaml"
+	// KeySize is the size of keyring keys and data keys: AES-256.
+	KeySize = 32
+
+	labelManifests = "manifests"
+	labelBefore    = "before"
+	labelAfter     = "after"
+	labelDigest    = "kronoform redaction digest"
+)
+
+// ErrUnknownKey is returned when content is encrypted with a key that is not in the keyring.
+var ErrUnknownKey = errors.New("key is not in the keyring")
+
+// File is the format of a keyring file:
+//
+//	current: 2025-08
+//	digest: 2025-02
+//	keys:
+//	  2025-02: <base64 of 32 random bytes>
+//	  2025-08: <base64 of 32 random bytes>
+type File struct {
+	// Current is the ID of the key new data keys are encrypted with.
+	Current string `json:"current"`
+	// Keys maps key IDs to base64-encoded 256-bit keys. Keys that were rotated out stay
+	// in the keyring until no data key is encrypted with them any more.
+	Keys map[string]string `json:"keys"`
+	// Digest is the ID of the key the digests of redacted values are keyed with. It
+	// defaults to Current; pin it before rotating so that digests recorded before and
+	// after the rotation can still be compared.
+	Digest string `json:"digest,omitempty"`
+}
+
+// Config selects where a keyring is read from: a local key file or a Secret, given as
+// <namespace>/<name>. At most one may be set.
+type Config struct {
+	// KeyFile is the path of a keyring file.
+	KeyFile string `json:"keyFile,omitempty"`
+	// Secret names the Secret holding the keyring file under SecretKey.
+	Secret string `json:"secret,omitempty"`
+}
+
+// Enabled reports whether a keyring is configured.
+func (c Config) Enabled() bool {
+	return c.KeyFile != "" || c.Secret != ""
+}
+
+// Load reads the keyring selected by cfg, using c to read a Secret. It returns nil
+// if no keyring is configured.
+func Load(ctx context.Context, cfg Config, c client.Reader) (*Keyring, error) {
+	switch {
+	case cfg.KeyFile != "" && cfg.Secret != "":
+		return nil, fmt.Errorf("a keyring is read from a key file or a secret, not both")
+	case cfg.KeyFile != "":
+		return LoadFile(cfg.KeyFile)
+	case cfg.Secret != "":
+		namespace, name, ok := strings.Cut(cfg.Secret, "/")
+		if !ok || namespace == "" || name == "" {
+			return nil, fmt.Errorf("invalid keyring secret %q: expected <namespace>/<name>", cfg.Secret)
+		}
+		return LoadSecret(ctx, c, namespace, name)
+	}
+	return nil, nil
+}
+
+// Keyring holds the keys data keys are encrypted with.
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+	digest  []byte
+}
+
+// Parse reads a keyring file.
+func Parse(data []byte) (*Keyring, error) {
+	file := File{}
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid keyring: %w", err)
+	}
+	if file.Current == "" {
+		return nil, fmt.Errorf("invalid keyring: current is required")
+	}
+	if _, ok := file.Keys[file.Current]; !ok {
+		return nil, fmt.Errorf("invalid keyring: current key %q is not in keys", file.Current)
+	}
+	if file.Digest == "" {
+		file.Digest = file.Current
+	}
+	if _, ok := file.Keys[file.Digest]; !ok {
+		return nil, fmt.Errorf("invalid keyring: digest key %q is not in keys", file.Digest)
+	}
+	k := &Keyring{current: file.Current, keys: map[string]cipher.AEAD{}}
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != KeySize {
+			return nil, fmt.Errorf("invalid keyring: key %q must be %d base64-encoded bytes", id, KeySize)
+		}
+		if k.keys[id], err = newAEAD(key); err != nil {
+			return nil, err
+		}
+		if id == file.Digest {
+			// The key is not used as is, so that the digests reveal nothing about it
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(labelDigest))
+			k.digest = mac.Sum(nil)
+		}
+	}
+	return k, nil
+}
+
+// LoadFile reads a keyring from a local file.
+func LoadFile(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - the key file is chosen by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring %s: %w", path, err)
+	}
+	return Parse(data)
+}
+
+// LoadSecret reads a keyring from the SecretKey entry of a Secret.
+func LoadSecret(ctx context.Context, c client.Reader, namespace, name string) (*Keyring, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to read keyring secret %s/%s: %w", namespace, name, err)
+	}
+	data, ok := secret.Data[SecretKey]
+	if !ok {
+		return nil, fmt.Errorf("keyring secret %s/%s has no %s", namespace, name, SecretKey)
+	}
+	return Parse(data)
+}
+
+// Current returns the ID of the key new data keys are encrypted with.
+func (k *Keyring) Current() string {
+	return k.current
+}
+
+// DigestKey returns the key redacted values are digested with, derived from the digest
+// key of the keyring. Every holder of the keyring derives the same key, so digests
+// recorded by the plugin and the manager of a cluster can be compared.
+func (k *Keyring) DigestKey() []byte {
+	return k.digest
+}
+
+// KeyIDs returns the IDs of the keys of the keyring.
+func (k *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SealManifests encrypts manifests under a new data key.
+func (k *Keyring) SealManifests(content string) (string, *historyv1alpha1.DataKey, error) {
+	sealed, key, err := k.seal([]string{labelManifests}, []string{content})
+	if err != nil {
+		return "", nil, err
+	}
+	return sealed[0], key, nil
+}
+
+// SealStates stores the before and after states of a resource snapshot encrypted under
+// a new data key.
+func (k *Keyring) SealStates(rs *historyv1alpha1.ResourceSnapshot, before, after string) error {
+	sealed, key, err := k.seal([]string{labelBefore, labelAfter}, []string{before, after})
+	if err != nil {
+		return err
+	}
+	rs.Before, rs.After, rs.Encoding, rs.Key = sealed[0], sealed[1], historyv1alpha1.EncodingEncrypted, key
+	return nil
+}
+
+// SealSnapshot encrypts the manifests of a snapshot that are stored inline and not
+// encrypted yet. It does nothing if k is nil, so that records are stored unencrypted
+// when no keyring is configured.
+func (k *Keyring) SealSnapshot(s *historyv1alpha1.KronoformSnapshot) error {
+	if k == nil {
+		return nil
+	}
+	return k.sealInline(&s.Spec.Manifests, &s.Spec.ManifestsEncoding, &s.Spec.ManifestsKey, s.Spec.ManifestsChunks)
+}
+
+// SealHistory encrypts the manifests of a history that are stored inline and its
+// resource states, where they are not encrypted yet. It does nothing if k is nil.
+func (k *Keyring) SealHistory(h *historyv1alpha1.KronoformHistory) error {
+	if k == nil {
+		return nil
+	}
+	if err := k.sealInline(&h.Spec.Manifests, &h.Spec.ManifestsEncoding, &h.Spec.ManifestsKey, h.Spec.ManifestsChunks); err != nil {
+		return err
+	}
+	return k.SealStatus(&h.Status)
+}
+
+// SealStatus encrypts the resource states of a history status that are not encrypted
+// yet. Resource snapshots without states are left as they are. It does nothing if k
+// is nil.
+func (k *Keyring) SealStatus(status *historyv1alpha1.KronoformHistoryStatus) error {
+	if k == nil {
+		return nil
+	}
+	for i := range status.ResourceSnapshots {
+		rs := &status.ResourceSnapshots[i]
+		if rs.Encoding == historyv1alpha1.EncodingEncrypted || (rs.Before == "" && rs.After == "") {
+			continue
+		}
+		before, after, err := payload.States(*rs)
+		if err != nil {
+			return err
+		}
+		if err := k.SealStates(rs, before, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Manifests returns the content of manifests stored inline with the given encoding,
+// decrypting them if needed. A nil keyring decodes manifests that are not encrypted.
+func (k *Keyring) Manifests(data, encoding string, key *historyv1alpha1.DataKey) (string, error) {
+	if encoding != historyv1alpha1.EncodingEncrypted {
+		return payload.Decode(data, encoding)
+	}
+	opened, err := k.open(key, []string{labelManifests}, []string{data})
+	if err != nil {
+		return "", err
+	}
+	return opened[0], nil
+}
+
+// States returns the decoded before and after states of a resource snapshot, decrypting
+// them if needed. A nil keyring decodes states that are not encrypted.
+func (k *Keyring) States(rs historyv1alpha1.ResourceSnapshot) (before, after string, err error) {
+	if rs.Encoding != historyv1alpha1.EncodingEncrypted {
+		return payload.States(rs)
+	}
+	opened, err := k.open(rs.Key, []string{labelBefore, labelAfter}, []string{rs.Before, rs.After})
+	if err != nil {
+		return "", "", err
+	}
+	return opened[0], opened[1], nil
+}
+
+// Rewrap re-encrypts a data key with the current key. It returns nil if the data key is
+// already encrypted with the current key.
+func (k *Keyring) Rewrap(key *historyv1alpha1.DataKey) (*historyv1alpha1.DataKey, error) {
+	if key == nil || key.KeyID == k.current {
+		return nil, nil
+	}
+	dataKey, err := k.unwrap(key)
+	if err != nil {
+		return nil, err
+	}
+	return k.wrap(dataKey)
+}
+
+// sealInline encrypts manifests stored inline with the given encoding. Empty manifests,
+// manifests stored in chunks and encrypted manifests are left as they are.
+func (k *Keyring) sealInline(data, encoding *string, key **historyv1alpha1.DataKey, chunks int32) error {
+	if *data == "" || chunks > 0 || *encoding == historyv1alpha1.EncodingEncrypted {
+		return nil
+	}
+	content, err := payload.Decode(*data, *encoding)
+	if err != nil {
+		return err
+	}
+	sealed, dataKey, err := k.SealManifests(content)
+	if err != nil {
+		return err
+	}
+	*data, *encoding, *key = sealed, historyv1alpha1.EncodingEncrypted, dataKey
+	return nil
+}
+
+// seal encrypts contents under a new data key, binding each to its label so that
+// entries cannot be swapped. Empty contents stay empty so that a missing state can
+// still be told apart.
+func (k *Keyring) seal(labels, contents []string) ([]string, *historyv1alpha1.DataKey, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	sealed := make([]string, len(contents))
+	for i, content := range contents {
+		if content == "" {
+			continue
+		}
+		compressed, err := payload.Compress(content)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sealed[i], err = sealWith(aead, compressed, labels[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	key, err := k.wrap(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sealed, key, nil
+}
+
+// open decrypts contents sealed under a data key.
+func (k *Keyring) open(key *historyv1alpha1.DataKey, labels, data []string) ([]string, error) {
+	if key == nil {
+		return nil, fmt.Errorf("encrypted content has no data key")
+	}
+	if k == nil {
+		return nil, fmt.Errorf("content is encrypted with key %s and no keyring is configured", key.KeyID)
+	}
+	dataKey, err := k.unwrap(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	opened := make([]string, len(data))
+	for i, sealed := range data {
+		if sealed == "" {
+			continue
+		}
+		compressed, err := openWith(aead, sealed, labels[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s: %w", labels[i], err)
+		}
+		if opened[i], err = payload.Decompress(compressed); err != nil {
+			return nil, err
+		}
+	}
+	return opened, nil
+}
+
+// wrap encrypts a data key with the current key.
+func (k *Keyring) wrap(dataKey []byte) (*historyv1alpha1.DataKey, error) {
+	sealed, err := sealWith(k.keys[k.current], dataKey, keyLabel(k.current))
+	if err != nil {
+		return nil, err
+	}
+	return &historyv1alpha1.DataKey{KeyID: k.current, Key: sealed}, nil
+}
+
+// unwrap decrypts a data key.
+func (k *Keyring) unwrap(key *historyv1alpha1.DataKey) ([]byte, error) {
+	aead, ok := k.keys[key.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("content is encrypted with key %s: %w", key.KeyID, ErrUnknownKey)
+	}
+	dataKey, err := openWith(aead, key.Key, keyLabel(key.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key with key %s: %w", key.KeyID, err)
+	}
+	if len(dataKey) != KeySize {
+		return nil, fmt.Errorf("invalid data key encrypted with key %s", key.KeyID)
+	}
+	return dataKey, nil
+}
+
+// keyLabel binds a data key to the ID of the key it is encrypted with.
+func keyLabel(id string) string {
+	return "data-key:" + id
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealWith encrypts plaintext and returns the base64 encoding of the nonce followed by
+// the ciphertext.
+func sealWith(aead cipher.AEAD, plaintext []byte, label string) (string, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, []byte(label))), nil
+}
+
+func openWith(aead cipher.AEAD, sealed, label string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 content: %w", err)
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted content is truncated")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(label))
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package envelope
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+// keyringFile returns a keyring file with keys filled with the given bytes.
+func keyringFile(current string, keys map[string]byte) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "current: %s\nkeys:\n", current)
+	for id, fill := range keys {
+		fmt.Fprintf(&b, "  %s: %s\n", id, base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(fill), KeySize))))
+	}
+	return []byte(b.String())
+}
+
+func TestSealAndOpen(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	keyring, err := Parse(keyringFile("k1", map[string]byte{"k1": 'a'}))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	manifests := "apiVersion: v1\nkind: ConfigMap\ndata:\n  password: hunter2\n"
+	h := &historyv1alpha1.KronoformHistory{
+		Spec: historyv1alpha1.KronoformHistorySpec{Manifests: manifests},
+		Status: historyv1alpha1.KronoformHistoryStatus{
+			ResourceSnapshots: []historyv1alpha1.ResourceSnapshot{
+				{Kind: "ConfigMap", Name: "settings", After: manifests},
+				{Kind: "ConfigMap", Name: "unchanged"},
+			},
+		},
+	}
+	g.Expect(keyring.SealHistory(h)).To(gomega.Succeed())
+	g.Expect(h.Spec.ManifestsEncoding).To(gomega.Equal(historyv1alpha1.EncodingEncrypted))
+	g.Expect(h.Spec.ManifestsKey.KeyID).To(gomega.Equal("k1"))
+	g.Expect(h.Spec.Manifests).NotTo(gomega.ContainSubstring("hunter2"))
+
+	rs := h.Status.ResourceSnapshots[0]
+	g.Expect(rs.Encoding).To(gomega.Equal(historyv1alpha1.EncodingEncrypted))
+	g.Expect(rs.Before).To(gomega.BeEmpty())
+	g.Expect(h.Status.ResourceSnapshots[1].Encoding).To(gomega.BeEmpty())
+
+	g.Expect(keyring.Manifests(h.Spec.Manifests, h.Spec.ManifestsEncoding, h.Spec.ManifestsKey)).To(gomega.Equal(manifests))
+	before, after, err := keyring.States(rs)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(before).To(gomega.BeEmpty())
+	g.Expect(after).To(gomega.Equal(manifests))
+
+	// Sealing again leaves encrypted content alone
+	sealed := h.DeepCopy()
+	g.Expect(keyring.SealHistory(sealed)).To(gomega.Succeed())
+	g.Expect(sealed).To(gomega.Equal(h))
+
+	// Content cannot be read without the keyring, or moved to another entry
+	var none *Keyring
+	_, err = none.Manifests(h.Spec.Manifests, h.Spec.ManifestsEncoding, h.Spec.ManifestsKey)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("no keyring is configured")))
+	_, err = keyring.Manifests(rs.After, historyv1alpha1.EncodingEncrypted, rs.Key)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("failed to decrypt manifests")))
+
+	// A nil keyring leaves content unencrypted
+	plain := &historyv1alpha1.KronoformSnapshot{Spec: historyv1alpha1.KronoformSnapshotSpec{Manifests: manifests}}
+	g.Expect(none.SealSnapshot(plain)).To(gomega.Succeed())
+	g.Expect(plain.Spec.ManifestsEncoding).To(gomega.BeEmpty())
+	g.Expect(none.Manifests(plain.Spec.Manifests, plain.Spec.ManifestsEncoding, nil)).To(gomega.Equal(manifests))
+}
+
+func TestRewrap(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	old, err := Parse(keyringFile("k1", map[string]byte{"k1": 'a'}))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	sealed, key, err := old.SealManifests("kind: ConfigMap\n")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	rotated, err := Parse(keyringFile("k2", map[string]byte{"k1": 'a', "k2": 'b'}))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(rotated.KeyIDs()).To(gomega.Equal([]string{"k1", "k2"}))
+
+	// Content encrypted with a rotated-out key can still be read
+	g.Expect(rotated.Manifests(sealed, historyv1alpha1.EncodingEncrypted, key)).To(gomega.Equal("kind: ConfigMap\n"))
+
+	rewrapped, err := rotated.Rewrap(key)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(rewrapped.KeyID).To(gomega.Equal("k2"))
+	g.Expect(rotated.Rewrap(rewrapped)).To(gomega.BeNil())
+
+	// Once rewrapped, the old key can be removed
+	current, err := Parse(keyringFile("k2", map[string]byte{"k2": 'b'}))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(current.Manifests(sealed, historyv1alpha1.EncodingEncrypted, rewrapped)).To(gomega.Equal("kind: ConfigMap\n"))
+	_, err = current.Manifests(sealed, historyv1alpha1.EncodingEncrypted, key)
+	g.Expect(err).To(gomega.MatchError(ErrUnknownKey))
+
+	// A data key cannot be passed off as encrypted with another key
+	forged := &historyv1alpha1.DataKey{KeyID: "k2", Key: key.Key}
+	_, err = rotated.Manifests(sealed, historyv1alpha1.EncodingEncrypted, forged)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("failed to decrypt data key")))
+}
+
+func TestParse(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	_, err := Parse([]byte("keys: {}\n"))
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("current is required")))
+	_, err = Parse(keyringFile("k2", map[string]byte{"k1": 'a'}))
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`current key "k2" is not in keys`)))
+	_, err = Parse([]byte("current: k1\nkeys:\n  k1: c2hvcnQ=\n"))
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("must be 32 base64-encoded bytes")))
+	_, err = Parse([]byte("current: k1\nkeys: {}\nextra: true\n"))
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("invalid keyring")))
+	_, err = Parse(append(keyringFile("k1", map[string]byte{"k1": 'a'}), "digest: k0\n"...))
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`digest key "k0" is not in keys`)))
+}
+
+func TestDigestKey(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	old, err := Parse(keyringFile("k1", map[string]byte{"k1": 'a'}))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(old.DigestKey()).To(gomega.HaveLen(32))
+	g.Expect(old.DigestKey()).NotTo(gomega.Equal([]byte(strings.Repeat("a", KeySize))))
+
+	// Rotating changes the digest key unless it is pinned
+	rotated, err := Parse(keyringFile("k2", map[string]byte{"k1": 'a', "k2": 'b'}))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(rotated.DigestKey()).NotTo(gomega.Equal(old.DigestKey()))
+	pinned, err := Parse(append(keyringFile("k2", map[string]byte{"k1": 'a', "k2": 'b'}), "digest: k1\n"...))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(pinned.DigestKey()).To(gomega.Equal(old.DigestKey()))
+}
+
+func TestLoad(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	keyring, err := Load(ctx, Config{}, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(keyring).To(gomega.BeNil())
+
+	path := filepath.Join(t.TempDir(), "keyring.yaml")
+	g.Expect(os.WriteFile(path, keyringFile("k1", map[string]byte{"k1": 'a'}), 0o600)).To(gomega.Succeed())
+	keyring, err = Load(ctx, Config{KeyFile: path}, nil)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(keyring.Current()).To(gomega.Equal("k1"))
+
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).To(gomega.Succeed())
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "keyring", Namespace: "kronoform-system"},
+		Data:       map[string][]byte{SecretKey: keyringFile("k2", map[string]byte{"k2": 'b'})},
+	}).Build()
+	keyring, err = Load(ctx, Config{Secret: "kronoform-system/keyring"}, reader)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(keyring.Current()).To(gomega.Equal("k2"))
+
+	_, err = Load(ctx, Config{Secret: "keyring"}, reader)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("expected <namespace>/<name>")))
+	_, err = Load(ctx, Config{Secret: "kronoform-system/missing"}, reader)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("failed to read keyring secret")))
+	_, err = Load(ctx, Config{KeyFile: path, Secret: "kronoform-system/keyring"}, reader)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("not both")))
+}
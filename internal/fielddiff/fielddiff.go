@@ -0,0 +1,434 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fielddiff computes field-level differences between Kubernetes objects.
+package fielddiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+// Change describes a single field that differs between two objects.
+// Before or After is nil when the field is absent on that side.
+type Change struct {
+	Path   string
+	Before interface{}
+	After  interface{}
+}
+
+// ignoredAnnotations are not part of the state of an object: kubectl apply writes the
+// last applied configuration, which duplicates the object itself, and the annotations
+// naming the history of a resource are set after its changes are recorded.
+var ignoredAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	historyv1alpha1.AnnotationHistory,
+	historyv1alpha1.AnnotationHistoryNamespace,
+	historyv1alpha1.AnnotationHistoryID,
+	historyv1alpha1.AnnotationAppliedBy,
+}
+
+// serverFields are metadata fields populated by the API server rather than by users.
+var serverFields = []string{
+	"uid",
+	"resourceVersion",
+	"generation",
+	"creationTimestamp",
+	"deletionTimestamp",
+	"deletionGracePeriodSeconds",
+	"managedFields",
+	"selfLink",
+}
+
+// Parse decodes a YAML or JSON document into a normalized object.
+func Parse(content string) (map[string]interface{}, error) {
+	obj := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(content), &obj); err != nil {
+		return nil, err
+	}
+	return Normalize(obj)
+}
+
+// Normalize returns a copy of obj without status, server-populated metadata and the
+// annotations that are not part of its state,
+// with all numbers represented as float64 so that objects decoded from YAML and
+// objects read from the API server compare equal.
+func Normalize(obj map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	normalized := map[string]interface{}{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+
+	delete(normalized, "status")
+	if metadata, ok := normalized["metadata"].(map[string]interface{}); ok {
+		for _, field := range serverFields {
+			delete(metadata, field)
+		}
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			for _, annotation := range ignoredAnnotations {
+				delete(annotations, annotation)
+			}
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+	}
+	return normalized, nil
+}
+
+// Diff returns the leaf fields that differ between before and after, ordered by path.
+func Diff(before, after map[string]interface{}) []Change {
+	var changes []Change
+	diffValue("", before, after, &changes)
+	return changes
+}
+
+func diffValue(path string, before, after interface{}, changes *[]Change) {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		for _, key := range unionKeys(beforeMap, afterMap) {
+			diffValue(path+formatKey(key), beforeMap[key], afterMap[key], changes)
+		}
+		return
+	}
+
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if beforeIsList && afterIsList {
+		for i := 0; i < len(beforeList) || i < len(afterList); i++ {
+			var b, a interface{}
+			if i < len(beforeList) {
+				b = beforeList[i]
+			}
+			if i < len(afterList) {
+				a = afterList[i]
+			}
+			diffValue(fmt.Sprintf("%s[%d]", path, i), b, a, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		if path == "" {
+			path = "."
+		}
+		*changes = append(*changes, Change{Path: path, Before: before, After: after})
+	}
+}
+
+// Field is a leaf field of an object.
+type Field struct {
+	Path  string
+	Value interface{}
+}
+
+// Fields returns the leaf fields of obj ordered by path, using the paths of Diff. Empty
+// maps and lists are leaves, except for an empty obj which has no fields.
+func Fields(obj map[string]interface{}) []Field {
+	var fields []Field
+	if len(obj) == 0 {
+		return nil
+	}
+	collectFields("", obj, &fields)
+	return fields
+}
+
+func collectFields(path string, value interface{}, fields *[]Field) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) > 0 {
+			for _, key := range unionKeys(v, nil) {
+				collectFields(path+formatKey(key), v[key], fields)
+			}
+			return
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			for i, item := range v {
+				collectFields(fmt.Sprintf("%s[%d]", path, i), item, fields)
+			}
+			return
+		}
+	}
+	if path == "" {
+		path = "."
+	}
+	*fields = append(*fields, Field{Path: path, Value: value})
+}
+
+// Lookup returns the value at path in obj, or false when it is absent. The path is
+// written as the paths of Diff, e.g. .spec.containers[0].image or
+// .metadata.labels["app.kubernetes.io/name"], optionally within braces as for kubectl
+// -o jsonpath; "." is obj itself.
+func Lookup(obj map[string]interface{}, path string) (interface{}, bool, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, false, err
+	}
+	var value interface{} = obj
+	for _, segment := range segments {
+		switch s := segment.(type) {
+		case string:
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, false, nil
+			}
+			if value, ok = m[s]; !ok {
+				return nil, false, nil
+			}
+		case int:
+			list, ok := value.([]interface{})
+			if !ok || s >= len(list) {
+				return nil, false, nil
+			}
+			value = list[s]
+		}
+	}
+	return value, true, nil
+}
+
+// Set sets the value at path in obj, written as for Lookup. The map or list holding the
+// field must exist; list items must exist too, or be appended right after the last one.
+func Set(obj map[string]interface{}, path string, value interface{}) error {
+	parent, last, err := fieldParent(obj, path)
+	if err != nil {
+		return err
+	}
+	switch s := last.(type) {
+	case string:
+		if m, ok := parent.(map[string]interface{}); ok {
+			m[s] = value
+			return nil
+		}
+	case int:
+		list, ok := parent.([]interface{})
+		if ok && s < len(list) {
+			list[s] = value
+			return nil
+		}
+		if ok && s == len(list) {
+			replaceList(obj, path, append(list, value))
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot set %q: its parent does not exist", path)
+}
+
+// Delete removes the field at path from obj, written as for Lookup. List items are
+// removed from their list. Deleting an absent field does nothing.
+func Delete(obj map[string]interface{}, path string) error {
+	parent, last, err := fieldParent(obj, path)
+	if err != nil {
+		return err
+	}
+	switch s := last.(type) {
+	case string:
+		if m, ok := parent.(map[string]interface{}); ok {
+			delete(m, s)
+		}
+	case int:
+		list, ok := parent.([]interface{})
+		if !ok || s >= len(list) {
+			return nil
+		}
+		replaceList(obj, path, append(list[:s:s], list[s+1:]...))
+	}
+	return nil
+}
+
+// replaceList replaces the list holding the item at path with list, as lists change
+// length by being replaced in their own parent.
+func replaceList(obj map[string]interface{}, path string, list []interface{}) {
+	parent, key, _ := fieldParent(obj, path[:strings.LastIndex(path, "[")])
+	switch k := key.(type) {
+	case string:
+		if m, ok := parent.(map[string]interface{}); ok {
+			m[k] = list
+		}
+	case int:
+		if l, ok := parent.([]interface{}); ok && k < len(l) {
+			l[k] = list
+		}
+	}
+}
+
+// fieldParent returns the map or list holding the field at path, nil if it does not
+// exist, and the key or index of the field in it.
+func fieldParent(obj map[string]interface{}, path string) (interface{}, interface{}, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(segments) == 0 {
+		return nil, nil, fmt.Errorf("invalid field %q: it is the object itself", path)
+	}
+	var parent interface{} = obj
+	for _, segment := range segments[:len(segments)-1] {
+		switch s := segment.(type) {
+		case string:
+			m, _ := parent.(map[string]interface{})
+			parent = m[s]
+		case int:
+			list, _ := parent.([]interface{})
+			parent = nil
+			if s < len(list) {
+				parent = list[s]
+			}
+		}
+	}
+	return parent, segments[len(segments)-1], nil
+}
+
+// parsePath splits a path into map keys and list indexes.
+func parsePath(path string) ([]interface{}, error) {
+	rest := strings.TrimSpace(path)
+	if strings.HasPrefix(rest, "{") && strings.HasSuffix(rest, "}") {
+		rest = strings.TrimSpace(rest[1 : len(rest)-1])
+	}
+	if rest == "." {
+		return nil, nil
+	}
+	if !strings.HasPrefix(rest, ".") && !strings.HasPrefix(rest, "[") {
+		return nil, fmt.Errorf("invalid path %q: expected e.g. .spec.replicas", path)
+	}
+	var segments []interface{}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			if end == 1 {
+				return nil, fmt.Errorf("invalid path %q: empty field name", path)
+			}
+			segments = append(segments, rest[1:end])
+			rest = rest[end:]
+		case '[':
+			if strings.HasPrefix(rest, `["`) {
+				quoted, err := strconv.QuotedPrefix(rest[1:])
+				if err != nil || !strings.HasPrefix(rest[1+len(quoted):], "]") {
+					return nil, fmt.Errorf("invalid path %q: unterminated key", path)
+				}
+				key, _ := strconv.Unquote(quoted)
+				segments = append(segments, key)
+				rest = rest[len(quoted)+2:]
+				continue
+			}
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unterminated index", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid path %q: expected a list index or a quoted key within brackets", path)
+			}
+			segments = append(segments, index)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", path, rest[0])
+		}
+	}
+	return segments, nil
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var simpleKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// formatKey renders a map key as a path segment, quoting keys such as
+// annotation names that contain dots or slashes.
+func formatKey(key string) string {
+	if simpleKey.MatchString(key) {
+		return "." + key
+	}
+	return fmt.Sprintf("[%q]", key)
+}
+
+// FormatValue renders a field value compactly for display.
+// Absent fields are shown as "<none>".
+func FormatValue(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// ManagedPaths returns the paths, written as those of Diff, of the leaf fields set in a
+// managedFields entry in the FieldsV1 format. List items are keyed by value rather than
+// by index there, so lists are returned as a whole when one of their items is managed.
+func ManagedPaths(fieldsV1 []byte) ([]string, error) {
+	set := map[string]interface{}{}
+	if err := json.Unmarshal(fieldsV1, &set); err != nil {
+		return nil, err
+	}
+	var paths []string
+	collectManaged("", set, &paths)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func collectManaged(path string, set map[string]interface{}, paths *[]string) {
+	if path != "" && len(set) == 0 {
+		*paths = append(*paths, path)
+		return
+	}
+	for key, value := range set {
+		child, _ := value.(map[string]interface{})
+		if name, ok := strings.CutPrefix(key, "f:"); ok {
+			collectManaged(path+formatKey(name), child, paths)
+			continue
+		}
+		// k:, v: and i: entries are list items, "." the field holding the others
+		if key != "." && path != "" {
+			*paths = append(*paths, path)
+			return
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package fielddiff
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+func TestDiff(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	before, err := Parse(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  annotations:
+    app.kubernetes.io/version: "1"
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.21
+`)
+	g.Expect(err).To(gomega.BeNil())
+
+	after, err := Parse(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  annotations:
+    app.kubernetes.io/version: "2"
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.21
+        - name: sidecar
+          image: busybox
+`)
+	g.Expect(err).To(gomega.BeNil())
+
+	changes := Diff(before, after)
+	g.Expect(changes).To(gomega.Equal([]Change{
+		{Path: `.metadata.annotations["app.kubernetes.io/version"]`, Before: "1", After: "2"},
+		{Path: ".spec.replicas", Before: float64(2), After: float64(1)},
+		{Path: ".spec.template.spec.containers[1]", Before: nil, After: map[string]interface{}{
+			"name": "sidecar", "image": "busybox",
+		}},
+	}))
+}
+
+func TestFields(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	obj, err := Parse(`
+kind: ConfigMap
+metadata:
+  name: config
+  labels:
+    app.kubernetes.io/name: web
+data: {}
+spec:
+  ports: [80, 443]
+`)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(Fields(obj)).To(gomega.Equal([]Field{
+		{Path: ".data", Value: map[string]interface{}{}},
+		{Path: ".kind", Value: "ConfigMap"},
+		{Path: `.metadata.labels["app.kubernetes.io/name"]`, Value: "web"},
+		{Path: ".metadata.name", Value: "config"},
+		{Path: ".spec.ports[0]", Value: float64(80)},
+		{Path: ".spec.ports[1]", Value: float64(443)},
+	}))
+}
+
+func TestLookup(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	obj, err := Parse(`
+metadata:
+  labels:
+    app.kubernetes.io/name: web
+spec:
+  containers:
+    - name: web
+      image: nginx:1.21
+`)
+	g.Expect(err).To(gomega.BeNil())
+
+	for path, expected := range map[string]interface{}{
+		".spec.containers[0].image":                  "nginx:1.21",
+		"{.spec.containers[0].name}":                 "web",
+		`.metadata.labels["app.kubernetes.io/name"]`: "web",
+		".": obj,
+	} {
+		value, ok, err := Lookup(obj, path)
+		g.Expect(err).To(gomega.BeNil(), path)
+		g.Expect(ok).To(gomega.BeTrue(), path)
+		g.Expect(value).To(gomega.Equal(expected), path)
+	}
+
+	for _, path := range []string{".spec.containers[1].image", ".spec.replicas", ".metadata.labels.missing", ".spec.containers.name"} {
+		_, ok, err := Lookup(obj, path)
+		g.Expect(err).To(gomega.BeNil(), path)
+		g.Expect(ok).To(gomega.BeFalse(), path)
+	}
+
+	for _, path := range []string{"spec", ".spec..replicas", ".spec.containers[x]", `.metadata.labels["name]`, ".spec.containers[0"} {
+		_, _, err := Lookup(obj, path)
+		g.Expect(err).NotTo(gomega.BeNil(), path)
+	}
+}
+
+func TestSet(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	obj, err := Parse(`
+data:
+  password: secret
+spec:
+  containers:
+    - name: web
+`)
+	g.Expect(err).To(gomega.BeNil())
+
+	g.Expect(Set(obj, ".data.password", "changed")).To(gomega.Succeed())
+	g.Expect(Set(obj, ".spec.containers[0].image", "nginx")).To(gomega.Succeed())
+	g.Expect(obj).To(gomega.Equal(map[string]interface{}{
+		"data": map[string]interface{}{"password": "changed"},
+		"spec": map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"name": "web", "image": "nginx"},
+		}},
+	}))
+
+	g.Expect(Set(obj, ".spec.containers[1]", map[string]interface{}{"name": "proxy"})).To(gomega.Succeed())
+	g.Expect(obj["spec"]).To(gomega.Equal(map[string]interface{}{"containers": []interface{}{
+		map[string]interface{}{"name": "web", "image": "nginx"},
+		map[string]interface{}{"name": "proxy"},
+	}}))
+
+	g.Expect(Set(obj, ".spec.containers[3]", "nginx")).NotTo(gomega.Succeed())
+	g.Expect(Set(obj, ".spec.containers[2].image", "nginx")).NotTo(gomega.Succeed())
+	g.Expect(Set(obj, ".metadata.labels.app", "web")).NotTo(gomega.Succeed())
+	g.Expect(Set(obj, ".", "web")).NotTo(gomega.Succeed())
+}
+
+func TestDelete(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	obj, err := Parse(`
+data:
+  password: secret
+  user: admin
+spec:
+  containers:
+    - name: web
+    - name: sidecar
+    - name: proxy
+`)
+	g.Expect(err).To(gomega.BeNil())
+
+	g.Expect(Delete(obj, ".data.password")).To(gomega.Succeed())
+	g.Expect(Delete(obj, ".spec.containers[1]")).To(gomega.Succeed())
+	g.Expect(Delete(obj, ".metadata.labels.app")).To(gomega.Succeed())
+	g.Expect(Delete(obj, ".spec.containers[5]")).To(gomega.Succeed())
+	g.Expect(obj).To(gomega.Equal(map[string]interface{}{
+		"data": map[string]interface{}{"user": "admin"},
+		"spec": map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"name": "web"},
+			map[string]interface{}{"name": "proxy"},
+		}},
+	}))
+	g.Expect(Delete(obj, ".")).NotTo(gomega.Succeed())
+}
+
+func TestManagedPaths(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	paths, err := ManagedPaths([]byte(`{
+		"f:metadata": {"f:annotations": {".": {}, "f:deployment.kubernetes.io/revision": {}}},
+		"f:spec": {"f:replicas": {}, "f:template": {"f:spec": {"f:containers": {"k:{\"name\":\"web\"}": {".": {}, "f:image": {}}}}}}
+	}`))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(paths).To(gomega.Equal([]string{
+		`.metadata.annotations["deployment.kubernetes.io/revision"]`,
+		`.spec.replicas`,
+		`.spec.template.spec.containers`,
+	}))
+}
+
+func TestNormalize(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	live := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":            "test",
+			"resourceVersion": "123",
+			"uid":             "abc",
+			"managedFields":   []interface{}{map[string]interface{}{"manager": "kubectl"}},
+			"annotations": map[string]interface{}{
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+				historyv1alpha1.AnnotationHistory:                  "kronoform-history-abc",
+				historyv1alpha1.AnnotationHistoryID:                "0123456789ab",
+			},
+		},
+		"data":   map[string]interface{}{"key": "value"},
+		"status": map[string]interface{}{"phase": "Active"},
+	}
+	recorded, err := Parse("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\ndata:\n  key: value\n")
+	g.Expect(err).To(gomega.BeNil())
+
+	normalized, err := Normalize(live)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(Diff(recorded, normalized)).To(gomega.BeEmpty())
+}
+
+func TestFormatValue(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	g.Expect(FormatValue(nil)).To(gomega.Equal("<none>"))
+	g.Expect(FormatValue("nginx")).To(gomega.Equal("nginx"))
+	g.Expect(FormatValue(float64(3))).To(gomega.Equal("3"))
+	g.Expect(FormatValue(map[string]interface{}{"a": "b"})).To(gomega.Equal(`{"a":"b"}`))
+}
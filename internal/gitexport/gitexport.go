@@ -0,0 +1,339 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitexport writes the change timeline to a git repository, one commit per
+// history, so that cluster state can be browsed with git log, git diff and git blame.
+// It runs the git binary, which must be installed. It is shared by the manager and the
+// kubectl plugin.
+package gitexport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+)
+
+const (
+	// HistoryTrailer is the commit trailer naming the history a commit was exported
+	// from, as <namespace>/<name>. It makes exports incremental.
+	HistoryTrailer = "Kronoform-History"
+
+	// TicketTrailer is the commit trailer naming the ticket a change belongs to.
+	TicketTrailer = "Kronoform-Ticket"
+
+	// clusterScope is the directory of resources without a namespace.
+	clusterScope = "_"
+	// committerName and committerEmail identify the exporter as committer, so that
+	// exporting the same histories always produces the same commits.
+	committerName  = "kronoform"
+	committerEmail = "kronoform@kronoform.invalid"
+	// authorDomain completes the email of users whose name is not an address.
+	authorDomain = "kronoform.invalid"
+)
+
+// Exporter commits histories to a local repository. Each resource is kept in its own
+// file, <namespace>/<kind>[.<group>]/<name>.yaml, holding its After state; resources of
+// cluster scope are kept under "_". Each commit is authored by AppliedBy at AppliedAt
+// and carries the description of the history as its message.
+type Exporter struct {
+	// Repo is the path of the repository. It is initialized if it does not exist.
+	Repo string
+	// Git is the git binary. It defaults to "git" on the PATH.
+	Git string
+}
+
+// Export commits the histories that are not in the repository yet, oldest first, and
+// returns how many were committed. Histories already exported are recognized by their
+// HistoryTrailer, so Export can be run repeatedly over the full list of histories.
+// The export stops at the first history whose content cannot be read, such as an
+// encrypted one without its key, so that commits stay in the order histories were
+// applied: committing it later on top of newer histories would put its older states back.
+// It and the histories after it are tried again on the next run.
+func (e *Exporter) Export(ctx context.Context, histories []historyv1alpha1.KronoformHistory) (int, error) {
+	if err := e.init(ctx); err != nil {
+		return 0, err
+	}
+	exported, err := e.exported(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := make([]historyv1alpha1.KronoformHistory, 0, len(histories))
+	for _, h := range histories {
+		if !exported[trailerValue(&h)] {
+			pending = append(pending, h)
+		}
+	}
+	history.Sort(pending)
+
+	for i := range pending {
+		h := &pending[i]
+		files, err := resourceFiles(h)
+		if err != nil {
+			return i, fmt.Errorf("stopped at history %s/%s, %d histories left: %w", h.Namespace, h.Name, len(pending)-i, err)
+		}
+		if err := e.commit(ctx, h, files); err != nil {
+			return i, fmt.Errorf("failed to export history %s/%s: %w", h.Namespace, h.Name, err)
+		}
+	}
+	return len(pending), nil
+}
+
+// init creates the repository if needed and checks that it has no uncommitted changes,
+// which would otherwise be committed along with the next history.
+func (e *Exporter) init(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(e.Repo, ".git")); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(e.Repo, 0o755); err != nil {
+			return err
+		}
+		if _, err := e.git(ctx, nil, nil, "init", "--quiet"); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	status, err := e.git(ctx, nil, nil, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(status) != "" {
+		return fmt.Errorf("repository %s has uncommitted changes", e.Repo)
+	}
+	return nil
+}
+
+// exported returns the histories recorded in the trailers of the current branch.
+func (e *Exporter) exported(ctx context.Context) (map[string]bool, error) {
+	exported := map[string]bool{}
+	if _, err := e.git(ctx, nil, nil, "rev-parse", "--quiet", "--verify", "HEAD"); err != nil {
+		// A new repository has no commits yet
+		return exported, nil
+	}
+	out, err := e.git(ctx, nil, nil, "log", "--format=%(trailers:key="+HistoryTrailer+",valueonly)")
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			exported[line] = true
+		}
+	}
+	return exported, nil
+}
+
+// commit writes the resource files of a history and commits them. A history that
+// leaves every file unchanged still gets a commit, so that the timeline is complete.
+func (e *Exporter) commit(ctx context.Context, h *historyv1alpha1.KronoformHistory, files []file) error {
+	for _, f := range files {
+		target := filepath.Join(e.Repo, filepath.FromSlash(f.path))
+		if f.content == "" {
+			if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, []byte(f.content), 0o644); err != nil {
+			return err
+		}
+	}
+	if _, err := e.git(ctx, nil, nil, "add", "--all"); err != nil {
+		return err
+	}
+
+	date := history.RecordedAt(h).UTC().Format(time.RFC3339)
+	name, email := author(h.Spec.AppliedBy)
+	env := []string{
+		"GIT_AUTHOR_NAME=" + name,
+		"GIT_AUTHOR_EMAIL=" + email,
+		"GIT_AUTHOR_DATE=" + date,
+		"GIT_COMMITTER_NAME=" + committerName,
+		"GIT_COMMITTER_EMAIL=" + committerEmail,
+		"GIT_COMMITTER_DATE=" + date,
+	}
+	_, err := e.git(ctx, env, strings.NewReader(message(h)),
+		"commit", "--quiet", "--no-verify", "--allow-empty", "--cleanup=verbatim", "--file=-")
+	return err
+}
+
+// git runs a git command in the repository and returns its output. Commits are never
+// signed, as the exporter has no key of the users it commits for.
+func (e *Exporter) git(ctx context.Context, env []string, stdin io.Reader, args ...string) (string, error) {
+	binary := e.Git
+	if binary == "" {
+		binary = "git"
+	}
+	cmd := exec.CommandContext(ctx, binary, append([]string{"-C", e.Repo, "-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, detail)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+// file is the content of a resource file, empty if the file is to be removed.
+type file struct {
+	path    string
+	content string
+}
+
+// resourceFiles returns the files a history writes. Histories recording resource states
+// write the After state of each resource and remove deleted ones. Histories recorded
+// without states write the applied manifests instead; resources without a namespace are
+// then filed under the namespace of the history, as their scope is not known.
+func resourceFiles(h *historyv1alpha1.KronoformHistory) ([]file, error) {
+	if err := history.Readable(h); err != nil {
+		return nil, err
+	}
+	var files []file
+	if len(h.Status.ResourceSnapshots) > 0 {
+		for _, rs := range h.Status.ResourceSnapshots {
+			if rs.Operation == historyv1alpha1.OperationUnchanged {
+				continue
+			}
+			path, err := resourcePath(history.KeyOf(rs))
+			if err != nil {
+				return nil, err
+			}
+			content := rs.After
+			if rs.Operation == historyv1alpha1.OperationDeleted {
+				content = ""
+			}
+			files = append(files, file{path: path, content: withNewline(content)})
+		}
+		return files, nil
+	}
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(h.Spec.Manifests), 4096)
+	for {
+		var document map[string]interface{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed manifests: %w", err)
+		}
+		if document == nil {
+			continue
+		}
+		rs := historyv1alpha1.ResourceSnapshot{}
+		rs.APIVersion, _ = document["apiVersion"].(string)
+		rs.Kind, _ = document["kind"].(string)
+		if metadata, ok := document["metadata"].(map[string]interface{}); ok {
+			rs.Name, _ = metadata["name"].(string)
+			rs.Namespace, _ = metadata["namespace"].(string)
+		}
+		if rs.Namespace == "" {
+			rs.Namespace = h.Namespace
+		}
+		path, err := resourcePath(history.KeyOf(rs))
+		if err != nil {
+			return nil, err
+		}
+		data, err := yaml.Marshal(document)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file{path: path, content: string(data)})
+	}
+	return files, nil
+}
+
+// resourcePath returns the path of the file of a resource, relative to the repository.
+func resourcePath(key history.ResourceKey) (string, error) {
+	kind := strings.ToLower(key.Kind)
+	if key.Group != "" {
+		kind += "." + key.Group
+	}
+	namespace := key.Namespace
+	if namespace == "" {
+		namespace = clusterScope
+	}
+	for _, element := range []string{namespace, kind, key.Name} {
+		if element == "" || element == "." || element == ".." || strings.ContainsAny(element, `/\`) {
+			return "", fmt.Errorf("cannot export resource %s: invalid path element %q", key, element)
+		}
+	}
+	return namespace + "/" + kind + "/" + key.Name + ".yaml", nil
+}
+
+// message returns the commit message of a history: the message it was applied with, or
+// its description or summary if it has none, followed by the TicketTrailer when it
+// belongs to a ticket and the HistoryTrailer.
+func message(h *historyv1alpha1.KronoformHistory) string {
+	subject := strings.TrimSpace(h.Spec.Message)
+	if subject == "" {
+		subject = strings.TrimSpace(h.Spec.Description)
+	}
+	if subject == "" {
+		subject = strings.TrimSpace(h.Status.Summary)
+	}
+	if subject == "" {
+		subject = "Apply " + h.Name
+	}
+	trailers := HistoryTrailer + ": " + trailerValue(h) + "\n"
+	if ticket := strings.TrimSpace(h.Spec.Ticket); ticket != "" && !strings.Contains(ticket, "\n") {
+		trailers = TicketTrailer + ": " + ticket + "\n" + trailers
+	}
+	return subject + "\n\n" + trailers
+}
+
+func trailerValue(h *historyv1alpha1.KronoformHistory) string {
+	return h.Namespace + "/" + h.Name
+}
+
+// author returns the git identity of a user. Users that are not email addresses, such
+// as service accounts, get an address in a reserved domain.
+func author(user string) (string, string) {
+	sanitize := strings.NewReplacer("<", "", ">", "", "\n", " ")
+	user = strings.TrimSpace(sanitize.Replace(user))
+	if user == "" {
+		user = "unknown"
+	}
+	if strings.Contains(user, "@") {
+		return user, user
+	}
+	return user, strings.ReplaceAll(user, " ", ".") + "@" + authorDomain
+}
+
+func withNewline(s string) string {
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		return s + "\n"
+	}
+	return s
+}
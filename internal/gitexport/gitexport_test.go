@@ -0,0 +1,165 @@
+package gitexport
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+func newHistory(name, user, description string, appliedAt time.Time, resources ...historyv1alpha1.ResourceSnapshot) historyv1alpha1.KronoformHistory {
+	at := metav1.NewTime(appliedAt)
+	return historyv1alpha1.KronoformHistory{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       historyv1alpha1.KronoformHistorySpec{AppliedBy: user, Description: description},
+		Status: historyv1alpha1.KronoformHistoryStatus{
+			AppliedAt:         &at,
+			ResourceSnapshots: resources,
+		},
+	}
+}
+
+func gitOutput(t *testing.T, repo string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return string(out)
+}
+
+func TestExport(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+	repo := filepath.Join(t.TempDir(), "timeline")
+	exporter := &Exporter{Repo: repo}
+
+	base := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	web := historyv1alpha1.ResourceSnapshot{
+		APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "default",
+		Operation: historyv1alpha1.OperationCreated, After: "kind: Deployment\nreplicas: 1\n",
+	}
+	role := historyv1alpha1.ResourceSnapshot{
+		APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "reader",
+		Operation: historyv1alpha1.OperationCreated, After: "kind: ClusterRole",
+	}
+	scaled := web
+	scaled.Operation, scaled.After = historyv1alpha1.OperationUpdated, "kind: Deployment\nreplicas: 3\n"
+	deleted := role
+	deleted.Operation, deleted.After = historyv1alpha1.OperationDeleted, ""
+
+	// Histories are committed in the order they were applied, whatever the list order
+	histories := []historyv1alpha1.KronoformHistory{
+		newHistory("second", "bob", "Scale web", base.Add(time.Hour), scaled, deleted),
+		newHistory("first", "alice@example.com", "Deploy web", base, web, role),
+	}
+	count, err := exporter.Export(ctx, histories)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(count).To(gomega.Equal(2))
+
+	log := gitOutput(t, repo, "log", "--format=%an <%ae>|%aI|%cI|%s")
+	g.Expect(strings.Split(strings.TrimSpace(log), "\n")).To(gomega.Equal([]string{
+		"bob <bob@kronoform.invalid>|2025-08-06T11:00:00+00:00|2025-08-06T11:00:00+00:00|Scale web",
+		"alice@example.com <alice@example.com>|2025-08-06T10:00:00+00:00|2025-08-06T10:00:00+00:00|Deploy web",
+	}))
+	g.Expect(gitOutput(t, repo, "show", "HEAD~1:_/clusterrole.rbac.authorization.k8s.io/reader.yaml")).
+		To(gomega.Equal("kind: ClusterRole\n"))
+
+	data, err := os.ReadFile(filepath.Join(repo, "default", "deployment.apps", "web.yaml"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(data)).To(gomega.Equal("kind: Deployment\nreplicas: 3\n"))
+	_, err = os.Stat(filepath.Join(repo, "_", "clusterrole.rbac.authorization.k8s.io", "reader.yaml"))
+	g.Expect(os.IsNotExist(err)).To(gomega.BeTrue())
+
+	// Exported histories are skipped on the next run
+	third := newHistory("third", "bob", "", base.Add(2*time.Hour))
+	third.Spec.Message, third.Spec.Ticket = "Switch the cache to fast mode", "OPS-1234"
+	third.Spec.Manifests = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  mode: fast\n"
+	count, err = exporter.Export(ctx, append(histories, third))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(count).To(gomega.Equal(1))
+	g.Expect(gitOutput(t, repo, "rev-list", "--count", "HEAD")).To(gomega.Equal("3\n"))
+	g.Expect(gitOutput(t, repo, "log", "-1", "--format=%B")).
+		To(gomega.Equal("Switch the cache to fast mode\n\nKronoform-Ticket: OPS-1234\nKronoform-History: default/third\n\n"))
+	data, err = os.ReadFile(filepath.Join(repo, "default", "configmap", "settings.yaml"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(data)).To(gomega.ContainSubstring("mode: fast"))
+}
+
+func TestExportStopsAtUnreadableHistories(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+	repo := filepath.Join(t.TempDir(), "timeline")
+	exporter := &Exporter{Repo: repo}
+
+	base := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	created := historyv1alpha1.ResourceSnapshot{
+		APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "default",
+		Operation: historyv1alpha1.OperationCreated, After: "kind: Deployment\nreplicas: 1\n",
+	}
+	scaled := created
+	scaled.Operation, scaled.After = historyv1alpha1.OperationUpdated, "kind: Deployment\nreplicas: 3\n"
+	sealed := scaled
+	sealed.After, sealed.Encoding = "c2VhbGVk", historyv1alpha1.EncodingEncrypted
+	resized := created
+	resized.Operation, resized.After = historyv1alpha1.OperationUpdated, "kind: Deployment\nreplicas: 5\n"
+	histories := []historyv1alpha1.KronoformHistory{
+		newHistory("first", "alice", "Deploy web", base, created),
+		newHistory("second", "alice", "Scale web", base.Add(time.Hour), sealed),
+		newHistory("third", "bob", "Resize web", base.Add(2*time.Hour), resized),
+	}
+
+	// The histories after an unreadable one wait for it
+	count, err := exporter.Export(ctx, histories)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("stopped at history default/second, 2 histories left")))
+	g.Expect(count).To(gomega.Equal(1))
+	g.Expect(gitOutput(t, repo, "log", "--format=%s")).To(gomega.Equal("Deploy web\n"))
+
+	// Once readable, it is committed in the order it was applied
+	histories[1].Status.ResourceSnapshots[0] = scaled
+	count, err = exporter.Export(ctx, histories)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(count).To(gomega.Equal(2))
+	g.Expect(gitOutput(t, repo, "log", "--format=%s")).To(gomega.Equal("Resize web\nScale web\nDeploy web\n"))
+	g.Expect(gitOutput(t, repo, "show", "HEAD~1:default/deployment.apps/web.yaml")).
+		To(gomega.Equal("kind: Deployment\nreplicas: 3\n"))
+	data, err := os.ReadFile(filepath.Join(repo, "default", "deployment.apps", "web.yaml"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(data)).To(gomega.Equal("kind: Deployment\nreplicas: 5\n"))
+}
+
+func TestExportRefusesUncommittedChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	g := gomega.NewGomegaWithT(t)
+	repo := t.TempDir()
+	gitOutput(t, repo, "init", "--quiet")
+	g.Expect(os.WriteFile(filepath.Join(repo, "notes.txt"), []byte("local edit"), 0o644)).To(gomega.Succeed())
+
+	_, err := (&Exporter{Repo: repo}).Export(context.Background(), nil)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("uncommitted changes")))
+}
+
+func TestResourcePathRejectsTraversal(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	h := newHistory("h", "alice", "", time.Now(), historyv1alpha1.ResourceSnapshot{
+		APIVersion: "v1", Kind: "ConfigMap", Name: "../../escape", Namespace: "default", After: "x",
+	})
+	_, err := resourceFiles(&h)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("invalid path element")))
+}
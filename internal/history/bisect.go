@@ -0,0 +1,82 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/yu-kod/kronoform/internal/fielddiff"
+)
+
+// Bisection is the change of a timeline found by Bisect.
+type Bisection struct {
+	Entry *Entry
+	// Before and After are the values at the path around the change, nil when absent
+	Before, After interface{}
+}
+
+// Bisect walks a timeline and returns the first entry that changed the value at path
+// from the oldest recorded one, or with match, the first entry that made the value match
+// when it did not before. Absent values are passed to match as nil. Entries taken from
+// manifests that do not set the path are skipped. It returns nil when no entry qualifies.
+func Bisect(entries []Entry, path string, match func(value interface{}) bool) (*Bisection, error) {
+	var previous interface{}
+	known := false
+	for i := range entries {
+		entry := &entries[i]
+		after, ok, err := lookupState(entry.After, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the state recorded by history %s: %w", entry.History.Name, err)
+		}
+		if entry.Partial() && !ok {
+			continue
+		}
+		// The recorded state before the change also covers changes that were not recorded
+		if !entry.Partial() && entry.Before != "" {
+			if previous, _, err = lookupState(entry.Before, path); err != nil {
+				return nil, fmt.Errorf("failed to read the state recorded by history %s: %w", entry.History.Name, err)
+			}
+			known = true
+		}
+
+		var changed bool
+		if match == nil {
+			changed = known && !reflect.DeepEqual(previous, after)
+		} else {
+			changed = match(after) && !(known && match(previous))
+		}
+		if changed {
+			bisection := &Bisection{Entry: entry, After: after}
+			if known {
+				bisection.Before = previous
+			}
+			return bisection, nil
+		}
+		previous, known = after, true
+	}
+	return nil, nil
+}
+
+// lookupState returns the value at path in a recorded state.
+func lookupState(state, path string) (interface{}, bool, error) {
+	obj, err := fielddiff.Parse(state)
+	if err != nil {
+		return nil, false, err
+	}
+	return fielddiff.Lookup(obj, path)
+}
@@ -0,0 +1,61 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+func TestBisect(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	base := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	config := func(before, after string) historyv1alpha1.ResourceSnapshot {
+		return historyv1alpha1.ResourceSnapshot{
+			APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "default",
+			Operation: "Updated", Before: before, After: after,
+		}
+	}
+	created := newHistory("created", base, config("", configMapState("  color: blue\n")))
+	created.Status.ResourceSnapshots[0].Operation = "Created"
+	sized := newHistory("sized", base.Add(time.Hour))
+	sized.Spec.Manifests = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  size: large\n"
+	recolored := newHistory("recolored", base.Add(2*time.Hour), config(
+		configMapState("  color: blue\n  size: large\n"), configMapState("  color: red\n  size: large\n")))
+	// green was set without being recorded
+	repainted := newHistory("repainted", base.Add(3*time.Hour), config(
+		configMapState("  color: green\n  size: large\n"), configMapState("  color: yellow\n  size: large\n")))
+
+	entries, err := Timeline([]historyv1alpha1.KronoformHistory{repainted, recolored, sized, created}, "configmap/config")
+	g.Expect(err).To(gomega.BeNil())
+
+	bisection, err := Bisect(entries, ".data.color", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(bisection.Entry.History.Name).To(gomega.Equal("recolored"))
+	g.Expect(bisection.Before).To(gomega.Equal("blue"))
+	g.Expect(bisection.After).To(gomega.Equal("red"))
+
+	bisection, err = Bisect(entries, ".data.size", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(bisection.Entry.History.Name).To(gomega.Equal("sized"))
+	g.Expect(bisection.Before).To(gomega.BeNil())
+
+	bisection, err = Bisect(entries, ".data.color", func(value interface{}) bool { return value == "yellow" })
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(bisection.Entry.History.Name).To(gomega.Equal("repainted"))
+	g.Expect(bisection.Before).To(gomega.Equal("green"))
+
+	bisection, err = Bisect(entries, ".data.color", func(value interface{}) bool { return value == "blue" })
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(bisection.Entry.History.Name).To(gomega.Equal("created"))
+
+	bisection, err = Bisect(entries, ".data.shape", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(bisection).To(gomega.BeNil())
+
+	_, err = Bisect(entries, "data", nil)
+	g.Expect(err).NotTo(gomega.BeNil())
+}
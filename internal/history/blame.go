@@ -0,0 +1,109 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/yu-kod/kronoform/internal/fielddiff"
+)
+
+// BlameLine is a field of the current state of a resource with the change that last
+// set it.
+type BlameLine struct {
+	Path  string
+	Value interface{}
+	// Entry is the change that last set the field, nil when it was changed without
+	// being recorded
+	Entry *Entry
+}
+
+// Blame attributes each field of the latest recorded state of a resource to the entry
+// of its timeline that last changed it. Fields whose value differs from the one left by
+// the previous entry without the entry changing them were changed outside of the
+// recorded histories and are left unattributed. Entries holding only the applied
+// manifest attribute the fields it sets and never unset fields.
+func Blame(entries []Entry) ([]BlameLine, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no history recorded the resource")
+	}
+	last := entries[len(entries)-1]
+	if !last.Partial() && last.After == "" {
+		return nil, fmt.Errorf("%s was deleted by history %s (%s)", last.Key, ID(last.History), last.History.Name)
+	}
+
+	values := map[string]interface{}{}
+	owners := map[string]*Entry{}
+	for i := range entries {
+		entry := &entries[i]
+		after, err := stateFields(entry.After)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the state recorded by history %s: %w", entry.History.Name, err)
+		}
+		if entry.Partial() {
+			for path, value := range after {
+				if old, ok := values[path]; !ok || !reflect.DeepEqual(old, value) {
+					values[path] = value
+					owners[path] = entry
+				}
+			}
+			continue
+		}
+
+		before, err := stateFields(entry.Before)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the state recorded by history %s: %w", entry.History.Name, err)
+		}
+		next := map[string]*Entry{}
+		for path, value := range after {
+			old, existed := before[path]
+			switch {
+			case !existed || !reflect.DeepEqual(old, value):
+				next[path] = entry
+			case reflect.DeepEqual(values[path], value) && owners[path] != nil:
+				next[path] = owners[path]
+			}
+		}
+		values, owners = after, next
+	}
+
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	lines := make([]BlameLine, len(paths))
+	for i, path := range paths {
+		lines[i] = BlameLine{Path: path, Value: values[path], Entry: owners[path]}
+	}
+	return lines, nil
+}
+
+// stateFields returns the leaf fields of a recorded state by path.
+func stateFields(state string) (map[string]interface{}, error) {
+	obj, err := fielddiff.Parse(state)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	for _, f := range fielddiff.Fields(obj) {
+		fields[f.Path] = f.Value
+	}
+	return fields, nil
+}
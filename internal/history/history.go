@@ -0,0 +1,152 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package history contains helpers for ordering KronoformHistory records and
+// looking up the resources they touched. It is shared by the manager and the
+// kubectl plugin.
+package history
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+// ResourceKey identifies a resource independently of the API version it was recorded with.
+type ResourceKey struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// String renders the key as kind[.group]/[namespace/]name.
+func (k ResourceKey) String() string {
+	kind := k.Kind
+	if k.Group != "" {
+		kind += "." + k.Group
+	}
+	if k.Namespace == "" {
+		return fmt.Sprintf("%s/%s", kind, k.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", kind, k.Namespace, k.Name)
+}
+
+// KeyOf returns the key of a recorded resource snapshot.
+func KeyOf(rs historyv1alpha1.ResourceSnapshot) ResourceKey {
+	gv, _ := schema.ParseGroupVersion(rs.APIVersion)
+	return ResourceKey{Group: gv.Group, Kind: rs.Kind, Namespace: rs.Namespace, Name: rs.Name}
+}
+
+// UnreadableAnnotation is set by stores on a history they return without its content,
+// such as an archived history whose archive cannot be read, and holds the reason. It is
+// only set on the returned copy and never saved.
+const UnreadableAnnotation = "history.yu-kod.github.io/unreadable"
+
+// ErrUnreadable is wrapped by the errors of histories returned without their content.
+var ErrUnreadable = errors.New("content is not available")
+
+// Readable returns an error wrapping payload.ErrEncrypted if the manifests or resource
+// states of h are still encrypted, as returned by a store that could not decrypt them,
+// and one wrapping ErrUnreadable if the store could not read them at all.
+func Readable(h *historyv1alpha1.KronoformHistory) error {
+	if err := unavailable(h); err != nil {
+		return err
+	}
+	if h.Spec.ManifestsEncoding == historyv1alpha1.EncodingEncrypted {
+		return sealed(h, "manifests")
+	}
+	for _, rs := range h.Status.ResourceSnapshots {
+		if rs.Encoding == historyv1alpha1.EncodingEncrypted {
+			return sealed(h, "states of "+KeyOf(rs).String())
+		}
+	}
+	return nil
+}
+
+// unavailable returns the error of a history returned without its content, nil for others.
+func unavailable(h *historyv1alpha1.KronoformHistory) error {
+	reason, ok := h.Annotations[UnreadableAnnotation]
+	if !ok {
+		return nil
+	}
+	return fmt.Errorf("cannot read the content of history %s: %w: %s", h.Name, ErrUnreadable, reason)
+}
+
+// sealed returns the error of content of h that is still encrypted.
+func sealed(h *historyv1alpha1.KronoformHistory, content string) error {
+	return fmt.Errorf("cannot read the %s recorded by history %s: %w with a key that is not configured",
+		content, h.Name, payload.ErrEncrypted)
+}
+
+// RecordedAt returns when a history was applied, falling back to its creation time
+// for records whose status has not been populated.
+func RecordedAt(h *historyv1alpha1.KronoformHistory) time.Time {
+	if h.Status.AppliedAt != nil {
+		return h.Status.AppliedAt.Time
+	}
+	return h.CreationTimestamp.Time
+}
+
+// Less reports whether a was recorded before b. Ties are broken by namespace and name
+// so that the order is stable across list calls.
+func Less(a, b *historyv1alpha1.KronoformHistory) bool {
+	ta, tb := RecordedAt(a), RecordedAt(b)
+	if !ta.Equal(tb) {
+		return ta.Before(tb)
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+// Sort orders histories from oldest to newest.
+func Sort(items []historyv1alpha1.KronoformHistory) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return Less(&items[i], &items[j])
+	})
+}
+
+// Find returns the snapshot of the resource identified by key within a history.
+func Find(h *historyv1alpha1.KronoformHistory, key ResourceKey) (*historyv1alpha1.ResourceSnapshot, bool) {
+	for i := range h.Status.ResourceSnapshots {
+		if KeyOf(h.Status.ResourceSnapshots[i]) == key {
+			return &h.Status.ResourceSnapshots[i], true
+		}
+	}
+	return nil, false
+}
+
+// Latest returns the most recent history that recorded the resource identified by key.
+func Latest(items []historyv1alpha1.KronoformHistory, key ResourceKey) *historyv1alpha1.KronoformHistory {
+	var latest *historyv1alpha1.KronoformHistory
+	for i := range items {
+		if _, ok := Find(&items[i], key); !ok {
+			continue
+		}
+		if latest == nil || Less(latest, &items[i]) {
+			latest = &items[i]
+		}
+	}
+	return latest
+}
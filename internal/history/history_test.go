@@ -0,0 +1,52 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+func newHistory(name string, appliedAt time.Time, resources ...historyv1alpha1.ResourceSnapshot) historyv1alpha1.KronoformHistory {
+	at := metav1.NewTime(appliedAt)
+	return historyv1alpha1.KronoformHistory{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status: historyv1alpha1.KronoformHistoryStatus{
+			AppliedAt:         &at,
+			ResourceSnapshots: resources,
+		},
+	}
+}
+
+func TestSortAndLatest(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	base := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	web := historyv1alpha1.ResourceSnapshot{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "default"}
+	webV1beta := historyv1alpha1.ResourceSnapshot{APIVersion: "apps/v1beta1", Kind: "Deployment", Name: "web", Namespace: "default"}
+	config := historyv1alpha1.ResourceSnapshot{APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "default"}
+
+	items := []historyv1alpha1.KronoformHistory{
+		newHistory("third", base.Add(2*time.Hour), config),
+		newHistory("first", base, webV1beta),
+		newHistory("second", base.Add(time.Hour), web, config),
+	}
+
+	Sort(items)
+	g.Expect([]string{items[0].Name, items[1].Name, items[2].Name}).To(gomega.Equal([]string{"first", "second", "third"}))
+
+	g.Expect(Latest(items, KeyOf(web)).Name).To(gomega.Equal("second"))
+	g.Expect(Latest(items, KeyOf(config)).Name).To(gomega.Equal("third"))
+	g.Expect(Latest(items, ResourceKey{Kind: "Secret", Name: "missing"})).To(gomega.BeNil())
+}
+
+func TestResourceKeyString(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	g.Expect(ResourceKey{Group: "apps", Kind: "Deployment", Namespace: "default", Name: "web"}.String()).
+		To(gomega.Equal("Deployment.apps/default/web"))
+	g.Expect(ResourceKey{Kind: "Namespace", Name: "prod"}.String()).To(gomega.Equal("Namespace/prod"))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+// IDLength is the number of hex characters of a history ID.
+const IDLength = 12
+
+// idContent is the content a history ID is derived from.
+type idContent struct {
+	Namespace         string                               `json:"namespace"`
+	Name              string                               `json:"name,omitempty"`
+	Spec              historyv1alpha1.KronoformHistorySpec `json:"spec"`
+	RecordedAt        string                               `json:"recordedAt"`
+	ResourceSnapshots []historyv1alpha1.ResourceSnapshot   `json:"resourceSnapshots,omitempty"`
+}
+
+// AssignID sets the ID of a new history, unless it already has one, from a digest of its
+// content and the time it was applied, to the nanosecond. Histories without an applied
+// time yet use the current time.
+func AssignID(h *historyv1alpha1.KronoformHistory) error {
+	if h.Spec.ID != "" {
+		return nil
+	}
+	recordedAt := time.Now()
+	if h.Status.AppliedAt != nil {
+		recordedAt = h.Status.AppliedAt.Time
+	}
+	content := idContent{
+		Namespace:         h.Namespace,
+		Name:              h.Name,
+		Spec:              h.Spec,
+		RecordedAt:        recordedAt.UTC().Format(time.RFC3339Nano),
+		ResourceSnapshots: h.Status.ResourceSnapshots,
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	h.Spec.ID = digest(data)
+	return nil
+}
+
+// ID returns the ID of a history. Histories recorded before IDs were assigned get one
+// derived from their UID.
+func ID(h *historyv1alpha1.KronoformHistory) string {
+	if h.Spec.ID != "" {
+		return h.Spec.ID
+	}
+	return digest([]byte(h.UID))
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:IDLength]
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+// StateYAML renders an object as YAML for a ResourceSnapshot, without managedFields.
+func StateYAML(obj *unstructured.Unstructured) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	state := obj.DeepCopy()
+	state.SetManagedFields(nil)
+	data, err := yaml.Marshal(state.Object)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// LastManager returns the managedFields manager that most recently updated the
+// object's main resource, or an empty string if none is recorded.
+func LastManager(obj *unstructured.Unstructured) string {
+	var manager string
+	var latest *metav1.Time
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Subresource != "" {
+			continue
+		}
+		if latest == nil || (entry.Time != nil && latest.Before(entry.Time)) {
+			manager = entry.Manager
+			latest = entry.Time
+		}
+	}
+	return manager
+}
+
+// RecordedByApply reports whether a change made by the given field manager is recorded by
+// the applied history of kubectl kronoform apply: it was made with the kronoform field
+// manager, or it names a new history in the history annotation, as kronoform does once a
+// history is recorded. Audited and observed changes are not recorded a second time then.
+func RecordedByApply(manager string, before, after *unstructured.Unstructured) bool {
+	if manager == historyv1alpha1.FieldManager {
+		return true
+	}
+	if after == nil {
+		return false
+	}
+	recorded := after.GetAnnotations()[historyv1alpha1.AnnotationHistory]
+	return recorded != "" && (before == nil || before.GetAnnotations()[historyv1alpha1.AnnotationHistory] != recorded)
+}
+
+// FromChange builds a history recording a single resource transition. before is nil for
+// creations and after is nil for deletions. The history is placed in the resource's
+// namespace; callers must set a namespace for cluster-scoped resources.
+func FromChange(before, after *unstructured.Unstructured, source, actor string) (*historyv1alpha1.KronoformHistory, error) {
+	current, operation := after, historyv1alpha1.OperationUpdated
+	switch {
+	case before == nil && after == nil:
+		return nil, fmt.Errorf("a change needs a before or an after state")
+	case before == nil:
+		operation = historyv1alpha1.OperationCreated
+	case after == nil:
+		current, operation = before, historyv1alpha1.OperationDeleted
+	}
+
+	beforeYAML, err := StateYAML(before)
+	if err != nil {
+		return nil, err
+	}
+	afterYAML, err := StateYAML(after)
+	if err != nil {
+		return nil, err
+	}
+	manifests := afterYAML
+	if manifests == "" {
+		manifests = beforeYAML
+	}
+
+	if actor == "" {
+		actor = "unknown"
+	}
+	now := metav1.Now()
+	gvk := current.GroupVersionKind()
+
+	rs := historyv1alpha1.ResourceSnapshot{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Name:       current.GetName(),
+		Namespace:  current.GetNamespace(),
+		Operation:  operation,
+	}
+	if err := payload.EncodeStates(&rs, beforeYAML, afterYAML); err != nil {
+		return nil, err
+	}
+
+	return &historyv1alpha1.KronoformHistory{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("kronoform-%s-", strings.ToLower(source)),
+			Namespace:    current.GetNamespace(),
+		},
+		Spec: historyv1alpha1.KronoformHistorySpec{
+			Manifests:          manifests,
+			Source:             source,
+			Description:        fmt.Sprintf("%s %s/%s %s by %s", source, gvk.Kind, current.GetName(), strings.ToLower(operation), actor),
+			AppliedBy:          actor,
+			ResourceTypes:      []string{gvk.Kind},
+			ResourceNames:      []string{current.GetName()},
+			ResourceNamespaces: []string{current.GetNamespace()},
+		},
+		Status: historyv1alpha1.KronoformHistoryStatus{
+			AppliedAt:         &now,
+			ResourceSnapshots: []historyv1alpha1.ResourceSnapshot{rs},
+			Summary:           fmt.Sprintf("%s %s/%s", operation, gvk.Kind, current.GetName()),
+		},
+	}, nil
+}
+
+// Create creates a history together with its status. The history is given an ID if it
+// has none, plain manifests are encoded, and manifests and resource states are stored in
+// chunks if they are too large for the history, each or together. The status subresource
+// is ignored on create, so it is written with a separate update afterwards. A history
+// whose chunks cannot be stored is deleted again rather than left pointing at missing
+// chunks.
+func Create(ctx context.Context, c client.Client, h *historyv1alpha1.KronoformHistory) error {
+	if err := AssignID(h); err != nil {
+		return err
+	}
+	var chunks []string
+	if h.Spec.ManifestsChunks == 0 {
+		var err error
+		h.Spec.Manifests, h.Spec.ManifestsEncoding, chunks, err = payload.PackStored(h.Spec.Manifests, h.Spec.ManifestsEncoding)
+		if err != nil {
+			return fmt.Errorf("failed to encode manifests: %w", err)
+		}
+		h.Spec.ManifestsChunks = int32(len(chunks))
+	}
+
+	status := h.Status.DeepCopy()
+	states := payload.SplitStates(status, payload.InlineBudget-len(h.Spec.Manifests))
+	if err := c.Create(ctx, h); err != nil {
+		return err
+	}
+	if err := createChunks(ctx, c, h, chunks, len(status.ResourceSnapshots), states); err != nil {
+		if deleteErr := c.Delete(ctx, h); deleteErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to delete incomplete history %s: %w", h.Name, deleteErr))
+		}
+		return err
+	}
+	h.Status = *status
+	return c.Status().Update(ctx, h)
+}
+
+// createChunks stores the chunks of the manifests and of the states of the resources
+// snapshots of h.
+func createChunks(ctx context.Context, c client.Client, h *historyv1alpha1.KronoformHistory,
+	manifests []string, resources int, states map[string][]string) error {
+	if err := payload.CreateChunks(ctx, c, h, "", manifests); err != nil {
+		return err
+	}
+	for i := range resources {
+		for _, part := range []string{payload.StatesPart(i, false), payload.StatesPart(i, true)} {
+			if err := payload.CreateChunks(ctx, c, h, part, states[part]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
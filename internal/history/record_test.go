@@ -0,0 +1,177 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+func newConfigMap(data map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "settings",
+			"namespace": "default",
+		},
+		"data": data,
+	}}
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate}})
+	return obj
+}
+
+func TestFromChange(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	before := newConfigMap(map[string]interface{}{"mode": "a"})
+	after := newConfigMap(map[string]interface{}{"mode": "b"})
+
+	h, err := FromChange(before, after, historyv1alpha1.HistorySourceObserved, "kubectl-edit")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(h.Namespace).To(gomega.Equal("default"))
+	g.Expect(h.GenerateName).To(gomega.Equal("kronoform-observed-"))
+	g.Expect(h.Spec.Source).To(gomega.Equal(historyv1alpha1.HistorySourceObserved))
+	g.Expect(h.Spec.AppliedBy).To(gomega.Equal("kubectl-edit"))
+	g.Expect(h.Spec.Manifests).To(gomega.ContainSubstring("mode: b"))
+	g.Expect(h.Spec.Manifests).NotTo(gomega.ContainSubstring("managedFields"))
+	g.Expect(h.Status.ResourceSnapshots).To(gomega.HaveLen(1))
+
+	rs := h.Status.ResourceSnapshots[0]
+	g.Expect(rs.Operation).To(gomega.Equal(historyv1alpha1.OperationUpdated))
+	g.Expect(rs.Before).To(gomega.ContainSubstring("mode: a"))
+	g.Expect(rs.After).To(gomega.ContainSubstring("mode: b"))
+
+	deleted, err := FromChange(before, nil, historyv1alpha1.HistorySourceObserved, "")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(deleted.Status.ResourceSnapshots[0].Operation).To(gomega.Equal(historyv1alpha1.OperationDeleted))
+	g.Expect(deleted.Status.ResourceSnapshots[0].After).To(gomega.BeEmpty())
+	g.Expect(deleted.Spec.Manifests).To(gomega.ContainSubstring("mode: a"))
+	g.Expect(deleted.Spec.AppliedBy).To(gomega.Equal("unknown"))
+
+	_, err = FromChange(nil, nil, historyv1alpha1.HistorySourceObserved, "")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestLastManager(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	earlier := metav1.NewTime(time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC))
+	later := metav1.NewTime(earlier.Add(time.Minute))
+
+	obj := newConfigMap(nil)
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: "kronoform", Time: &later},
+		{Manager: "kubectl-edit", Time: &earlier},
+		{Manager: "status-writer", Time: &later, Subresource: "status"},
+	})
+	g.Expect(LastManager(obj)).To(gomega.Equal("kronoform"))
+
+	obj.SetManagedFields(nil)
+	g.Expect(LastManager(obj)).To(gomega.BeEmpty())
+}
+
+func TestRecordedByApply(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	before := newConfigMap(map[string]interface{}{"mode": "a"})
+	after := newConfigMap(map[string]interface{}{"mode": "b"})
+	g.Expect(RecordedByApply(historyv1alpha1.FieldManager, nil, after)).To(gomega.BeTrue())
+	g.Expect(RecordedByApply(historyv1alpha1.FieldManager, before, nil)).To(gomega.BeTrue())
+	g.Expect(RecordedByApply("kubectl-edit", before, after)).To(gomega.BeFalse())
+	g.Expect(RecordedByApply("kubectl-edit", before, nil)).To(gomega.BeFalse())
+
+	// Naming a new history marks the change as recorded, keeping the name does not
+	after.SetAnnotations(map[string]string{historyv1alpha1.AnnotationHistory: "kronoform-history-1"})
+	g.Expect(RecordedByApply("", before, after)).To(gomega.BeTrue())
+	g.Expect(RecordedByApply("", nil, after)).To(gomega.BeTrue())
+	before.SetAnnotations(after.GetAnnotations())
+	g.Expect(RecordedByApply("kubectl-edit", before, after)).To(gomega.BeFalse())
+}
+
+func TestCreateKeepsStatus(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(historyv1alpha1.AddToScheme(scheme)).To(gomega.Succeed())
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&historyv1alpha1.KronoformHistory{}).
+		Build()
+
+	h, err := FromChange(nil, newConfigMap(map[string]interface{}{"mode": "a"}), historyv1alpha1.HistorySourceObserved, "kubectl-edit")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	h.Name = "observed"
+	g.Expect(Create(context.TODO(), c, h)).To(gomega.Succeed())
+
+	stored := &historyv1alpha1.KronoformHistory{}
+	g.Expect(c.Get(context.TODO(), client.ObjectKeyFromObject(h), stored)).To(gomega.Succeed())
+	g.Expect(stored.Status.ResourceSnapshots).To(gomega.HaveLen(1))
+	g.Expect(stored.Status.AppliedAt).NotTo(gomega.BeNil())
+}
+
+func TestCreateChunksLargeStates(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.TODO()
+
+	scheme := runtime.NewScheme()
+	g.Expect(historyv1alpha1.AddToScheme(scheme)).To(gomega.Succeed())
+	builder := func() *fake.ClientBuilder {
+		return fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&historyv1alpha1.KronoformHistory{})
+	}
+
+	// Random content does not compress, so it still exceeds the chunk threshold once encoded
+	random := rand.New(rand.NewSource(1))
+	blob := make([]byte, 2*payload.ChunkThreshold)
+	for i := range blob {
+		blob[i] = "abcdefghijklmnopqrstuvwxyz0123456789"[random.Intn(36)]
+	}
+	newHistory := func() *historyv1alpha1.KronoformHistory {
+		h, err := FromChange(nil, newConfigMap(map[string]interface{}{"blob": string(blob)}),
+			historyv1alpha1.HistorySourceObserved, "kubectl-edit")
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		h.Name = "large"
+		return h
+	}
+
+	c := builder().Build()
+	h := newHistory()
+	_, want, err := payload.States(h.Status.ResourceSnapshots[0])
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(Create(ctx, c, h)).To(gomega.Succeed())
+
+	stored := &historyv1alpha1.KronoformHistory{}
+	g.Expect(c.Get(ctx, client.ObjectKeyFromObject(h), stored)).To(gomega.Succeed())
+	g.Expect(stored.Status.ResourceSnapshots[0].After).To(gomega.BeEmpty())
+	g.Expect(stored.Status.ResourceSnapshots[0].AfterChunks).To(gomega.BeNumerically(">", 1))
+	g.Expect(payload.ReassembleStates(ctx, c, stored)).To(gomega.Succeed())
+	_, after, err := payload.States(stored.Status.ResourceSnapshots[0])
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(after).To(gomega.Equal(want))
+
+	// A history whose chunks cannot be stored is not left behind
+	c = builder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if _, ok := obj.(*historyv1alpha1.KronoformChunk); ok {
+				return errors.New("quota exceeded")
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	}).Build()
+	g.Expect(Create(ctx, c, newHistory())).To(gomega.MatchError(gomega.ContainSubstring("quota exceeded")))
+	histories := &historyv1alpha1.KronoformHistoryList{}
+	g.Expect(c.List(ctx, histories)).To(gomega.Succeed())
+	g.Expect(histories.Items).To(gomega.BeEmpty())
+}
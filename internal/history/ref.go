@@ -0,0 +1,280 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+// MinPrefixLength is the shortest ID prefix accepted as a reference.
+const MinPrefixLength = 4
+
+// ErrUnknownRef is returned when a reference designates no history.
+var ErrUnknownRef = errors.New("no history matches")
+
+var (
+	headRef     = regexp.MustCompile(`^HEAD((?:[~^][0-9]*)*)$`)
+	relativeRef = regexp.MustCompile(`^([0-9]+)\s*([a-z]+)\s+ago$`)
+	hexRef      = regexp.MustCompile(`^[0-9a-f]+$`)
+)
+
+// units are the units accepted in relative times, e.g. @{2 hours ago}.
+var units = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "second": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute,
+	"h": time.Hour, "hour": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour,
+}
+
+// absoluteLayouts are the layouts accepted for absolute times, e.g. @{2025-01-02 15:04}.
+var absoluteLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// Resolve returns the history of items that ref designates, the way git resolves revisions:
+//
+//   - the name of a history, or a unique prefix of its ID of at least MinPrefixLength characters
+//   - HEAD for the latest history, and HEAD~N (or HEAD^) for the Nth one before it
+//   - @{<time>} for the latest history applied at or before a time, either relative to now
+//     (@{1h ago}, @{2 days ago}, @{yesterday}) or absolute (@{2025-01-02 15:04})
+//
+// Any of these may be followed by :<resource>, e.g. HEAD~1:deployment.apps/web, to consider
+// only the histories that recorded that resource. A resource is written kind[.group]/name
+// or kind[.group]/namespace/name. Without a revision, as in :configmap/settings, the
+// reference designates the latest history of the resource.
+func Resolve(items []historyv1alpha1.KronoformHistory, ref string, now time.Time) (*historyv1alpha1.KronoformHistory, error) {
+	rev, resource := splitResource(ref)
+	candidates := make([]historyv1alpha1.KronoformHistory, 0, len(items))
+	if resource != "" {
+		match, err := parseResource(resource)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			if recordsResource(&items[i], match) {
+				candidates = append(candidates, items[i])
+			}
+		}
+	} else {
+		candidates = append(candidates, items...)
+	}
+	Sort(candidates)
+
+	switch {
+	case rev == "":
+		return fromHead(candidates, ref, 0)
+	case headRef.MatchString(rev):
+		back, err := parseAncestry(headRef.FindStringSubmatch(rev)[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid reference %q: %w", ref, err)
+		}
+		return fromHead(candidates, ref, back)
+	case strings.HasPrefix(rev, "@{") && strings.HasSuffix(rev, "}"):
+		at, err := ParseTime(strings.TrimSpace(rev[2:len(rev)-1]), now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reference %q: %w", ref, err)
+		}
+		for i := len(candidates) - 1; i >= 0; i-- {
+			if !RecordedAt(&candidates[i]).After(at) {
+				return &candidates[i], nil
+			}
+		}
+		return nil, fmt.Errorf("%w %s: nothing was applied before %s", ErrUnknownRef, ref, at.Format(time.RFC3339))
+	}
+
+	for i := range candidates {
+		if candidates[i].Name == rev {
+			return &candidates[i], nil
+		}
+	}
+	if len(rev) < MinPrefixLength || !hexRef.MatchString(rev) {
+		return nil, fmt.Errorf("%w %s", ErrUnknownRef, ref)
+	}
+	var matches []*historyv1alpha1.KronoformHistory
+	for i := range candidates {
+		if strings.HasPrefix(ID(&candidates[i]), rev) {
+			matches = append(matches, &candidates[i])
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w %s", ErrUnknownRef, ref)
+	case 1:
+		return matches[0], nil
+	}
+	names := make([]string, len(matches))
+	for i, h := range matches {
+		names[i] = fmt.Sprintf("%s (%s)", ID(h), h.Name)
+	}
+	return nil, fmt.Errorf("ambiguous reference %q matches %s", ref, strings.Join(names, ", "))
+}
+
+// splitResource splits the resource scope off a reference. Resources contain a slash
+// and times do not end in one, so the colons of absolute times are not mistaken for it.
+func splitResource(ref string) (rev, resource string) {
+	i := strings.LastIndex(ref, ":")
+	if i < 0 || !strings.Contains(ref[i+1:], "/") || strings.Contains(ref[i+1:], "}") {
+		return ref, ""
+	}
+	return ref[:i], ref[i+1:]
+}
+
+// fromHead returns the history back histories before the latest one.
+func fromHead(sorted []historyv1alpha1.KronoformHistory, ref string, back int) (*historyv1alpha1.KronoformHistory, error) {
+	if back >= len(sorted) {
+		return nil, fmt.Errorf("%w %s: only %d histories are recorded", ErrUnknownRef, ref, len(sorted))
+	}
+	return &sorted[len(sorted)-1-back], nil
+}
+
+// parseAncestry counts the histories designated by a sequence of ~N and ^ suffixes.
+func parseAncestry(suffix string) (int, error) {
+	back := 0
+	for suffix != "" {
+		op := suffix[0]
+		suffix = suffix[1:]
+		digits := len(suffix) - len(strings.TrimLeft(suffix, "0123456789"))
+		n := 1
+		if digits > 0 {
+			var err error
+			if n, err = strconv.Atoi(suffix[:digits]); err != nil {
+				return 0, err
+			}
+			suffix = suffix[digits:]
+		}
+		if op == '^' && n > 1 {
+			return 0, fmt.Errorf("histories have a single parent: use HEAD~%d", n)
+		}
+		back += n
+	}
+	return back, nil
+}
+
+// ParseTime parses a time written as in @{...} references, relative to now (1h ago,
+// 2 days ago, yesterday) or absolute in the location of now (2025-01-02 15:04).
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	switch value {
+	case "now":
+		return now, nil
+	case "yesterday":
+		return now.Add(-24 * time.Hour), nil
+	}
+	if m := relativeRef.FindStringSubmatch(value); m != nil {
+		unit, ok := units[strings.TrimSuffix(m[2], "s")]
+		if !ok {
+			unit, ok = units[m[2]]
+		}
+		if !ok {
+			return time.Time{}, fmt.Errorf("unknown unit %q", m[2])
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-time.Duration(n) * unit), nil
+	}
+	for _, layout := range absoluteLayouts {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown time %q: use e.g. 1h ago, yesterday or 2025-01-02 15:04", value)
+}
+
+// resourceMatch matches the resources a reference is scoped to, or every resource if all
+// is set.
+type resourceMatch struct {
+	kind, group, namespace, name string
+	all                          bool
+}
+
+// parseResource parses kind[.group]/name or kind[.group]/namespace/name.
+func parseResource(resource string) (resourceMatch, error) {
+	parts := strings.Split(resource, "/")
+	m := resourceMatch{}
+	switch len(parts) {
+	case 2:
+		m.name = parts[1]
+	case 3:
+		m.namespace, m.name = parts[1], parts[2]
+	default:
+		return m, fmt.Errorf("invalid resource %q: expected kind[.group]/[namespace/]name", resource)
+	}
+	m.kind, m.group, _ = strings.Cut(parts[0], ".")
+	if m.kind == "" || m.name == "" {
+		return m, fmt.Errorf("invalid resource %q: expected kind[.group]/[namespace/]name", resource)
+	}
+	return m, nil
+}
+
+// MatchResource returns whether keys identify a resource written kind[.group]/name or
+// kind[.group]/namespace/name. Cluster-scoped resources match whatever the namespace.
+func MatchResource(resource string) (func(ResourceKey) bool, error) {
+	m, err := parseResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	return m.matches, nil
+}
+
+// recordsResource reports whether a history recorded a resource matching m, the way
+// collect finds it: among the resource states, or else in the applied manifests.
+// Encrypted manifests are matched against the resources the history lists.
+func recordsResource(h *historyv1alpha1.KronoformHistory, m resourceMatch) bool {
+	for _, rs := range h.Status.ResourceSnapshots {
+		if m.matches(KeyOf(rs)) {
+			return true
+		}
+	}
+	if h.Spec.Manifests == "" {
+		return false
+	}
+	if h.Spec.ManifestsEncoding == historyv1alpha1.EncodingEncrypted {
+		return len(h.Spec.ResourceNames) > 0 && listsOther(h, nil, m)
+	}
+	objects, err := manifestObjects(h.Spec.Manifests)
+	if err != nil {
+		return false
+	}
+	for _, obj := range objects {
+		if m.matches(objectKey(obj, h.Namespace)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether key identifies a resource matching m. Cluster-scoped
+// resources match whatever the namespace.
+func (m resourceMatch) matches(key ResourceKey) bool {
+	return m.all || (strings.EqualFold(key.Kind, m.kind) && key.Name == m.name &&
+		(m.group == "" || key.Group == m.group) &&
+		(m.namespace == "" || key.Namespace == "" || key.Namespace == m.namespace))
+}
+
+// mayMatch reports whether a resource of unknown group, such as one listed in the spec of a
+// history, may match m.
+func (m resourceMatch) mayMatch(key ResourceKey) bool {
+	key.Group = m.group
+	return m.matches(key)
+}
@@ -0,0 +1,124 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+func TestAssignID(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	at := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	h := newHistory("first", at)
+	h.Spec.Manifests = "kind: ConfigMap\n"
+	g.Expect(AssignID(&h)).To(gomega.Succeed())
+	g.Expect(h.Spec.ID).To(gomega.MatchRegexp(`^[0-9a-f]{12}$`))
+	g.Expect(ID(&h)).To(gomega.Equal(h.Spec.ID))
+
+	// The ID is derived from the content and kept once assigned
+	same := newHistory("first", at)
+	same.Spec.Manifests = "kind: ConfigMap\n"
+	g.Expect(AssignID(&same)).To(gomega.Succeed())
+	g.Expect(same.Spec.ID).To(gomega.Equal(h.Spec.ID))
+	other := newHistory("first", at.Add(time.Nanosecond))
+	other.Spec.Manifests = "kind: ConfigMap\n"
+	g.Expect(AssignID(&other)).To(gomega.Succeed())
+	g.Expect(other.Spec.ID).NotTo(gomega.Equal(h.Spec.ID))
+	h.Spec.Manifests = "kind: Secret\n"
+	g.Expect(AssignID(&h)).To(gomega.Succeed())
+	g.Expect(h.Spec.ID).To(gomega.Equal(same.Spec.ID))
+
+	// Histories recorded without an ID get one from their UID
+	legacy := newHistory("legacy", at)
+	legacy.UID = "0b7e3f9c-3c4e-4a8e-9d4c-1f2a3b4c5d6e"
+	g.Expect(ID(&legacy)).To(gomega.HaveLen(IDLength))
+}
+
+func TestResolve(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	now := time.Date(2025, 8, 6, 12, 0, 0, 0, time.UTC)
+	web := historyv1alpha1.ResourceSnapshot{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "default"}
+	config := historyv1alpha1.ResourceSnapshot{APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "default"}
+
+	items := []historyv1alpha1.KronoformHistory{
+		newHistory("third", now.Add(-30*time.Minute), config),
+		newHistory("first", now.Add(-3*time.Hour), web),
+		newHistory("second", now.Add(-2*time.Hour), web, config),
+	}
+	items[0].Spec.ID, items[1].Spec.ID, items[2].Spec.ID = "ab12cd34ef56", "ab12ff000000", "0f0f0f0f0f0f"
+
+	resolve := func(ref string) string {
+		h, err := Resolve(items, ref, now)
+		g.Expect(err).NotTo(gomega.HaveOccurred(), ref)
+		return h.Name
+	}
+	g.Expect(resolve("second")).To(gomega.Equal("second"))
+	g.Expect(resolve("ab12c")).To(gomega.Equal("third"))
+	g.Expect(resolve("0f0f")).To(gomega.Equal("second"))
+	g.Expect(resolve("HEAD")).To(gomega.Equal("third"))
+	g.Expect(resolve("HEAD~1")).To(gomega.Equal("second"))
+	g.Expect(resolve("HEAD^^")).To(gomega.Equal("first"))
+	g.Expect(resolve("HEAD~1~1")).To(gomega.Equal("first"))
+	g.Expect(resolve("@{1h ago}")).To(gomega.Equal("second"))
+	g.Expect(resolve("@{150 minutes ago}")).To(gomega.Equal("first"))
+	g.Expect(resolve("@{2025-08-06 11:45}")).To(gomega.Equal("third"))
+	g.Expect(resolve("@{2025-08-06T10:00:00Z}")).To(gomega.Equal("second"))
+
+	// Resource scopes count only the histories that recorded the resource
+	g.Expect(resolve("HEAD:deployment/web")).To(gomega.Equal("second"))
+	g.Expect(resolve(":Deployment.apps/default/web")).To(gomega.Equal("second"))
+	g.Expect(resolve("HEAD~1:deployment.apps/web")).To(gomega.Equal("first"))
+	g.Expect(resolve("@{1h ago}:configmap/config")).To(gomega.Equal("second"))
+	g.Expect(resolve("@{2025-08-06 11:45}:deployment/web")).To(gomega.Equal("second"))
+
+	_, err := Resolve(items, "ab12", now)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("ambiguous reference")))
+	_, err = Resolve(items, "ab1", now)
+	g.Expect(err).To(gomega.MatchError(ErrUnknownRef))
+	_, err = Resolve(items, "HEAD~3", now)
+	g.Expect(err).To(gomega.MatchError(ErrUnknownRef))
+	_, err = Resolve(items, "HEAD~1:deployment.batch/web", now)
+	g.Expect(err).To(gomega.MatchError(ErrUnknownRef))
+
+	_, err = Resolve(items, "@{4h ago}", now)
+	g.Expect(err).To(gomega.MatchError(ErrUnknownRef))
+	_, err = Resolve(items, "@{1 fortnight ago}", now)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`unknown unit "fortnight"`)))
+	_, err = Resolve(items, "HEAD^2", now)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("single parent")))
+}
+
+func TestResolveManifests(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	now := time.Date(2025, 8, 6, 12, 0, 0, 0, time.UTC)
+	config := historyv1alpha1.ResourceSnapshot{APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "default"}
+	// Histories recorded without resource states are matched by their manifests, like in
+	// the timeline, and encrypted ones by the resources they list
+	applied := newHistory("applied", now.Add(-2*time.Hour))
+	applied.Spec.Manifests = "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"
+	sealed := newHistory("sealed", now.Add(-3*time.Hour))
+	sealed.Spec.Manifests, sealed.Spec.ManifestsEncoding = "c2VhbGVk", historyv1alpha1.EncodingEncrypted
+	sealed.Spec.ResourceTypes, sealed.Spec.ResourceNames = []string{"Deployment"}, []string{"web"}
+	sealed.Spec.ResourceNamespaces = []string{"default"}
+	items := []historyv1alpha1.KronoformHistory{newHistory("latest", now.Add(-time.Hour), config), applied, sealed}
+
+	resolve := func(ref string) string {
+		h, err := Resolve(items, ref, now)
+		g.Expect(err).NotTo(gomega.HaveOccurred(), ref)
+		return h.Name
+	}
+	g.Expect(resolve(":deployment.apps/web")).To(gomega.Equal("applied"))
+	g.Expect(resolve("HEAD~1:deployment/default/web")).To(gomega.Equal("sealed"))
+	g.Expect(resolve(":configmap/config")).To(gomega.Equal("latest"))
+
+	_, err := Resolve(items, "HEAD~1:configmap/config", now)
+	g.Expect(err).To(gomega.MatchError(ErrUnknownRef))
+	_, err = Resolve(items, ":deployment/default/api", now)
+	g.Expect(err).To(gomega.MatchError(ErrUnknownRef))
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/fielddiff"
+)
+
+// OperationApplied marks timeline entries taken from the manifests of a history that did
+// not record the state of the resource. Their After state is the applied manifest, which
+// holds only the fields it sets.
+const OperationApplied = "Applied"
+
+// Entry is a change of a resource recorded by a history.
+type Entry struct {
+	History *historyv1alpha1.KronoformHistory
+	Key     ResourceKey
+	// Operation is the recorded operation, or OperationApplied
+	Operation string
+	// Before and After are the states around the change. After is empty when the
+	// resource was deleted, and Before when it was created or is unknown. For entries
+	// taken from manifests, Before is the manifest applied previously.
+	Before, After string
+}
+
+// Partial reports whether the After state of the entry holds only the applied fields.
+func (e Entry) Partial() bool {
+	return e.Operation == OperationApplied
+}
+
+// Timeline returns the changes of a resource recorded by items, oldest first. The resource
+// is written kind[.group]/name or kind[.group]/namespace/name; cluster-scoped resources
+// match whatever the namespace. Histories that recorded the state of the resource
+// contribute it; histories that recorded only manifests contribute the manifest of the
+// resource when it differs from the previous one. items must be decoded, as returned by
+// the storage package, and are not modified.
+func Timeline(items []historyv1alpha1.KronoformHistory, resource string) ([]Entry, error) {
+	match, err := parseResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	return collect(items, match)
+}
+
+// Changes returns the changes of every resource recorded by items, oldest first.
+func Changes(items []historyv1alpha1.KronoformHistory) ([]Entry, error) {
+	return collect(items, resourceMatch{all: true})
+}
+
+// collect returns the changes recorded by items of the resources matching m, oldest first.
+func collect(items []historyv1alpha1.KronoformHistory, m resourceMatch) ([]Entry, error) {
+	sorted := make([]*historyv1alpha1.KronoformHistory, len(items))
+	for i := range items {
+		sorted[i] = &items[i]
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return Less(sorted[i], sorted[j])
+	})
+
+	var entries []Entry
+	lastApplied := map[ResourceKey]map[string]interface{}{}
+	for _, h := range sorted {
+		if err := unavailable(h); err != nil {
+			if m.all || recordsResource(h, m) || (len(h.Spec.ResourceNames) > 0 && listsOther(h, nil, m)) {
+				return nil, err
+			}
+			continue
+		}
+		recorded := map[ResourceKey]bool{}
+		for _, rs := range h.Status.ResourceSnapshots {
+			key := KeyOf(rs)
+			if !m.matches(key) {
+				continue
+			}
+			if rs.Encoding == historyv1alpha1.EncodingEncrypted {
+				return nil, sealed(h, "states of "+key.String())
+			}
+			recorded[key] = true
+			entries = append(entries, Entry{History: h, Key: key, Operation: rs.Operation, Before: rs.Before, After: rs.After})
+		}
+		if h.Spec.Manifests == "" {
+			continue
+		}
+		if h.Spec.ManifestsEncoding == historyv1alpha1.EncodingEncrypted {
+			if listsOther(h, recorded, m) {
+				return nil, sealed(h, "manifests")
+			}
+			continue
+		}
+		objects, err := manifestObjects(h.Spec.Manifests)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifests of history %s: %w", h.Name, err)
+		}
+		for _, obj := range objects {
+			key := objectKey(obj, h.Namespace)
+			if recorded[key] || !m.matches(key) {
+				continue
+			}
+			normalized, err := fielddiff.Normalize(obj)
+			if err != nil {
+				return nil, err
+			}
+			previous, applied := lastApplied[key]
+			if applied && reflect.DeepEqual(previous, normalized) {
+				continue
+			}
+			lastApplied[key] = normalized
+			entry := Entry{History: h, Key: key, Operation: OperationApplied}
+			if entry.After, err = marshalState(normalized); err != nil {
+				return nil, err
+			}
+			if applied {
+				if entry.Before, err = marshalState(previous); err != nil {
+					return nil, err
+				}
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// listsOther reports whether the manifests of h may hold a resource matching m that is not
+// among the recorded states. Histories list the kinds of the resources they applied without
+// their group, and histories that list no resources may hold any.
+func listsOther(h *historyv1alpha1.KronoformHistory, recorded map[ResourceKey]bool, m resourceMatch) bool {
+	if len(h.Spec.ResourceNames) == 0 {
+		return true
+	}
+	for i, name := range h.Spec.ResourceNames {
+		key := ResourceKey{Name: name}
+		if i < len(h.Spec.ResourceTypes) {
+			key.Kind = h.Spec.ResourceTypes[i]
+		}
+		if i < len(h.Spec.ResourceNamespaces) {
+			key.Namespace = h.Spec.ResourceNamespaces[i]
+		}
+		if !m.mayMatch(key) {
+			continue
+		}
+		covered := false
+		for k := range recorded {
+			covered = covered || (strings.EqualFold(k.Kind, key.Kind) && k.Namespace == key.Namespace && k.Name == key.Name)
+		}
+		if !covered {
+			return true
+		}
+	}
+	return false
+}
+
+func marshalState(obj map[string]interface{}) (string, error) {
+	state, err := yaml.Marshal(obj)
+	return string(state), err
+}
+
+// State is the state of a resource at a point in time.
+type State struct {
+	Key ResourceKey
+	// State is the recorded state of the resource at the time, empty when it did not
+	// exist as far as recorded
+	State string
+	// Partial is set when State is an applied manifest rather than a recorded state
+	Partial bool
+	// At is the last change of the resource at or before the time, nil when it was
+	// first recorded after it
+	At *Entry
+	// Latest is the last recorded change of the resource
+	Latest *Entry
+}
+
+// Present reports whether the resource existed at the time, as far as recorded.
+func (s State) Present() bool {
+	return s.State != ""
+}
+
+// StatesAt reconstructs the state at a time of every resource recorded by items from
+// their timelines, ordered by key. A resource first recorded after the time by a change
+// that recorded its state before is reconstructed in that state. Resources that did not
+// exist at the time are included, so that they can be told apart from resources never
+// recorded.
+func StatesAt(items []historyv1alpha1.KronoformHistory, at time.Time) ([]State, error) {
+	entries, err := Changes(items)
+	if err != nil {
+		return nil, err
+	}
+	states := map[ResourceKey]*State{}
+	var keys []ResourceKey
+	for i := range entries {
+		entry := &entries[i]
+		state, ok := states[entry.Key]
+		if !ok {
+			state = &State{Key: entry.Key}
+			states[entry.Key] = state
+			keys = append(keys, entry.Key)
+			if RecordedAt(entry.History).After(at) && !entry.Partial() {
+				state.State = entry.Before
+			}
+		}
+		if !RecordedAt(entry.History).After(at) {
+			state.At = entry
+			state.State, state.Partial = entry.After, entry.Partial()
+		}
+		state.Latest = entry
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	result := make([]State, len(keys))
+	for i, key := range keys {
+		result[i] = *states[key]
+	}
+	return result, nil
+}
+
+// manifestObjects decodes the documents of multi-document manifests.
+func manifestObjects(manifests string) ([]map[string]interface{}, error) {
+	var objects []map[string]interface{}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifests), 4096)
+	for {
+		var obj map[string]interface{}
+		err := decoder.Decode(&obj)
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if obj != nil {
+			objects = append(objects, obj)
+		}
+	}
+}
+
+// objectKey returns the key of a manifest object. Objects without a namespace are
+// applied to the namespace the history is stored in.
+func objectKey(obj map[string]interface{}, namespace string) ResourceKey {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	if ns, _ := metadata["namespace"].(string); ns != "" {
+		namespace = ns
+	}
+	gv, _ := schema.ParseGroupVersion(apiVersion)
+	return ResourceKey{Group: gv.Group, Kind: kind, Namespace: namespace, Name: name}
+}
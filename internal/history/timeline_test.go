@@ -0,0 +1,165 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+func configMapState(data string) string {
+	return "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  namespace: default\ndata:\n" + data
+}
+
+func TestTimelineAndBlame(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	base := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	config := func(operation, before, after string) historyv1alpha1.ResourceSnapshot {
+		return historyv1alpha1.ResourceSnapshot{
+			APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "default",
+			Operation: operation, Before: before, After: after,
+		}
+	}
+
+	created := newHistory("created", base, config("Created", "", configMapState("  color: blue\n  size: small\n")))
+	applied := newHistory("applied", base.Add(time.Hour))
+	applied.Spec.Manifests = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  color: red\n  size: small\n" +
+		"---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: other\n"
+	reapplied := newHistory("reapplied", base.Add(2*time.Hour))
+	reapplied.Spec.Manifests = applied.Spec.Manifests
+	observed := newHistory("observed", base.Add(3*time.Hour), config("Updated",
+		// shape was added outside of the recorded histories
+		configMapState("  color: red\n  size: small\n  shape: round\n"),
+		configMapState("  color: red\n  size: large\n  shape: round\n")))
+	unrelated := newHistory("unrelated", base.Add(4*time.Hour), historyv1alpha1.ResourceSnapshot{
+		APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "other", Operation: "Updated",
+	})
+
+	items := []historyv1alpha1.KronoformHistory{unrelated, observed, reapplied, applied, created}
+	entries, err := Timeline(items, "configmap/default/config")
+	g.Expect(err).To(gomega.BeNil())
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.History.Name
+	}
+	// reapplying the same manifest is not a change
+	g.Expect(names).To(gomega.Equal([]string{"created", "applied", "observed"}))
+	g.Expect(entries[1].Operation).To(gomega.Equal(OperationApplied))
+	g.Expect(entries[1].Key).To(gomega.Equal(ResourceKey{Kind: "ConfigMap", Namespace: "default", Name: "config"}))
+
+	lines, err := Blame(entries)
+	g.Expect(err).To(gomega.BeNil())
+	owners := map[string]string{}
+	for _, line := range lines {
+		owner := "-"
+		if line.Entry != nil {
+			owner = line.Entry.History.Name
+		}
+		owners[line.Path] = owner
+	}
+	g.Expect(owners).To(gomega.HaveKeyWithValue(".data.color", "applied"))
+	g.Expect(owners).To(gomega.HaveKeyWithValue(".data.size", "observed"))
+	g.Expect(owners).To(gomega.HaveKeyWithValue(".data.shape", "-"))
+	g.Expect(owners).To(gomega.HaveKeyWithValue(".metadata.name", "created"))
+
+	_, err = Timeline(items, "configmap")
+	g.Expect(err).NotTo(gomega.BeNil())
+
+	deleted := newHistory("deleted", base.Add(5*time.Hour), config("Deleted", configMapState("  color: red\n"), ""))
+	entries, err = Timeline(append(items, deleted), "configmap/default/config")
+	g.Expect(err).To(gomega.BeNil())
+	_, err = Blame(entries)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("was deleted by history")))
+}
+
+func TestTimelineSealed(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	base := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	settings := historyv1alpha1.ResourceSnapshot{
+		APIVersion: "v1", Kind: "ConfigMap", Name: "settings", Namespace: "default", Operation: "Updated",
+		Before: "c2VhbGVk", After: "c2VhbGVk", Encoding: historyv1alpha1.EncodingEncrypted,
+	}
+	config := historyv1alpha1.ResourceSnapshot{
+		APIVersion: "v1", Kind: "ConfigMap", Name: "config", Namespace: "default", Operation: "Created",
+		After: configMapState("  color: blue\n"),
+	}
+	encrypted := newHistory("encrypted", base, settings)
+	applied := newHistory("applied", base.Add(time.Hour))
+	applied.Spec.Manifests, applied.Spec.ManifestsEncoding = "c2VhbGVk", historyv1alpha1.EncodingEncrypted
+	applied.Spec.ResourceTypes, applied.Spec.ResourceNames = []string{"Deployment"}, []string{"web"}
+	applied.Spec.ResourceNamespaces = []string{"default"}
+	items := []historyv1alpha1.KronoformHistory{encrypted, applied, newHistory("plain", base.Add(2*time.Hour), config)}
+
+	// Histories whose encrypted content is not needed do not get in the way
+	entries, err := Timeline(items, "configmap/default/config")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(entries).To(gomega.HaveLen(1))
+	g.Expect(entries[0].History.Name).To(gomega.Equal("plain"))
+
+	_, err = Timeline(items, "configmap/default/settings")
+	g.Expect(err).To(gomega.MatchError(payload.ErrEncrypted))
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("states of ConfigMap/default/settings recorded by history encrypted")))
+	_, err = Timeline(items, "deployment.apps/default/web")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("manifests recorded by history applied")))
+	_, err = Changes(items)
+	g.Expect(err).To(gomega.MatchError(payload.ErrEncrypted))
+
+	// Histories returned without their content are only needed for what they recorded
+	missing := newHistory("missing", base.Add(3*time.Hour), historyv1alpha1.ResourceSnapshot{
+		APIVersion: "v1", Kind: "ConfigMap", Name: "archived", Namespace: "default", Operation: "Updated",
+	})
+	missing.Annotations = map[string]string{UnreadableAnnotation: "archive no longer exists"}
+	items = append(items, missing)
+	_, err = Timeline(items, "configmap/default/config")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = Timeline(items, "configmap/default/archived")
+	g.Expect(err).To(gomega.MatchError(ErrUnreadable))
+	g.Expect(Readable(&missing)).To(gomega.MatchError(gomega.ContainSubstring("archive no longer exists")))
+}
+
+func TestStatesAt(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	base := time.Date(2025, 8, 6, 10, 0, 0, 0, time.UTC)
+	resource := func(kind, name, operation, before, after string) historyv1alpha1.ResourceSnapshot {
+		return historyv1alpha1.ResourceSnapshot{
+			APIVersion: "v1", Kind: kind, Name: name, Namespace: "default",
+			Operation: operation, Before: before, After: after,
+		}
+	}
+	items := []historyv1alpha1.KronoformHistory{
+		newHistory("create", base, resource("ConfigMap", "config", "Created", "", configMapState("  color: blue\n"))),
+		newHistory("update", base.Add(time.Hour), resource("ConfigMap", "config", "Updated",
+			configMapState("  color: blue\n"), configMapState("  color: red\n"))),
+		newHistory("remove", base.Add(2*time.Hour), resource("Secret", "old", "Deleted", "kind: Secret\n", "")),
+		newHistory("add", base.Add(3*time.Hour), resource("Service", "web", "Created", "", "kind: Service\n")),
+	}
+
+	states, err := StatesAt(items, base.Add(90*time.Minute))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(states).To(gomega.HaveLen(3))
+
+	g.Expect(states[0].Key.Kind).To(gomega.Equal("ConfigMap"))
+	g.Expect(states[0].Present()).To(gomega.BeTrue())
+	g.Expect(states[0].At.History.Name).To(gomega.Equal("update"))
+	g.Expect(states[0].State).To(gomega.ContainSubstring("color: red"))
+
+	// the secret was first recorded when it was deleted, after the time
+	g.Expect(states[1].Key.Kind).To(gomega.Equal("Secret"))
+	g.Expect(states[1].At).To(gomega.BeNil())
+	g.Expect(states[1].State).To(gomega.Equal("kind: Secret\n"))
+
+	g.Expect(states[2].Key.Kind).To(gomega.Equal("Service"))
+	g.Expect(states[2].Present()).To(gomega.BeFalse())
+	g.Expect(states[2].Latest.History.Name).To(gomega.Equal("add"))
+
+	states, err = StatesAt(items, base.Add(150*time.Minute))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(states[1].At.History.Name).To(gomega.Equal("remove"))
+	g.Expect(states[1].Present()).To(gomega.BeFalse())
+}
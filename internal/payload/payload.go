@@ -0,0 +1,383 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package payload stores large manifests and resource states inside records. Content above
+// CompressThreshold is gzip-compressed and base64-encoded, and encoded manifests and states
+// above ChunkThreshold are split across KronoformChunk objects owned by the record. Records
+// written before encoding existed carry no marker and are read as plain text. Encrypted
+// content is stored and chunked like encoded content, but only decrypted by the envelope
+// package.
+package payload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+const (
+	// CompressThreshold is the size above which content is compressed.
+	CompressThreshold = 16 << 10
+	// ChunkThreshold is the encoded size above which manifests and states are moved to chunks.
+	ChunkThreshold = 512 << 10
+	// ChunkSize is the size of the data held by each chunk.
+	ChunkSize = 512 << 10
+	// InlineBudget is the total size of the manifests and states kept inside a record, which
+	// leaves room for the rest of it under the 1.5MiB etcd limit on the size of an object.
+	InlineBudget = 1 << 20
+	// MaxDecodedSize bounds the size of decompressed content.
+	MaxDecodedSize = 64 << 20
+)
+
+// ErrEncrypted is returned when decoding encrypted content, which needs a key.
+var ErrEncrypted = errors.New("content is encrypted")
+
+// Encode returns content as stored in a record, compressing it when it is larger than
+// CompressThreshold.
+func Encode(content string) (data, encoding string, err error) {
+	if len(content) <= CompressThreshold {
+		return content, historyv1alpha1.EncodingPlain, nil
+	}
+	if data, err = encodeAlways(content); err != nil {
+		return "", "", err
+	}
+	return data, historyv1alpha1.EncodingGzipBase64, nil
+}
+
+// Decode returns the content stored as data with the given encoding.
+func Decode(data, encoding string) (string, error) {
+	switch encoding {
+	case historyv1alpha1.EncodingPlain:
+		return data, nil
+	case historyv1alpha1.EncodingGzipBase64:
+		compressed, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return "", fmt.Errorf("invalid base64 content: %w", err)
+		}
+		return Decompress(compressed)
+	case historyv1alpha1.EncodingEncrypted:
+		return "", ErrEncrypted
+	default:
+		return "", fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// Compress gzip-compresses content.
+func Compress(content string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress returns the content of gzip-compressed data, up to MaxDecodedSize.
+func Decompress(compressed []byte) (string, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", fmt.Errorf("invalid gzip content: %w", err)
+	}
+	content, err := io.ReadAll(io.LimitReader(reader, MaxDecodedSize+1))
+	if err != nil {
+		return "", fmt.Errorf("invalid gzip content: %w", err)
+	}
+	if len(content) > MaxDecodedSize {
+		return "", fmt.Errorf("decoded content exceeds %d bytes", MaxDecodedSize)
+	}
+	return string(content), nil
+}
+
+// Pack encodes manifests for a record. When the encoded manifests are larger than
+// ChunkThreshold, data is empty and the encoded manifests are returned as chunks to be
+// stored with CreateChunks once the record exists.
+func Pack(content string) (data, encoding string, chunks []string, err error) {
+	data, encoding, err = Encode(content)
+	if err != nil {
+		return "", "", nil, err
+	}
+	data, chunks = Split(data)
+	return data, encoding, chunks, nil
+}
+
+// PackStored prepares manifests as set on a record for storage: plain manifests are
+// packed, while encoded or encrypted ones are only split into chunks when too large.
+func PackStored(data, encoding string) (string, string, []string, error) {
+	if encoding == historyv1alpha1.EncodingPlain {
+		return Pack(data)
+	}
+	data, chunks := Split(data)
+	return data, encoding, chunks, nil
+}
+
+// Split moves encoded data larger than ChunkThreshold to chunks, returning empty data.
+func Split(data string) (string, []string) {
+	if len(data) <= ChunkThreshold {
+		return data, nil
+	}
+	return "", chunk(data)
+}
+
+// chunk cuts data into chunks of ChunkSize.
+func chunk(data string) []string {
+	var chunks []string
+	for start := 0; start < len(data); start += ChunkSize {
+		end := min(start+ChunkSize, len(data))
+		chunks = append(chunks, data[start:end])
+	}
+	return chunks
+}
+
+// CreateChunks stores chunks of a part of the payload of owner, which must already exist:
+// its manifests for the empty part, or a part named by StatesPart. The chunks are owned by
+// the record so that they are deleted with it.
+func CreateChunks(ctx context.Context, c client.Client, owner client.Object, part string, chunks []string) error {
+	for i, data := range chunks {
+		name := fmt.Sprintf("%s-%d", owner.GetName(), i)
+		if part != "" {
+			name = fmt.Sprintf("%s-%s-%d", owner.GetName(), part, i)
+		}
+		chunk := &historyv1alpha1.KronoformChunk{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: owner.GetNamespace(),
+				Labels:    map[string]string{historyv1alpha1.ChunkOwnerUIDLabel: string(owner.GetUID())},
+			},
+			Spec: historyv1alpha1.KronoformChunkSpec{Index: int32(i), Data: data, Part: part},
+		}
+		if err := controllerutil.SetOwnerReference(owner, chunk, c.Scheme()); err != nil {
+			return err
+		}
+		if err := c.Create(ctx, chunk); err != nil {
+			return fmt.Errorf("failed to create chunk %d of %s: %w", i, owner.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// Unpack returns the decoded manifests of owner, reassembling them from its chunks if
+// count is greater than zero.
+func Unpack(ctx context.Context, c client.Reader, owner client.Object, data, encoding string, count int32) (string, error) {
+	data, err := Reassemble(ctx, c, owner, data, count)
+	if err != nil {
+		return "", err
+	}
+	return Decode(data, encoding)
+}
+
+// Reassemble returns the encoded manifests of owner, joining its chunks if count is
+// greater than zero.
+func Reassemble(ctx context.Context, c client.Reader, owner client.Object, data string, count int32) (string, error) {
+	if count == 0 {
+		return data, nil
+	}
+	parts, err := listChunks(ctx, c, owner)
+	if err != nil {
+		return "", err
+	}
+	return join(owner, "manifests", parts[""], count)
+}
+
+// ReassembleStates moves the resource states of h that are stored in chunks back inline.
+func ReassembleStates(ctx context.Context, c client.Reader, h *historyv1alpha1.KronoformHistory) error {
+	var parts map[string][]historyv1alpha1.KronoformChunk
+	for i := range h.Status.ResourceSnapshots {
+		rs := &h.Status.ResourceSnapshots[i]
+		if rs.BeforeChunks == 0 && rs.AfterChunks == 0 {
+			continue
+		}
+		if parts == nil {
+			var err error
+			if parts, err = listChunks(ctx, c, h); err != nil {
+				return err
+			}
+		}
+		for _, state := range []struct {
+			part  string
+			data  *string
+			count *int32
+		}{
+			{StatesPart(i, false), &rs.Before, &rs.BeforeChunks},
+			{StatesPart(i, true), &rs.After, &rs.AfterChunks},
+		} {
+			if *state.count == 0 {
+				continue
+			}
+			data, err := join(h, state.part, parts[state.part], *state.count)
+			if err != nil {
+				return err
+			}
+			*state.data, *state.count = data, 0
+		}
+	}
+	return nil
+}
+
+// StatesPart returns the part of the chunks holding the before or after state of the i-th
+// resource snapshot of a history.
+func StatesPart(i int, after bool) string {
+	if after {
+		return fmt.Sprintf("states.%d.after", i)
+	}
+	return fmt.Sprintf("states.%d.before", i)
+}
+
+// SplitStates moves encoded resource states larger than ChunkThreshold to chunks, then the
+// largest of the remaining states until those left inline fit in budget, returning the
+// chunks of each part to be stored with CreateChunks once the history exists.
+func SplitStates(status *historyv1alpha1.KronoformHistoryStatus, budget int) map[string][]string {
+	type state struct {
+		part  string
+		data  *string
+		count *int32
+	}
+	var inline []state
+	size := 0
+	parts := map[string][]string{}
+	for i := range status.ResourceSnapshots {
+		rs := &status.ResourceSnapshots[i]
+		for _, s := range []state{
+			{StatesPart(i, false), &rs.Before, &rs.BeforeChunks},
+			{StatesPart(i, true), &rs.After, &rs.AfterChunks},
+		} {
+			var chunks []string
+			if *s.data, chunks = Split(*s.data); chunks != nil {
+				*s.count, parts[s.part] = int32(len(chunks)), chunks
+			} else if *s.data != "" {
+				inline = append(inline, s)
+				size += len(*s.data)
+			}
+		}
+	}
+
+	sort.SliceStable(inline, func(i, j int) bool {
+		return len(*inline[i].data) > len(*inline[j].data)
+	})
+	for _, s := range inline {
+		if size <= budget {
+			break
+		}
+		chunks := chunk(*s.data)
+		size -= len(*s.data)
+		*s.data, *s.count, parts[s.part] = "", int32(len(chunks)), chunks
+	}
+	return parts
+}
+
+// listChunks returns the chunks of owner by part.
+func listChunks(ctx context.Context, c client.Reader, owner client.Object) (map[string][]historyv1alpha1.KronoformChunk, error) {
+	chunks := &historyv1alpha1.KronoformChunkList{}
+	if err := c.List(ctx, chunks, client.InNamespace(owner.GetNamespace()),
+		client.MatchingLabels{historyv1alpha1.ChunkOwnerUIDLabel: string(owner.GetUID())}); err != nil {
+		return nil, fmt.Errorf("failed to list chunks of %s: %w", owner.GetName(), err)
+	}
+	parts := map[string][]historyv1alpha1.KronoformChunk{}
+	for _, chunk := range chunks.Items {
+		parts[chunk.Spec.Part] = append(parts[chunk.Spec.Part], chunk)
+	}
+	return parts, nil
+}
+
+// join returns the data of the chunks of a part of the payload of owner in order.
+func join(owner client.Object, part string, chunks []historyv1alpha1.KronoformChunk, count int32) (string, error) {
+	if int32(len(chunks)) != count {
+		return "", fmt.Errorf("%s has %d of %d chunks of its %s", owner.GetName(), len(chunks), count, part)
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Spec.Index < chunks[j].Spec.Index
+	})
+	var buf bytes.Buffer
+	for i, chunk := range chunks {
+		if chunk.Spec.Index != int32(i) {
+			return "", fmt.Errorf("%s is missing chunk %d of its %s", owner.GetName(), i, part)
+		}
+		buf.WriteString(chunk.Spec.Data)
+	}
+	return buf.String(), nil
+}
+
+// SnapshotManifests returns the decoded manifests of a snapshot.
+func SnapshotManifests(ctx context.Context, c client.Reader, s *historyv1alpha1.KronoformSnapshot) (string, error) {
+	return Unpack(ctx, c, s, s.Spec.Manifests, s.Spec.ManifestsEncoding, s.Spec.ManifestsChunks)
+}
+
+// HistoryManifests returns the decoded manifests of a history.
+func HistoryManifests(ctx context.Context, c client.Reader, h *historyv1alpha1.KronoformHistory) (string, error) {
+	return Unpack(ctx, c, h, h.Spec.Manifests, h.Spec.ManifestsEncoding, h.Spec.ManifestsChunks)
+}
+
+// EncodeStates stores the before and after states of a resource snapshot. Both states
+// share one encoding, chosen by the larger of the two.
+func EncodeStates(rs *historyv1alpha1.ResourceSnapshot, before, after string) error {
+	if max(len(before), len(after)) <= CompressThreshold {
+		rs.Before, rs.After, rs.Encoding = before, after, historyv1alpha1.EncodingPlain
+		return nil
+	}
+	var err error
+	if rs.Before, err = encodeAlways(before); err != nil {
+		return err
+	}
+	if rs.After, err = encodeAlways(after); err != nil {
+		return err
+	}
+	rs.Encoding = historyv1alpha1.EncodingGzipBase64
+	return nil
+}
+
+// States returns the decoded before and after states of a resource snapshot.
+func States(rs historyv1alpha1.ResourceSnapshot) (before, after string, err error) {
+	if before, err = decodeState(rs.Before, rs.Encoding); err != nil {
+		return "", "", err
+	}
+	if after, err = decodeState(rs.After, rs.Encoding); err != nil {
+		return "", "", err
+	}
+	return before, after, nil
+}
+
+// encodeAlways compresses content regardless of its size. Empty content stays empty so
+// that a missing state can still be told apart.
+func encodeAlways(content string) (string, error) {
+	if content == "" {
+		return "", nil
+	}
+	compressed, err := Compress(content)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(compressed), nil
+}
+
+func decodeState(data, encoding string) (string, error) {
+	if data == "" {
+		return "", nil
+	}
+	return Decode(data, encoding)
+}
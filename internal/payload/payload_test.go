@@ -0,0 +1,139 @@
+package payload
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	small := "apiVersion: v1\nkind: ConfigMap\n"
+	data, encoding, err := Encode(small)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(encoding).To(gomega.Equal(historyv1alpha1.EncodingPlain))
+	g.Expect(data).To(gomega.Equal(small))
+
+	large := strings.Repeat(small, CompressThreshold/len(small)+1)
+	data, encoding, err = Encode(large)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(encoding).To(gomega.Equal(historyv1alpha1.EncodingGzipBase64))
+	g.Expect(len(data)).To(gomega.BeNumerically("<", len(large)))
+	g.Expect(Decode(data, encoding)).To(gomega.Equal(large))
+
+	_, err = Decode("not base64!", historyv1alpha1.EncodingGzipBase64)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("invalid base64")))
+	_, err = Decode("bm90IGd6aXA=", historyv1alpha1.EncodingGzipBase64)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("invalid gzip")))
+	_, err = Decode(small, "zstd")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("unknown encoding")))
+}
+
+func TestPackUnpack(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	if err := historyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add scheme: %v", err)
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	// Random content does not compress, so it still exceeds the chunk threshold once encoded
+	letters := "abcdefghijklmnopqrstuvwxyz0123456789"
+	random := rand.New(rand.NewSource(1))
+	var builder strings.Builder
+	for builder.Len() < 2*ChunkThreshold {
+		builder.WriteByte(letters[random.Intn(len(letters))])
+	}
+	content := builder.String()
+
+	data, encoding, chunks, err := Pack(content)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(data).To(gomega.BeEmpty())
+	g.Expect(encoding).To(gomega.Equal(historyv1alpha1.EncodingGzipBase64))
+	g.Expect(len(chunks)).To(gomega.BeNumerically(">", 1))
+
+	snapshot := &historyv1alpha1.KronoformSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "large", Namespace: "default", UID: types.UID("large")},
+		Spec:       historyv1alpha1.KronoformSnapshotSpec{ManifestsEncoding: encoding, ManifestsChunks: int32(len(chunks))},
+	}
+	g.Expect(fakeClient.Create(ctx, snapshot)).To(gomega.Succeed())
+
+	_, err = SnapshotManifests(ctx, fakeClient, snapshot)
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("has 0 of")))
+
+	g.Expect(CreateChunks(ctx, fakeClient, snapshot, "", chunks)).To(gomega.Succeed())
+	g.Expect(SnapshotManifests(ctx, fakeClient, snapshot)).To(gomega.Equal(content))
+
+	stored := &historyv1alpha1.KronoformChunkList{}
+	g.Expect(fakeClient.List(ctx, stored)).To(gomega.Succeed())
+	g.Expect(stored.Items).To(gomega.HaveLen(len(chunks)))
+	g.Expect(stored.Items[0].OwnerReferences).To(gomega.HaveLen(1))
+	g.Expect(stored.Items[0].OwnerReferences[0].Name).To(gomega.Equal("large"))
+}
+
+func TestEncodeStates(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	rs := historyv1alpha1.ResourceSnapshot{}
+	g.Expect(EncodeStates(&rs, "", "kind: ConfigMap\n")).To(gomega.Succeed())
+	g.Expect(rs.Encoding).To(gomega.Equal(historyv1alpha1.EncodingPlain))
+	g.Expect(rs.After).To(gomega.Equal("kind: ConfigMap\n"))
+
+	large := "kind: ConfigMap\ndata:\n  blob: " + strings.Repeat("x", CompressThreshold) + "\n"
+	g.Expect(EncodeStates(&rs, "", large)).To(gomega.Succeed())
+	g.Expect(rs.Encoding).To(gomega.Equal(historyv1alpha1.EncodingGzipBase64))
+	g.Expect(rs.Before).To(gomega.BeEmpty())
+
+	before, after, err := States(rs)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(before).To(gomega.BeEmpty())
+	g.Expect(after).To(gomega.Equal(large))
+}
+
+func TestSplitStatesBudget(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// Each state fits under the chunk threshold, but together they exceed the budget
+	status := &historyv1alpha1.KronoformHistoryStatus{}
+	for i := range 4 {
+		status.ResourceSnapshots = append(status.ResourceSnapshots, historyv1alpha1.ResourceSnapshot{
+			After:    strings.Repeat("x", ChunkThreshold-i),
+			Encoding: historyv1alpha1.EncodingGzipBase64,
+		})
+	}
+	status.ResourceSnapshots[0].Before = "small"
+
+	parts := SplitStates(status, InlineBudget)
+	inline := 0
+	for _, rs := range status.ResourceSnapshots {
+		inline += len(rs.Before) + len(rs.After)
+	}
+	g.Expect(inline).To(gomega.BeNumerically("<=", InlineBudget))
+	// The largest states are moved first, and small ones are kept inline
+	g.Expect(parts).To(gomega.HaveLen(2))
+	g.Expect(parts).To(gomega.HaveKey(StatesPart(0, true)))
+	g.Expect(parts).To(gomega.HaveKey(StatesPart(1, true)))
+	g.Expect(status.ResourceSnapshots[0].After).To(gomega.BeEmpty())
+	g.Expect(status.ResourceSnapshots[0].AfterChunks).To(gomega.Equal(int32(1)))
+	g.Expect(status.ResourceSnapshots[0].Before).To(gomega.Equal("small"))
+	g.Expect(status.ResourceSnapshots[2].AfterChunks).To(gomega.BeZero())
+	g.Expect(status.ResourceSnapshots[3].After).To(gomega.HaveLen(ChunkThreshold - 3))
+	g.Expect(strings.Join(parts[StatesPart(1, true)], "") == strings.Repeat("x", ChunkThreshold-1)).To(gomega.BeTrue())
+
+	// States are only moved when the manifests leave too little room
+	status.ResourceSnapshots = []historyv1alpha1.ResourceSnapshot{{After: "small"}}
+	g.Expect(SplitStates(status, InlineBudget)).To(gomega.BeEmpty())
+	g.Expect(SplitStates(status, 0)).To(gomega.HaveKey(StatesPart(0, true)))
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// segmentKind is the kind of a step of a path.
+type segmentKind int
+
+const (
+	// segmentField selects a map key.
+	segmentField segmentKind = iota
+	// segmentWildcard selects every map value or list element.
+	segmentWildcard
+	// segmentIndex selects a list element.
+	segmentIndex
+	// segmentFilter selects the list elements whose field matches.
+	segmentFilter
+	// segmentDescend applies the rest of the path at every depth.
+	segmentDescend
+)
+
+// segment is a step of a compiled path.
+type segment struct {
+	kind  segmentKind
+	name  string
+	index int
+	// filter: field compared, and either an exact value or a pattern
+	field   string
+	value   string
+	pattern *regexp.Regexp
+}
+
+// path is a compiled JSONPath expression.
+type path []segment
+
+// parsePath compiles the subset of JSONPath used by redaction rules:
+//
+//	$.spec.template.spec.containers[*].env[?(@.name=~"PASSWORD|TOKEN")].value
+//	$..env[?(@.name=="API_KEY")].value
+//	.data['tls.key']
+//
+// Fields are selected with .name or ['name'], every value with .* or [*], list elements
+// with [n], and list elements by a field with [?(@.field=="value")] or, with a regular
+// expression, [?(@.field=~"pattern")]. ".." applies the rest of the path at every depth.
+// The leading $ is optional.
+func parsePath(expr string) (path, error) {
+	s := strings.TrimSpace(expr)
+	s = strings.TrimPrefix(s, "$")
+	if s == "" {
+		return nil, fmt.Errorf("invalid path %q: the path is empty", expr)
+	}
+	var p path
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			p = append(p, segment{kind: segmentDescend})
+			s = s[2:]
+			if s == "" || s[0] == '.' {
+				return nil, fmt.Errorf("invalid path %q: .. must be followed by a field", expr)
+			}
+			if s[0] != '[' {
+				s = "." + s
+			}
+		case s[0] == '.':
+			name, rest := splitName(s[1:])
+			if name == "" {
+				return nil, fmt.Errorf("invalid path %q: missing field name", expr)
+			}
+			if name == "*" {
+				p = append(p, segment{kind: segmentWildcard})
+			} else {
+				p = append(p, segment{kind: segmentField, name: name})
+			}
+			s = rest
+		case s[0] == '[':
+			end := closingBracket(s)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unterminated [", expr)
+			}
+			seg, err := parseBracket(s[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", expr, err)
+			}
+			p = append(p, seg)
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", expr, s)
+		}
+	}
+	if p[len(p)-1].kind == segmentDescend {
+		return nil, fmt.Errorf("invalid path %q: the path ends with ..", expr)
+	}
+	return p, nil
+}
+
+// splitName splits a field name from the rest of a path.
+func splitName(s string) (string, string) {
+	end := strings.IndexAny(s, ".[")
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], s[end:]
+}
+
+// closingBracket returns the index of the ] closing the bracket s starts with, skipping
+// quoted strings.
+func closingBracket(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote != 0 && s[i] == '\\':
+			i++
+		case quote != 0 && s[i] == quote:
+			quote = 0
+		case quote == 0 && (s[i] == '"' || s[i] == '\''):
+			quote = s[i]
+		case quote == 0 && s[i] == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+var filterExpr = regexp.MustCompile(`^\?\(\s*@\.([A-Za-z0-9_-]+)\s*(==|=~)\s*("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')\s*\)$`)
+
+func parseBracket(s string) (segment, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "*":
+		return segment{kind: segmentWildcard}, nil
+	case strings.HasPrefix(s, "?"):
+		match := filterExpr.FindStringSubmatch(s)
+		if match == nil {
+			return segment{}, fmt.Errorf(`unsupported filter %q, use ?(@.field=="value") or ?(@.field=~"pattern")`, s)
+		}
+		value, err := unquote(match[3])
+		if err != nil {
+			return segment{}, err
+		}
+		seg := segment{kind: segmentFilter, field: match[1], value: value}
+		if match[2] == "=~" {
+			if seg.pattern, err = regexp.Compile(value); err != nil {
+				return segment{}, fmt.Errorf("invalid pattern %q: %w", value, err)
+			}
+		}
+		return seg, nil
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`):
+		name, err := unquote(s)
+		if err != nil {
+			return segment{}, err
+		}
+		return segment{kind: segmentField, name: name}, nil
+	default:
+		index, err := strconv.Atoi(s)
+		if err != nil || index < 0 {
+			return segment{}, fmt.Errorf("unsupported selector [%s]", s)
+		}
+		return segment{kind: segmentIndex, index: index}, nil
+	}
+}
+
+// unquote removes the single or double quotes around a string.
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != s[len(s)-1] {
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+	if s[0] == '\'' {
+		s = `"` + strings.ReplaceAll(strings.ReplaceAll(s[1:len(s)-1], `\'`, `'`), `"`, `\"`) + `"`
+	}
+	return strconv.Unquote(s)
+}
+
+// apply replaces every value the path selects with replace, returning the new value
+// and whether anything was replaced.
+func (p path) apply(value interface{}, replace func(interface{}) (interface{}, bool)) (interface{}, bool) {
+	if len(p) == 0 {
+		if value == nil {
+			return value, false
+		}
+		return replace(value)
+	}
+	seg, rest := p[0], p[1:]
+	changed := false
+	switch seg.kind {
+	case segmentDescend:
+		value, changed = rest.apply(value, replace)
+		eachChild(value, func(child interface{}) (interface{}, bool) {
+			return p.apply(child, replace)
+		}, &changed)
+	case segmentField:
+		if m, ok := value.(map[string]interface{}); ok {
+			if child, ok := m[seg.name]; ok {
+				var c bool
+				if m[seg.name], c = rest.apply(child, replace); c {
+					changed = true
+				}
+			}
+		}
+	case segmentWildcard:
+		eachChild(value, func(child interface{}) (interface{}, bool) {
+			return rest.apply(child, replace)
+		}, &changed)
+	case segmentIndex:
+		if l, ok := value.([]interface{}); ok && seg.index < len(l) {
+			var c bool
+			if l[seg.index], c = rest.apply(l[seg.index], replace); c {
+				changed = true
+			}
+		}
+	case segmentFilter:
+		if l, ok := value.([]interface{}); ok {
+			for i, item := range l {
+				if seg.matches(item) {
+					var c bool
+					if l[i], c = rest.apply(item, replace); c {
+						changed = true
+					}
+				}
+			}
+		}
+	}
+	return value, changed
+}
+
+// eachChild applies fn to every map value or list element of value in place.
+func eachChild(value interface{}, fn func(interface{}) (interface{}, bool), changed *bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			var c bool
+			if v[key], c = fn(child); c {
+				*changed = true
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			var c bool
+			if v[i], c = fn(child); c {
+				*changed = true
+			}
+		}
+	}
+}
+
+// matches reports whether a list element passes a filter.
+func (s segment) matches(item interface{}) bool {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	value, ok := m[s.field].(string)
+	if !ok {
+		return false
+	}
+	if s.pattern != nil {
+		return s.pattern.MatchString(value)
+	}
+	return value == s.value
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package redact removes sensitive values from objects before they are recorded. The
+// data of Secrets is redacted by default, and rules select fields of other kinds. Each
+// redacted value is replaced by an HMAC-SHA256 digest of itself, so that changes to it
+// are still detected while the value is not stored. Digests are keyed with a key derived
+// from the keyring of the cluster, so that guessed values cannot be checked against them
+// without it. It is shared by the manager and the kubectl plugin.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Prefix starts every redacted value, which is followed by the HMAC-SHA256 digest of
+	// the original value.
+	Prefix = "kronoform-redacted:sha256:"
+
+	// Redacted and Changed replace redacted values when they are shown, depending on
+	// whether the value differs from the one it is compared with.
+	Redacted = "<redacted>"
+	Changed  = "<changed (redacted)>"
+
+	// lastAppliedAnnotation is written by kubectl apply and duplicates the object itself,
+	// including any redacted value.
+	lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+var redactedValue = regexp.MustCompile(regexp.QuoteMeta(Prefix) + `[0-9a-f]{64}\b`)
+
+// Rule redacts the fields of a kind selected by a JSONPath expression.
+type Rule struct {
+	// Kind is the kind of the objects the rule applies to, as Kind or Kind.group. A kind
+	// without a group matches the kind in every group; "*" matches all kinds.
+	Kind string `json:"kind"`
+	// Path selects the redacted fields, e.g.
+	// $.spec.template.spec.containers[*].env[?(@.name=~"PASSWORD")].value.
+	Path string `json:"path"`
+}
+
+// Config configures redaction.
+type Config struct {
+	// KeepSecrets records the data of Secrets as is. It is redacted by default.
+	KeepSecrets bool `json:"keepSecrets,omitempty"`
+	// Rules redact fields of other kinds.
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// rule is a compiled Rule.
+type rule struct {
+	kind  string
+	group string
+	any   bool
+	path  path
+}
+
+// Redactor redacts objects according to a Config. A nil Redactor redacts the data of
+// Secrets only, as the default Config does. Values are digested with an empty key unless
+// one is set with WithKey, as is the case when no keyring is configured.
+type Redactor struct {
+	keepSecrets bool
+	rules       []rule
+	key         []byte
+}
+
+// New compiles a Config.
+func New(cfg Config) (*Redactor, error) {
+	r := &Redactor{keepSecrets: cfg.KeepSecrets}
+	for i, ruleCfg := range cfg.Rules {
+		if ruleCfg.Kind == "" {
+			return nil, fmt.Errorf("redaction rule %d: kind is required", i)
+		}
+		p, err := parsePath(ruleCfg.Path)
+		if err != nil {
+			return nil, fmt.Errorf("redaction rule %d: %w", i, err)
+		}
+		compiled := rule{path: p, any: ruleCfg.Kind == "*"}
+		compiled.kind, compiled.group, _ = strings.Cut(ruleCfg.Kind, ".")
+		r.rules = append(r.rules, compiled)
+	}
+	return r, nil
+}
+
+// WithKey returns a copy of the Redactor that keys the digests of redacted values with
+// key, such as envelope.Keyring.DigestKey. Records redacted with different keys hold
+// different digests of the same values.
+func (r *Redactor) WithKey(key []byte) *Redactor {
+	keyed := &Redactor{}
+	if r != nil {
+		*keyed = *r
+	}
+	keyed.key = key
+	return keyed
+}
+
+// LoadFile compiles the Config read from a YAML file. The default Config is used if path
+// is empty.
+func LoadFile(path string) (*Redactor, error) {
+	cfg := Config{}
+	if path != "" {
+		data, err := os.ReadFile(path) // #nosec G304 - the file is chosen by the operator
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redaction config %s: %w", path, err)
+		}
+		if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid redaction config %s: %w", path, err)
+		}
+	}
+	return New(cfg)
+}
+
+// Object redacts an object in place and reports whether anything was redacted. Lists
+// of kind List are redacted item by item.
+func (r *Redactor) Object(obj map[string]interface{}) bool {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	gv, _ := schema.ParseGroupVersion(apiVersion)
+
+	changed := false
+	if kind == "List" && gv.Group == "" {
+		if items, ok := obj["items"].([]interface{}); ok {
+			for _, item := range items {
+				if m, ok := item.(map[string]interface{}); ok && r.Object(m) {
+					changed = true
+				}
+			}
+		}
+		return changed
+	}
+
+	if kind == "Secret" && gv.Group == "" && (r == nil || !r.keepSecrets) {
+		for _, field := range []string{"data", "stringData"} {
+			data, ok := obj[field].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for key, value := range data {
+				if s, ok := value.(string); ok && !IsRedacted(s) {
+					data[key] = r.secretValue(s, field == "data")
+					changed = true
+				}
+			}
+		}
+	}
+	if r != nil {
+		for _, rule := range r.rules {
+			if !rule.any && (rule.kind != kind || rule.group != "" && rule.group != gv.Group) {
+				continue
+			}
+			if _, c := rule.path.apply(obj, r.redactValue); c {
+				changed = true
+			}
+		}
+	}
+
+	// The last applied configuration would still hold the redacted values
+	if changed {
+		if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+				if applied, ok := annotations[lastAppliedAnnotation].(string); ok && !IsRedacted(applied) {
+					annotations[lastAppliedAnnotation] = r.Value(applied)
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// Unstructured returns a redacted copy of an object, or nil for nil.
+func (r *Redactor) Unstructured(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	if obj == nil {
+		return nil
+	}
+	redacted := obj.DeepCopy()
+	r.Object(redacted.Object)
+	return redacted
+}
+
+// Manifests redacts a multi-document YAML or JSON stream. The stream is returned as is if
+// nothing needs to be redacted, and otherwise re-rendered as YAML documents.
+func (r *Redactor) Manifests(manifests string) (string, error) {
+	var documents []map[string]interface{}
+	changed := false
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifests), 4096)
+	for {
+		var document map[string]interface{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("cannot redact malformed manifests: %w", err)
+		}
+		if document == nil {
+			continue
+		}
+		if r.Object(document) {
+			changed = true
+		}
+		documents = append(documents, document)
+	}
+	if !changed {
+		return manifests, nil
+	}
+
+	rendered := make([]string, 0, len(documents))
+	for _, document := range documents {
+		data, err := yaml.Marshal(document)
+		if err != nil {
+			return "", err
+		}
+		rendered = append(rendered, string(data))
+	}
+	return strings.Join(rendered, "---\n"), nil
+}
+
+// Value returns the redacted form of a value: the digest of a string, or of the JSON
+// encoding of any other value.
+func (r *Redactor) Value(value interface{}) string {
+	data, ok := value.(string)
+	if !ok {
+		encoded, _ := json.Marshal(value)
+		data = string(encoded)
+	}
+	return r.digest([]byte(data))
+}
+
+// IsRedacted reports whether a value has been redacted.
+func IsRedacted(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && len(s) == len(Prefix)+sha256.Size*2 && redactedValue.MatchString(s)
+}
+
+// Mask replaces the redacted values in two renderings of the same content, such as the
+// states before and after a change, for display. Values of after that do not occur in
+// before are shown as Changed, all others as Redacted.
+func Mask(before, after string) (string, string) {
+	seen := map[string]bool{}
+	for _, value := range redactedValue.FindAllString(before, -1) {
+		seen[value] = true
+	}
+	after = redactedValue.ReplaceAllStringFunc(after, func(value string) string {
+		if seen[value] {
+			return Redacted
+		}
+		return Changed
+	})
+	return redactedValue.ReplaceAllString(before, Redacted), after
+}
+
+// redactValue replaces a value selected by a rule.
+func (r *Redactor) redactValue(value interface{}) (interface{}, bool) {
+	if IsRedacted(value) {
+		return value, false
+	}
+	return r.Value(value), true
+}
+
+// secretValue redacts a value of a Secret. Values of data are base64 encoded; they are
+// digested decoded so that data and stringData holding the same value redact alike.
+func (r *Redactor) secretValue(value string, encoded bool) string {
+	if encoded {
+		if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+			return r.digest(decoded)
+		}
+	}
+	return r.digest([]byte(value))
+}
+
+func (r *Redactor) digest(data []byte) string {
+	var key []byte
+	if r != nil {
+		key = r.key
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return Prefix + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,165 @@
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+func parse(t *testing.T, content string) map[string]interface{} {
+	t.Helper()
+	obj := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(content), &obj); err != nil {
+		t.Fatalf("invalid test object: %v", err)
+	}
+	return obj
+}
+
+func TestSecretsAreRedactedByDefault(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	secret := parse(t, `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: '{"data":{"password":"aHVudGVyMg=="}}'
+data:
+  password: aHVudGVyMg==
+stringData:
+  user: admin
+`)
+	var r *Redactor
+	g.Expect(r.Object(secret)).To(gomega.BeTrue())
+
+	data := secret["data"].(map[string]interface{})
+	stringData := secret["stringData"].(map[string]interface{})
+	g.Expect(IsRedacted(data["password"])).To(gomega.BeTrue())
+	g.Expect(IsRedacted(stringData["user"])).To(gomega.BeTrue())
+	// Values are digested decoded, so data and stringData holding the same value agree
+	g.Expect(data["password"]).To(gomega.Equal(r.Value("hunter2")))
+	annotations := secret["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+	g.Expect(IsRedacted(annotations[lastAppliedAnnotation])).To(gomega.BeTrue())
+
+	// Redacting twice leaves the digests alone
+	g.Expect(r.Object(secret)).To(gomega.BeFalse())
+
+	keep, err := New(Config{KeepSecrets: true})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(keep.Object(parse(t, "apiVersion: v1\nkind: Secret\ndata:\n  password: aHVudGVyMg==\n"))).To(gomega.BeFalse())
+}
+
+func TestRules(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	r, err := New(Config{Rules: []Rule{
+		{Kind: "Deployment.apps", Path: `$.spec.template.spec.containers[*].env[?(@.name=~"PASSWORD|TOKEN")].value`},
+		{Kind: "*", Path: `$..env[?(@.name=="API_KEY")].value`},
+		{Kind: "ConfigMap", Path: `.data['tls.key']`},
+	}})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+
+	deployment := parse(t, `
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        env:
+        - name: DB_PASSWORD
+          value: hunter2
+        - name: LOG_LEVEL
+          value: debug
+      initContainers:
+      - name: init
+        env:
+        - name: API_KEY
+          value: abc
+`)
+	g.Expect(r.Object(deployment)).To(gomega.BeTrue())
+	rendered, err := yaml.Marshal(deployment)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(string(rendered)).NotTo(gomega.ContainSubstring("hunter2"))
+	g.Expect(string(rendered)).NotTo(gomega.ContainSubstring("abc"))
+	g.Expect(string(rendered)).To(gomega.ContainSubstring("value: debug"))
+
+	configMap := parse(t, "apiVersion: v1\nkind: ConfigMap\ndata:\n  tls.key: private\n  tls.crt: public\n")
+	g.Expect(r.Object(configMap)).To(gomega.BeTrue())
+	g.Expect(IsRedacted(configMap["data"].(map[string]interface{})["tls.key"])).To(gomega.BeTrue())
+	g.Expect(configMap["data"].(map[string]interface{})["tls.crt"]).To(gomega.Equal("public"))
+
+	// Rules naming a group do not apply to other groups
+	other := parse(t, "apiVersion: example.com/v1\nkind: Deployment\nspec:\n  template:\n    spec:\n      containers:\n      - env:\n        - name: PASSWORD\n          value: x\n")
+	g.Expect(r.Object(other)).To(gomega.BeFalse())
+}
+
+func TestInvalidRules(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	for _, path := range []string{"", "$", "spec", ".spec[", ".spec[?(@.name>1)]", "$..", `.env[?(@.name=~"(")]`} {
+		_, err := New(Config{Rules: []Rule{{Kind: "Pod", Path: path}}})
+		g.Expect(err).To(gomega.HaveOccurred(), "path %q", path)
+	}
+	_, err := New(Config{Rules: []Rule{{Path: ".spec"}}})
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("kind is required")))
+}
+
+func TestManifests(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	var r *Redactor
+	plain := "# comments are kept when nothing is redacted\napiVersion: v1\nkind: ConfigMap\n"
+	redacted, err := r.Manifests(plain)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(redacted).To(gomega.Equal(plain))
+
+	redacted, err = r.Manifests(plain + "---\napiVersion: v1\nkind: Secret\nstringData:\n  token: s3cr3t\n---\n")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(redacted).NotTo(gomega.ContainSubstring("s3cr3t"))
+	g.Expect(strings.Count(redacted, "---\n")).To(gomega.Equal(1))
+
+	_, err = r.Manifests("kind: [")
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestMask(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	var r *Redactor
+	same, old, changed := r.Value("a"), r.Value("b"), r.Value("c")
+	before, after := Mask("user: "+same+"\npassword: "+old+"\n", "user: "+same+"\npassword: "+changed+"\n")
+	g.Expect(before).To(gomega.Equal("user: <redacted>\npassword: <redacted>\n"))
+	g.Expect(after).To(gomega.Equal("user: <redacted>\npassword: <changed (redacted)>\n"))
+}
+
+func TestKeyedDigests(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	var unkeyed *Redactor
+	keyed := unkeyed.WithKey([]byte("cluster-a"))
+	other := keyed.WithKey([]byte("cluster-b"))
+	sum := sha256.Sum256([]byte("hunter2"))
+	g.Expect(unkeyed.Value("hunter2")).NotTo(gomega.Equal(Prefix + hex.EncodeToString(sum[:])))
+	g.Expect(keyed.Value("hunter2")).NotTo(gomega.Equal(unkeyed.Value("hunter2")))
+	g.Expect(keyed.Value("hunter2")).NotTo(gomega.Equal(other.Value("hunter2")))
+	g.Expect(IsRedacted(keyed.Value("hunter2"))).To(gomega.BeTrue())
+
+	// Values redacted with the same key can still be compared
+	secret := parse(t, "apiVersion: v1\nkind: Secret\ndata:\n  password: aHVudGVyMg==\n")
+	g.Expect(unkeyed.WithKey([]byte("cluster-a")).Object(secret)).To(gomega.BeTrue())
+	g.Expect(secret["data"].(map[string]interface{})["password"]).To(gomega.Equal(keyed.Value("hunter2")))
+
+	// WithKey keeps the configuration
+	r, err := New(Config{KeepSecrets: true, Rules: []Rule{{Kind: "ConfigMap", Path: ".data.token"}}})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	configMap := parse(t, "apiVersion: v1\nkind: ConfigMap\ndata:\n  token: s3cr3t\n")
+	g.Expect(r.WithKey([]byte("cluster-a")).Object(configMap)).To(gomega.BeTrue())
+	g.Expect(configMap["data"].(map[string]interface{})["token"]).To(gomega.Equal(keyed.Value("s3cr3t")))
+}
@@ -0,0 +1,408 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rollout waits for the rollouts of workloads to complete and tells how they
+// ended, like kubectl rollout status, so that the health of a change can be recorded
+// with it.
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+// maxPodFailures bounds the pod failures recorded per workload.
+const maxPodFailures = 5
+
+// Progress is how far the rollout of a workload got.
+type Progress struct {
+	// Done is set when the rollout completed
+	Done bool
+	// Failed is set when the rollout cannot complete, e.g. a Job failed or a Deployment
+	// exceeded its progress deadline
+	Failed bool
+	// Replicas and Ready are the pods desired and ready, as in WorkloadRollout
+	Replicas, Ready int32
+	Message         string
+}
+
+// Supported reports whether the rollouts of a kind are waited for.
+func Supported(gk schema.GroupKind) bool {
+	switch gk {
+	case schema.GroupKind{Group: "apps", Kind: "Deployment"},
+		schema.GroupKind{Group: "apps", Kind: "StatefulSet"},
+		schema.GroupKind{Group: "apps", Kind: "DaemonSet"},
+		schema.GroupKind{Group: "batch", Kind: "Job"}:
+		return true
+	}
+	return false
+}
+
+// Check returns the progress of the rollout of a workload from its live state.
+func Check(obj *unstructured.Unstructured) (Progress, error) {
+	switch obj.GroupVersionKind().GroupKind() {
+	case schema.GroupKind{Group: "apps", Kind: "Deployment"}:
+		d := &appsv1.Deployment{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, d); err != nil {
+			return Progress{}, err
+		}
+		return deploymentProgress(d), nil
+	case schema.GroupKind{Group: "apps", Kind: "StatefulSet"}:
+		sts := &appsv1.StatefulSet{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, sts); err != nil {
+			return Progress{}, err
+		}
+		return statefulSetProgress(sts), nil
+	case schema.GroupKind{Group: "apps", Kind: "DaemonSet"}:
+		ds := &appsv1.DaemonSet{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, ds); err != nil {
+			return Progress{}, err
+		}
+		return daemonSetProgress(ds), nil
+	case schema.GroupKind{Group: "batch", Kind: "Job"}:
+		job := &batchv1.Job{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, job); err != nil {
+			return Progress{}, err
+		}
+		return jobProgress(job), nil
+	}
+	return Progress{}, fmt.Errorf("rollouts of %s are not supported", obj.GetKind())
+}
+
+func deploymentProgress(d *appsv1.Deployment) Progress {
+	p := Progress{Replicas: replicasOf(d.Spec.Replicas), Ready: d.Status.ReadyReplicas}
+	switch {
+	case d.Generation > d.Status.ObservedGeneration:
+		p.Message = "waiting for the rollout to be observed"
+	case progressDeadlineExceeded(d.Status.Conditions):
+		p.Failed = true
+		p.Message = "exceeded its progress deadline"
+	case d.Status.UpdatedReplicas < p.Replicas:
+		p.Message = fmt.Sprintf("%d of %d updated replicas", d.Status.UpdatedReplicas, p.Replicas)
+	case d.Status.Replicas > d.Status.UpdatedReplicas:
+		p.Message = fmt.Sprintf("%d old replicas pending termination", d.Status.Replicas-d.Status.UpdatedReplicas)
+	case d.Status.AvailableReplicas < d.Status.UpdatedReplicas:
+		p.Message = fmt.Sprintf("%d of %d updated replicas available", d.Status.AvailableReplicas, d.Status.UpdatedReplicas)
+	default:
+		p.Done = true
+		p.Message = "successfully rolled out"
+	}
+	return p
+}
+
+func progressDeadlineExceeded(conditions []appsv1.DeploymentCondition) bool {
+	for _, c := range conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
+func statefulSetProgress(sts *appsv1.StatefulSet) Progress {
+	p := Progress{Replicas: replicasOf(sts.Spec.Replicas), Ready: sts.Status.ReadyReplicas}
+	// Pods of partitioned rollouts below the partition are not updated
+	updated := p.Replicas
+	if ru := sts.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil {
+		updated -= *ru.Partition
+	}
+	switch {
+	case sts.Status.ObservedGeneration == 0 || sts.Generation > sts.Status.ObservedGeneration:
+		p.Message = "waiting for the rollout to be observed"
+	case sts.Status.ReadyReplicas < p.Replicas:
+		p.Message = fmt.Sprintf("%d of %d replicas ready", sts.Status.ReadyReplicas, p.Replicas)
+	case sts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType:
+		// Pods are only updated when deleted, there is no rollout to wait for
+		p.Done = true
+		p.Message = "replicas ready, pods are updated when deleted"
+	case sts.Status.UpdatedReplicas < updated:
+		p.Message = fmt.Sprintf("%d of %d updated replicas", sts.Status.UpdatedReplicas, updated)
+	case updated == p.Replicas && sts.Status.UpdateRevision != sts.Status.CurrentRevision:
+		p.Message = fmt.Sprintf("waiting for the update to revision %s", sts.Status.UpdateRevision)
+	default:
+		p.Done = true
+		p.Message = "successfully rolled out"
+	}
+	return p
+}
+
+func daemonSetProgress(ds *appsv1.DaemonSet) Progress {
+	p := Progress{Replicas: ds.Status.DesiredNumberScheduled, Ready: ds.Status.NumberReady}
+	switch {
+	case ds.Generation > ds.Status.ObservedGeneration:
+		p.Message = "waiting for the rollout to be observed"
+	case ds.Status.UpdatedNumberScheduled < ds.Status.DesiredNumberScheduled:
+		p.Message = fmt.Sprintf("%d of %d updated pods", ds.Status.UpdatedNumberScheduled, ds.Status.DesiredNumberScheduled)
+	case ds.Status.NumberAvailable < ds.Status.DesiredNumberScheduled:
+		p.Message = fmt.Sprintf("%d of %d updated pods available", ds.Status.NumberAvailable, ds.Status.DesiredNumberScheduled)
+	default:
+		p.Done = true
+		p.Message = "successfully rolled out"
+	}
+	return p
+}
+
+func jobProgress(job *batchv1.Job) Progress {
+	p := Progress{Replicas: replicasOf(job.Spec.Completions), Ready: job.Status.Succeeded}
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			p.Done = true
+			p.Message = "completed"
+			return p
+		case batchv1.JobFailed:
+			p.Failed = true
+			p.Message = strings.TrimSpace(fmt.Sprintf("failed: %s %s", c.Reason, c.Message))
+			return p
+		}
+	}
+	p.Message = fmt.Sprintf("%d of %d completions succeeded", job.Status.Succeeded, p.Replicas)
+	return p
+}
+
+func replicasOf(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// PodFailures returns why pods are failing: containers waiting for a reason other than
+// being created, containers that exited with an error, failed pods and pods that cannot
+// be scheduled. They are ordered by pod, at most maxPodFailures of them.
+func PodFailures(pods []corev1.Pod) []historyv1alpha1.PodFailure {
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	var failures []historyv1alpha1.PodFailure
+	for _, pod := range pods {
+		failures = append(failures, podFailures(&pod)...)
+		if len(failures) >= maxPodFailures {
+			return failures[:maxPodFailures]
+		}
+	}
+	return failures
+}
+
+func podFailures(pod *corev1.Pod) []historyv1alpha1.PodFailure {
+	if pod.Status.Phase == corev1.PodFailed {
+		reason := pod.Status.Reason
+		if reason == "" {
+			reason = string(corev1.PodFailed)
+		}
+		return []historyv1alpha1.PodFailure{{Pod: pod.Name, Reason: reason, Message: pod.Status.Message}}
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse && c.Reason != "" {
+			return []historyv1alpha1.PodFailure{{Pod: pod.Name, Reason: c.Reason, Message: c.Message}}
+		}
+	}
+
+	var failures []historyv1alpha1.PodFailure
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		failure := historyv1alpha1.PodFailure{Pod: pod.Name, Container: cs.Name}
+		switch waiting, terminated := cs.State.Waiting, cs.State.Terminated; {
+		case waiting != nil && waiting.Reason != "" && waiting.Reason != "ContainerCreating" && waiting.Reason != "PodInitializing":
+			failure.Reason, failure.Message = waiting.Reason, waiting.Message
+			// Crash loops are better explained by how the container last exited
+			if last := cs.LastTerminationState.Terminated; last != nil && waiting.Reason == "CrashLoopBackOff" {
+				failure.Message = fmt.Sprintf("last exited with %s (exit code %d)", last.Reason, last.ExitCode)
+			}
+		case terminated != nil && terminated.ExitCode != 0:
+			failure.Reason, failure.Message = terminated.Reason, terminated.Message
+			if failure.Reason == "" {
+				failure.Reason = "Error"
+			}
+			if failure.Message == "" {
+				failure.Message = fmt.Sprintf("exit code %d", terminated.ExitCode)
+			}
+		default:
+			continue
+		}
+		failures = append(failures, failure)
+	}
+	return failures
+}
+
+// Wait waits until the rollouts of workloads completed or failed, or timeout passed,
+// checking them every interval, and returns how each ended. The pod failures of the
+// workloads that did not complete are recorded with them.
+func Wait(ctx context.Context, c client.Client, workloads []*unstructured.Unstructured,
+	timeout, interval time.Duration) ([]historyv1alpha1.WorkloadRollout, error) {
+	rollouts := make([]historyv1alpha1.WorkloadRollout, len(workloads))
+	progress := make([]Progress, len(workloads))
+	pending := len(workloads)
+	deadline := time.Now().Add(timeout)
+	for {
+		for i, workload := range workloads {
+			if rollouts[i].Outcome != "" {
+				continue
+			}
+			live := &unstructured.Unstructured{}
+			live.SetGroupVersionKind(workload.GroupVersionKind())
+			err := c.Get(ctx, client.ObjectKeyFromObject(workload), live)
+			if apierrors.IsNotFound(err) {
+				progress[i] = Progress{Failed: true, Message: "not found"}
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to get %s %s: %w", workload.GetKind(), workload.GetName(), err)
+			} else if progress[i], err = Check(live); err != nil {
+				return nil, err
+			}
+
+			switch {
+			case progress[i].Done:
+				rollouts[i].Outcome = historyv1alpha1.RolloutComplete
+			case progress[i].Failed:
+				rollouts[i].Outcome = historyv1alpha1.RolloutFailed
+			default:
+				continue
+			}
+			pending--
+			if rollouts[i], err = ended(ctx, c, workload, live, rollouts[i].Outcome, progress[i]); err != nil {
+				return nil, err
+			}
+		}
+		if pending == 0 || !time.Now().Before(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+
+	for i, workload := range workloads {
+		if rollouts[i].Outcome != "" {
+			continue
+		}
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(workload.GroupVersionKind())
+		if err := c.Get(ctx, client.ObjectKeyFromObject(workload), live); err != nil {
+			return nil, fmt.Errorf("failed to get %s %s: %w", workload.GetKind(), workload.GetName(), err)
+		}
+		var err error
+		if rollouts[i], err = ended(ctx, c, workload, live, historyv1alpha1.RolloutTimedOut, progress[i]); err != nil {
+			return nil, err
+		}
+	}
+	return rollouts, nil
+}
+
+// ended returns how the rollout of a workload ended, with the failures of its pods unless
+// it completed.
+func ended(ctx context.Context, c client.Client, workload, live *unstructured.Unstructured,
+	outcome string, progress Progress) (historyv1alpha1.WorkloadRollout, error) {
+	rollout := historyv1alpha1.WorkloadRollout{
+		APIVersion:    workload.GetAPIVersion(),
+		Kind:          workload.GetKind(),
+		Name:          workload.GetName(),
+		Namespace:     workload.GetNamespace(),
+		Outcome:       outcome,
+		Replicas:      progress.Replicas,
+		ReadyReplicas: progress.Ready,
+		Message:       progress.Message,
+	}
+	if outcome == historyv1alpha1.RolloutComplete || live.Object == nil {
+		return rollout, nil
+	}
+	pods, err := workloadPods(ctx, c, live)
+	if err != nil {
+		return rollout, err
+	}
+	rollout.PodFailures = PodFailures(pods)
+	return rollout, nil
+}
+
+// workloadPods lists the pods matching the selector of a workload.
+func workloadPods(ctx context.Context, c client.Client, workload *unstructured.Unstructured) ([]corev1.Pod, error) {
+	raw, found, err := unstructured.NestedMap(workload.Object, "spec", "selector")
+	if err != nil || !found {
+		return nil, err
+	}
+	labelSelector := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, labelSelector); err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(workload.GetNamespace()),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list the pods of %s %s: %w", workload.GetKind(), workload.GetName(), err)
+	}
+	return pods.Items, nil
+}
+
+// Condition returns the Healthy condition of a history whose workloads rolled out as
+// rollouts tell. Its reason is the worst outcome, and its message describes the
+// workloads that did not complete.
+func Condition(rollouts []historyv1alpha1.WorkloadRollout) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    historyv1alpha1.ConditionHealthy,
+		Status:  metav1.ConditionTrue,
+		Reason:  historyv1alpha1.RolloutComplete,
+		Message: fmt.Sprintf("%d workload(s) rolled out", len(rollouts)),
+	}
+	var unhealthy []string
+	for _, r := range rollouts {
+		if r.Outcome == historyv1alpha1.RolloutComplete {
+			continue
+		}
+		condition.Status = metav1.ConditionFalse
+		if condition.Reason != historyv1alpha1.RolloutFailed {
+			condition.Reason = r.Outcome
+		}
+		unhealthy = append(unhealthy, Describe(r))
+	}
+	if len(unhealthy) > 0 {
+		condition.Message = strings.Join(unhealthy, "; ")
+	}
+	return condition
+}
+
+// Describe summarizes how the rollout of a workload ended, e.g.
+// "Deployment/web: TimedOut, 1/3 ready, ImagePullBackOff".
+func Describe(r historyv1alpha1.WorkloadRollout) string {
+	parts := []string{r.Outcome, fmt.Sprintf("%d/%d ready", r.ReadyReplicas, r.Replicas)}
+	seen := map[string]bool{}
+	for _, f := range r.PodFailures {
+		if !seen[f.Reason] {
+			seen[f.Reason] = true
+			parts = append(parts, f.Reason)
+		}
+	}
+	return fmt.Sprintf("%s/%s: %s", r.Kind, r.Name, strings.Join(parts, ", "))
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+func toUnstructured(t *testing.T, obj runtime.Object, apiVersion, kind string) *unstructured.Unstructured {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	return u
+}
+
+func replicas(n int32) *int32 {
+	return &n
+}
+
+func deployment(name string, desired, updated, available int32, conditions ...appsv1.DeploymentCondition) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Generation: 2},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: replicas(desired),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2, Replicas: updated, UpdatedReplicas: updated,
+			ReadyReplicas: available, AvailableReplicas: available, Conditions: conditions,
+		},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	progress, err := Check(toUnstructured(t, deployment("web", 3, 3, 3), "apps/v1", "Deployment"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(progress).To(gomega.Equal(Progress{Done: true, Replicas: 3, Ready: 3, Message: "successfully rolled out"}))
+
+	progress, err = Check(toUnstructured(t, deployment("web", 3, 3, 1), "apps/v1", "Deployment"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(progress).To(gomega.Equal(Progress{Replicas: 3, Ready: 1, Message: "1 of 3 updated replicas available"}))
+
+	stuck := deployment("web", 3, 1, 0, appsv1.DeploymentCondition{
+		Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded",
+	})
+	progress, err = Check(toUnstructured(t, stuck, "apps/v1", "Deployment"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(progress.Failed).To(gomega.BeTrue())
+	g.Expect(progress.Message).To(gomega.Equal("exceeded its progress deadline"))
+
+	// pods below the partition are not updated
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Generation: 1},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: replicas(3),
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: replicas(2)},
+			},
+		},
+		Status: appsv1.StatefulSetStatus{ObservedGeneration: 1, ReadyReplicas: 3, UpdatedReplicas: 1},
+	}
+	progress, err = Check(toUnstructured(t, sts, "apps/v1", "StatefulSet"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(progress.Done).To(gomega.BeTrue())
+
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default", Generation: 1},
+		Status: appsv1.DaemonSetStatus{
+			ObservedGeneration: 1, DesiredNumberScheduled: 4, UpdatedNumberScheduled: 2, NumberReady: 3, NumberAvailable: 3,
+		},
+	}
+	progress, err = Check(toUnstructured(t, ds, "apps/v1", "DaemonSet"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(progress).To(gomega.Equal(Progress{Replicas: 4, Ready: 3, Message: "2 of 4 updated pods"}))
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "default"},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+			Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded",
+			Message: "Job has reached the specified backoff limit",
+		}}},
+	}
+	progress, err = Check(toUnstructured(t, job, "batch/v1", "Job"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(progress).To(gomega.Equal(Progress{
+		Failed: true, Replicas: 1, Message: "failed: BackoffLimitExceeded Job has reached the specified backoff limit",
+	}))
+
+	_, err = Check(toUnstructured(t, &corev1.ConfigMap{}, "v1", "ConfigMap"))
+	g.Expect(err).NotTo(gomega.BeNil())
+}
+
+func TestPodFailures(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-b"},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "app",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+				LastTerminationState: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1},
+				},
+			}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-a"},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: "app",
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+						Reason: "ImagePullBackOff", Message: "Back-off pulling image \"web:bad\"",
+					}},
+				},
+				{
+					Name:  "sidecar",
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}},
+				},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-c"},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{
+				Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable",
+				Message: "0/3 nodes are available: 3 Insufficient cpu.",
+			}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-d"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	}
+	g.Expect(PodFailures(pods)).To(gomega.Equal([]historyv1alpha1.PodFailure{
+		{Pod: "web-a", Container: "app", Reason: "ImagePullBackOff", Message: "Back-off pulling image \"web:bad\""},
+		{Pod: "web-b", Container: "app", Reason: "CrashLoopBackOff", Message: "last exited with Error (exit code 1)"},
+		{Pod: "web-c", Reason: "Unschedulable", Message: "0/3 nodes are available: 3 Insufficient cpu."},
+	}))
+}
+
+func TestWait(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	web := deployment("web", 2, 2, 2)
+	api := deployment("api", 3, 3, 1)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate", Namespace: "default"},
+		Spec:       batchv1.JobSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"job": "migrate"}}},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+			Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded",
+		}}},
+	}
+	pullFailure := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "api"}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "app",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+			}}},
+		}
+	}
+	jobPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "migrate-x", Namespace: "default", Labels: map[string]string{"job": "migrate"}},
+		Status: corev1.PodStatus{Phase: corev1.PodFailed, ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "migrate",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2}},
+		}}},
+	}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).
+		WithObjects(web, api, job, pullFailure("api-2"), pullFailure("api-1"), jobPod).Build()
+
+	workloads := []*unstructured.Unstructured{
+		toUnstructured(t, web, "apps/v1", "Deployment"),
+		toUnstructured(t, api, "apps/v1", "Deployment"),
+		toUnstructured(t, job, "batch/v1", "Job"),
+		toUnstructured(t, deployment("gone", 1, 0, 0), "apps/v1", "Deployment"),
+	}
+	rollouts, err := Wait(ctx, c, workloads, 20*time.Millisecond, 5*time.Millisecond)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(rollouts).To(gomega.HaveLen(4))
+
+	g.Expect(rollouts[0]).To(gomega.Equal(historyv1alpha1.WorkloadRollout{
+		APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "default",
+		Outcome: historyv1alpha1.RolloutComplete, Replicas: 2, ReadyReplicas: 2, Message: "successfully rolled out",
+	}))
+	g.Expect(rollouts[1].Outcome).To(gomega.Equal(historyv1alpha1.RolloutTimedOut))
+	g.Expect(rollouts[1].ReadyReplicas).To(gomega.Equal(int32(1)))
+	g.Expect(rollouts[1].PodFailures).To(gomega.Equal([]historyv1alpha1.PodFailure{
+		{Pod: "api-1", Container: "app", Reason: "ImagePullBackOff"},
+		{Pod: "api-2", Container: "app", Reason: "ImagePullBackOff"},
+	}))
+	g.Expect(rollouts[2].Outcome).To(gomega.Equal(historyv1alpha1.RolloutFailed))
+	g.Expect(rollouts[2].PodFailures).To(gomega.Equal([]historyv1alpha1.PodFailure{
+		{Pod: "migrate-x", Reason: "Failed"},
+	}))
+	g.Expect(rollouts[3].Outcome).To(gomega.Equal(historyv1alpha1.RolloutFailed))
+	g.Expect(rollouts[3].Message).To(gomega.Equal("not found"))
+
+	condition := Condition(rollouts)
+	g.Expect(condition.Status).To(gomega.Equal(metav1.ConditionFalse))
+	g.Expect(condition.Reason).To(gomega.Equal(historyv1alpha1.RolloutFailed))
+	g.Expect(condition.Message).To(gomega.Equal("Deployment/api: TimedOut, 1/3 ready, ImagePullBackOff; " +
+		"Job/migrate: Failed, 0/1 ready, Failed; Deployment/gone: Failed, 0/0 ready"))
+
+	condition = Condition(rollouts[:1])
+	g.Expect(condition.Status).To(gomega.Equal(metav1.ConditionTrue))
+	g.Expect(condition.Message).To(gomega.Equal("1 workload(s) rolled out"))
+}
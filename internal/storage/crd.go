@@ -0,0 +1,289 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/envelope"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+func init() {
+	Register(BackendCRD, func(cfg Config) (Store, error) {
+		if cfg.Client == nil {
+			return nil, fmt.Errorf("the %s storage backend requires a Kubernetes client", BackendCRD)
+		}
+		c, err := cfg.Client()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create k8s client: %w", err)
+		}
+		store := NewCRDStore(c)
+		store.Keyring = cfg.Keyring
+		return store, nil
+	})
+}
+
+// CRDStore keeps records as KronoformSnapshot and KronoformHistory resources in the
+// cluster. Large manifests and states are encoded on save and decoded on read.
+type CRDStore struct {
+	Client client.Client
+	// Keyring encrypts the manifests and states of new records and decrypts stored ones.
+	// Records are saved unencrypted if nil.
+	Keyring *envelope.Keyring
+}
+
+var _ Store = &CRDStore{}
+
+// NewCRDStore returns a Store backed by the kronoform custom resources.
+func NewCRDStore(c client.Client) *CRDStore {
+	return &CRDStore{Client: c}
+}
+
+// SaveSnapshot implements Store.
+func (s *CRDStore) SaveSnapshot(ctx context.Context, snapshot *historyv1alpha1.KronoformSnapshot) error {
+	if snapshot.ResourceVersion != "" {
+		stored := &historyv1alpha1.KronoformSnapshot{}
+		if err := s.Client.Get(ctx, client.ObjectKeyFromObject(snapshot), stored); err != nil {
+			return err
+		}
+		stored.ResourceVersion = snapshot.ResourceVersion
+		if err := s.update(ctx, stored, snapshot); err != nil {
+			return err
+		}
+		if !equality.Semantic.DeepEqual(stored.Status, snapshot.Status) {
+			stored.Status = snapshot.Status
+			if err := s.Client.Status().Update(ctx, stored); err != nil {
+				return err
+			}
+		}
+		snapshot.ObjectMeta = stored.ObjectMeta
+		return nil
+	}
+
+	// Large manifests are compressed, and moved to chunks if they are still too large
+	stored := snapshot.DeepCopy()
+	if err := s.Keyring.SealSnapshot(stored); err != nil {
+		return fmt.Errorf("failed to encrypt manifests: %w", err)
+	}
+	data, encoding, chunks, err := payload.PackStored(stored.Spec.Manifests, stored.Spec.ManifestsEncoding)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifests: %w", err)
+	}
+	stored.Spec.Manifests, stored.Spec.ManifestsEncoding, stored.Spec.ManifestsChunks = data, encoding, int32(len(chunks))
+	if err := s.Client.Create(ctx, stored); err != nil {
+		return err
+	}
+	if err := payload.CreateChunks(ctx, s.Client, stored, "", chunks); err != nil {
+		// A snapshot that is not linked to a history yet can be deleted by its creator
+		if deleteErr := s.Client.Delete(ctx, stored); deleteErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to delete incomplete snapshot %s: %w", stored.Name, deleteErr))
+		}
+		return err
+	}
+	// The status subresource is ignored on create
+	if !equality.Semantic.DeepEqual(stored.Status, snapshot.Status) {
+		stored.Status = snapshot.Status
+		if err := s.Client.Status().Update(ctx, stored); err != nil {
+			return err
+		}
+	}
+	snapshot.ObjectMeta = stored.ObjectMeta
+	return nil
+}
+
+// SaveHistory implements Store.
+func (s *CRDStore) SaveHistory(ctx context.Context, h *historyv1alpha1.KronoformHistory) error {
+	status := h.Status.DeepCopy()
+	if err := encodeStatus(s.Keyring, status); err != nil {
+		return err
+	}
+
+	if h.ResourceVersion != "" {
+		stored := &historyv1alpha1.KronoformHistory{}
+		if err := s.Client.Get(ctx, client.ObjectKeyFromObject(h), stored); err != nil {
+			return err
+		}
+		stored.ResourceVersion = h.ResourceVersion
+		if err := s.update(ctx, stored, h); err != nil {
+			return err
+		}
+		recorded := stored.Status.DeepCopy()
+		if err := payload.ReassembleStates(ctx, s.Client, stored); err != nil {
+			return err
+		}
+		if err := plainStates(s.Keyring, stored); err != nil {
+			return err
+		}
+		// The chain link is set by the controller manager and never changes
+		expected := h.Status.DeepCopy()
+		expected.Chain, status.Chain = recorded.Chain, recorded.Chain
+		if !equality.Semantic.DeepEqual(stored.Status, *expected) {
+			// States that did not change are kept as stored rather than encrypted again
+			for i := range status.ResourceSnapshots {
+				if i < len(recorded.ResourceSnapshots) &&
+					equality.Semantic.DeepEqual(stored.Status.ResourceSnapshots[i], h.Status.ResourceSnapshots[i]) {
+					status.ResourceSnapshots[i] = recorded.ResourceSnapshots[i]
+				}
+			}
+			stored.Status = *status
+			if err := s.Client.Status().Update(ctx, stored); err != nil {
+				return err
+			}
+		}
+		h.ObjectMeta = stored.ObjectMeta
+		return nil
+	}
+
+	// The ID is derived from the content before it is encrypted
+	if err := history.AssignID(h); err != nil {
+		return err
+	}
+	stored := h.DeepCopy()
+	stored.Status = *status
+	if err := s.Keyring.SealHistory(stored); err != nil {
+		return fmt.Errorf("failed to encrypt manifests: %w", err)
+	}
+	if err := history.Create(ctx, s.Client, stored); err != nil {
+		return err
+	}
+	h.ObjectMeta = stored.ObjectMeta
+	return nil
+}
+
+// update writes the metadata of obj to the stored record when it changed.
+func (s *CRDStore) update(ctx context.Context, stored, obj client.Object) error {
+	if equality.Semantic.DeepEqual(stored.GetLabels(), obj.GetLabels()) &&
+		equality.Semantic.DeepEqual(stored.GetAnnotations(), obj.GetAnnotations()) &&
+		equality.Semantic.DeepEqual(stored.GetOwnerReferences(), obj.GetOwnerReferences()) &&
+		equality.Semantic.DeepEqual(stored.GetFinalizers(), obj.GetFinalizers()) {
+		return nil
+	}
+	stored.SetLabels(obj.GetLabels())
+	stored.SetAnnotations(obj.GetAnnotations())
+	stored.SetOwnerReferences(obj.GetOwnerReferences())
+	stored.SetFinalizers(obj.GetFinalizers())
+	return s.Client.Update(ctx, stored)
+}
+
+// GetSnapshot implements Store.
+func (s *CRDStore) GetSnapshot(ctx context.Context, namespace, name string) (*historyv1alpha1.KronoformSnapshot, error) {
+	snapshot := &historyv1alpha1.KronoformSnapshot{}
+	if err := s.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, snapshot); err != nil {
+		return nil, err
+	}
+	if err := s.decodeSnapshot(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// GetHistory implements Store.
+func (s *CRDStore) GetHistory(ctx context.Context, namespace, name string) (*historyv1alpha1.KronoformHistory, error) {
+	h := &historyv1alpha1.KronoformHistory{}
+	if err := s.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, h); err != nil {
+		return nil, err
+	}
+	if err := s.decodeHistory(ctx, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ListSnapshots implements Store.
+func (s *CRDStore) ListSnapshots(ctx context.Context, opts ListOptions) ([]historyv1alpha1.KronoformSnapshot, error) {
+	snapshots := &historyv1alpha1.KronoformSnapshotList{}
+	if err := s.Client.List(ctx, snapshots, listOptions(opts)...); err != nil {
+		return nil, err
+	}
+	for i := range snapshots.Items {
+		if err := s.decodeSnapshot(ctx, &snapshots.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+	sortByKey(snapshots.Items)
+	return snapshots.Items, nil
+}
+
+// ListHistories implements Store.
+func (s *CRDStore) ListHistories(ctx context.Context, opts ListOptions) ([]historyv1alpha1.KronoformHistory, error) {
+	histories := &historyv1alpha1.KronoformHistoryList{}
+	if err := s.Client.List(ctx, histories, listOptions(opts)...); err != nil {
+		return nil, err
+	}
+	for i := range histories.Items {
+		if err := s.decodeHistory(ctx, &histories.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+	sortByKey(histories.Items)
+	return histories.Items, nil
+}
+
+// DeleteSnapshot implements Store.
+func (s *CRDStore) DeleteSnapshot(ctx context.Context, namespace, name string) error {
+	snapshot := &historyv1alpha1.KronoformSnapshot{}
+	snapshot.Namespace, snapshot.Name = namespace, name
+	return s.Client.Delete(ctx, snapshot)
+}
+
+// DeleteHistory implements Store. Chunks and snapshots owned by the history are
+// removed by the garbage collector.
+func (s *CRDStore) DeleteHistory(ctx context.Context, namespace, name string) error {
+	h := &historyv1alpha1.KronoformHistory{}
+	h.Namespace, h.Name = namespace, name
+	return s.Client.Delete(ctx, h)
+}
+
+func (s *CRDStore) decodeSnapshot(ctx context.Context, snapshot *historyv1alpha1.KronoformSnapshot) error {
+	data, err := payload.Reassemble(ctx, s.Client, snapshot, snapshot.Spec.Manifests, snapshot.Spec.ManifestsChunks)
+	if err != nil {
+		return fmt.Errorf("failed to read manifests of snapshot %s: %w", snapshot.Name, err)
+	}
+	snapshot.Spec.Manifests, snapshot.Spec.ManifestsChunks = data, 0
+	return plainSnapshot(s.Keyring, snapshot)
+}
+
+func (s *CRDStore) decodeHistory(ctx context.Context, h *historyv1alpha1.KronoformHistory) error {
+	data, err := payload.Reassemble(ctx, s.Client, h, h.Spec.Manifests, h.Spec.ManifestsChunks)
+	if err != nil {
+		return fmt.Errorf("failed to read manifests of history %s: %w", h.Name, err)
+	}
+	h.Spec.Manifests, h.Spec.ManifestsChunks = data, 0
+	if err := payload.ReassembleStates(ctx, s.Client, h); err != nil {
+		return fmt.Errorf("failed to read states of history %s: %w", h.Name, err)
+	}
+	return plainHistory(s.Keyring, h)
+}
+
+func listOptions(opts ListOptions) []client.ListOption {
+	var options []client.ListOption
+	if opts.Namespace != "" {
+		options = append(options, client.InNamespace(opts.Namespace))
+	}
+	if len(opts.Labels) > 0 {
+		options = append(options, client.MatchingLabels(opts.Labels))
+	}
+	return options
+}
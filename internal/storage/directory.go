@@ -0,0 +1,162 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+func init() {
+	Register(BackendDirectory, func(cfg Config) (Store, error) {
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("the %s storage backend requires a path", BackendDirectory)
+		}
+		return NewDirectoryStore(cfg.Path), nil
+	})
+}
+
+// NewDirectoryStore returns a Store that keeps records as YAML files below root, one per
+// record at <namespace>/<resource>/<name>.yaml, for use without a cluster or to keep
+// records under version control.
+func NewDirectoryStore(root string) Store {
+	return &objectStore{
+		snapshots: &directoryTable[historyv1alpha1.KronoformSnapshot]{
+			root: root, resource: snapshotResource.Resource, kind: "KronoformSnapshot",
+		},
+		histories: &directoryTable[historyv1alpha1.KronoformHistory]{
+			root: root, resource: historyResource.Resource, kind: "KronoformHistory",
+		},
+	}
+}
+
+// directoryTable keeps the records of one kind in files. The objectStore serializes
+// access within a process; concurrent writers in other processes are not coordinated.
+type directoryTable[T any] struct {
+	root     string
+	resource string
+	kind     string
+}
+
+func (t *directoryTable[T]) path(key types.NamespacedName) (string, error) {
+	// Names end up in file paths, so they must not be able to escape the root
+	for _, part := range []string{key.Namespace, key.Name} {
+		if errs := validation.IsDNS1123Subdomain(part); part != "" && len(errs) > 0 {
+			return "", fmt.Errorf("invalid name %q: %s", part, strings.Join(errs, ", "))
+		}
+	}
+	return filepath.Join(t.root, namespaceDir(key.Namespace), t.resource, key.Name+".yaml"), nil
+}
+
+func (t *directoryTable[T]) load(_ context.Context, key types.NamespacedName) (*T, error) {
+	path, err := t.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return t.read(path)
+}
+
+func (t *directoryTable[T]) read(path string) (*T, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - path is built from validated names
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	obj := new(T)
+	if err := yaml.Unmarshal(data, obj); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return obj, nil
+}
+
+func (t *directoryTable[T]) store(_ context.Context, obj *T) error {
+	object := any(obj).(client.Object)
+	object.GetObjectKind().SetGroupVersionKind(historyv1alpha1.GroupVersion.WithKind(t.kind))
+	path, err := t.path(client.ObjectKeyFromObject(object))
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	// Write through a temporary file so that a record is never left half written
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (t *directoryTable[T]) remove(_ context.Context, key types.NamespacedName) (bool, error) {
+	path, err := t.path(key)
+	if err != nil {
+		return false, err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (t *directoryTable[T]) all(_ context.Context, namespace string) ([]T, error) {
+	pattern := filepath.Join(t.root, "*", t.resource, "*.yaml")
+	if namespace != "" {
+		pattern = filepath.Join(t.root, namespaceDir(namespace), t.resource, "*.yaml")
+	}
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var items []T
+	for _, path := range paths {
+		obj, err := t.read(path)
+		if err != nil {
+			return nil, err
+		}
+		if obj != nil {
+			items = append(items, *obj)
+		}
+	}
+	return items, nil
+}
+
+// namespaceDir is the directory holding the records of a namespace. Records without a
+// namespace are kept apart under a name no namespace can have.
+func namespaceDir(namespace string) string {
+	if namespace == "" {
+		return "_"
+	}
+	return namespace
+}
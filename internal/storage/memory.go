@@ -0,0 +1,74 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+func init() {
+	Register(BackendMemory, func(Config) (Store, error) {
+		return NewMemoryStore(), nil
+	})
+}
+
+// NewMemoryStore returns a Store that keeps records in memory. Records are lost when
+// the process exits, so it is meant for tests.
+func NewMemoryStore() Store {
+	return &objectStore{
+		snapshots: &memoryTable[historyv1alpha1.KronoformSnapshot]{},
+		histories: &memoryTable[historyv1alpha1.KronoformHistory]{},
+	}
+}
+
+// memoryTable keeps records in a map. The objectStore serializes access to it.
+type memoryTable[T any] struct {
+	items map[types.NamespacedName]*T
+}
+
+func (t *memoryTable[T]) load(_ context.Context, key types.NamespacedName) (*T, error) {
+	return t.items[key], nil
+}
+
+func (t *memoryTable[T]) store(_ context.Context, obj *T) error {
+	if t.items == nil {
+		t.items = map[types.NamespacedName]*T{}
+	}
+	t.items[client.ObjectKeyFromObject(any(obj).(client.Object))] = obj
+	return nil
+}
+
+func (t *memoryTable[T]) remove(_ context.Context, key types.NamespacedName) (bool, error) {
+	_, ok := t.items[key]
+	delete(t.items, key)
+	return ok, nil
+}
+
+func (t *memoryTable[T]) all(_ context.Context, namespace string) ([]T, error) {
+	var items []T
+	for key, obj := range t.items {
+		if namespace == "" || key.Namespace == namespace {
+			items = append(items, *obj)
+		}
+	}
+	return items, nil
+}
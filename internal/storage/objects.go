@@ -0,0 +1,225 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/envelope"
+	"github.com/yu-kod/kronoform/internal/history"
+)
+
+// record constrains the record types stored by an objectStore.
+type record[T any] interface {
+	*T
+	client.Object
+}
+
+// table holds the records of one kind for backends that store the objects themselves.
+type table[T any] interface {
+	// load returns the record with the given key, or nil if there is none.
+	load(ctx context.Context, key types.NamespacedName) (*T, error)
+	// store creates or replaces a record.
+	store(ctx context.Context, obj *T) error
+	// remove deletes a record, reporting whether it existed.
+	remove(ctx context.Context, key types.NamespacedName) (bool, error)
+	// all returns the records in a namespace, or in all namespaces if empty.
+	all(ctx context.Context, namespace string) ([]T, error)
+}
+
+// objectStore implements Store on top of tables, giving the memory and directory
+// backends the semantics of the API server: generated names, resource versions and
+// specs that cannot change once saved.
+type objectStore struct {
+	mu        sync.Mutex
+	snapshots table[historyv1alpha1.KronoformSnapshot]
+	histories table[historyv1alpha1.KronoformHistory]
+}
+
+var _ Store = &objectStore{}
+
+func (s *objectStore) SaveSnapshot(ctx context.Context, snapshot *historyv1alpha1.KronoformSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return save(ctx, s.snapshots, snapshotResource, snapshot, unencrypted(plainSnapshot),
+		func(stored, obj *historyv1alpha1.KronoformSnapshot) error {
+			stored.Status = *obj.Status.DeepCopy()
+			return nil
+		})
+}
+
+func (s *objectStore) SaveHistory(ctx context.Context, h *historyv1alpha1.KronoformHistory) error {
+	if h.ResourceVersion == "" {
+		if err := history.AssignID(h); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return save(ctx, s.histories, historyResource, h, unencrypted(plainHistory),
+		func(stored, obj *historyv1alpha1.KronoformHistory) error {
+			stored.Status = *obj.Status.DeepCopy()
+			return plainStates(nil, stored)
+		})
+}
+
+func (s *objectStore) GetSnapshot(ctx context.Context, namespace, name string) (*historyv1alpha1.KronoformSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return get(ctx, s.snapshots, snapshotResource, namespace, name)
+}
+
+func (s *objectStore) GetHistory(ctx context.Context, namespace, name string) (*historyv1alpha1.KronoformHistory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return get(ctx, s.histories, historyResource, namespace, name)
+}
+
+func (s *objectStore) ListSnapshots(ctx context.Context, opts ListOptions) ([]historyv1alpha1.KronoformSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return list(ctx, s.snapshots, opts)
+}
+
+func (s *objectStore) ListHistories(ctx context.Context, opts ListOptions) ([]historyv1alpha1.KronoformHistory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return list(ctx, s.histories, opts)
+}
+
+func (s *objectStore) DeleteSnapshot(ctx context.Context, namespace, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return remove(ctx, s.snapshots, snapshotResource, namespace, name)
+}
+
+func (s *objectStore) DeleteHistory(ctx context.Context, namespace, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return remove(ctx, s.histories, historyResource, namespace, name)
+}
+
+// unencrypted adapts a decoding function to the records of an objectStore, which does
+// not support encryption.
+func unencrypted[PT any](plain func(*envelope.Keyring, PT) error) func(PT) error {
+	return func(obj PT) error {
+		return plain(nil, obj)
+	}
+}
+
+// save creates obj when it has no resource version yet, and otherwise replaces the
+// metadata and, through setStatus, the status of the stored record.
+func save[T any, PT record[T]](ctx context.Context, t table[T], resource schema.GroupResource, obj PT,
+	plain func(PT) error, setStatus func(stored, obj PT) error) error {
+	if obj.GetResourceVersion() == "" {
+		stored := obj.DeepCopyObject().(PT)
+		if err := plain(stored); err != nil {
+			return err
+		}
+		if stored.GetName() != "" {
+			existing, err := t.load(ctx, client.ObjectKeyFromObject(stored))
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				return apierrors.NewAlreadyExists(resource, stored.GetName())
+			}
+		}
+		if err := assignMetadata(stored); err != nil {
+			return err
+		}
+		stored.SetResourceVersion("1")
+		if err := t.store(ctx, (*T)(stored)); err != nil {
+			return err
+		}
+		*obj = *(*T)(stored.DeepCopyObject().(PT))
+		return nil
+	}
+
+	existing, err := t.load(ctx, client.ObjectKeyFromObject(obj))
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return notFound(resource, obj.GetName())
+	}
+	stored := PT(existing)
+	if stored.GetResourceVersion() != obj.GetResourceVersion() {
+		return apierrors.NewConflict(resource, obj.GetName(),
+			fmt.Errorf("the record was modified: resource version %s is not the latest", obj.GetResourceVersion()))
+	}
+	stored.SetLabels(obj.GetLabels())
+	stored.SetAnnotations(obj.GetAnnotations())
+	stored.SetOwnerReferences(obj.GetOwnerReferences())
+	stored.SetFinalizers(obj.GetFinalizers())
+	if err := setStatus(stored, obj); err != nil {
+		return err
+	}
+	version, _ := strconv.Atoi(stored.GetResourceVersion())
+	stored.SetResourceVersion(strconv.Itoa(version + 1))
+	if err := t.store(ctx, existing); err != nil {
+		return err
+	}
+	*obj = *(*T)(stored.DeepCopyObject().(PT))
+	return nil
+}
+
+func get[T any, PT record[T]](ctx context.Context, t table[T], resource schema.GroupResource, namespace, name string) (*T, error) {
+	existing, err := t.load(ctx, types.NamespacedName{Namespace: namespace, Name: name})
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, notFound(resource, name)
+	}
+	return (*T)(PT(existing).DeepCopyObject().(PT)), nil
+}
+
+func list[T any, PT record[T]](ctx context.Context, t table[T], opts ListOptions) ([]T, error) {
+	all, err := t.all(ctx, opts.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	var items []T
+	for i := range all {
+		if opts.matches(PT(&all[i])) {
+			items = append(items, *(*T)(PT(&all[i]).DeepCopyObject().(PT)))
+		}
+	}
+	sortByKey[T, PT](items)
+	return items, nil
+}
+
+func remove[T any](ctx context.Context, t table[T], resource schema.GroupResource, namespace, name string) error {
+	removed, err := t.remove(ctx, types.NamespacedName{Namespace: namespace, Name: name})
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return notFound(resource, name)
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+)
+
+// Environment variables holding the credentials of the s3 backend, as used by the AWS CLI.
+const (
+	envAccessKeyID     = "AWS_ACCESS_KEY_ID"
+	envSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+	envSessionToken    = "AWS_SESSION_TOKEN"
+)
+
+// S3Config configures the s3 backend. Credentials are read from the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables so that they are not
+// kept in configuration files.
+type S3Config struct {
+	// Endpoint is the URL of the S3 API, e.g. http://localhost:9000 for MinIO. It defaults
+	// to the AWS endpoint of Region.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Region is the region requests are signed for. It defaults to us-east-1.
+	Region string `json:"region,omitempty"`
+	// Bucket is the bucket histories are archived in.
+	Bucket string `json:"bucket"`
+	// Prefix is prepended to the keys of archived histories.
+	Prefix string `json:"prefix,omitempty"`
+	// Cluster identifies the cluster in the keys of archived histories. It defaults to "default".
+	Cluster string `json:"cluster,omitempty"`
+	// PathStyle addresses the bucket in the path rather than the host name, as MinIO and
+	// most other S3-compatible servers require.
+	PathStyle bool `json:"pathStyle,omitempty"`
+	// WriteOnce only creates objects whose key is free, so that archives are never
+	// overwritten. Combine it with object lock or a bucket policy denying deletes for a
+	// write-once bucket.
+	WriteOnce bool `json:"writeOnce,omitempty"`
+}
+
+func init() {
+	Register(BackendS3, func(cfg Config) (Store, error) {
+		if cfg.S3 == nil || cfg.S3.Bucket == "" {
+			return nil, fmt.Errorf("the %s storage backend requires a bucket", BackendS3)
+		}
+		crd, err := New(Config{Backend: BackendCRD, Client: cfg.Client, Keyring: cfg.Keyring})
+		if err != nil {
+			return nil, err
+		}
+		credentials := S3Credentials{
+			AccessKeyID:     os.Getenv(envAccessKeyID),
+			SecretAccessKey: os.Getenv(envSecretAccessKey),
+			SessionToken:    os.Getenv(envSessionToken),
+		}
+		if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+			return nil, fmt.Errorf("the %s storage backend requires %s and %s", BackendS3, envAccessKeyID, envSecretAccessKey)
+		}
+		return NewS3Store(crd.(*CRDStore), *cfg.S3, credentials, http.DefaultClient)
+	})
+}
+
+// S3Store archives histories in an S3-compatible bucket. Each history is written as one
+// object, keyed by cluster, namespace and time, holding its manifests and resource
+// states; the KronoformHistory resource keeps only a reference to it. Snapshots are kept
+// as custom resources.
+type S3Store struct {
+	*CRDStore
+
+	config S3Config
+	client *s3Client
+}
+
+var _ Store = &S3Store{}
+
+// NewS3Store returns a Store archiving histories in the bucket configured by cfg and
+// keeping the rest of the records with crd.
+func NewS3Store(crd *CRDStore, cfg S3Config, credentials S3Credentials, httpClient *http.Client) (*S3Store, error) {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	if cfg.Cluster == "" {
+		cfg.Cluster = "default"
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	return &S3Store{
+		CRDStore: crd,
+		config:   cfg,
+		client: &s3Client{
+			endpoint:    endpoint,
+			region:      cfg.Region,
+			bucket:      cfg.Bucket,
+			pathStyle:   cfg.PathStyle,
+			credentials: credentials,
+			http:        httpClient,
+			now:         time.Now,
+		},
+	}, nil
+}
+
+// SaveHistory implements Store. A new history is archived before its resource is created,
+// so that the resource never points at a missing object. An archive left behind by a
+// failed create is not referenced by any history.
+func (s *S3Store) SaveHistory(ctx context.Context, h *historyv1alpha1.KronoformHistory) error {
+	if h.ResourceVersion != "" {
+		pointer := h.DeepCopy()
+		delete(pointer.Annotations, history.UnreadableAnnotation)
+		stripArchived(pointer)
+		if err := s.CRDStore.SaveHistory(ctx, pointer); err != nil {
+			return err
+		}
+		h.ObjectMeta = pointer.ObjectMeta
+		return nil
+	}
+
+	if err := history.AssignID(h); err != nil {
+		return err
+	}
+	archived := h.DeepCopy()
+	if err := assignName(archived); err != nil {
+		return err
+	}
+	if err := plainHistory(s.Keyring, archived); err != nil {
+		return err
+	}
+	if err := s.Keyring.SealHistory(archived); err != nil {
+		return fmt.Errorf("failed to encrypt history %s: %w", archived.Name, err)
+	}
+	archived.SetGroupVersionKind(historyv1alpha1.GroupVersion.WithKind("KronoformHistory"))
+	data, err := yaml.Marshal(archived)
+	if err != nil {
+		return err
+	}
+
+	key := s.key(archived)
+	if err := s.client.put(ctx, key, data, s.config.WriteOnce); err != nil {
+		return fmt.Errorf("failed to archive history %s: %w", archived.Name, err)
+	}
+	sum := sha256.Sum256(data)
+	pointer := archived.DeepCopy()
+	pointer.Spec.Archive = &historyv1alpha1.ArchiveReference{
+		URL:    fmt.Sprintf("s3://%s/%s", s.config.Bucket, key),
+		SHA256: hex.EncodeToString(sum[:]),
+		Size:   int64(len(data)),
+	}
+	stripArchived(pointer)
+	if err := s.CRDStore.SaveHistory(ctx, pointer); err != nil {
+		return err
+	}
+	h.ObjectMeta = pointer.ObjectMeta
+	h.Spec.Archive = pointer.Spec.Archive
+	return nil
+}
+
+// GetHistory implements Store, reading archived content back from the bucket.
+func (s *S3Store) GetHistory(ctx context.Context, namespace, name string) (*historyv1alpha1.KronoformHistory, error) {
+	h, err := s.CRDStore.GetHistory(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.restore(ctx, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ListHistories implements Store, reading archived content back from the bucket. Histories
+// whose archive cannot be read are returned without their content, marked with
+// history.UnreadableAnnotation, so that one such history does not hide the others.
+func (s *S3Store) ListHistories(ctx context.Context, opts ListOptions) ([]historyv1alpha1.KronoformHistory, error) {
+	histories, err := s.CRDStore.ListHistories(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range histories {
+		h := &histories[i]
+		if err := s.restore(ctx, h); err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			if h.Annotations == nil {
+				h.Annotations = map[string]string{}
+			}
+			h.Annotations[history.UnreadableAnnotation] = err.Error()
+		}
+	}
+	return histories, nil
+}
+
+// DeleteHistory implements Store. Only the resource is deleted; the archived object is
+// left to the retention rules of the bucket.
+func (s *S3Store) DeleteHistory(ctx context.Context, namespace, name string) error {
+	return s.CRDStore.DeleteHistory(ctx, namespace, name)
+}
+
+// key returns the object key of a history: <prefix>/<cluster>/<namespace>/<date>/<time>-<name>.yaml.
+func (s *S3Store) key(h *historyv1alpha1.KronoformHistory) string {
+	at := time.Now().UTC()
+	if h.Status.AppliedAt != nil {
+		at = h.Status.AppliedAt.UTC()
+	}
+	namespace := h.Namespace
+	if namespace == "" {
+		namespace = "_"
+	}
+	return path.Join(s.config.Prefix, s.config.Cluster, namespace, at.Format("2006/01/02"),
+		at.Format("20060102T150405Z")+"-"+h.Name+".yaml")
+}
+
+// restore fills in the manifests and resource states of an archived history, checking
+// that the archive is the one the history references.
+func (s *S3Store) restore(ctx context.Context, h *historyv1alpha1.KronoformHistory) error {
+	ref := h.Spec.Archive
+	if ref == nil {
+		return nil
+	}
+	prefix := fmt.Sprintf("s3://%s/", s.config.Bucket)
+	if !strings.HasPrefix(ref.URL, prefix) {
+		return fmt.Errorf("history %s is archived in %s, not in bucket %s", h.Name, ref.URL, s.config.Bucket)
+	}
+	data, err := s.client.get(ctx, strings.TrimPrefix(ref.URL, prefix))
+	if errors.Is(err, errNoSuchKey) {
+		return fmt.Errorf("archive of history %s no longer exists: %s", h.Name, ref.URL)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read archive of history %s: %w", h.Name, err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != ref.SHA256 {
+		return fmt.Errorf("archive of history %s does not match its digest: %s", h.Name, ref.URL)
+	}
+
+	archived := &historyv1alpha1.KronoformHistory{}
+	if err := yaml.Unmarshal(data, archived); err != nil {
+		return fmt.Errorf("invalid archive of history %s: %w", h.Name, err)
+	}
+	if archived.Name != h.Name || archived.Namespace != h.Namespace {
+		return fmt.Errorf("archive %s holds history %s/%s, not %s/%s",
+			ref.URL, archived.Namespace, archived.Name, h.Namespace, h.Name)
+	}
+	if err := plainHistory(s.Keyring, archived); err != nil {
+		return err
+	}
+	// Content that could not be decrypted keeps its encoding and data key, so that it is
+	// not taken for plain content
+	h.Spec.Manifests, h.Spec.ManifestsEncoding, h.Spec.ManifestsKey =
+		archived.Spec.Manifests, archived.Spec.ManifestsEncoding, archived.Spec.ManifestsKey
+	for i := range h.Status.ResourceSnapshots {
+		if i < len(archived.Status.ResourceSnapshots) {
+			rs, from := &h.Status.ResourceSnapshots[i], archived.Status.ResourceSnapshots[i]
+			rs.Before, rs.After, rs.Encoding, rs.Key = from.Before, from.After, from.Encoding, from.Key
+		}
+	}
+	return nil
+}
+
+// stripArchived removes the archived content from a history, keeping the reference.
+func stripArchived(h *historyv1alpha1.KronoformHistory) {
+	if h.Spec.Archive == nil {
+		return
+	}
+	h.Spec.Manifests, h.Spec.ManifestsEncoding, h.Spec.ManifestsChunks, h.Spec.ManifestsKey = "", historyv1alpha1.EncodingPlain, 0, nil
+	for i := range h.Status.ResourceSnapshots {
+		rs := &h.Status.ResourceSnapshots[i]
+		rs.Before, rs.After, rs.Encoding, rs.Key = "", "", historyv1alpha1.EncodingPlain, nil
+	}
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package storage persists snapshots and histories for the kubectl plugin. The Store
+// interface hides where records live: the CRD backend keeps them in the cluster, the
+// s3 backend archives histories in object storage, the directory backend keeps records
+// in local YAML files and the memory backend in process, for tests.
+// Other backends register themselves with Register and are selected by name in Config.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/envelope"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+// Store saves, reads and deletes kronoform records. Records returned by a Store carry
+// their manifests and resource states decoded, whatever encoding the backend uses.
+// Encrypted content the Store cannot decrypt, for lack of a keyring or of the key it was
+// encrypted with, is returned as stored and keeps EncodingEncrypted, so that one such
+// record does not hide the others; commands that need it check with history.Readable.
+// Lookups of missing records fail with a Kubernetes NotFound error, so callers can use
+// apierrors.IsNotFound with every backend.
+type Store interface {
+	// SaveSnapshot creates the snapshot, or updates the metadata and status of an
+	// existing one when it carries a resource version. The spec of a saved snapshot
+	// never changes. The snapshot is updated with the name and metadata assigned.
+	SaveSnapshot(ctx context.Context, snapshot *historyv1alpha1.KronoformSnapshot) error
+	// SaveHistory creates or updates a history like SaveSnapshot.
+	SaveHistory(ctx context.Context, history *historyv1alpha1.KronoformHistory) error
+	// GetSnapshot returns a snapshot by namespace and name.
+	GetSnapshot(ctx context.Context, namespace, name string) (*historyv1alpha1.KronoformSnapshot, error)
+	// GetHistory returns a history by namespace and name.
+	GetHistory(ctx context.Context, namespace, name string) (*historyv1alpha1.KronoformHistory, error)
+	// ListSnapshots returns the snapshots matching opts, ordered by namespace and name.
+	ListSnapshots(ctx context.Context, opts ListOptions) ([]historyv1alpha1.KronoformSnapshot, error)
+	// ListHistories returns the histories matching opts, ordered by namespace and name.
+	ListHistories(ctx context.Context, opts ListOptions) ([]historyv1alpha1.KronoformHistory, error)
+	// DeleteSnapshot deletes a snapshot.
+	DeleteSnapshot(ctx context.Context, namespace, name string) error
+	// DeleteHistory deletes a history.
+	DeleteHistory(ctx context.Context, namespace, name string) error
+}
+
+// ListOptions filters listed records.
+type ListOptions struct {
+	// Namespace restricts the records to a namespace. All namespaces are listed if empty.
+	Namespace string
+	// Labels restricts the records to those carrying all of these labels.
+	Labels map[string]string
+}
+
+// matches reports whether a record satisfies the options.
+func (o ListOptions) matches(obj metav1.Object) bool {
+	if o.Namespace != "" && obj.GetNamespace() != o.Namespace {
+		return false
+	}
+	return labels.SelectorFromSet(o.Labels).Matches(labels.Set(obj.GetLabels()))
+}
+
+// Backend names.
+const (
+	BackendCRD       = "crd"
+	BackendMemory    = "memory"
+	BackendDirectory = "directory"
+	BackendS3        = "s3"
+)
+
+// Config selects and configures a storage backend.
+type Config struct {
+	// Backend is the name of the backend. The CRD backend is used if empty.
+	Backend string `json:"backend,omitempty"`
+	// Path is the directory the directory backend stores records in.
+	Path string `json:"path,omitempty"`
+	// S3 configures the s3 backend.
+	S3 *S3Config `json:"s3,omitempty"`
+
+	// Client returns the Kubernetes client used by the CRD backend.
+	Client func() (client.Client, error) `json:"-"`
+	// Keyring encrypts the manifests and resource states of records saved by the CRD and
+	// s3 backends, and decrypts them on read. Records are saved unencrypted if nil.
+	Keyring *envelope.Keyring `json:"-"`
+}
+
+// Factory creates a Store from its configuration.
+type Factory func(cfg Config) (Store, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+)
+
+// Register makes a backend available under a name. It panics if the name is taken, as
+// backends register themselves from init functions.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("storage backend %q is already registered", name))
+	}
+	factories[name] = factory
+}
+
+// Backends returns the names of the registered backends.
+func Backends() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the Store selected by cfg.
+func New(cfg Config) (Store, error) {
+	backend := cfg.Backend
+	if backend == "" {
+		backend = BackendCRD
+	}
+	factoriesMu.RLock()
+	factory, ok := factories[backend]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage backend %q (available: %v)", backend, Backends())
+	}
+	if cfg.Keyring != nil && backend != BackendCRD && backend != BackendS3 {
+		return nil, fmt.Errorf("the %s storage backend does not support encryption", backend)
+	}
+	return factory(cfg)
+}
+
+var (
+	snapshotResource = historyv1alpha1.GroupVersion.WithResource("kronoformsnapshots").GroupResource()
+	historyResource  = historyv1alpha1.GroupVersion.WithResource("kronoformhistories").GroupResource()
+)
+
+func notFound(resource schema.GroupResource, name string) error {
+	return apierrors.NewNotFound(resource, name)
+}
+
+// assignMetadata fills in the metadata the API server would assign to a new record in
+// backends that do not run one.
+func assignMetadata(obj metav1.Object) error {
+	if err := assignName(obj); err != nil {
+		return err
+	}
+	obj.SetUID(uuid.NewUUID())
+	obj.SetCreationTimestamp(metav1.Now())
+	return nil
+}
+
+// assignName generates the name of a new record from its generateName, as the API server
+// would, for backends that need the name before the record is created.
+func assignName(obj metav1.Object) error {
+	if obj.GetName() != "" {
+		return nil
+	}
+	if obj.GetGenerateName() == "" {
+		return fmt.Errorf("name or generateName is required")
+	}
+	obj.SetName(obj.GetGenerateName() + utilrand.String(5))
+	return nil
+}
+
+// plainSnapshot decodes the manifests of a snapshot that are stored inline, decrypting
+// them with keyring if they are encrypted. Encrypted manifests it cannot decrypt are kept.
+func plainSnapshot(keyring *envelope.Keyring, s *historyv1alpha1.KronoformSnapshot) error {
+	if s.Spec.ManifestsChunks > 0 {
+		return fmt.Errorf("snapshot %s stores its manifests in chunks", s.Name)
+	}
+	manifests, err := keyring.Manifests(s.Spec.Manifests, s.Spec.ManifestsEncoding, s.Spec.ManifestsKey)
+	if err != nil {
+		if s.Spec.ManifestsEncoding == historyv1alpha1.EncodingEncrypted {
+			return nil
+		}
+		return fmt.Errorf("failed to decode manifests of snapshot %s: %w", s.Name, err)
+	}
+	s.Spec.Manifests, s.Spec.ManifestsEncoding, s.Spec.ManifestsKey = manifests, historyv1alpha1.EncodingPlain, nil
+	return nil
+}
+
+// plainHistory decodes the manifests stored inline and the resource states of a history.
+// Encrypted content it cannot decrypt is kept.
+func plainHistory(keyring *envelope.Keyring, h *historyv1alpha1.KronoformHistory) error {
+	if h.Spec.ManifestsChunks > 0 {
+		return fmt.Errorf("history %s stores its manifests in chunks", h.Name)
+	}
+	manifests, err := keyring.Manifests(h.Spec.Manifests, h.Spec.ManifestsEncoding, h.Spec.ManifestsKey)
+	switch {
+	case err == nil:
+		h.Spec.Manifests, h.Spec.ManifestsEncoding, h.Spec.ManifestsKey = manifests, historyv1alpha1.EncodingPlain, nil
+	case h.Spec.ManifestsEncoding != historyv1alpha1.EncodingEncrypted:
+		return fmt.Errorf("failed to decode manifests of history %s: %w", h.Name, err)
+	}
+	return plainStates(keyring, h)
+}
+
+// plainStates decodes the resource states of a history. Encrypted states it cannot
+// decrypt are kept.
+func plainStates(keyring *envelope.Keyring, h *historyv1alpha1.KronoformHistory) error {
+	for i := range h.Status.ResourceSnapshots {
+		rs := &h.Status.ResourceSnapshots[i]
+		before, after, err := keyring.States(*rs)
+		if err != nil {
+			if rs.Encoding == historyv1alpha1.EncodingEncrypted {
+				continue
+			}
+			return fmt.Errorf("failed to decode states of history %s: %w", h.Name, err)
+		}
+		rs.Before, rs.After, rs.Encoding, rs.Key = before, after, historyv1alpha1.EncodingPlain, nil
+	}
+	return nil
+}
+
+// encodeStatus prepares the resource states of a history for storage: they are
+// encrypted with keyring if set, and compressed when large otherwise.
+func encodeStatus(keyring *envelope.Keyring, status *historyv1alpha1.KronoformHistoryStatus) error {
+	if err := keyring.SealStatus(status); err != nil {
+		return err
+	}
+	for i := range status.ResourceSnapshots {
+		rs := &status.ResourceSnapshots[i]
+		if rs.Encoding == historyv1alpha1.EncodingPlain {
+			if err := payload.EncodeStates(rs, rs.Before, rs.After); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sortByKey orders records by namespace and name.
+func sortByKey[T any, PT interface {
+	*T
+	metav1.Object
+}](items []T) {
+	sort.Slice(items, func(i, j int) bool {
+		a, b := PT(&items[i]), PT(&items[j])
+		if a.GetNamespace() != b.GetNamespace() {
+			return a.GetNamespace() < b.GetNamespace()
+		}
+		return a.GetName() < b.GetName()
+	})
+}
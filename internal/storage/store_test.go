@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/envelope"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+// backends returns a fresh store of every built-in backend.
+func backends(t *testing.T) map[string]Store {
+	scheme := runtime.NewScheme()
+	if err := historyv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add scheme: %v", err)
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).
+		WithStatusSubresource(&historyv1alpha1.KronoformSnapshot{}, &historyv1alpha1.KronoformHistory{}).
+		Build()
+
+	return map[string]Store{
+		BackendCRD:       NewCRDStore(fakeClient),
+		BackendMemory:    NewMemoryStore(),
+		BackendDirectory: NewDirectoryStore(t.TempDir()),
+	}
+}
+
+func TestStore(t *testing.T) {
+	large := "apiVersion: v1\nkind: ConfigMap\ndata:\n  blob: " + strings.Repeat("x", 2*payload.CompressThreshold) + "\n"
+
+	for name, store := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			g := gomega.NewGomegaWithT(t)
+			ctx := context.Background()
+
+			snapshot := &historyv1alpha1.KronoformSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: "snapshot", Namespace: "default", Labels: map[string]string{"team": "a"}},
+				Spec:       historyv1alpha1.KronoformSnapshotSpec{Manifests: large},
+				Status:     historyv1alpha1.KronoformSnapshotStatus{Phase: "Pending"},
+			}
+			g.Expect(store.SaveSnapshot(ctx, snapshot)).To(gomega.Succeed())
+			g.Expect(snapshot.ResourceVersion).NotTo(gomega.BeEmpty())
+			g.Expect(apierrors.IsAlreadyExists(store.SaveSnapshot(ctx, &historyv1alpha1.KronoformSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: "snapshot", Namespace: "default"},
+			}))).To(gomega.BeTrue())
+
+			h := &historyv1alpha1.KronoformHistory{
+				ObjectMeta: metav1.ObjectMeta{GenerateName: "kronoform-history-", Namespace: "default"},
+				Spec:       historyv1alpha1.KronoformHistorySpec{Manifests: large, SnapshotRef: snapshot.Name},
+				Status: historyv1alpha1.KronoformHistoryStatus{
+					Summary:           "Successfully applied manifests",
+					ResourceSnapshots: []historyv1alpha1.ResourceSnapshot{{Kind: "ConfigMap", Name: "blob", After: large}},
+				},
+			}
+			g.Expect(store.SaveHistory(ctx, h)).To(gomega.Succeed())
+			g.Expect(h.Name).To(gomega.HavePrefix("kronoform-history-"))
+
+			// Records are read back decoded, with their status
+			storedSnapshot, err := store.GetSnapshot(ctx, "default", "snapshot")
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(storedSnapshot.Spec.Manifests).To(gomega.Equal(large))
+			g.Expect(storedSnapshot.Status.Phase).To(gomega.Equal("Pending"))
+
+			storedHistory, err := store.GetHistory(ctx, "default", h.Name)
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(storedHistory.Spec.Manifests).To(gomega.Equal(large))
+			g.Expect(storedHistory.Status.Summary).To(gomega.Equal("Successfully applied manifests"))
+			g.Expect(storedHistory.Status.ResourceSnapshots[0].After).To(gomega.Equal(large))
+
+			// Saving an existing record updates its metadata and status
+			storedSnapshot.Status.Phase = "Completed"
+			storedSnapshot.Status.HistoryRef = h.Name
+			storedSnapshot.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: historyv1alpha1.GroupVersion.String(), Kind: "KronoformHistory", Name: h.Name, UID: h.UID,
+			}}
+			g.Expect(store.SaveSnapshot(ctx, storedSnapshot)).To(gomega.Succeed())
+			storedSnapshot, err = store.GetSnapshot(ctx, "default", "snapshot")
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(storedSnapshot.Status.HistoryRef).To(gomega.Equal(h.Name))
+			g.Expect(storedSnapshot.OwnerReferences).To(gomega.HaveLen(1))
+
+			other := &historyv1alpha1.KronoformSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "staging"},
+				Spec:       historyv1alpha1.KronoformSnapshotSpec{Manifests: "kind: ConfigMap\n"},
+			}
+			g.Expect(store.SaveSnapshot(ctx, other)).To(gomega.Succeed())
+
+			snapshots, err := store.ListSnapshots(ctx, ListOptions{})
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(snapshots).To(gomega.HaveLen(2))
+			g.Expect(snapshots[0].Name).To(gomega.Equal("snapshot"))
+			snapshots, err = store.ListSnapshots(ctx, ListOptions{Namespace: "staging"})
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(snapshots).To(gomega.HaveLen(1))
+			g.Expect(snapshots[0].Name).To(gomega.Equal("other"))
+			snapshots, err = store.ListSnapshots(ctx, ListOptions{Labels: map[string]string{"team": "a"}})
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(snapshots).To(gomega.HaveLen(1))
+			g.Expect(snapshots[0].Name).To(gomega.Equal("snapshot"))
+
+			histories, err := store.ListHistories(ctx, ListOptions{Namespace: "default"})
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(histories).To(gomega.HaveLen(1))
+
+			g.Expect(store.DeleteSnapshot(ctx, "staging", "other")).To(gomega.Succeed())
+			_, err = store.GetSnapshot(ctx, "staging", "other")
+			g.Expect(apierrors.IsNotFound(err)).To(gomega.BeTrue())
+			g.Expect(apierrors.IsNotFound(store.DeleteHistory(ctx, "default", "missing"))).To(gomega.BeTrue())
+		})
+	}
+}
+
+func TestCRDStoreEncryption(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	g.Expect(historyv1alpha1.AddToScheme(scheme)).To(gomega.Succeed())
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).
+		WithStatusSubresource(&historyv1alpha1.KronoformSnapshot{}, &historyv1alpha1.KronoformHistory{}).
+		Build()
+	keyring, err := envelope.Parse([]byte("current: k1\nkeys:\n  k1: " +
+		base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", envelope.KeySize))) + "\n"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	store := NewCRDStore(fakeClient)
+	store.Keyring = keyring
+
+	manifests := "apiVersion: v1\nkind: ConfigMap\ndata:\n  password: hunter2\n"
+	g.Expect(store.SaveSnapshot(ctx, &historyv1alpha1.KronoformSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "snapshot", Namespace: "default"},
+		Spec:       historyv1alpha1.KronoformSnapshotSpec{Manifests: manifests},
+	})).To(gomega.Succeed())
+	h := &historyv1alpha1.KronoformHistory{
+		ObjectMeta: metav1.ObjectMeta{Name: "history", Namespace: "default"},
+		Spec:       historyv1alpha1.KronoformHistorySpec{Manifests: manifests, SnapshotRef: "snapshot"},
+		Status: historyv1alpha1.KronoformHistoryStatus{
+			ResourceSnapshots: []historyv1alpha1.ResourceSnapshot{{Kind: "ConfigMap", Name: "settings", After: manifests}},
+		},
+	}
+	g.Expect(store.SaveHistory(ctx, h)).To(gomega.Succeed())
+
+	// Records are stored encrypted
+	raw := &historyv1alpha1.KronoformHistory{}
+	g.Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(h), raw)).To(gomega.Succeed())
+	g.Expect(raw.Spec.ManifestsEncoding).To(gomega.Equal(historyv1alpha1.EncodingEncrypted))
+	g.Expect(raw.Spec.ManifestsKey.KeyID).To(gomega.Equal("k1"))
+	g.Expect(raw.Spec.Manifests).NotTo(gomega.ContainSubstring("hunter2"))
+	g.Expect(raw.Status.ResourceSnapshots[0].Encoding).To(gomega.Equal(historyv1alpha1.EncodingEncrypted))
+	rawSnapshot := &historyv1alpha1.KronoformSnapshot{}
+	g.Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "snapshot"}, rawSnapshot)).To(gomega.Succeed())
+	g.Expect(rawSnapshot.Spec.ManifestsEncoding).To(gomega.Equal(historyv1alpha1.EncodingEncrypted))
+
+	// and read back decrypted
+	stored, err := store.GetHistory(ctx, "default", "history")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(stored.Spec.Manifests).To(gomega.Equal(manifests))
+	g.Expect(stored.Spec.ManifestsKey).To(gomega.BeNil())
+	g.Expect(stored.Status.ResourceSnapshots[0].After).To(gomega.Equal(manifests))
+	snapshot, err := store.GetSnapshot(ctx, "default", "snapshot")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(snapshot.Spec.Manifests).To(gomega.Equal(manifests))
+
+	// Status updates keep unchanged states as they are stored
+	stored.Status.Summary = "Successfully applied manifests"
+	g.Expect(store.SaveHistory(ctx, stored)).To(gomega.Succeed())
+	updated := &historyv1alpha1.KronoformHistory{}
+	g.Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(h), updated)).To(gomega.Succeed())
+	g.Expect(updated.Status.Summary).To(gomega.Equal("Successfully applied manifests"))
+	g.Expect(updated.Status.ResourceSnapshots).To(gomega.Equal(raw.Status.ResourceSnapshots))
+
+	// Without the keyring, encrypted content is listed as stored next to readable records
+	g.Expect(NewCRDStore(fakeClient).SaveHistory(ctx, &historyv1alpha1.KronoformHistory{
+		ObjectMeta: metav1.ObjectMeta{Name: "plain", Namespace: "default"},
+		Spec:       historyv1alpha1.KronoformHistorySpec{Manifests: "kind: Service\n", SnapshotRef: "snapshot"},
+	})).To(gomega.Succeed())
+	histories, err := NewCRDStore(fakeClient).ListHistories(ctx, ListOptions{Namespace: "default"})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(histories).To(gomega.HaveLen(2))
+	g.Expect(histories[0].Spec.ManifestsEncoding).To(gomega.Equal(historyv1alpha1.EncodingEncrypted))
+	g.Expect(histories[0].Status.ResourceSnapshots[0].Encoding).To(gomega.Equal(historyv1alpha1.EncodingEncrypted))
+	g.Expect(history.Readable(&histories[0])).To(gomega.MatchError(payload.ErrEncrypted))
+	g.Expect(history.Readable(&histories[1])).To(gomega.Succeed())
+	g.Expect(histories[1].Spec.Manifests).To(gomega.Equal("kind: Service\n"))
+
+	_, err = New(Config{Backend: BackendDirectory, Path: t.TempDir(), Keyring: keyring})
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("does not support encryption")))
+}
+
+func TestNew(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	g.Expect(Backends()).To(gomega.ContainElements(BackendCRD, BackendMemory, BackendDirectory))
+
+	store, err := New(Config{Backend: BackendDirectory, Path: t.TempDir()})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(store).NotTo(gomega.BeNil())
+
+	_, err = New(Config{Backend: BackendDirectory})
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("requires a path")))
+	_, err = New(Config{})
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("requires a Kubernetes client")))
+	_, err = New(Config{Backend: BackendS3})
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("requires a bucket")))
+	_, err = New(Config{Backend: "etcd"})
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring(`unknown storage backend "etcd"`)))
+}
+
+func TestDirectoryStoreRejectsUnsafeNames(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	store := NewDirectoryStore(t.TempDir())
+	_, err := store.GetHistory(context.Background(), "default", "../../etc/passwd")
+	g.Expect(err).To(gomega.MatchError(gomega.ContainSubstring("invalid name")))
+}
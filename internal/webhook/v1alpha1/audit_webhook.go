@@ -0,0 +1,287 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/envelope"
+	"github.com/yu-kod/kronoform/internal/fielddiff"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/redact"
+)
+
+// log is for logging in this package.
+var auditlog = logf.Log.WithName("audit-webhook")
+
+// AuditWebhookPath is the path the audit webhook is served on.
+const AuditWebhookPath = "/audit-history-yu-kod-github-io-v1alpha1"
+
+// AuditNamespaceLabel is the label, set to "enabled", of the namespaces whose writes are
+// sent to the audit webhook.
+const AuditNamespaceLabel = "history.yu-kod.github.io/audit"
+
+// defaultAuditQueueSize is the number of admission requests buffered for recording.
+const defaultAuditQueueSize = 1024
+
+// SetupAuditWebhookWithManager registers the audit webhook in the manager, together
+// with the worker that records the admitted requests.
+func SetupAuditWebhookWithManager(mgr ctrl.Manager, recorder *AuditRecorder) error {
+	mgr.GetWebhookServer().Register(AuditWebhookPath, &webhook.Admission{Handler: recorder})
+	return mgr.Add(recorder)
+}
+
+// The audit webhook never rejects a request and only records it after admission, so it
+// is registered with failurePolicy=ignore. It is only sent requests in namespaces labeled
+// with AuditNamespaceLabel, through the namespaceSelector set in
+// config/webhook/manifests.yaml. Adjust the resources to the kinds to audit.
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformhistories,verbs=create
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformchunks,verbs=create;get;list
+// +kubebuilder:rbac:groups=history.yu-kod.github.io,resources=kronoformhistories/status,verbs=get;update;patch
+// +kubebuilder:webhook:path=/audit-history-yu-kod-github-io-v1alpha1,mutating=false,failurePolicy=ignore,sideEffects=NoneOnDryRun,groups="";apps,resources=configmaps;services;deployments;statefulsets;daemonsets,verbs=create;update;delete,versions=v1,name=vaudit-v1alpha1.kb.io,admissionReviewVersions=v1,timeoutSeconds=5
+
+// AuditRecorder is an admission handler that records the writes it is sent as a
+// KronoformHistory carrying the identity of the requester, except those of kubectl
+// kronoform apply, which are recorded by their applied history. Requests are always
+// allowed; recording happens asynchronously so that the API server is never slowed
+// down or blocked by the history store.
+type AuditRecorder struct {
+	Client client.Client
+
+	// Resources are the resources recorded among those the API server sends. Nothing is
+	// recorded if empty.
+	Resources []schema.GroupResource
+	// HistoryNamespace is where histories of cluster-scoped resources are stored.
+	HistoryNamespace string
+	// QueueSize is the number of requests buffered for recording. Requests that
+	// arrive while the buffer is full are dropped and logged.
+	QueueSize int
+	// Redactor redacts the recorded states. Secret data is redacted if nil.
+	Redactor *redact.Redactor
+	// Keyring encrypts the recorded manifests and states. They are stored unencrypted if nil.
+	Keyring *envelope.Keyring
+
+	once    sync.Once
+	entries chan auditEntry
+}
+
+// auditEntry is an admitted write waiting to be recorded.
+type auditEntry struct {
+	operation admissionv1.Operation
+	userInfo  authenticationv1.UserInfo
+	before    *unstructured.Unstructured
+	after     *unstructured.Unstructured
+	// fieldManager is the field manager claimed by the client
+	fieldManager string
+}
+
+var _ admission.Handler = &AuditRecorder{}
+
+// Handle queues the request for recording and allows it.
+func (r *AuditRecorder) Handle(_ context.Context, req admission.Request) admission.Response {
+	entry, ok := r.entryFor(req)
+	if !ok {
+		return admission.Allowed("")
+	}
+
+	select {
+	case r.queue() <- *entry:
+	default:
+		auditlog.Info("Audit queue is full, dropping request", "operation", req.Operation,
+			"resource", req.Resource.String(), "namespace", req.Namespace, "name", req.Name, "user", req.UserInfo.Username)
+	}
+	return admission.Allowed("")
+}
+
+// entryFor converts an admission request into an audit entry. It returns false for
+// requests that are not recorded.
+func (r *AuditRecorder) entryFor(req admission.Request) (*auditEntry, bool) {
+	if req.DryRun != nil && *req.DryRun {
+		return nil, false
+	}
+	if req.SubResource != "" || req.Resource.Group == historyv1alpha1.GroupVersion.Group {
+		return nil, false
+	}
+	if !r.audited(schema.GroupResource{Group: req.Resource.Group, Resource: req.Resource.Resource}) {
+		return nil, false
+	}
+	entry := &auditEntry{operation: req.Operation, userInfo: req.UserInfo, fieldManager: fieldManager(req)}
+	var err error
+	if entry.before, err = decodeObject(req.OldObject.Raw, req); err != nil {
+		auditlog.Error(err, "Failed to decode old object", "resource", req.Resource.String(), "name", req.Name)
+		return nil, false
+	}
+	if entry.after, err = decodeObject(req.Object.Raw, req); err != nil {
+		auditlog.Error(err, "Failed to decode object", "resource", req.Resource.String(), "name", req.Name)
+		return nil, false
+	}
+	if req.Operation == admissionv1.Delete {
+		entry.after = nil
+	}
+	if entry.before == nil && entry.after == nil {
+		return nil, false
+	}
+	if entry.before != nil && entry.after != nil && !changed(entry.before, entry.after) {
+		// e.g. the annotations naming the history of a resource
+		return nil, false
+	}
+	if history.RecordedByApply(entry.fieldManager, entry.before, entry.after) {
+		// Writes made by kubectl kronoform apply are recorded by their applied history
+		return nil, false
+	}
+	return entry, true
+}
+
+func (r *AuditRecorder) audited(resource schema.GroupResource) bool {
+	for _, candidate := range r.Resources {
+		if candidate == resource {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *AuditRecorder) queue() chan auditEntry {
+	r.once.Do(func() {
+		size := r.QueueSize
+		if size <= 0 {
+			size = defaultAuditQueueSize
+		}
+		r.entries = make(chan auditEntry, size)
+	})
+	return r.entries
+}
+
+// Start records queued requests until the context is done.
+func (r *AuditRecorder) Start(ctx context.Context) error {
+	entries := r.queue()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-entries:
+			r.record(ctx, entry)
+		}
+	}
+}
+
+// NeedLeaderElection reports false: every replica serves the webhook and must record
+// the requests it admitted.
+func (r *AuditRecorder) NeedLeaderElection() bool {
+	return false
+}
+
+func (r *AuditRecorder) record(ctx context.Context, entry auditEntry) {
+	h, err := history.FromChange(r.Redactor.Unstructured(entry.before), r.Redactor.Unstructured(entry.after),
+		historyv1alpha1.HistorySourceAdmission, entry.userInfo.Username)
+	if err != nil {
+		auditlog.Error(err, "Failed to build history for admitted request")
+		return
+	}
+	h.Spec.FieldManager = entry.fieldManager
+	h.Spec.AppliedByGroups = entry.userInfo.Groups
+	h.Spec.AppliedByUID = entry.userInfo.UID
+	if h.Namespace == "" {
+		h.Namespace = r.HistoryNamespace
+	}
+	if err := r.Keyring.SealHistory(h); err != nil {
+		auditlog.Error(err, "Failed to encrypt history for admitted request")
+		return
+	}
+
+	if err := history.Create(ctx, r.Client, h); err != nil {
+		auditlog.Error(err, "Failed to record admitted request", "summary", h.Status.Summary, "user", entry.userInfo.Username)
+		return
+	}
+	auditlog.Info("Recorded admitted request", "history", h.Name, "summary", h.Status.Summary,
+		"operation", entry.operation, "user", entry.userInfo.Username)
+}
+
+// decodeObject decodes an object of an admission request. Objects being created may not
+// carry the name and namespace yet; they are taken from the request.
+func decodeObject(raw []byte, req admission.Request) (*unstructured.Unstructured, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(raw); err != nil {
+		return nil, err
+	}
+	if obj.GetName() == "" {
+		obj.SetName(req.Name)
+	}
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(req.Namespace)
+	}
+	return obj, nil
+}
+
+// changed reports whether an update changed the state of an object, rather than its
+// server-populated fields or annotations that are not part of its state.
+func changed(before, after *unstructured.Unstructured) bool {
+	beforeState, err := fielddiff.Normalize(before.Object)
+	if err != nil {
+		return true
+	}
+	afterState, err := fielddiff.Normalize(after.Object)
+	if err != nil {
+		return true
+	}
+	return len(fielddiff.Diff(beforeState, afterState)) > 0
+}
+
+// fieldManager returns the field manager given in the options of the request.
+func fieldManager(req admission.Request) string {
+	if len(req.Options.Raw) == 0 {
+		return ""
+	}
+	var options struct {
+		FieldManager string `json:"fieldManager"`
+	}
+	if err := json.Unmarshal(req.Options.Raw, &options); err != nil {
+		return ""
+	}
+	return options.FieldManager
+}
+
+// ParseAuditResources parses a comma-separated list of resources in the form
+// resource[.group], e.g. "configmaps,deployments.apps".
+func ParseAuditResources(value string) []schema.GroupResource {
+	var resources []schema.GroupResource
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		resources = append(resources, schema.ParseGroupResource(item))
+	}
+	return resources
+}
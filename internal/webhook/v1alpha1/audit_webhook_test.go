@@ -0,0 +1,179 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+var _ = Describe("Audit Webhook", func() {
+	const namespace = "default"
+
+	var recorder *AuditRecorder
+
+	configMap := func(value string) runtime.RawExtension {
+		return runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap",` +
+			`"metadata":{"name":"audited","namespace":"default"},"data":{"key":"` + value + `"}}`)}
+	}
+
+	request := func(operation admissionv1.Operation) admission.Request {
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			UID:       "uid",
+			Operation: operation,
+			Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			Namespace: namespace,
+			Name:      "audited",
+			UserInfo:  authenticationv1.UserInfo{Username: "alice", Groups: []string{"dev"}},
+		}}
+	}
+
+	BeforeEach(func() {
+		recorder = &AuditRecorder{
+			Client:           k8sClient,
+			Resources:        []schema.GroupResource{{Resource: "configmaps"}},
+			HistoryNamespace: namespace,
+			QueueSize:        4,
+		}
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.DeleteAllOf(ctx, &historyv1alpha1.KronoformHistory{}, client.InNamespace(namespace))).To(Succeed())
+	})
+
+	It("should allow the request and record it with the requester identity", func() {
+		req := request(admissionv1.Update)
+		req.OldObject = configMap("a")
+		req.Object = configMap("b")
+
+		Expect(recorder.Handle(ctx, req).Allowed).To(BeTrue())
+		Expect(recorder.queue()).To(HaveLen(1))
+
+		entry := <-recorder.queue()
+		Expect(entry.userInfo.Username).To(Equal("alice"))
+		recorder.record(ctx, entry)
+
+		histories := &historyv1alpha1.KronoformHistoryList{}
+		Expect(k8sClient.List(ctx, histories, client.InNamespace(namespace))).To(Succeed())
+		recorded := histories.Items
+		Expect(recorded).To(HaveLen(1))
+		Expect(recorded[0].Spec.Source).To(Equal(historyv1alpha1.HistorySourceAdmission))
+		// The test client is not the manager's service account, so the stamping webhook records
+		// the user envtest authenticates it as and keeps the requester as the claimed identity
+		Expect(recorded[0].Spec.AppliedBy).To(Equal("admin"))
+		Expect(recorded[0].Spec.ClaimedBy).To(Equal("alice"))
+		Expect(recorded[0].Status.ResourceSnapshots).To(HaveLen(1))
+		Expect(recorded[0].Status.ResourceSnapshots[0].Operation).To(Equal(historyv1alpha1.OperationUpdated))
+		Expect(recorded[0].Status.ResourceSnapshots[0].Before).To(ContainSubstring("key: a"))
+		Expect(recorded[0].Status.ResourceSnapshots[0].After).To(ContainSubstring("key: b"))
+	})
+
+	It("should record deletions from the old object", func() {
+		req := request(admissionv1.Delete)
+		req.OldObject = configMap("a")
+
+		Expect(recorder.Handle(ctx, req).Allowed).To(BeTrue())
+		entry := <-recorder.queue()
+		Expect(entry.before).NotTo(BeNil())
+		Expect(entry.after).To(BeNil())
+	})
+
+	It("should skip dry-run, unchanged and unaudited requests", func() {
+		dryRun := request(admissionv1.Create)
+		dryRun.Object = configMap("a")
+		dryRun.DryRun = &[]bool{true}[0]
+
+		stamped := request(admissionv1.Update)
+		stamped.OldObject = configMap("a")
+		stamped.Object = runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap",` +
+			`"metadata":{"name":"audited","namespace":"default",` +
+			`"annotations":{"history.yu-kod.github.io/history":"kronoform-history-1"}},"data":{"key":"a"}}`)}
+
+		recorder.Resources = []schema.GroupResource{{Group: "apps", Resource: "deployments"}}
+		unaudited := request(admissionv1.Create)
+		unaudited.Object = configMap("a")
+
+		for _, req := range []admission.Request{dryRun, stamped, unaudited} {
+			Expect(recorder.Handle(ctx, req).Allowed).To(BeTrue())
+		}
+		Expect(recorder.queue()).To(BeEmpty())
+	})
+
+	It("should skip requests recorded by kubectl kronoform apply", func() {
+		applied := request(admissionv1.Create)
+		applied.Object = configMap("a")
+		applied.Options = runtime.RawExtension{Raw: []byte(`{"fieldManager":"kronoform"}`)}
+
+		// A change naming a new history was recorded by it, whoever made it
+		annotated := request(admissionv1.Update)
+		annotated.OldObject = configMap("a")
+		annotated.Object = runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap",` +
+			`"metadata":{"name":"audited","namespace":"default",` +
+			`"annotations":{"history.yu-kod.github.io/history":"kronoform-history-1"}},"data":{"key":"b"}}`)}
+
+		for _, req := range []admission.Request{applied, annotated} {
+			Expect(recorder.Handle(ctx, req).Allowed).To(BeTrue())
+		}
+		Expect(recorder.queue()).To(BeEmpty())
+
+		// Later changes to an annotated resource are recorded
+		edited := request(admissionv1.Update)
+		edited.OldObject = annotated.Object
+		edited.Object = runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap",` +
+			`"metadata":{"name":"audited","namespace":"default",` +
+			`"annotations":{"history.yu-kod.github.io/history":"kronoform-history-1"}},"data":{"key":"c"}}`)}
+		edited.Options = runtime.RawExtension{Raw: []byte(`{"fieldManager":"kubectl-edit"}`)}
+		Expect(recorder.Handle(ctx, edited).Allowed).To(BeTrue())
+		entry := <-recorder.queue()
+		Expect(entry.fieldManager).To(Equal("kubectl-edit"))
+	})
+
+	It("should record nothing unless resources are configured", func() {
+		recorder.Resources = nil
+		req := request(admissionv1.Create)
+		req.Object = configMap("a")
+
+		Expect(recorder.Handle(ctx, req).Allowed).To(BeTrue())
+		Expect(recorder.queue()).To(BeEmpty())
+	})
+
+	It("should allow requests and drop them when the queue is full", func() {
+		req := request(admissionv1.Create)
+		req.Object = configMap("a")
+
+		for range 6 {
+			Expect(recorder.Handle(ctx, req).Allowed).To(BeTrue())
+		}
+		Expect(recorder.queue()).To(HaveLen(4))
+	})
+
+	It("should parse audited resources", func() {
+		Expect(ParseAuditResources("configmaps, deployments.apps")).To(Equal([]schema.GroupResource{
+			{Resource: "configmaps"},
+			{Group: "apps", Resource: "deployments"},
+		}))
+	})
+})
@@ -19,9 +19,13 @@ package v1alpha1
 import (
 	"context"
 	"fmt"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -29,98 +33,104 @@ import (
 	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
 )
 
-// nolint:unused
 // log is for logging in this package.
 var kronoformlog = logf.Log.WithName("kronoform-resource")
 
 // SetupKronoformWebhookWithManager registers the webhook for Kronoform in the manager.
 func SetupKronoformWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).For(&historyv1alpha1.Kronoform{}).
-		WithValidator(&KronoformCustomValidator{}).
+		WithValidator(&KronoformCustomValidator{Reader: mgr.GetAPIReader()}).
 		WithDefaulter(&KronoformCustomDefaulter{}).
 		Complete()
 }
 
-// TODO(user): EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
-
 // +kubebuilder:webhook:path=/mutate-history-yu-kod-github-io-v1alpha1-kronoform,mutating=true,failurePolicy=fail,sideEffects=None,groups=history.yu-kod.github.io,resources=kronoforms,verbs=create;update,versions=v1alpha1,name=mkronoform-v1alpha1.kb.io,admissionReviewVersions=v1
 
 // KronoformCustomDefaulter struct is responsible for setting default values on the custom resource of the
-// Kind Kronoform when those are created or updated.
+// Kind Kronoform when those are created or updated. It sets when the Kronoform was requested and
+// describes it if no description was given.
 //
 // NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
 // as it is used only for temporary operations and does not need to be deeply copied.
-type KronoformCustomDefaulter struct {
-	// TODO(user): Add more fields as needed for defaulting
-}
+type KronoformCustomDefaulter struct{}
 
 var _ webhook.CustomDefaulter = &KronoformCustomDefaulter{}
 
 // Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind Kronoform.
-func (d *KronoformCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+func (d *KronoformCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
 	kronoform, ok := obj.(*historyv1alpha1.Kronoform)
-
 	if !ok {
 		return fmt.Errorf("expected an Kronoform object but got %T", obj)
 	}
 	kronoformlog.Info("Defaulting for Kronoform", "name", kronoform.GetName())
 
-	// TODO(user): fill in your defaulting logic.
-
+	spec := &kronoform.Spec
+	if spec.RequestedAt == nil {
+		now := metav1.NewTime(time.Now().UTC().Truncate(time.Second))
+		spec.RequestedAt = &now
+	}
+	if spec.Description == "" {
+		requestedBy := "unknown"
+		if req, err := admission.RequestFromContext(ctx); err == nil {
+			requestedBy = req.UserInfo.Username
+		}
+		spec.Description = fmt.Sprintf("Requested by %s at %s", requestedBy, spec.RequestedAt.UTC().Format(time.RFC3339))
+	}
 	return nil
 }
 
-// TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
 // NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
 // Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
 // +kubebuilder:webhook:path=/validate-history-yu-kod-github-io-v1alpha1-kronoform,mutating=false,failurePolicy=fail,sideEffects=None,groups=history.yu-kod.github.io,resources=kronoforms,verbs=create;update,versions=v1alpha1,name=vkronoform-v1alpha1.kb.io,admissionReviewVersions=v1
 
 // KronoformCustomValidator struct is responsible for validating the Kronoform resource
-// when it is created, updated, or deleted.
+// when it is created or updated: its manifests must be well-formed Kubernetes objects within
+// MaxManifestsSize, its target namespace a valid name and its snapshot reference must exist.
 //
 // NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
 // as this struct is used only for temporary operations and does not need to be deeply copied.
 type KronoformCustomValidator struct {
-	// TODO(user): Add more fields as needed for validation
+	// Reader looks up referenced snapshots. It should read from the API server rather than
+	// a cache so that snapshots created just before are found.
+	Reader client.Reader
 }
 
 var _ webhook.CustomValidator = &KronoformCustomValidator{}
 
 // ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Kronoform.
-func (v *KronoformCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+func (v *KronoformCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
 	kronoform, ok := obj.(*historyv1alpha1.Kronoform)
 	if !ok {
 		return nil, fmt.Errorf("expected a Kronoform object but got %T", obj)
 	}
 	kronoformlog.Info("Validation for Kronoform upon creation", "name", kronoform.GetName())
 
-	// TODO(user): fill in your validation logic upon object creation.
-
-	return nil, nil
+	return nil, v.validate(ctx, kronoform)
 }
 
 // ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Kronoform.
-func (v *KronoformCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+func (v *KronoformCustomValidator) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
 	kronoform, ok := newObj.(*historyv1alpha1.Kronoform)
 	if !ok {
 		return nil, fmt.Errorf("expected a Kronoform object for the newObj but got %T", newObj)
 	}
 	kronoformlog.Info("Validation for Kronoform upon update", "name", kronoform.GetName())
 
-	// TODO(user): fill in your validation logic upon object update.
-
-	return nil, nil
+	return nil, v.validate(ctx, kronoform)
 }
 
 // ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Kronoform.
-func (v *KronoformCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
-	kronoform, ok := obj.(*historyv1alpha1.Kronoform)
-	if !ok {
-		return nil, fmt.Errorf("expected a Kronoform object but got %T", obj)
-	}
-	kronoformlog.Info("Validation for Kronoform upon deletion", "name", kronoform.GetName())
-
-	// TODO(user): fill in your validation logic upon object deletion.
-
+// A Kronoform is not part of the audit record, so it can be deleted like any other object.
+func (v *KronoformCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
 	return nil, nil
 }
+
+// validate checks the spec of a Kronoform.
+func (v *KronoformCustomValidator) validate(ctx context.Context, kronoform *historyv1alpha1.Kronoform) error {
+	specPath := field.NewPath("spec")
+	errs := validateManifests(kronoform.Spec.Manifests, historyv1alpha1.EncodingPlain, 0, specPath.Child("manifests"))
+	errs = append(errs, validateNamespaceName(kronoform.Spec.TargetNamespace, specPath.Child("targetNamespace"))...)
+	errs = append(errs, validateSnapshotRef(ctx, v.Reader, kronoform.Namespace, kronoform.Spec.SnapshotRef,
+		specPath.Child("snapshotRef"))...)
+	return invalid("Kronoform", kronoform.Name, errs)
+}
@@ -17,71 +17,119 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
-	// TODO (user): Add any additional imports if needed
 )
 
 var _ = Describe("Kronoform Webhook", func() {
 	var (
-		obj       *historyv1alpha1.Kronoform
-		oldObj    *historyv1alpha1.Kronoform
 		validator KronoformCustomValidator
 		defaulter KronoformCustomDefaulter
 	)
 
 	BeforeEach(func() {
-		obj = &historyv1alpha1.Kronoform{}
-		oldObj = &historyv1alpha1.Kronoform{}
-		validator = KronoformCustomValidator{}
-		Expect(validator).NotTo(BeNil(), "Expected validator to be initialized")
+		scheme := runtime.NewScheme()
+		Expect(historyv1alpha1.AddToScheme(scheme)).To(Succeed())
+		reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&historyv1alpha1.KronoformSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"},
+		}).Build()
+		validator = KronoformCustomValidator{Reader: reader}
 		defaulter = KronoformCustomDefaulter{}
-		Expect(defaulter).NotTo(BeNil(), "Expected defaulter to be initialized")
-		Expect(oldObj).NotTo(BeNil(), "Expected oldObj to be initialized")
-		Expect(obj).NotTo(BeNil(), "Expected obj to be initialized")
-		// TODO (user): Add any setup logic common to all tests
 	})
 
-	AfterEach(func() {
-		// TODO (user): Add any teardown logic common to all tests
-	})
+	DescribeTable("validating Kronoforms",
+		func(spec historyv1alpha1.KronoformSpec, path, detail string) {
+			kronoform := &historyv1alpha1.Kronoform{
+				ObjectMeta: metav1.ObjectMeta{Name: "kronoform", Namespace: "default"},
+				Spec:       spec,
+			}
+			_, createErr := validator.ValidateCreate(context.Background(), kronoform)
+			_, updateErr := validator.ValidateUpdate(context.Background(), &historyv1alpha1.Kronoform{}, kronoform)
+			for _, err := range []error{createErr, updateErr} {
+				if path == "" {
+					Expect(err).NotTo(HaveOccurred())
+					continue
+				}
+				Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring(path))
+				Expect(err.Error()).To(ContainSubstring(detail))
+			}
+		},
+		Entry("valid", historyv1alpha1.KronoformSpec{
+			Manifests: validManifests, TargetNamespace: "prod", SnapshotRef: "existing",
+		}, "", ""),
+		Entry("without manifests", historyv1alpha1.KronoformSpec{}, "", ""),
+		Entry("malformed manifests", historyv1alpha1.KronoformSpec{Manifests: "kind: [x"}, "spec.manifests", "malformed YAML"),
+		Entry("documents that are not objects", historyv1alpha1.KronoformSpec{Manifests: "metadata:\n  name: settings\n"},
+			"spec.manifests", "kind is required"),
+		Entry("oversized manifests", historyv1alpha1.KronoformSpec{
+			Manifests: "apiVersion: v1\nkind: ConfigMap\ndata:\n  blob: " + strings.Repeat("x", MaxManifestsSize) + "\n",
+		}, "spec.manifests", "exceeds the 1Mi limit"),
+		Entry("invalid target namespace", historyv1alpha1.KronoformSpec{Manifests: validManifests, TargetNamespace: "Prod_1"},
+			"spec.targetNamespace", "RFC 1123"),
+		Entry("missing snapshot", historyv1alpha1.KronoformSpec{Manifests: validManifests, SnapshotRef: "missing"},
+			"spec.snapshotRef", "Not found"),
+	)
 
-	Context("When creating Kronoform under Defaulting Webhook", func() {
-		// TODO (user): Add logic for defaulting webhooks
-		// Example:
-		// It("Should apply defaults when a required field is empty", func() {
-		//     By("simulating a scenario where defaults should be applied")
-		//     obj.SomeFieldWithDefault = ""
-		//     By("calling the Default method to apply defaults")
-		//     defaulter.Default(ctx, obj)
-		//     By("checking that the default values are set")
-		//     Expect(obj.SomeFieldWithDefault).To(Equal("default_value"))
-		// })
+	It("Should admit the deletion of a Kronoform", func() {
+		Expect(validator.ValidateDelete(context.Background(), &historyv1alpha1.Kronoform{})).Error().NotTo(HaveOccurred())
 	})
 
-	Context("When creating or updating Kronoform under Validating Webhook", func() {
-		// TODO (user): Add logic for validating webhooks
-		// Example:
-		// It("Should deny creation if a required field is missing", func() {
-		//     By("simulating an invalid creation scenario")
-		//     obj.SomeRequiredField = ""
-		//     Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
-		// })
-		//
-		// It("Should admit creation if all required fields are present", func() {
-		//     By("simulating an invalid creation scenario")
-		//     obj.SomeRequiredField = "valid_value"
-		//     Expect(validator.ValidateCreate(ctx, obj)).To(BeNil())
-		// })
-		//
-		// It("Should validate updates correctly", func() {
-		//     By("simulating a valid update scenario")
-		//     oldObj.SomeRequiredField = "updated_value"
-		//     obj.SomeRequiredField = "updated_value"
-		//     Expect(validator.ValidateUpdate(ctx, oldObj, obj)).To(BeNil())
-		// })
-	})
+	DescribeTable("defaulting Kronoforms",
+		func(spec historyv1alpha1.KronoformSpec, description string) {
+			kronoform := &historyv1alpha1.Kronoform{Spec: spec}
+			ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: admissionv1.Create,
+					UserInfo:  authenticationv1.UserInfo{Username: "alice"},
+				},
+			})
+			Expect(defaulter.Default(ctx, kronoform)).To(Succeed())
+			Expect(kronoform.Spec.RequestedAt).NotTo(BeNil())
+			if spec.RequestedAt != nil {
+				Expect(kronoform.Spec.RequestedAt.Equal(spec.RequestedAt)).To(BeTrue())
+			}
+			Expect(kronoform.Spec.Description).To(HavePrefix(description))
+		},
+		Entry("missing description and timestamp", historyv1alpha1.KronoformSpec{}, "Requested by alice at "),
+		Entry("given timestamp", historyv1alpha1.KronoformSpec{
+			RequestedAt: &metav1.Time{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+		}, "Requested by alice at 2025-01-02T03:04:05Z"),
+		Entry("given description", historyv1alpha1.KronoformSpec{Description: "Roll out v2"}, "Roll out v2"),
+	)
+
+	Context("When creating Kronoforms through the API server", func() {
+		It("Should reject an invalid Kronoform and default a valid one", func() {
+			kronoform := &historyv1alpha1.Kronoform{
+				ObjectMeta: metav1.ObjectMeta{Name: "invalid-kronoform", Namespace: "default"},
+				Spec:       historyv1alpha1.KronoformSpec{Manifests: "kind: [ConfigMap", TargetNamespace: "Not_A_Label"},
+			}
+			err := k8sClient.Create(ctx, kronoform)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.manifests"))
+			Expect(err.Error()).To(ContainSubstring("spec.targetNamespace"))
 
+			kronoform = &historyv1alpha1.Kronoform{
+				ObjectMeta: metav1.ObjectMeta{Name: "valid-kronoform", Namespace: "default"},
+				Spec:       historyv1alpha1.KronoformSpec{Manifests: validManifests},
+			}
+			Expect(k8sClient.Create(ctx, kronoform)).To(Succeed())
+			Expect(kronoform.Spec.RequestedAt).NotTo(BeNil())
+			Expect(kronoform.Spec.Description).NotTo(BeEmpty())
+			Expect(k8sClient.Delete(ctx, kronoform)).To(Succeed())
+		})
+	})
 })
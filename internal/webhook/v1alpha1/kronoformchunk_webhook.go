@@ -0,0 +1,97 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+// log is for logging in this package.
+var kronoformchunklog = logf.Log.WithName("kronoformchunk-resource")
+
+// SetupKronoformChunkWebhookWithManager registers the webhook for KronoformChunk in the manager.
+func SetupKronoformChunkWebhookWithManager(mgr ctrl.Manager, policy RecordPolicy) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&historyv1alpha1.KronoformChunk{}).
+		WithValidator(&KronoformChunkCustomValidator{Policy: policy}).
+		Complete()
+}
+
+// NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
+// Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
+// +kubebuilder:webhook:path=/validate-history-yu-kod-github-io-v1alpha1-kronoformchunk,mutating=false,failurePolicy=fail,sideEffects=None,groups=history.yu-kod.github.io,resources=kronoformchunks,verbs=update;delete,versions=v1alpha1,name=vkronoformchunk-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// KronoformChunkCustomValidator struct is responsible for validating the KronoformChunk resource
+// when it is updated or deleted. Chunks hold part of the manifests of a snapshot or history, so
+// they are as immutable as the record they belong to: their spec cannot change and their
+// deletion is restricted by the RecordPolicy.
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as this struct is used only for temporary operations and does not need to be deeply copied.
+type KronoformChunkCustomValidator struct {
+	Policy RecordPolicy
+}
+
+var _ webhook.CustomValidator = &KronoformChunkCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type KronoformChunk.
+func (v *KronoformChunkCustomValidator) ValidateCreate(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type KronoformChunk.
+// Spec and label changes are rejected, as the label ties a chunk to its record, and only the
+// controller manager may change its owner references and finalizers.
+func (v *KronoformChunkCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldChunk, ok := oldObj.(*historyv1alpha1.KronoformChunk)
+	if !ok {
+		return nil, fmt.Errorf("expected a KronoformChunk object for the oldObj but got %T", oldObj)
+	}
+	kronoformchunk, ok := newObj.(*historyv1alpha1.KronoformChunk)
+	if !ok {
+		return nil, fmt.Errorf("expected a KronoformChunk object for the newObj but got %T", newObj)
+	}
+	kronoformchunklog.Info("Validation for KronoformChunk upon update", "name", kronoformchunk.GetName())
+
+	if !equality.Semantic.DeepEqual(oldChunk.Spec, kronoformchunk.Spec) ||
+		oldChunk.Labels[historyv1alpha1.ChunkOwnerUIDLabel] != kronoformchunk.Labels[historyv1alpha1.ChunkOwnerUIDLabel] {
+		return nil, fmt.Errorf("KronoformChunk %s is immutable: spec cannot be changed after creation", kronoformchunk.Name)
+	}
+
+	return nil, v.Policy.checkMetadataUpdate(ctx, "KronoformChunk", kronoformchunk.Name, oldChunk, kronoformchunk)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type KronoformChunk.
+// Chunks are normally deleted by the garbage collector together with their record.
+func (v *KronoformChunkCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	kronoformchunk, ok := obj.(*historyv1alpha1.KronoformChunk)
+	if !ok {
+		return nil, fmt.Errorf("expected a KronoformChunk object but got %T", obj)
+	}
+	kronoformchunklog.Info("Validation for KronoformChunk upon deletion", "name", kronoformchunk.GetName())
+
+	return nil, v.Policy.checkDelete(ctx, "KronoformChunk", kronoformchunk.Name)
+}
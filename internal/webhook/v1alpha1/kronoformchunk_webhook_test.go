@@ -0,0 +1,88 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+var _ = Describe("KronoformChunk Webhook", func() {
+	var (
+		obj       *historyv1alpha1.KronoformChunk
+		oldObj    *historyv1alpha1.KronoformChunk
+		validator KronoformChunkCustomValidator
+	)
+
+	BeforeEach(func() {
+		oldObj = &historyv1alpha1.KronoformChunk{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "history-0",
+				Namespace: "default",
+				Labels:    map[string]string{historyv1alpha1.ChunkOwnerUIDLabel: "uid"},
+			},
+			Spec: historyv1alpha1.KronoformChunkSpec{Index: 0, Data: "H4sI"},
+		}
+		obj = oldObj.DeepCopy()
+		validator = KronoformChunkCustomValidator{Policy: RecordPolicy{
+			ServiceAccount:  DefaultServiceAccount,
+			BreakGlassGroup: DefaultBreakGlassGroup,
+		}}
+	})
+
+	Context("When updating KronoformChunk under Validating Webhook", func() {
+		It("Should deny data and owner changes", func() {
+			obj.Spec.Data = "tampered"
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("spec cannot be changed")))
+
+			obj = oldObj.DeepCopy()
+			obj.Labels[historyv1alpha1.ChunkOwnerUIDLabel] = "other"
+			Expect(validator.ValidateUpdate(requestContext("", DefaultServiceAccount), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("spec cannot be changed")))
+		})
+
+		It("Should deny metadata changes by other users than the service account", func() {
+			obj.Finalizers = []string{"example.com/keep"}
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("owner references and finalizers can only be changed")))
+			Expect(validator.ValidateUpdate(requestContext("", DefaultServiceAccount), oldObj, obj)).Error().
+				NotTo(HaveOccurred())
+		})
+
+		It("Should let the garbage collector remove owner references", func() {
+			oldObj.OwnerReferences = []metav1.OwnerReference{{Kind: "KronoformHistory", Name: "history", UID: "uid"}}
+			obj = oldObj.DeepCopy()
+			obj.OwnerReferences = nil
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().To(HaveOccurred())
+			Expect(validator.ValidateUpdate(requestContext("", "system:serviceaccount:kube-system:generic-garbage-collector"),
+				oldObj, obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When deleting KronoformChunk under Validating Webhook", func() {
+		It("Should restrict deletion", func() {
+			Expect(validator.ValidateDelete(requestContext("", "alice"), obj)).Error().To(HaveOccurred())
+			Expect(validator.ValidateDelete(requestContext("", "bob", DefaultBreakGlassGroup), obj)).Error().NotTo(HaveOccurred())
+			Expect(validator.ValidateDelete(requestContext("", "system:serviceaccount:kube-system:generic-garbage-collector"),
+				obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+})
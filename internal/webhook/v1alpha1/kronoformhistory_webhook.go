@@ -0,0 +1,275 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/history"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+// log is for logging in this package.
+var kronoformhistorylog = logf.Log.WithName("kronoformhistory-resource")
+
+// SetupKronoformHistoryWebhookWithManager registers the webhook for KronoformHistory in the manager.
+func SetupKronoformHistoryWebhookWithManager(mgr ctrl.Manager, policy RecordPolicy) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&historyv1alpha1.KronoformHistory{}).
+		WithValidator(&KronoformHistoryCustomValidator{Policy: policy, Reader: mgr.GetAPIReader()}).
+		WithDefaulter(&KronoformHistoryCustomDefaulter{Policy: policy}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-history-yu-kod-github-io-v1alpha1-kronoformhistory,mutating=true,failurePolicy=fail,sideEffects=None,groups=history.yu-kod.github.io,resources=kronoformhistories;kronoformhistories/status,verbs=create;update,versions=v1alpha1,name=mkronoformhistory-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// KronoformHistoryCustomDefaulter struct is responsible for setting default values on the custom resource of the
+// Kind KronoformHistory when those are created. It replaces the self-reported AppliedBy with the
+// authenticated user of the request, keeping the reported value in ClaimedBy.
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as it is used only for temporary operations and does not need to be deeply copied.
+type KronoformHistoryCustomDefaulter struct {
+	Policy RecordPolicy
+}
+
+var _ webhook.CustomDefaulter = &KronoformHistoryCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind KronoformHistory.
+func (d *KronoformHistoryCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	kronoformhistory, ok := obj.(*historyv1alpha1.KronoformHistory)
+	if !ok {
+		return fmt.Errorf("expected an KronoformHistory object but got %T", obj)
+	}
+	kronoformhistorylog.Info("Defaulting for KronoformHistory", "name", kronoformhistory.GetName())
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("admission request not found in context: %w", err)
+	}
+	spec, status := &kronoformhistory.Spec, &kronoformhistory.Status
+
+	switch {
+	case req.Operation == admissionv1.Create:
+		if err := d.Policy.stampIdentity(ctx, &identity{
+			AppliedBy: &spec.AppliedBy, Groups: &spec.AppliedByGroups, UID: &spec.AppliedByUID, ClaimedBy: &spec.ClaimedBy,
+		}); err != nil {
+			return err
+		}
+		if spec.Description == "" {
+			spec.Description = fmt.Sprintf("Applied by %s", spec.AppliedBy)
+		}
+		// Histories created without the plugin or the controller manager get an ID here
+		if err := history.AssignID(kronoformhistory); err != nil {
+			return err
+		}
+	case req.SubResource == "status":
+		// Record when the history was applied if the recorder did not
+		if status.AppliedAt == nil && (status.Summary != "" || len(status.ResourceSnapshots) > 0) {
+			now := metav1.Now()
+			status.AppliedAt = &now
+		}
+	}
+
+	return nil
+}
+
+// NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
+// Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
+// +kubebuilder:webhook:path=/validate-history-yu-kod-github-io-v1alpha1-kronoformhistory,mutating=false,failurePolicy=fail,sideEffects=None,groups=history.yu-kod.github.io,resources=kronoformhistories;kronoformhistories/status,verbs=create;update;delete,versions=v1alpha1,name=vkronoformhistory-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// KronoformHistoryCustomValidator struct is responsible for validating the KronoformHistory resource
+// when it is created, updated, or deleted. Histories are audit records, so their spec cannot change
+// after creation and their status and deletion are restricted by the RecordPolicy.
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as this struct is used only for temporary operations and does not need to be deeply copied.
+type KronoformHistoryCustomValidator struct {
+	Policy RecordPolicy
+	// Reader looks up referenced objects. It should read from the API server rather than a
+	// cache so that objects created just before are found.
+	Reader client.Reader
+}
+
+var _ webhook.CustomValidator = &KronoformHistoryCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type KronoformHistory.
+func (v *KronoformHistoryCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	kronoformhistory, ok := obj.(*historyv1alpha1.KronoformHistory)
+	if !ok {
+		return nil, fmt.Errorf("expected a KronoformHistory object but got %T", obj)
+	}
+	kronoformhistorylog.Info("Validation for KronoformHistory upon creation", "name", kronoformhistory.GetName())
+
+	specPath := field.NewPath("spec")
+	errs := validateManifests(kronoformhistory.Spec.Manifests, kronoformhistory.Spec.ManifestsEncoding,
+		kronoformhistory.Spec.ManifestsChunks, specPath.Child("manifests"))
+	errs = append(errs, validateManifestsKey(kronoformhistory.Spec.ManifestsEncoding, kronoformhistory.Spec.ManifestsKey,
+		specPath.Child("manifestsKey"))...)
+	errs = append(errs, validateSnapshotRef(ctx, v.Reader, kronoformhistory.Namespace,
+		kronoformhistory.Spec.SnapshotRef, specPath.Child("snapshotRef"))...)
+	// Observed and audited changes are recorded by the controller manager, which cannot know why
+	if kronoformhistory.Spec.Source == "" || kronoformhistory.Spec.Source == historyv1alpha1.HistorySourceApplied {
+		errs = append(errs, v.Policy.checkMessage(kronoformhistory.Namespace, kronoformhistory.Spec.Message,
+			specPath.Child("message"))...)
+	}
+
+	return nil, invalid("KronoformHistory", kronoformhistory.Name, errs)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type KronoformHistory.
+// Spec changes are rejected, and so are changes to the labels, owner references and finalizers of a
+// recorded history by anyone but the controller manager; annotations may still change.
+func (v *KronoformHistoryCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldHistory, ok := oldObj.(*historyv1alpha1.KronoformHistory)
+	if !ok {
+		return nil, fmt.Errorf("expected a KronoformHistory object for the oldObj but got %T", oldObj)
+	}
+	kronoformhistory, ok := newObj.(*historyv1alpha1.KronoformHistory)
+	if !ok {
+		return nil, fmt.Errorf("expected a KronoformHistory object for the newObj but got %T", newObj)
+	}
+	kronoformhistorylog.Info("Validation for KronoformHistory upon update", "name", kronoformhistory.GetName())
+
+	if !equality.Semantic.DeepEqual(oldHistory.Spec, kronoformhistory.Spec) && !kronoformhistoryReencoded(ctx, v.Reader, oldHistory, kronoformhistory) {
+		return nil, fmt.Errorf("KronoformHistory %s is immutable: spec cannot be changed after creation", kronoformhistory.Name)
+	}
+	if keysReplaced(oldHistory, kronoformhistory) {
+		if err := v.Policy.checkRewrap(ctx, "KronoformHistory", kronoformhistory.Name); err != nil {
+			return nil, err
+		}
+	}
+	if oldHistory.Status.Chain != nil && !equality.Semantic.DeepEqual(oldHistory.Status.Chain, kronoformhistory.Status.Chain) {
+		return nil, fmt.Errorf("KronoformHistory %s is immutable: its chain link cannot be changed", kronoformhistory.Name)
+	}
+	statusChanged := isSubResource(ctx, "status") || !equality.Semantic.DeepEqual(oldHistory.Status, kronoformhistory.Status)
+	if statusChanged && !statesReencoded(oldHistory.Status, kronoformhistory.Status) {
+		// The status is recorded once by the creator; later changes, including the chain
+		// link, come from the controller manager
+		recorded := oldHistory.Status.AppliedAt != nil || kronoformhistory.Status.Chain != nil
+		if err := v.Policy.checkStatusUpdate(ctx, "KronoformHistory", kronoformhistory.Name, recorded,
+			oldHistory.Spec.AppliedBy, oldHistory.Spec.AppliedByUID); err != nil {
+			return nil, err
+		}
+	}
+	if oldHistory.Status.AppliedAt != nil || oldHistory.Status.Chain != nil {
+		if err := v.Policy.checkMetadataUpdate(ctx, "KronoformHistory", kronoformhistory.Name, oldHistory, kronoformhistory); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type KronoformHistory.
+func (v *KronoformHistoryCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	kronoformhistory, ok := obj.(*historyv1alpha1.KronoformHistory)
+	if !ok {
+		return nil, fmt.Errorf("expected a KronoformHistory object but got %T", obj)
+	}
+	kronoformhistorylog.Info("Validation for KronoformHistory upon deletion", "name", kronoformhistory.GetName())
+
+	// A history whose status was never recorded, such as one whose chunks could not be
+	// stored, may be withdrawn by the user who created it
+	if kronoformhistory.Status.AppliedAt == nil && kronoformhistory.Status.Chain == nil {
+		user, err := requestUser(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if isCreator(user, kronoformhistory.Spec.AppliedBy, kronoformhistory.Spec.AppliedByUID) {
+			return nil, nil
+		}
+	}
+	return nil, v.Policy.checkDelete(ctx, "KronoformHistory", kronoformhistory.Name)
+}
+
+// kronoformhistoryReencoded reports whether an update only changed how the manifests are stored.
+func kronoformhistoryReencoded(ctx context.Context, reader client.Reader, oldObj, newObj *historyv1alpha1.KronoformHistory) bool {
+	oldSpec, newSpec := oldObj.Spec.DeepCopy(), newObj.Spec.DeepCopy()
+	oldSpec.Manifests, oldSpec.ManifestsEncoding, oldSpec.ManifestsChunks, oldSpec.ManifestsKey = "", "", 0, nil
+	newSpec.Manifests, newSpec.ManifestsEncoding, newSpec.ManifestsChunks, newSpec.ManifestsKey = "", "", 0, nil
+	if !equality.Semantic.DeepEqual(oldSpec, newSpec) {
+		return false
+	}
+	if (oldObj.Spec.ManifestsKey == nil) != (newObj.Spec.ManifestsKey == nil) {
+		return false
+	}
+	return reencoded(ctx, reader, newObj,
+		storedManifests{Data: oldObj.Spec.Manifests, Encoding: oldObj.Spec.ManifestsEncoding, Chunks: oldObj.Spec.ManifestsChunks},
+		storedManifests{Data: newObj.Spec.Manifests, Encoding: newObj.Spec.ManifestsEncoding, Chunks: newObj.Spec.ManifestsChunks})
+}
+
+// keysReplaced reports whether an update replaced a data key of the manifests or of the
+// recorded resource states.
+func keysReplaced(oldObj, newObj *historyv1alpha1.KronoformHistory) bool {
+	if !equality.Semantic.DeepEqual(oldObj.Spec.ManifestsKey, newObj.Spec.ManifestsKey) {
+		return true
+	}
+	for i := range oldObj.Status.ResourceSnapshots {
+		if i < len(newObj.Status.ResourceSnapshots) &&
+			!equality.Semantic.DeepEqual(oldObj.Status.ResourceSnapshots[i].Key, newObj.Status.ResourceSnapshots[i].Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// statesReencoded reports whether a status update only changed the encoding of the
+// recorded resource states. Encrypted states cannot be decrypted here, so they must stay
+// as they are; only their data key may be encrypted again.
+func statesReencoded(oldStatus, newStatus historyv1alpha1.KronoformHistoryStatus) bool {
+	if len(oldStatus.ResourceSnapshots) != len(newStatus.ResourceSnapshots) ||
+		equality.Semantic.DeepEqual(oldStatus, newStatus) {
+		return false
+	}
+	oldStatus, newStatus = *oldStatus.DeepCopy(), *newStatus.DeepCopy()
+	for i := range oldStatus.ResourceSnapshots {
+		if !sameStates(oldStatus.ResourceSnapshots[i], newStatus.ResourceSnapshots[i]) {
+			return false
+		}
+		for _, rs := range []*historyv1alpha1.ResourceSnapshot{&oldStatus.ResourceSnapshots[i], &newStatus.ResourceSnapshots[i]} {
+			rs.Before, rs.After, rs.Encoding, rs.Key = "", "", "", nil
+		}
+	}
+	return equality.Semantic.DeepEqual(oldStatus, newStatus)
+}
+
+// sameStates reports whether two resource snapshots record the same states.
+func sameStates(oldRS, newRS historyv1alpha1.ResourceSnapshot) bool {
+	if oldRS.Encoding == historyv1alpha1.EncodingEncrypted || newRS.Encoding == historyv1alpha1.EncodingEncrypted {
+		return oldRS.Encoding == newRS.Encoding && oldRS.Before == newRS.Before && oldRS.After == newRS.After &&
+			newRS.Key != nil
+	}
+	oldBefore, oldAfter, err := payload.States(oldRS)
+	if err != nil {
+		return false
+	}
+	newBefore, newAfter, err := payload.States(newRS)
+	return err == nil && oldBefore == newBefore && oldAfter == newAfter && newRS.Key == nil
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+// requestContext returns a context carrying an update or delete request made by the given user.
+func requestContext(subResource, username string, groups ...string) context.Context {
+	return admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation:   admissionv1.Update,
+			SubResource: subResource,
+			UserInfo:    authenticationv1.UserInfo{Username: username, Groups: groups},
+		},
+	})
+}
+
+// createContext returns a context carrying a create request made by the given user.
+func createContext(username string, groups ...string) context.Context {
+	return admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			UserInfo:  authenticationv1.UserInfo{Username: username, Groups: groups},
+		},
+	})
+}
+
+var _ = Describe("KronoformHistory Webhook", func() {
+	var (
+		obj       *historyv1alpha1.KronoformHistory
+		oldObj    *historyv1alpha1.KronoformHistory
+		validator KronoformHistoryCustomValidator
+		defaulter KronoformHistoryCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		now := metav1.Now()
+		oldObj = &historyv1alpha1.KronoformHistory{
+			ObjectMeta: metav1.ObjectMeta{Name: "history", Namespace: "default"},
+			Spec: historyv1alpha1.KronoformHistorySpec{
+				Manifests:   "apiVersion: v1\nkind: ConfigMap",
+				SnapshotRef: "snapshot",
+				AppliedBy:   "alice",
+			},
+			Status: historyv1alpha1.KronoformHistoryStatus{AppliedAt: &now},
+		}
+		obj = oldObj.DeepCopy()
+		validator = KronoformHistoryCustomValidator{Policy: RecordPolicy{
+			ServiceAccount:  DefaultServiceAccount,
+			BreakGlassGroup: DefaultBreakGlassGroup,
+		}}
+		defaulter = KronoformHistoryCustomDefaulter{Policy: validator.Policy}
+	})
+
+	Context("When creating KronoformHistory under Defaulting Webhook", func() {
+		It("Should stamp the authenticated user and keep the claimed one", func() {
+			ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Create, UserInfo: authenticationv1.UserInfo{
+					Username: "bob@example.com",
+					UID:      "1234",
+					Groups:   []string{"dev", "system:authenticated"},
+				}},
+			})
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.AppliedBy).To(Equal("bob@example.com"))
+			Expect(obj.Spec.AppliedByUID).To(Equal("1234"))
+			Expect(obj.Spec.AppliedByGroups).To(ConsistOf("dev", "system:authenticated"))
+			Expect(obj.Spec.ClaimedBy).To(Equal("alice"))
+		})
+
+		It("Should not record a claim matching the authenticated user", func() {
+			Expect(defaulter.Default(createContext("alice"), obj)).To(Succeed())
+			Expect(obj.Spec.AppliedBy).To(Equal("alice"))
+			Expect(obj.Spec.ClaimedBy).To(BeEmpty())
+		})
+
+		It("Should keep the identity recorded by the controller manager", func() {
+			Expect(defaulter.Default(createContext(DefaultServiceAccount), obj)).To(Succeed())
+			Expect(obj.Spec.AppliedBy).To(Equal("alice"))
+			Expect(obj.Spec.ClaimedBy).To(BeEmpty())
+		})
+
+		It("Should default the description", func() {
+			obj.Spec.Description = ""
+			Expect(defaulter.Default(createContext("alice"), obj)).To(Succeed())
+			Expect(obj.Spec.Description).To(Equal("Applied by alice"))
+		})
+
+		It("Should assign an ID and keep one set by the recorder", func() {
+			Expect(defaulter.Default(createContext("alice"), obj)).To(Succeed())
+			Expect(obj.Spec.ID).To(MatchRegexp(`^[0-9a-f]{12}$`))
+
+			recorded := oldObj.DeepCopy()
+			recorded.Spec.ID = "0123456789ab"
+			Expect(defaulter.Default(createContext(DefaultServiceAccount), recorded)).To(Succeed())
+			Expect(recorded.Spec.ID).To(Equal("0123456789ab"))
+		})
+
+		It("Should default the applied timestamp when the status is recorded", func() {
+			obj.Status = historyv1alpha1.KronoformHistoryStatus{Summary: "Successfully applied manifests"}
+			Expect(defaulter.Default(requestContext("status", "alice"), obj)).To(Succeed())
+			Expect(obj.Status.AppliedAt).NotTo(BeNil())
+		})
+	})
+
+	Context("When creating KronoformHistory under Validating Webhook", func() {
+		It("Should require a message on applied changes in the configured namespaces", func() {
+			validator.Policy.MessageNamespaces = []string{"default"}
+			obj.Spec.Source = historyv1alpha1.HistorySourceApplied
+			Expect(validator.ValidateCreate(createContext("alice"), obj)).Error().
+				To(MatchError(ContainSubstring("spec.message: Required value")))
+
+			obj.Spec.Message = "Rotate the database password"
+			Expect(validator.ValidateCreate(createContext("alice"), obj)).Error().NotTo(HaveOccurred())
+
+			observed := oldObj.DeepCopy()
+			observed.Spec.Source = historyv1alpha1.HistorySourceObserved
+			Expect(validator.ValidateCreate(createContext(DefaultServiceAccount), observed)).Error().NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When updating KronoformHistory under Validating Webhook", func() {
+		It("Should deny spec changes from any user", func() {
+			obj.Spec.Manifests = "kind: Secret"
+			Expect(validator.ValidateUpdate(requestContext("", DefaultServiceAccount), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("spec cannot be changed")))
+		})
+
+		It("Should admit annotation changes", func() {
+			obj.Annotations = map[string]string{"team": "platform"}
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny label, owner and finalizer changes to a recorded history by other users", func() {
+			for _, change := range []func(*historyv1alpha1.KronoformHistory){
+				func(h *historyv1alpha1.KronoformHistory) { h.Labels = map[string]string{"team": "platform"} },
+				func(h *historyv1alpha1.KronoformHistory) {
+					h.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "uid"}}
+				},
+				func(h *historyv1alpha1.KronoformHistory) { h.Finalizers = []string{"example.com/keep"} },
+			} {
+				obj = oldObj.DeepCopy()
+				change(obj)
+				Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().
+					To(MatchError(ContainSubstring("owner references and finalizers can only be changed")))
+				Expect(validator.ValidateUpdate(requestContext("", DefaultServiceAccount), oldObj, obj)).Error().
+					NotTo(HaveOccurred())
+			}
+
+			// The owner of a history whose status was never recorded may still change them
+			oldObj.Status = historyv1alpha1.KronoformHistoryStatus{}
+			obj = oldObj.DeepCopy()
+			obj.Labels = map[string]string{"team": "platform"}
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should let the garbage collector and break-glass group remove finalizers only", func() {
+			oldObj.Finalizers = []string{"example.com/keep", "orphan"}
+			obj = oldObj.DeepCopy()
+			obj.Finalizers = []string{"example.com/keep"}
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().To(HaveOccurred())
+			Expect(validator.ValidateUpdate(requestContext("", "system:serviceaccount:kube-system:generic-garbage-collector"),
+				oldObj, obj)).Error().NotTo(HaveOccurred())
+			Expect(validator.ValidateUpdate(requestContext("", "bob", DefaultBreakGlassGroup), oldObj, obj)).Error().
+				NotTo(HaveOccurred())
+
+			obj.Finalizers = append(oldObj.Finalizers, "example.com/other")
+			Expect(validator.ValidateUpdate(requestContext("", "bob", DefaultBreakGlassGroup), oldObj, obj)).Error().
+				To(HaveOccurred())
+		})
+
+		It("Should allow status updates only from the service account", func() {
+			obj.Status.Summary = "rewritten"
+			Expect(validator.ValidateUpdate(requestContext("status", "alice"), oldObj, obj)).Error().To(HaveOccurred())
+			Expect(validator.ValidateUpdate(requestContext("status", DefaultServiceAccount), oldObj, obj)).Error().
+				NotTo(HaveOccurred())
+		})
+
+		It("Should admit re-encoding the manifests and states without changing them", func() {
+			obj.Spec.Manifests = mustEncode(oldObj.Spec.Manifests)
+			obj.Spec.ManifestsEncoding = historyv1alpha1.EncodingGzipBase64
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().NotTo(HaveOccurred())
+
+			oldObj.Status.ResourceSnapshots = []historyv1alpha1.ResourceSnapshot{{Kind: "ConfigMap", After: "kind: ConfigMap"}}
+			obj.Status.ResourceSnapshots = []historyv1alpha1.ResourceSnapshot{{
+				Kind: "ConfigMap", After: mustEncode("kind: ConfigMap"), Encoding: historyv1alpha1.EncodingGzipBase64,
+			}}
+			Expect(validator.ValidateUpdate(requestContext("status", "alice"), oldObj, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny re-encoding that changes the manifests or states", func() {
+			obj.Spec.Manifests = mustEncode("kind: Secret")
+			obj.Spec.ManifestsEncoding = historyv1alpha1.EncodingGzipBase64
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("spec cannot be changed")))
+
+			obj.Spec = oldObj.Spec
+			oldObj.Status.ResourceSnapshots = []historyv1alpha1.ResourceSnapshot{{Kind: "ConfigMap", After: "kind: ConfigMap"}}
+			obj.Status.ResourceSnapshots = []historyv1alpha1.ResourceSnapshot{{
+				Kind: "ConfigMap", After: mustEncode("kind: Secret"), Encoding: historyv1alpha1.EncodingGzipBase64,
+			}}
+			Expect(validator.ValidateUpdate(requestContext("status", "alice"), oldObj, obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should admit rotating the data keys of encrypted manifests and states only", func() {
+			oldObj.Spec.Manifests, oldObj.Spec.ManifestsEncoding = "c2VhbGVk", historyv1alpha1.EncodingEncrypted
+			oldObj.Spec.ManifestsKey = &historyv1alpha1.DataKey{KeyID: "2025-02", Key: "b2xk"}
+			oldObj.Status.ResourceSnapshots = []historyv1alpha1.ResourceSnapshot{{
+				Kind: "ConfigMap", After: "c2VhbGVk", Encoding: historyv1alpha1.EncodingEncrypted,
+				Key: &historyv1alpha1.DataKey{KeyID: "2025-02", Key: "b2xk"},
+			}}
+			obj = oldObj.DeepCopy()
+			obj.Spec.ManifestsKey = &historyv1alpha1.DataKey{KeyID: "2025-08", Key: "bmV3"}
+			Expect(validator.ValidateUpdate(requestContext("", "bob", DefaultBreakGlassGroup), oldObj, obj)).Error().
+				NotTo(HaveOccurred())
+			obj.Status.ResourceSnapshots[0].Key = &historyv1alpha1.DataKey{KeyID: "2025-08", Key: "bmV3"}
+			Expect(validator.ValidateUpdate(requestContext("status", "bob", DefaultBreakGlassGroup), oldObj, obj)).Error().
+				NotTo(HaveOccurred())
+			Expect(validator.ValidateUpdate(requestContext("status", DefaultServiceAccount), oldObj, obj)).Error().
+				NotTo(HaveOccurred())
+
+			// Encrypted content cannot be checked, so it cannot change
+			obj.Spec.Manifests = "b3RoZXI="
+			Expect(validator.ValidateUpdate(requestContext("", "bob", DefaultBreakGlassGroup), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("spec cannot be changed")))
+			obj.Spec = oldObj.Spec
+			obj.Status.ResourceSnapshots[0].After = "b3RoZXI="
+			Expect(validator.ValidateUpdate(requestContext("status", "bob", DefaultBreakGlassGroup), oldObj, obj)).Error().
+				To(HaveOccurred())
+		})
+
+		It("Should deny replacing data keys by other users", func() {
+			oldObj.Spec.Manifests, oldObj.Spec.ManifestsEncoding = "c2VhbGVk", historyv1alpha1.EncodingEncrypted
+			oldObj.Spec.ManifestsKey = &historyv1alpha1.DataKey{KeyID: "2025-02", Key: "b2xk"}
+			oldObj.Status.ResourceSnapshots = []historyv1alpha1.ResourceSnapshot{{
+				Kind: "ConfigMap", After: "c2VhbGVk", Encoding: historyv1alpha1.EncodingEncrypted,
+				Key: &historyv1alpha1.DataKey{KeyID: "2025-02", Key: "b2xk"},
+			}}
+			obj = oldObj.DeepCopy()
+			obj.Spec.ManifestsKey = &historyv1alpha1.DataKey{KeyID: "2025-02", Key: "c3dhcA=="}
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("data keys can only be replaced")))
+			obj.Spec = oldObj.Spec
+			obj.Status.ResourceSnapshots[0].Key = &historyv1alpha1.DataKey{KeyID: "2025-02", Key: "c3dhcA=="}
+			Expect(validator.ValidateUpdate(requestContext("status", "alice"), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("data keys can only be replaced")))
+		})
+
+		It("Should deny encrypting recorded manifests", func() {
+			obj.Spec.Manifests, obj.Spec.ManifestsEncoding = "c2VhbGVk", historyv1alpha1.EncodingEncrypted
+			obj.Spec.ManifestsKey = &historyv1alpha1.DataKey{KeyID: "2025-08", Key: "bmV3"}
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("spec cannot be changed")))
+		})
+
+		It("Should deny changing a chain link, even by the service account", func() {
+			oldObj.Status.Chain = &historyv1alpha1.ChainLink{Sequence: 2, Hash: "b2", Previous: "a1"}
+			obj = oldObj.DeepCopy()
+			obj.Status.Chain.Previous = "c3"
+			Expect(validator.ValidateUpdate(requestContext("status", DefaultServiceAccount), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("chain link cannot be changed")))
+			obj.Status.Chain = nil
+			Expect(validator.ValidateUpdate(requestContext("status", DefaultServiceAccount), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("chain link cannot be changed")))
+		})
+
+		It("Should only allow the service account to link a history", func() {
+			oldObj.Status = historyv1alpha1.KronoformHistoryStatus{}
+			obj.Status.Chain = &historyv1alpha1.ChainLink{Sequence: 1, Hash: "a1"}
+			Expect(validator.ValidateUpdate(requestContext("status", "alice"), oldObj, obj)).Error().To(HaveOccurred())
+			Expect(validator.ValidateUpdate(requestContext("status", DefaultServiceAccount), oldObj, obj)).Error().
+				NotTo(HaveOccurred())
+		})
+
+		It("Should allow the creator to populate an empty status", func() {
+			oldObj.Status = historyv1alpha1.KronoformHistoryStatus{}
+			Expect(validator.ValidateUpdate(requestContext("status", "alice"), oldObj, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny populating an empty status by anyone but the creator", func() {
+			oldObj.Status = historyv1alpha1.KronoformHistoryStatus{}
+			oldObj.Spec.AppliedByUID, obj.Spec.AppliedByUID = "alice-uid", "alice-uid"
+			Expect(validator.ValidateUpdate(requestContext("status", "bob"), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("can only be recorded by the user who created it")))
+			// A user taking the name of the creator is told apart by the UID
+			Expect(validator.ValidateUpdate(requestContext("status", "alice"), oldObj, obj)).Error().To(HaveOccurred())
+			Expect(validator.ValidateUpdate(requestContext("status", DefaultServiceAccount), oldObj, obj)).Error().
+				NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When deleting KronoformHistory under Validating Webhook", func() {
+		It("Should deny deletion by regular users", func() {
+			Expect(validator.ValidateDelete(requestContext("", "alice", "system:authenticated"), obj)).Error().
+				To(MatchError(ContainSubstring("can only be deleted by")))
+		})
+
+		It("Should let the creator withdraw a history whose status was never recorded", func() {
+			obj.Status = historyv1alpha1.KronoformHistoryStatus{}
+			Expect(validator.ValidateDelete(requestContext("", "alice"), obj)).Error().NotTo(HaveOccurred())
+			Expect(validator.ValidateDelete(requestContext("", "bob"), obj)).Error().To(HaveOccurred())
+		})
+
+		It("Should admit deletion by the service account, break-glass group and garbage collector", func() {
+			Expect(validator.ValidateDelete(requestContext("", DefaultServiceAccount), obj)).Error().NotTo(HaveOccurred())
+			Expect(validator.ValidateDelete(requestContext("", "bob", DefaultBreakGlassGroup), obj)).Error().NotTo(HaveOccurred())
+			Expect(validator.ValidateDelete(requestContext("", "system:serviceaccount:kube-system:generic-garbage-collector"), obj)).
+				Error().NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,207 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+// log is for logging in this package.
+var kronoformsnapshotlog = logf.Log.WithName("kronoformsnapshot-resource")
+
+// SetupKronoformSnapshotWebhookWithManager registers the webhook for KronoformSnapshot in the manager.
+func SetupKronoformSnapshotWebhookWithManager(mgr ctrl.Manager, policy RecordPolicy) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&historyv1alpha1.KronoformSnapshot{}).
+		WithValidator(&KronoformSnapshotCustomValidator{Policy: policy, Reader: mgr.GetAPIReader()}).
+		WithDefaulter(&KronoformSnapshotCustomDefaulter{Policy: policy}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-history-yu-kod-github-io-v1alpha1-kronoformsnapshot,mutating=true,failurePolicy=fail,sideEffects=None,groups=history.yu-kod.github.io,resources=kronoformsnapshots;kronoformsnapshots/status,verbs=create;update,versions=v1alpha1,name=mkronoformsnapshot-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// KronoformSnapshotCustomDefaulter struct is responsible for setting default values on the custom resource of the
+// Kind KronoformSnapshot when those are created. It replaces the self-reported AppliedBy with the
+// authenticated user of the request, keeping the reported value in ClaimedBy.
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as it is used only for temporary operations and does not need to be deeply copied.
+type KronoformSnapshotCustomDefaulter struct {
+	Policy RecordPolicy
+}
+
+var _ webhook.CustomDefaulter = &KronoformSnapshotCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind KronoformSnapshot.
+func (d *KronoformSnapshotCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	kronoformsnapshot, ok := obj.(*historyv1alpha1.KronoformSnapshot)
+	if !ok {
+		return fmt.Errorf("expected an KronoformSnapshot object but got %T", obj)
+	}
+	kronoformsnapshotlog.Info("Defaulting for KronoformSnapshot", "name", kronoformsnapshot.GetName())
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("admission request not found in context: %w", err)
+	}
+	spec, status := &kronoformsnapshot.Spec, &kronoformsnapshot.Status
+
+	switch {
+	case req.Operation == admissionv1.Create:
+		if err := d.Policy.stampIdentity(ctx, &identity{
+			AppliedBy: &spec.AppliedBy, Groups: &spec.AppliedByGroups, UID: &spec.AppliedByUID, ClaimedBy: &spec.ClaimedBy,
+		}); err != nil {
+			return err
+		}
+		if spec.Description == "" {
+			spec.Description = fmt.Sprintf("Created by %s at %s", spec.AppliedBy, time.Now().UTC().Format(time.RFC3339))
+		}
+	case req.SubResource == "status":
+		// Record when the snapshot was applied if the plugin did not
+		if status.AppliedAt == nil && status.HistoryRef != "" {
+			now := metav1.Now()
+			status.AppliedAt = &now
+		}
+	}
+
+	return nil
+}
+
+// NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
+// Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
+// +kubebuilder:webhook:path=/validate-history-yu-kod-github-io-v1alpha1-kronoformsnapshot,mutating=false,failurePolicy=fail,sideEffects=None,groups=history.yu-kod.github.io,resources=kronoformsnapshots;kronoformsnapshots/status,verbs=create;update;delete,versions=v1alpha1,name=vkronoformsnapshot-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// KronoformSnapshotCustomValidator struct is responsible for validating the KronoformSnapshot resource
+// when it is created, updated, or deleted. Snapshots linked to a history are part of the audit
+// record: their spec cannot change and their status and deletion are restricted by the RecordPolicy.
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as this struct is used only for temporary operations and does not need to be deeply copied.
+type KronoformSnapshotCustomValidator struct {
+	Policy RecordPolicy
+	// Reader looks up referenced objects. It should read from the API server rather than a
+	// cache so that objects created just before are found.
+	Reader client.Reader
+}
+
+var _ webhook.CustomValidator = &KronoformSnapshotCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type KronoformSnapshot.
+func (v *KronoformSnapshotCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	kronoformsnapshot, ok := obj.(*historyv1alpha1.KronoformSnapshot)
+	if !ok {
+		return nil, fmt.Errorf("expected a KronoformSnapshot object but got %T", obj)
+	}
+	kronoformsnapshotlog.Info("Validation for KronoformSnapshot upon creation", "name", kronoformsnapshot.GetName())
+
+	specPath := field.NewPath("spec")
+	errs := validateManifests(kronoformsnapshot.Spec.Manifests, kronoformsnapshot.Spec.ManifestsEncoding,
+		kronoformsnapshot.Spec.ManifestsChunks, specPath.Child("manifests"))
+	errs = append(errs, validateManifestsKey(kronoformsnapshot.Spec.ManifestsEncoding, kronoformsnapshot.Spec.ManifestsKey,
+		specPath.Child("manifestsKey"))...)
+	errs = append(errs, validateNamespaceName(kronoformsnapshot.Spec.TargetNamespace, specPath.Child("targetNamespace"))...)
+	errs = append(errs, v.Policy.checkMessage(kronoformsnapshot.Namespace, kronoformsnapshot.Spec.Message,
+		specPath.Child("message"))...)
+
+	return nil, invalid("KronoformSnapshot", kronoformsnapshot.Name, errs)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type KronoformSnapshot.
+// Spec changes are rejected. Once linked to a history, only the controller manager may change the
+// labels, owner references and finalizers of a snapshot; annotations may still change.
+func (v *KronoformSnapshotCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldSnapshot, ok := oldObj.(*historyv1alpha1.KronoformSnapshot)
+	if !ok {
+		return nil, fmt.Errorf("expected a KronoformSnapshot object for the oldObj but got %T", oldObj)
+	}
+	kronoformsnapshot, ok := newObj.(*historyv1alpha1.KronoformSnapshot)
+	if !ok {
+		return nil, fmt.Errorf("expected a KronoformSnapshot object for the newObj but got %T", newObj)
+	}
+	kronoformsnapshotlog.Info("Validation for KronoformSnapshot upon update", "name", kronoformsnapshot.GetName())
+
+	if !equality.Semantic.DeepEqual(oldSnapshot.Spec, kronoformsnapshot.Spec) && !kronoformsnapshotReencoded(ctx, v.Reader, oldSnapshot, kronoformsnapshot) {
+		return nil, fmt.Errorf("KronoformSnapshot %s is immutable: spec cannot be changed after creation", kronoformsnapshot.Name)
+	}
+	if !equality.Semantic.DeepEqual(oldSnapshot.Spec.ManifestsKey, kronoformsnapshot.Spec.ManifestsKey) {
+		if err := v.Policy.checkRewrap(ctx, "KronoformSnapshot", kronoformsnapshot.Name); err != nil {
+			return nil, err
+		}
+	}
+	if isSubResource(ctx, "status") || !equality.Semantic.DeepEqual(oldSnapshot.Status, kronoformsnapshot.Status) {
+		// The plugin completes a pending snapshot by linking it to its history; once linked,
+		// only the controller manager updates the status
+		recorded := oldSnapshot.Status.HistoryRef != ""
+		if err := v.Policy.checkStatusUpdate(ctx, "KronoformSnapshot", kronoformsnapshot.Name, recorded,
+			oldSnapshot.Spec.AppliedBy, oldSnapshot.Spec.AppliedByUID); err != nil {
+			return nil, err
+		}
+	}
+	if oldSnapshot.Status.HistoryRef != "" {
+		if err := v.Policy.checkMetadataUpdate(ctx, "KronoformSnapshot", kronoformsnapshot.Name, oldSnapshot, kronoformsnapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type KronoformSnapshot.
+// Snapshots that were never linked to a history, such as those of applies without changes, may be
+// deleted by anyone allowed to by RBAC.
+func (v *KronoformSnapshotCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	kronoformsnapshot, ok := obj.(*historyv1alpha1.KronoformSnapshot)
+	if !ok {
+		return nil, fmt.Errorf("expected a KronoformSnapshot object but got %T", obj)
+	}
+	kronoformsnapshotlog.Info("Validation for KronoformSnapshot upon deletion", "name", kronoformsnapshot.GetName())
+
+	if kronoformsnapshot.Status.HistoryRef == "" {
+		return nil, nil
+	}
+	return nil, v.Policy.checkDelete(ctx, "KronoformSnapshot", kronoformsnapshot.Name)
+}
+
+// kronoformsnapshotReencoded reports whether an update only changed how the manifests are stored.
+func kronoformsnapshotReencoded(ctx context.Context, reader client.Reader, oldObj, newObj *historyv1alpha1.KronoformSnapshot) bool {
+	oldSpec, newSpec := oldObj.Spec.DeepCopy(), newObj.Spec.DeepCopy()
+	oldSpec.Manifests, oldSpec.ManifestsEncoding, oldSpec.ManifestsChunks, oldSpec.ManifestsKey = "", "", 0, nil
+	newSpec.Manifests, newSpec.ManifestsEncoding, newSpec.ManifestsChunks, newSpec.ManifestsKey = "", "", 0, nil
+	if !equality.Semantic.DeepEqual(oldSpec, newSpec) {
+		return false
+	}
+	if (oldObj.Spec.ManifestsKey == nil) != (newObj.Spec.ManifestsKey == nil) {
+		return false
+	}
+	return reencoded(ctx, reader, newObj,
+		storedManifests{Data: oldObj.Spec.Manifests, Encoding: oldObj.Spec.ManifestsEncoding, Chunks: oldObj.Spec.ManifestsChunks},
+		storedManifests{Data: newObj.Spec.Manifests, Encoding: newObj.Spec.ManifestsEncoding, Chunks: newObj.Spec.ManifestsChunks})
+}
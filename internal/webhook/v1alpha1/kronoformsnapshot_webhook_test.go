@@ -0,0 +1,146 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+var _ = Describe("KronoformSnapshot Webhook", func() {
+	var (
+		obj       *historyv1alpha1.KronoformSnapshot
+		oldObj    *historyv1alpha1.KronoformSnapshot
+		validator KronoformSnapshotCustomValidator
+		defaulter KronoformSnapshotCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		oldObj = &historyv1alpha1.KronoformSnapshot{
+			ObjectMeta: metav1.ObjectMeta{Name: "snapshot", Namespace: "default"},
+			Spec:       historyv1alpha1.KronoformSnapshotSpec{Manifests: "apiVersion: v1\nkind: ConfigMap", AppliedBy: "alice"},
+			Status:     historyv1alpha1.KronoformSnapshotStatus{Phase: "Pending"},
+		}
+		obj = oldObj.DeepCopy()
+		validator = KronoformSnapshotCustomValidator{Policy: RecordPolicy{
+			ServiceAccount:  DefaultServiceAccount,
+			BreakGlassGroup: DefaultBreakGlassGroup,
+		}}
+		defaulter = KronoformSnapshotCustomDefaulter{Policy: validator.Policy}
+	})
+
+	Context("When creating KronoformSnapshot under Defaulting Webhook", func() {
+		It("Should replace a spoofed AppliedBy with the authenticated user", func() {
+			obj.Spec.AppliedBy = "admin"
+			Expect(defaulter.Default(createContext("alice", "dev"), obj)).To(Succeed())
+			Expect(obj.Spec.AppliedBy).To(Equal("alice"))
+			Expect(obj.Spec.AppliedByGroups).To(ConsistOf("dev"))
+			Expect(obj.Spec.ClaimedBy).To(Equal("admin"))
+			Expect(obj.Spec.Description).To(HavePrefix("Created by alice at "))
+		})
+	})
+
+	Context("When creating KronoformSnapshot under Validating Webhook", func() {
+		It("Should require a message in the configured namespaces", func() {
+			validator.Policy.MessageNamespaces = []string{"prod-*", "default"}
+			Expect(validator.ValidateCreate(createContext("alice"), obj)).Error().
+				To(MatchError(ContainSubstring("spec.message: Required value")))
+
+			obj.Spec.Message = "Scale up for the launch"
+			Expect(validator.ValidateCreate(createContext("alice"), obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Message = ""
+			obj.Namespace = "staging"
+			Expect(validator.ValidateCreate(createContext("alice"), obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When updating KronoformSnapshot under Validating Webhook", func() {
+		It("Should deny spec changes", func() {
+			obj.Spec.TargetNamespace = "prod"
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("spec cannot be changed")))
+		})
+
+		It("Should restrict replacing the data key of encrypted manifests", func() {
+			oldObj.Spec.Manifests, oldObj.Spec.ManifestsEncoding = "c2VhbGVk", historyv1alpha1.EncodingEncrypted
+			oldObj.Spec.ManifestsKey = &historyv1alpha1.DataKey{KeyID: "2025-02", Key: "b2xk"}
+			obj = oldObj.DeepCopy()
+			obj.Spec.ManifestsKey = &historyv1alpha1.DataKey{KeyID: "2025-08", Key: "bmV3"}
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("data keys can only be replaced")))
+			Expect(validator.ValidateUpdate(requestContext("", "bob", DefaultBreakGlassGroup), oldObj, obj)).Error().
+				NotTo(HaveOccurred())
+		})
+
+		It("Should let the plugin link a pending snapshot but not relink it", func() {
+			obj.Status.Phase = "Completed"
+			obj.Status.HistoryRef = "history"
+			Expect(validator.ValidateUpdate(requestContext("status", "alice"), oldObj, obj)).Error().NotTo(HaveOccurred())
+
+			linked := obj.DeepCopy()
+			obj.Status.HistoryRef = "other"
+			Expect(validator.ValidateUpdate(requestContext("status", "alice"), linked, obj)).Error().To(HaveOccurred())
+			Expect(validator.ValidateUpdate(requestContext("status", DefaultServiceAccount), linked, obj)).Error().
+				NotTo(HaveOccurred())
+		})
+
+		It("Should deny linking a pending snapshot by anyone but its creator", func() {
+			obj.Status.Phase = "Completed"
+			obj.Status.HistoryRef = "history"
+			Expect(validator.ValidateUpdate(requestContext("status", "bob"), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("can only be recorded by the user who created it")))
+		})
+
+		It("Should reserve metadata changes of a linked snapshot to the service account", func() {
+			oldObj.Status.HistoryRef = "history"
+			oldObj.Finalizers = []string{historyv1alpha1.HistoryLinkFinalizer}
+			obj = oldObj.DeepCopy()
+			obj.Labels = map[string]string{"team": "platform"}
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().
+				To(MatchError(ContainSubstring("owner references and finalizers can only be changed")))
+
+			obj = oldObj.DeepCopy()
+			obj.Finalizers = nil
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), oldObj, obj)).Error().To(HaveOccurred())
+			Expect(validator.ValidateUpdate(requestContext("", DefaultServiceAccount), oldObj, obj)).Error().
+				NotTo(HaveOccurred())
+
+			// The plugin makes a pending snapshot owned by its history before linking it
+			pending := oldObj.DeepCopy()
+			pending.Status.HistoryRef = ""
+			obj = pending.DeepCopy()
+			obj.OwnerReferences = []metav1.OwnerReference{{Kind: "KronoformHistory", Name: "history", UID: "uid"}}
+			Expect(validator.ValidateUpdate(requestContext("", "alice"), pending, obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When deleting KronoformSnapshot under Validating Webhook", func() {
+		It("Should admit deletion of unlinked snapshots", func() {
+			Expect(validator.ValidateDelete(requestContext("", "alice"), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should restrict deletion of linked snapshots", func() {
+			obj.Status.HistoryRef = "history"
+			Expect(validator.ValidateDelete(requestContext("", "alice"), obj)).Error().To(HaveOccurred())
+			Expect(validator.ValidateDelete(requestContext("", "bob", DefaultBreakGlassGroup), obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,245 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// DefaultServiceAccount is the username of the controller manager as deployed by config/default.
+	DefaultServiceAccount = "system:serviceaccount:kronoform-system:kronoform-controller-manager"
+	// DefaultBreakGlassGroup is the group whose members may delete records in an emergency.
+	DefaultBreakGlassGroup = "kronoform:break-glass"
+)
+
+// systemDeleters are Kubernetes controllers that delete records on behalf of a deletion
+// that was already authorized: the garbage collector removes snapshots whose history was
+// deleted, and the namespace controller empties terminating namespaces.
+var systemDeleters = []string{
+	"system:serviceaccount:kube-system:generic-garbage-collector",
+	"system:serviceaccount:kube-system:namespace-controller",
+}
+
+// RecordPolicy decides who may modify KronoformHistory and KronoformSnapshot records, and the
+// KronoformChunk objects holding their manifests, once they are created. Specs are immutable;
+// status, metadata and deletion are reserved for the controller manager, which also runs the
+// retention logic, and a break-glass group.
+// Data keys of encrypted records may only be replaced by the same users as may delete them.
+// It also decides what changes applied with kronoform must record.
+type RecordPolicy struct {
+	// ServiceAccount is the username the controller manager authenticates as.
+	ServiceAccount string
+	// BreakGlassGroup is a group whose members may delete records and rotate their data keys.
+	BreakGlassGroup string
+	// MessageNamespaces are the namespaces, or patterns such as prod-*, where changes
+	// applied with kronoform must carry a message explaining why they are made.
+	MessageNamespaces []string
+}
+
+// ParseNamespacePatterns parses a comma-separated list of namespaces or namespace patterns.
+func ParseNamespacePatterns(value string) []string {
+	var patterns []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			patterns = append(patterns, item)
+		}
+	}
+	return patterns
+}
+
+// requestUser returns the user of the admission request being validated.
+func requestUser(ctx context.Context) (authenticationv1.UserInfo, error) {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return authenticationv1.UserInfo{}, fmt.Errorf("admission request not found in context: %w", err)
+	}
+	return req.UserInfo, nil
+}
+
+// isSubResource reports whether the request being validated targets the given subresource.
+func isSubResource(ctx context.Context, subResource string) bool {
+	req, err := admission.RequestFromContext(ctx)
+	return err == nil && req.SubResource == subResource
+}
+
+func (p RecordPolicy) isServiceAccount(user authenticationv1.UserInfo) bool {
+	return p.ServiceAccount != "" && user.Username == p.ServiceAccount
+}
+
+func (p RecordPolicy) isBreakGlass(user authenticationv1.UserInfo) bool {
+	return p.BreakGlassGroup != "" && slices.Contains(user.Groups, p.BreakGlassGroup)
+}
+
+// requester returns the authenticated user to stamp on a new record. It returns false when
+// the record is created by the controller manager, which records changes on behalf of the
+// users it observed or audited and sets their identity itself.
+func (p RecordPolicy) requester(ctx context.Context) (authenticationv1.UserInfo, bool, error) {
+	user, err := requestUser(ctx)
+	if err != nil {
+		return user, false, err
+	}
+	return user, !p.isServiceAccount(user), nil
+}
+
+// identity points at the identity fields of a record's spec.
+type identity struct {
+	AppliedBy *string
+	Groups    *[]string
+	UID       *string
+	ClaimedBy *string
+}
+
+// stampIdentity replaces the self-reported identity of a new record with the authenticated
+// user of the request, keeping the reported name as the claimed one when it differs.
+func (p RecordPolicy) stampIdentity(ctx context.Context, id *identity) error {
+	user, stamp, err := p.requester(ctx)
+	if err != nil || !stamp {
+		return err
+	}
+	if *id.AppliedBy != user.Username {
+		*id.ClaimedBy = *id.AppliedBy
+	} else {
+		*id.ClaimedBy = ""
+	}
+	*id.AppliedBy = user.Username
+	*id.Groups = user.Groups
+	*id.UID = user.UID
+	return nil
+}
+
+// requiresMessage reports whether changes applied to a namespace must carry a message.
+func (p RecordPolicy) requiresMessage(namespace string) bool {
+	for _, pattern := range p.MessageNamespaces {
+		if matched, err := path.Match(pattern, namespace); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMessage requires a message on changes applied to the namespaces that need one.
+func (p RecordPolicy) checkMessage(namespace, message string, fldPath *field.Path) field.ErrorList {
+	if strings.TrimSpace(message) != "" || !p.requiresMessage(namespace) {
+		return nil
+	}
+	return field.ErrorList{field.Required(fldPath,
+		fmt.Sprintf("changes applied to namespace %s must explain why they are made", namespace))}
+}
+
+// isCreator reports whether user is the one stamped on a record when it was created.
+func isCreator(user authenticationv1.UserInfo, appliedBy, appliedByUID string) bool {
+	return user.Username == appliedBy && user.UID == appliedByUID
+}
+
+// checkStatusUpdate allows status writes from the controller manager. The user who created
+// a record, as stamped in AppliedBy and AppliedByUID, may also populate a status that has
+// not been recorded yet, as the plugin does right after creation.
+func (p RecordPolicy) checkStatusUpdate(ctx context.Context, kind, name string, recorded bool, appliedBy, appliedByUID string) error {
+	user, err := requestUser(ctx)
+	if err != nil {
+		return err
+	}
+	if p.isServiceAccount(user) {
+		return nil
+	}
+	if !recorded {
+		if isCreator(user, appliedBy, appliedByUID) {
+			return nil
+		}
+		return fmt.Errorf("%s %s can only be recorded by the user who created it or %s", kind, name, p.ServiceAccount)
+	}
+	return fmt.Errorf("%s %s is immutable: its status can only be updated by %s", kind, name, p.ServiceAccount)
+}
+
+// checkMetadataUpdate restricts changes to the labels, owner references and finalizers of a
+// recorded record, which tie it to the rest of the record and keep it from being deleted,
+// to the controller manager, which links snapshots to their history. Finalizers and owner
+// references may also be removed by the break-glass group and by the Kubernetes controllers
+// that carry out already authorized deletions.
+func (p RecordPolicy) checkMetadataUpdate(ctx context.Context, kind, name string, oldObj, newObj metav1.Object) error {
+	labelsChanged := !equality.Semantic.DeepEqual(oldObj.GetLabels(), newObj.GetLabels())
+	ownersChanged := !equality.Semantic.DeepEqual(oldObj.GetOwnerReferences(), newObj.GetOwnerReferences())
+	finalizersChanged := !equality.Semantic.DeepEqual(oldObj.GetFinalizers(), newObj.GetFinalizers())
+	if !labelsChanged && !ownersChanged && !finalizersChanged {
+		return nil
+	}
+	user, err := requestUser(ctx)
+	if err != nil {
+		return err
+	}
+	if p.isServiceAccount(user) {
+		return nil
+	}
+	removedOnly := !labelsChanged && isSubset(newObj.GetFinalizers(), oldObj.GetFinalizers()) &&
+		isSubset(newObj.GetOwnerReferences(), oldObj.GetOwnerReferences())
+	if removedOnly && (p.isBreakGlass(user) || slices.Contains(systemDeleters, user.Username)) {
+		return nil
+	}
+	return fmt.Errorf("%s %s is immutable: its labels, owner references and finalizers can only be changed by %s",
+		kind, name, p.ServiceAccount)
+}
+
+// isSubset reports whether every item of items is in all.
+func isSubset[T any](items, all []T) bool {
+	for _, item := range items {
+		if !slices.ContainsFunc(all, func(other T) bool { return equality.Semantic.DeepEqual(item, other) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkRewrap allows the data keys of encrypted records to be replaced by the controller
+// manager and the break-glass group only, as rotate-keys does. The webhook cannot decrypt a
+// record to check that a new key opens the same content, and the hash chain does not cover
+// data keys, so a key swapped by anyone else could not be told apart from a rotation.
+func (p RecordPolicy) checkRewrap(ctx context.Context, kind, name string) error {
+	user, err := requestUser(ctx)
+	if err != nil {
+		return err
+	}
+	if p.isServiceAccount(user) || p.isBreakGlass(user) {
+		return nil
+	}
+	return fmt.Errorf("%s %s is immutable: its data keys can only be replaced by %s or members of the %s group",
+		kind, name, p.ServiceAccount, p.BreakGlassGroup)
+}
+
+// checkDelete allows deletion by the controller manager, the break-glass group and the
+// Kubernetes controllers that carry out already authorized deletions.
+func (p RecordPolicy) checkDelete(ctx context.Context, kind, name string) error {
+	user, err := requestUser(ctx)
+	if err != nil {
+		return err
+	}
+	if p.isServiceAccount(user) || p.isBreakGlass(user) || slices.Contains(systemDeleters, user.Username) {
+		return nil
+	}
+	return fmt.Errorf("%s %s is immutable: it can only be deleted by %s or members of the %s group",
+		kind, name, p.ServiceAccount, p.BreakGlassGroup)
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+	"github.com/yu-kod/kronoform/internal/payload"
+)
+
+// MaxManifestsSize is the largest Manifests value accepted on snapshots and histories. Objects
+// are stored in etcd, which rejects requests larger than 1.5MiB, so this leaves room for the
+// rest of the object.
+const MaxManifestsSize = 1 << 20
+
+// validateManifests checks manifests as stored in a record: the stored data must fit within
+// MaxManifestsSize and decode to well-formed multi-document YAML whose documents are
+// Kubernetes objects. Manifests stored in chunks are written after the record is created,
+// so only the record side is checked for them, and encrypted manifests cannot be read, so
+// only their size is checked.
+func validateManifests(data, encoding string, chunks int32, path *field.Path) field.ErrorList {
+	if chunks > 0 {
+		if data != "" {
+			return field.ErrorList{field.Invalid(path, "<omitted>", "must be empty when the manifests are stored in chunks")}
+		}
+		return nil
+	}
+	if len(data) > MaxManifestsSize {
+		tooLong := field.TooLong(path, "", MaxManifestsSize)
+		tooLong.Detail = fmt.Sprintf("manifests are %s, which exceeds the %s limit; use a kubectl-kronoform version "+
+			"that compresses large manifests or split the apply into smaller files",
+			formatSize(len(data)), formatSize(MaxManifestsSize))
+		return field.ErrorList{tooLong}
+	}
+	if encoding == historyv1alpha1.EncodingEncrypted {
+		return nil
+	}
+	manifests, err := payload.Decode(data, encoding)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, "<omitted>", fmt.Sprintf("cannot decode %s manifests: %v", encoding, err))}
+	}
+
+	var errs field.ErrorList
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifests), 4096)
+	for index := 0; ; index++ {
+		var document map[string]interface{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, field.Invalid(path, fmt.Sprintf("document %d", index), fmt.Sprintf("malformed YAML: %v", err)))
+			break
+		}
+		if document == nil {
+			// Empty documents, e.g. a trailing "---", are skipped by kubectl too
+			continue
+		}
+		if apiVersion, _ := document["apiVersion"].(string); apiVersion == "" {
+			errs = append(errs, field.Invalid(path, fmt.Sprintf("document %d", index), "apiVersion is required"))
+		} else if _, err := schema.ParseGroupVersion(apiVersion); err != nil {
+			errs = append(errs, field.Invalid(path, fmt.Sprintf("document %d", index), fmt.Sprintf("invalid apiVersion: %v", err)))
+		}
+		if kind, _ := document["kind"].(string); kind == "" {
+			errs = append(errs, field.Invalid(path, fmt.Sprintf("document %d", index), "kind is required"))
+		}
+	}
+	return errs
+}
+
+// validateManifestsKey checks that encrypted manifests carry the data key they are
+// encrypted with, and that other manifests carry none.
+func validateManifestsKey(encoding string, key *historyv1alpha1.DataKey, path *field.Path) field.ErrorList {
+	switch {
+	case encoding == historyv1alpha1.EncodingEncrypted && key == nil:
+		return field.ErrorList{field.Required(path, "encrypted manifests require their data key")}
+	case encoding != historyv1alpha1.EncodingEncrypted && key != nil:
+		return field.ErrorList{field.Forbidden(path, "only encrypted manifests have a data key")}
+	}
+	return nil
+}
+
+// validateNamespaceName checks that a namespace reference is a valid DNS label, if set.
+func validateNamespaceName(namespace string, path *field.Path) field.ErrorList {
+	if namespace == "" {
+		return nil
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Label(namespace) {
+		errs = append(errs, field.Invalid(path, namespace, msg))
+	}
+	return errs
+}
+
+// validateSnapshotRef checks that the referenced snapshot exists in the namespace of the
+// history. Histories recorded without a snapshot, such as observed ones, have no reference.
+func validateSnapshotRef(ctx context.Context, reader client.Reader, namespace, name string, path *field.Path) field.ErrorList {
+	if name == "" || reader == nil {
+		return nil
+	}
+	snapshot := &historyv1alpha1.KronoformSnapshot{}
+	err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, snapshot)
+	if apierrors.IsNotFound(err) {
+		return field.ErrorList{field.NotFound(path, name)}
+	}
+	if err != nil {
+		return field.ErrorList{field.InternalError(path, fmt.Errorf("failed to get snapshot: %w", err))}
+	}
+	return nil
+}
+
+// storedManifests is how the manifests of a record are stored.
+type storedManifests struct {
+	Data     string
+	Encoding string
+	Chunks   int32
+}
+
+// reencoded reports whether the manifests of a record were only re-encoded, as the migrate
+// command does, and still decode to the same content. Encrypted manifests cannot be
+// decrypted here, so their data must stay as is; only their data key may change, as the
+// rotate-keys command encrypts it again.
+func reencoded(ctx context.Context, reader client.Reader, obj client.Object, before, after storedManifests) bool {
+	if before.Encoding == historyv1alpha1.EncodingEncrypted || after.Encoding == historyv1alpha1.EncodingEncrypted {
+		return before == after
+	}
+	if before == after || (reader == nil && (before.Chunks > 0 || after.Chunks > 0)) {
+		return false
+	}
+	oldContent, err := payload.Unpack(ctx, reader, obj, before.Data, before.Encoding, before.Chunks)
+	if err != nil {
+		return false
+	}
+	newContent, err := payload.Unpack(ctx, reader, obj, after.Data, after.Encoding, after.Chunks)
+	return err == nil && oldContent == newContent
+}
+
+// invalid turns field errors into the Invalid API error returned to the client.
+func invalid(kind, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(historyv1alpha1.GroupVersion.WithKind(kind).GroupKind(), name, errs)
+}
+
+func formatSize(size int) string {
+	return resource.NewQuantity(int64(size), resource.BinarySI).String()
+}
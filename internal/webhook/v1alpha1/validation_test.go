@@ -0,0 +1,220 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	historyv1alpha1 "github.com/yu-kod/kronoform/api/v1alpha1"
+)
+
+const validManifests = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+`
+
+var _ = Describe("Record validation", func() {
+	DescribeTable("validating manifests",
+		func(manifests string, errorType field.ErrorType, detail string) {
+			errs := validateManifests(manifests, historyv1alpha1.EncodingPlain, 0, field.NewPath("spec", "manifests"))
+			if errorType == "" {
+				Expect(errs).To(BeEmpty())
+				return
+			}
+			Expect(errs).NotTo(BeEmpty())
+			Expect(errs[0].Type).To(Equal(errorType))
+			Expect(errs[0].Field).To(Equal("spec.manifests"))
+			Expect(errs[0].Detail).To(ContainSubstring(detail))
+		},
+		Entry("multi-document YAML", validManifests, field.ErrorType(""), ""),
+		Entry("JSON", `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings"}}`, field.ErrorType(""), ""),
+		Entry("empty documents only", "---\n---\n", field.ErrorType(""), ""),
+		Entry("malformed YAML", "apiVersion: v1\nkind: [ConfigMap\n", field.ErrorTypeInvalid, "malformed YAML"),
+		Entry("missing kind", "apiVersion: v1\nmetadata:\n  name: settings\n", field.ErrorTypeInvalid, "kind is required"),
+		Entry("missing apiVersion in a later document", validManifests+"kind: Service\n",
+			field.ErrorTypeInvalid, "apiVersion is required"),
+		Entry("invalid apiVersion", "apiVersion: a/b/c\nkind: ConfigMap\n", field.ErrorTypeInvalid, "invalid apiVersion"),
+		Entry("oversized manifests", "apiVersion: v1\nkind: ConfigMap\ndata:\n  blob: "+strings.Repeat("x", MaxManifestsSize)+"\n",
+			field.ErrorTypeTooLong, "exceeds the 1Mi limit"),
+	)
+
+	DescribeTable("validating encoded manifests",
+		func(data, encoding string, chunks int32, valid bool) {
+			errs := validateManifests(data, encoding, chunks, field.NewPath("spec", "manifests"))
+			Expect(errs.ToAggregate() == nil).To(Equal(valid))
+		},
+		Entry("compressed manifests", mustEncode(validManifests), historyv1alpha1.EncodingGzipBase64, int32(0), true),
+		Entry("compressed malformed manifests", mustEncode("kind: [x"), historyv1alpha1.EncodingGzipBase64, int32(0), false),
+		Entry("corrupt compressed data", "bm90IGd6aXA=", historyv1alpha1.EncodingGzipBase64, int32(0), false),
+		Entry("unknown encoding", validManifests, "zstd", int32(0), false),
+		Entry("chunked manifests", "", historyv1alpha1.EncodingGzipBase64, int32(3), true),
+		Entry("chunked manifests with inline data", validManifests, historyv1alpha1.EncodingGzipBase64, int32(3), false),
+		Entry("encrypted manifests", "c2VhbGVk", historyv1alpha1.EncodingEncrypted, int32(0), true),
+	)
+
+	DescribeTable("validating manifests keys",
+		func(encoding string, key *historyv1alpha1.DataKey, valid bool) {
+			errs := validateManifestsKey(encoding, key, field.NewPath("spec", "manifestsKey"))
+			Expect(errs.ToAggregate() == nil).To(Equal(valid))
+		},
+		Entry("plain manifests", historyv1alpha1.EncodingPlain, nil, true),
+		Entry("encrypted manifests", historyv1alpha1.EncodingEncrypted, &historyv1alpha1.DataKey{KeyID: "a", Key: "b"}, true),
+		Entry("encrypted manifests without a key", historyv1alpha1.EncodingEncrypted, nil, false),
+		Entry("compressed manifests with a key", historyv1alpha1.EncodingGzipBase64, &historyv1alpha1.DataKey{KeyID: "a", Key: "b"}, false),
+	)
+
+	DescribeTable("validating namespace names",
+		func(namespace string, valid bool) {
+			errs := validateNamespaceName(namespace, field.NewPath("spec", "targetNamespace"))
+			Expect(errs.ToAggregate() == nil).To(Equal(valid))
+		},
+		Entry("empty", "", true),
+		Entry("DNS label", "team-a", true),
+		Entry("uppercase", "Team-A", false),
+		Entry("dots", "team.a", false),
+		Entry("too long", strings.Repeat("a", 64), false),
+	)
+
+	Context("When creating KronoformSnapshot and KronoformHistory under Validating Webhook", func() {
+		var (
+			snapshotValidator KronoformSnapshotCustomValidator
+			historyValidator  KronoformHistoryCustomValidator
+		)
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(historyv1alpha1.AddToScheme(scheme)).To(Succeed())
+			reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&historyv1alpha1.KronoformSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"},
+			}).Build()
+			snapshotValidator = KronoformSnapshotCustomValidator{Reader: reader}
+			historyValidator = KronoformHistoryCustomValidator{Reader: reader}
+		})
+
+		DescribeTable("snapshots",
+			func(spec historyv1alpha1.KronoformSnapshotSpec, path string) {
+				snapshot := &historyv1alpha1.KronoformSnapshot{
+					ObjectMeta: metav1.ObjectMeta{Name: "snapshot", Namespace: "default"},
+					Spec:       spec,
+				}
+				_, err := snapshotValidator.ValidateCreate(context.Background(), snapshot)
+				if path == "" {
+					Expect(err).NotTo(HaveOccurred())
+					return
+				}
+				Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring(path))
+			},
+			Entry("valid", historyv1alpha1.KronoformSnapshotSpec{Manifests: validManifests, TargetNamespace: "prod"}, ""),
+			Entry("malformed manifests", historyv1alpha1.KronoformSnapshotSpec{Manifests: "kind: [x"}, "spec.manifests"),
+			Entry("invalid target namespace", historyv1alpha1.KronoformSnapshotSpec{
+				Manifests: validManifests, TargetNamespace: "Prod_1",
+			}, "spec.targetNamespace"),
+		)
+
+		DescribeTable("histories",
+			func(spec historyv1alpha1.KronoformHistorySpec, path string) {
+				history := &historyv1alpha1.KronoformHistory{
+					ObjectMeta: metav1.ObjectMeta{Name: "history", Namespace: "default"},
+					Spec:       spec,
+				}
+				_, err := historyValidator.ValidateCreate(context.Background(), history)
+				if path == "" {
+					Expect(err).NotTo(HaveOccurred())
+					return
+				}
+				Expect(apierrors.IsInvalid(err)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring(path))
+			},
+			Entry("existing snapshot", historyv1alpha1.KronoformHistorySpec{Manifests: validManifests, SnapshotRef: "existing"}, ""),
+			Entry("observed without snapshot", historyv1alpha1.KronoformHistorySpec{
+				Manifests: validManifests, Source: historyv1alpha1.HistorySourceObserved,
+			}, ""),
+			Entry("missing snapshot", historyv1alpha1.KronoformHistorySpec{Manifests: validManifests, SnapshotRef: "missing"},
+				"spec.snapshotRef"),
+			Entry("malformed manifests", historyv1alpha1.KronoformHistorySpec{Manifests: "kind: [x", SnapshotRef: "existing"},
+				"spec.manifests"),
+		)
+	})
+
+	Context("When creating records through the API server", func() {
+		It("Should reject an invalid snapshot", func() {
+			snapshot := &historyv1alpha1.KronoformSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: "invalid-snapshot", Namespace: "default"},
+				Spec: historyv1alpha1.KronoformSnapshotSpec{
+					Manifests:       "kind: [ConfigMap",
+					TargetNamespace: "Not_A_Label",
+				},
+			}
+			err := k8sClient.Create(ctx, snapshot)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.manifests"))
+			Expect(err.Error()).To(ContainSubstring("spec.targetNamespace"))
+		})
+
+		It("Should reject a history referencing a missing snapshot and default valid ones", func() {
+			history := &historyv1alpha1.KronoformHistory{
+				ObjectMeta: metav1.ObjectMeta{Name: "dangling-history", Namespace: "default"},
+				Spec:       historyv1alpha1.KronoformHistorySpec{Manifests: validManifests, SnapshotRef: "missing"},
+			}
+			Expect(apierrors.IsInvalid(k8sClient.Create(ctx, history))).To(BeTrue())
+
+			snapshot := &historyv1alpha1.KronoformSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: "valid-snapshot", Namespace: "default"},
+				Spec:       historyv1alpha1.KronoformSnapshotSpec{Manifests: validManifests},
+			}
+			Expect(k8sClient.Create(ctx, snapshot)).To(Succeed())
+			Expect(snapshot.Spec.Description).NotTo(BeEmpty())
+			Expect(snapshot.Spec.AppliedBy).NotTo(BeEmpty())
+
+			history.Spec.SnapshotRef = snapshot.Name
+			Expect(k8sClient.Create(ctx, history)).To(Succeed())
+			Expect(history.Spec.Description).To(HavePrefix("Applied by "))
+
+			Expect(k8sClient.Delete(ctx, history)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, snapshot)).To(Succeed())
+		})
+	})
+})
+
+// mustEncode compresses content regardless of its size.
+func mustEncode(content string) string {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, _ = writer.Write([]byte(content))
+	_ = writer.Close()
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
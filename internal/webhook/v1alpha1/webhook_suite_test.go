@@ -112,6 +112,20 @@ var _ = BeforeSuite(func() {
 	err = SetupKronoformWebhookWithManager(mgr)
 	Expect(err).NotTo(HaveOccurred())
 
+	// The test client authenticates as a member of system:masters
+	testPolicy := RecordPolicy{ServiceAccount: DefaultServiceAccount, BreakGlassGroup: "system:masters"}
+	err = SetupKronoformHistoryWebhookWithManager(mgr, testPolicy)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = SetupKronoformSnapshotWebhookWithManager(mgr, testPolicy)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = SetupKronoformChunkWebhookWithManager(mgr, testPolicy)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = SetupAuditWebhookWithManager(mgr, &AuditRecorder{Client: mgr.GetClient(), HistoryNamespace: "default"})
+	Expect(err).NotTo(HaveOccurred())
+
 	// +kubebuilder:scaffold:webhook
 
 	go func() {